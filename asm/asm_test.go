@@ -1,16 +1,39 @@
 package asm
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 func TestParseFile(t *testing.T) {
 	golden := []struct {
 		path string
 	}{
+		{path: "testdata/const_poison.ll"},
+		{path: "testdata/inst_atomic.ll"},
 		{path: "testdata/inst_binary.ll"},
+		{path: "testdata/inst_unary.ll"},
 		{path: "testdata/inst_bitwise.ll"},
+		{path: "testdata/legacy.ll"},
+		{path: "testdata/metadata_di.ll"},
+		{path: "testdata/opaque_ptr.ll"},
+		{path: "testdata/param_attr.ll"},
+		{path: "testdata/summary.ll"},
+		{path: "testdata/term_callbr.ll"},
+		{path: "testdata/types_float.ll"},
+		{path: "testdata/types_target.ll"},
+		{path: "testdata/vector_scalable.ll"},
 	}
 	for _, g := range golden {
 		_, err := ParseFile(g.path)
@@ -26,7 +49,18 @@ func TestTranslate(t *testing.T) {
 		path string
 	}{
 		{path: "testdata/inst_binary.ll"},
+		{path: "testdata/inst_unary.ll"},
 		{path: "testdata/inst_bitwise.ll"},
+		{path: "testdata/inst_atomic.ll"},
+		{path: "testdata/opaque_ptr.ll"},
+		// callbr and freeze.
+		{path: "testdata/term_callbr.ll"},
+		// bfloat, x86_amx and hexadecimal floating-point constants.
+		{path: "testdata/types_float.ll"},
+		// Target extension types.
+		{path: "testdata/types_target.ll"},
+		// poison, dso_local_equivalent and no_cfi constants.
+		{path: "testdata/const_poison.ll"},
 	}
 	for _, g := range golden {
 		m, err := ParseFile(g.path)
@@ -51,3 +85,1458 @@ func TestTranslate(t *testing.T) {
 		}
 	}
 }
+
+func TestParseModule(t *testing.T) {
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/inst_binary.ll"},
+		{path: "testdata/inst_bitwise.ll"},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.path)
+		if err != nil {
+			t.Errorf("unable to read %q; %v", g.path, err)
+			continue
+		}
+		want := string(buf)
+		m1, err := ParseModule(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q into IR; %v", g.path, err)
+			continue
+		}
+		m2, err := ParseModuleFromReader(g.path, bytes.NewReader(buf))
+		if err != nil {
+			t.Errorf("unable to parse %q into IR from reader; %v", g.path, err)
+			continue
+		}
+		m3, err := ParseModuleFromBytes(g.path, buf)
+		if err != nil {
+			t.Errorf("unable to parse %q into IR from bytes; %v", g.path, err)
+			continue
+		}
+		for _, got := range []string{m1.Def(), m2.Def(), m3.Def()} {
+			if want != got {
+				t.Errorf("module mismatch; expected `%s`, got `%s`", want, got)
+			}
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "i32", want: "i32"},
+		{in: "{ i32, [4 x i8]* }", want: "{ i32, [4 x i8]* }"},
+		{in: "<2 x double>", want: "<2 x double>"},
+		{in: "ptr", want: "ptr"},
+		{in: "<vscale x 4 x i32>", want: "<vscale x 4 x i32>"},
+		{in: "{ i32, ptr addrspace(1) }", want: "{ i32, ptr addrspace(1) }"},
+		{in: "bfloat", want: "bfloat"},
+		{in: "x86_amx", want: "x86_amx"},
+		{in: `target("spirv.Image", void, 1, 0)`, want: `target("spirv.Image", void, 1, 0)`},
+	}
+	for _, g := range golden {
+		typ, err := ParseType(g.in, nil)
+		if err != nil {
+			t.Errorf("unable to parse type %q; %v", g.in, err)
+			continue
+		}
+		got := typ.String()
+		if g.want != got {
+			t.Errorf("type mismatch; expected `%s`, got `%s`", g.want, got)
+			continue
+		}
+	}
+}
+
+func TestParseConstant(t *testing.T) {
+	old, err := Parse("a.ll", "@s = constant [4 x i8] c\"foo\\00\"\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, err := Translate(old)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	c, err := ParseConstant("i32 42", nil)
+	if err != nil {
+		t.Fatalf("unable to parse constant; %v", err)
+	}
+	if i, ok := c.(*ir.ConstInt); !ok || i.X.Int64() != 42 || !i.Typ.Equal(types.I32) {
+		t.Errorf("constant mismatch; expected i32 42, got %v", c)
+	}
+	c, err = ParseConstant("i8* getelementptr ([4 x i8], [4 x i8]* @s, i64 0, i64 0)", m)
+	if err != nil {
+		t.Fatalf("unable to parse constant; %v", err)
+	}
+	gep, ok := c.(*ir.ExprGetElementPtr)
+	if !ok {
+		t.Fatalf("constant type mismatch; expected *ir.ExprGetElementPtr, got %T", c)
+	}
+	if gep.Src != m.Globals[0] {
+		t.Errorf("source mismatch of getelementptr; expected %q, got %v", m.Globals[0].GlobalName, gep.Src)
+	}
+	// Global identifiers not defined by the module.
+	if _, err := ParseConstant("i32* @x", m); err == nil {
+		t.Errorf("expected error when parsing constant referring to undefined global")
+	}
+}
+
+func TestParseFunction(t *testing.T) {
+	old, err := Parse("a.ll", "declare i32 @g(i32)\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, err := Translate(old)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	const content = `define i32 @f(i32 %x) {
+	%y = add i32 %x, 1
+	%z = call i32 @g(i32 %y)
+	ret i32 %z
+}
+`
+	f, err := ParseFunction(content, m)
+	if err != nil {
+		t.Fatalf("unable to parse function; %v", err)
+	}
+	if f.GlobalName != "f" || len(f.Blocks) != 1 || len(f.Blocks[0].Insts) != 2 {
+		t.Fatalf("function mismatch; expected @f with one basic block of two instructions, got %v", f)
+	}
+	if len(m.Funcs) != 1 {
+		t.Errorf("number of functions mismatch; expected function not to be added to module, got %d functions", len(m.Funcs))
+	}
+	add, ok := f.Blocks[0].Insts[0].(*ir.InstAdd)
+	if !ok || add.X != f.Params[0] {
+		t.Errorf("instruction mismatch; expected add of %%x, got %v", f.Blocks[0].Insts[0])
+	}
+	call, ok := f.Blocks[0].Insts[1].(*ir.InstCall)
+	if !ok || call.Callee != m.Funcs[0] || call.Args[0] != add {
+		t.Errorf("instruction mismatch; expected call of @g with %%y, got %v", f.Blocks[0].Insts[1])
+	}
+	// Undefined local identifiers.
+	if _, err := ParseFunction("define void @h() {\n\tret void\nfoo:\n\tbr label %bar\n}\n", m); err == nil {
+		t.Errorf("expected error when parsing function referring to undefined basic block")
+	}
+	// Opaque pointer type first occurring after alloca instruction.
+	f, err = ParseFunction("define void @k() {\n\t%a = alloca i32\n\tstore i32 1, ptr %a\n\tret void\n}\n", m)
+	if err != nil {
+		t.Fatalf("unable to parse function; %v", err)
+	}
+	if got := f.Blocks[0].Insts[0].(*ir.InstAlloca).Type().String(); got != "ptr" {
+		t.Errorf("alloca type mismatch; expected ptr, got %s", got)
+	}
+	if !isOpaquePointer(f.Typ) {
+		t.Errorf("function type mismatch; expected opaque pointer, got %v", f.Typ)
+	}
+}
+
+func TestParseInstruction(t *testing.T) {
+	old, err := Parse("a.ll", "declare void @g(i32)\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, err := Translate(old)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	const content = `define i32 @f(i32 %x, i32* %p) {
+	%y = add i32 %x, 1
+	ret i32 %y
+}
+`
+	f, err := ParseFunction(content, m)
+	if err != nil {
+		t.Fatalf("unable to parse function; %v", err)
+	}
+	x, p, y := f.Params[0], f.Params[1], f.Blocks[0].Insts[0]
+	// Value instruction.
+	inst, err := ParseInstruction("%z = mul i32 %y, 2", f, m)
+	if err != nil {
+		t.Fatalf("unable to parse instruction; %v", err)
+	}
+	mul, ok := inst.(*ir.InstMul)
+	if !ok {
+		t.Fatalf("instruction type mismatch; expected *ir.InstMul, got %T", inst)
+	}
+	if mul.LocalName != "z" || mul.X != y {
+		t.Errorf("instruction mismatch; expected %%z = mul of %%y, got %v", mul)
+	}
+	if len(f.Blocks[0].Insts) != 1 {
+		t.Errorf("number of instructions mismatch; expected instruction not to be added to function, got %d instructions", len(f.Blocks[0].Insts))
+	}
+	// Non-value instruction.
+	inst, err = ParseInstruction("store i32 %x, i32* %p", f, m)
+	if err != nil {
+		t.Fatalf("unable to parse instruction; %v", err)
+	}
+	if store, ok := inst.(*ir.InstStore); !ok || store.Src != x || store.Dst != p {
+		t.Errorf("instruction mismatch; expected store of %%x to %%p, got %v", inst)
+	}
+	// Global identifiers.
+	inst, err = ParseInstruction("call void @g(i32 %y)", f, m)
+	if err != nil {
+		t.Fatalf("unable to parse instruction; %v", err)
+	}
+	if call, ok := inst.(*ir.InstCall); !ok || call.Callee != m.Funcs[0] {
+		t.Errorf("instruction mismatch; expected call of @g, got %v", inst)
+	}
+	// Constant operands without function.
+	inst, err = ParseInstruction("add i32 1, 2", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to parse instruction; %v", err)
+	}
+	if _, ok := inst.(*ir.InstAdd); !ok {
+		t.Errorf("instruction type mismatch; expected *ir.InstAdd, got %T", inst)
+	}
+	// Alloca instruction of module using opaque pointers.
+	old, err = Parse("b.ll", "declare void @h(ptr)\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	opaque, err := Translate(old)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	inst, err = ParseInstruction("%a = alloca i32", nil, opaque)
+	if err != nil {
+		t.Fatalf("unable to parse instruction; %v", err)
+	}
+	if got := inst.(*ir.InstAlloca).Type().String(); got != "ptr" {
+		t.Errorf("alloca type mismatch; expected ptr, got %s", got)
+	}
+	// Invalid instructions.
+	for _, content := range []string{
+		// Redefinition of local identifier.
+		"%y = add i32 %x, 2",
+		// Undefined local identifier.
+		"%w = add i32 %v, 2",
+		// Undefined global identifier.
+		"call void @h()",
+	} {
+		if _, err := ParseInstruction(content, f, m); err == nil {
+			t.Errorf("expected error when parsing invalid instruction %q", content)
+		}
+	}
+}
+
+func TestTranslateWithSourceMap(t *testing.T) {
+	const path = "testdata/inst_binary.ll"
+	module, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q into AST; %v", path, err)
+	}
+	m, sm, err := TranslateWithSourceMap(module)
+	if err != nil {
+		t.Fatalf("unable to translate %q from AST to IR; %v", path, err)
+	}
+	f := m.Funcs[0]
+	span, ok := sm.Span(f)
+	if !ok {
+		t.Fatalf("unable to locate source span of function %q", f.GlobalName)
+	}
+	if span.Line != 1 {
+		t.Errorf("line mismatch of function %q; expected 1, got %d", f.GlobalName, span.Line)
+	}
+	// fsub double 7.0, 8.0
+	inst := f.Blocks[0].Insts[3]
+	span, ok = sm.Span(inst)
+	if !ok {
+		t.Fatalf("unable to locate source span of instruction %T", inst)
+	}
+	if span.Line != 5 {
+		t.Errorf("line mismatch of instruction %T; expected 5, got %d", inst, span.Line)
+	}
+	// 7.0
+	x := inst.(*ir.InstFSub).X
+	span, ok = sm.Span(x)
+	if !ok {
+		t.Fatalf("unable to locate source span of constant %T", x)
+	}
+	if span.Line != 5 {
+		t.Errorf("line mismatch of constant %T; expected 5, got %d", x, span.Line)
+	}
+}
+
+func TestTranslateWithOptions(t *testing.T) {
+	// Translate into existing module.
+	old1, err := Parse("a.ll", "@x = global i32 42\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, err := Translate(old1)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	old2, err := Parse("b.ll", "@x = external global i32\n@y = global i32* @x\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	if _, err := TranslateWithOptions(old2, &Options{Module: m}); err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	if len(m.Globals) != 2 {
+		t.Fatalf("number of globals mismatch; expected 2, got %d", len(m.Globals))
+	}
+	x, y := m.Globals[0], m.Globals[1]
+	if y.Init != x {
+		t.Errorf("initializer mismatch of %q; expected %q, got %v", y.GlobalName, x.GlobalName, y.Init)
+	}
+	// Translate with resolver of unknown globals.
+	old3, err := Parse("c.ll", "@z = global i32* @x\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m3, err := TranslateWithOptions(old3, &Options{Resolver: moduleResolver{m: m}})
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	if z := m3.Globals[0]; z.Init != x {
+		t.Errorf("initializer mismatch of %q; expected %q, got %v", z.GlobalName, x.GlobalName, z.Init)
+	}
+	// Translate type definitions referring to type definitions of the existing
+	// module and of the resolver.
+	old4, err := Parse("d.ll", "%T = type { i32 }\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m4, err := Translate(old4)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	old5, err := Parse("e.ll", "%U = type %T\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	if _, err := TranslateWithOptions(old5, &Options{Module: m4}); err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	m6, err := TranslateWithOptions(old5, &Options{Resolver: moduleResolver{m: m4}})
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	for _, m := range []*ir.Module{m4, m6} {
+		u := m.TypeDefs[len(m.TypeDefs)-1]
+		if name := typeAlias(u); name != "U" {
+			t.Errorf("type name mismatch; expected %q, got %q", "U", name)
+		}
+		if st, ok := u.(*types.StructType); !ok || len(st.Fields) != 1 {
+			t.Errorf("type definition mismatch of %q; expected { i32 }, got %v", "U", u)
+		}
+	}
+	// Translate with nil options.
+	if _, err := TranslateWithOptions(old1, nil); err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+}
+
+// moduleResolver resolves named types and global identifiers through lookup in
+// an IR module.
+type moduleResolver struct {
+	m *ir.Module
+}
+
+func (r moduleResolver) ResolveType(name string) (types.Type, error) {
+	for _, t := range r.m.TypeDefs {
+		if typeAlias(t) == name {
+			return t, nil
+		}
+	}
+	return nil, errors.Errorf("unable to locate type definition of named type %q", name)
+}
+
+func (r moduleResolver) ResolveGlobal(name string) (ir.Constant, error) {
+	for _, g := range r.m.Globals {
+		if g.GlobalName == name {
+			return g, nil
+		}
+	}
+	for _, f := range r.m.Funcs {
+		if f.GlobalName == name {
+			return f, nil
+		}
+	}
+	return nil, errors.Errorf("unable to locate global identifier %q", name)
+}
+
+func TestTranslateParamAttrs(t *testing.T) {
+	// The named type %S is only referred to by byval parameter attributes, and
+	// is not defined by the translated module.
+	const content = `declare void @g(ptr byval(%S) %x)
+
+define void @f(ptr %p) {
+	call void @g(ptr byval(%S) align 8 "x"="y" %p)
+	ret void
+}
+`
+	old1, err := Parse("a.ll", "%S = type { i32, i64 }\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m1, err := Translate(old1)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	old2, err := Parse("b.ll", content)
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	gen := newGenerator()
+	gen.resolver = moduleResolver{m: m1}
+	m2, err := gen.translate(old2)
+	if err != nil {
+		t.Fatalf("unable to translate from AST to IR; %v", err)
+	}
+	s := m1.TypeDefs[0]
+	if got, ok := gen.ts["S"]; !ok || got != s {
+		t.Errorf("named type mismatch of %q; expected %v, got %v", "S", s, got)
+	}
+	// Function parameter attributes.
+	g := m2.Funcs[0]
+	if attrs := g.Params[0].Attrs; len(attrs) != 1 || attrs[0] != (ir.Byval{Typ: s}) {
+		t.Errorf("parameter attributes mismatch of %q; expected byval(%%S), got %v", g.GlobalName, attrs)
+	}
+	// Call-site parameter attributes.
+	call := m2.Funcs[1].Blocks[0].Insts[0].(*ir.InstCall)
+	arg, ok := call.Args[0].(*ir.Arg)
+	if !ok {
+		t.Fatalf("argument type mismatch; expected *ir.Arg, got %T", call.Args[0])
+	}
+	want := []ir.ParamAttribute{ir.Byval{Typ: s}, ir.Align(8), ir.AttrPair{Key: "x", Value: "y"}}
+	if !reflect.DeepEqual(want, arg.Attrs) {
+		t.Errorf("call-site parameter attributes mismatch; expected %v, got %v", want, arg.Attrs)
+	}
+}
+
+func TestTranslateLegacy(t *testing.T) {
+	const path = "testdata/legacy.ll"
+	// Expected output of the upgraded module.
+	//
+	// NOTE: metadata is not yet translated, and is thus carried through by
+	// MetadataDefs.
+	const wantPath = "testdata/legacy_upgraded.ll"
+	old, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q into AST; %v", path, err)
+	}
+	// Implicit element types are rejected unless the legacy dialect is enabled.
+	if _, err := Translate(old); err == nil {
+		t.Fatalf("expected error when translating legacy %q without legacy option", path)
+	}
+	m, err := TranslateWithOptions(old, &Options{Legacy: true})
+	if err != nil {
+		t.Fatalf("unable to translate %q from AST to IR; %v", path, err)
+	}
+	p := m.Globals[1]
+	gep, ok := p.Init.(*ir.ExprGetElementPtr)
+	if !ok {
+		t.Fatalf("initializer type mismatch of %q; expected *ir.ExprGetElementPtr, got %T", p.GlobalName, p.Init)
+	}
+	if want := types.NewArray(4, types.I8); !gep.ElemType.Equal(want) {
+		t.Errorf("element type mismatch of %q; expected %v, got %v", p.GlobalName, want, gep.ElemType)
+	}
+	insts := m.Funcs[1].Blocks[0].Insts
+	for _, i := range []int{0, 1} {
+		load := insts[i].(*ir.InstLoad)
+		if !load.Typ.Equal(types.I32) {
+			t.Errorf("type mismatch of load %d; expected %v, got %v", i, types.I32, load.Typ)
+		}
+	}
+	if gep := insts[2].(*ir.InstGetElementPtr); !gep.ElemType.Equal(types.I32) || !gep.InBounds {
+		t.Errorf("getelementptr mismatch; expected inbounds with element type %v, got %v", types.I32, gep.ElemType)
+	}
+	call := insts[4].(*ir.InstCall)
+	if !call.Typ.Equal(types.I32) {
+		t.Errorf("call type mismatch; expected %v, got %v", types.I32, call.Typ)
+	}
+	buf, err := ioutil.ReadFile(wantPath)
+	if err != nil {
+		t.Fatalf("unable to read %q; %v", wantPath, err)
+	}
+	want := string(buf)
+	got := m.Def() + "\n" + strings.Join(MetadataDefs(old), "\n") + "\n"
+	if want != got {
+		t.Errorf("module mismatch; expected `%s`, got `%s`", want, got)
+	}
+}
+
+func TestTranslateInvalidAtomicOrdering(t *testing.T) {
+	golden := []struct {
+		// Instruction with invalid atomic ordering.
+		inst string
+	}{
+		// i=0
+		{inst: "%x = load atomic i32, i32* %p release, align 4"},
+		// i=1
+		{inst: "%x = load atomic i32, i32* %p acq_rel, align 4"},
+		// i=2
+		{inst: "store atomic i32 0, i32* %p acquire, align 4"},
+		// i=3
+		{inst: "store atomic i32 0, i32* %p acq_rel, align 4"},
+		// i=4
+		{inst: "fence unordered"},
+		// i=5
+		{inst: "fence monotonic"},
+		// i=6
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 unordered monotonic"},
+		// i=7
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 monotonic unordered"},
+		// i=8
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 seq_cst release"},
+		// i=9
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 seq_cst acq_rel"},
+		// i=10
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 monotonic acquire"},
+		// i=11
+		{inst: "%x = cmpxchg i32* %p, i32 0, i32 1 acquire seq_cst"},
+		// i=12
+		{inst: "%x = atomicrmw add i32* %p, i32 1 unordered"},
+	}
+	for i, g := range golden {
+		content := fmt.Sprintf("define void @f(i32* %%p) {\n\t%s\n\tret void\n}\n", g.inst)
+		old, err := Parse("inst.ll", content)
+		if err != nil {
+			t.Errorf("i=%d: unable to parse %q into AST; %v", i, g.inst, err)
+			continue
+		}
+		if _, err := Translate(old); err == nil {
+			t.Errorf("i=%d: expected error when translating %q", i, g.inst)
+		}
+	}
+}
+
+func TestIsStrongerOrdering(t *testing.T) {
+	golden := []struct {
+		a, b enum.AtomicOrdering
+		want bool
+	}{
+		// i=0
+		{a: enum.AtomicOrderingSeqCst, b: enum.AtomicOrderingAcqRel, want: true},
+		// i=1
+		{a: enum.AtomicOrderingAcquire, b: enum.AtomicOrderingMonotonic, want: true},
+		// i=2
+		{a: enum.AtomicOrderingMonotonic, b: enum.AtomicOrderingAcquire, want: false},
+		// i=3
+		{a: enum.AtomicOrderingAcquire, b: enum.AtomicOrderingRelease, want: false},
+		// i=4
+		{a: enum.AtomicOrderingRelease, b: enum.AtomicOrderingAcquire, want: false},
+		// i=5
+		{a: enum.AtomicOrderingSeqCst, b: enum.AtomicOrderingSeqCst, want: false},
+		// i=6
+		{a: enum.AtomicOrderingUnordered, b: enum.AtomicOrderingNone, want: true},
+	}
+	for i, g := range golden {
+		if got := isStrongerOrdering(g.a, g.b); g.want != got {
+			t.Errorf("i=%d: ordering strength mismatch of %v and %v; expected %v, got %v", i, g.a, g.b, g.want, got)
+		}
+	}
+}
+
+func TestTranslateSummary(t *testing.T) {
+	const path = "testdata/summary.ll"
+	old, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q into AST; %v", path, err)
+	}
+	s, err := TranslateSummary(old)
+	if err != nil {
+		t.Fatalf("unable to translate summary of %q; %v", path, err)
+	}
+	if len(s.Modules) != 1 || s.Modules[0].Path != "summary.bc" {
+		t.Fatalf("module mismatch; expected module %q, got %v", "summary.bc", s.Modules)
+	}
+	if s.Flags != 8 || s.BlockCount != 2 {
+		t.Errorf("flags or block count mismatch; expected 8 and 2, got %d and %d", s.Flags, s.BlockCount)
+	}
+	main := s.GlobalValue("main")
+	if main == nil {
+		t.Fatalf("unable to locate summary of global value %q", "main")
+	}
+	if want := uint64(15822663052811949562); main.GUID != want {
+		t.Errorf("GUID mismatch of %q; expected %d, got %d", "main", want, main.GUID)
+	}
+	f, ok := main.Summaries[0].(*FunctionSummary)
+	if !ok {
+		t.Fatalf("summary type mismatch of %q; expected *asm.FunctionSummary, got %T", "main", main.Summaries[0])
+	}
+	if f.Module != s.Modules[0] || f.Insts != 5 {
+		t.Errorf("function summary mismatch of %q; got module %v and %d instructions", "main", f.Module, f.Insts)
+	}
+	if len(f.Calls) != 1 || f.Calls[0].Callee != s.GlobalValue("h") {
+		t.Errorf("call edge mismatch of %q; expected call to %q, got %v", "main", "h", f.Calls)
+	}
+	if len(f.Refs) != 1 || f.Refs[0].GV != s.GlobalValue("g") || !f.Refs[0].ReadOnly {
+		t.Errorf("reference mismatch of %q; expected read-only reference to %q, got %v", "main", "g", f.Refs)
+	}
+	a, ok := s.GlobalValue("a").Summaries[0].(*AliasSummary)
+	if !ok || a.Aliasee != s.GlobalValue("g") {
+		t.Errorf("aliasee mismatch of %q; expected %q", "a", "g")
+	}
+	typeID := s.TypeID("T")
+	if typeID == nil {
+		t.Fatalf("unable to locate summary of type identifier %q", "T")
+	}
+	if typeID.TypeTestRes.Kind != "single" || len(typeID.WPDResolutions) != 1 || typeID.WPDResolutions[0].SingleImplName != "h" {
+		t.Errorf("type identifier summary mismatch of %q; got %+v", "T", typeID)
+	}
+}
+
+func TestMetadataDI(t *testing.T) {
+	module, err := ParseFile("testdata/metadata_di.ll")
+	if err != nil {
+		t.Fatalf("unable to parse %q into AST; %v", "testdata/metadata_di.ll", err)
+	}
+	golden := []struct {
+		// Type of specialized metadata node.
+		typ string
+		// Source text of fields.
+		fields []string
+	}{
+		// i=0
+		{typ: "*ast.DIArgList", fields: []string{"i32 %a", "i32 %b"}},
+		// i=1
+		{typ: "*ast.DICommonBlock", fields: []string{"scope: !8", "declaration: null", `name: "blk"`, "file: !1", "line: 3"}},
+		// i=2
+		{typ: "*ast.DIStringType", fields: []string{`name: "character(*)!2"`, "stringLength: !12", "stringLengthExpression: !DIExpression()", "size: 32", "align: 8"}},
+		// i=3
+		{typ: "*ast.DIGenericSubrange", fields: []string{"lowerBound: !DIExpression(DW_OP_constu, 1)", "upperBound: !DIExpression(DW_OP_constu, 10)", "stride: !DIExpression(DW_OP_constu, 4)"}},
+		// i=4
+		{typ: "*ast.DIAssignID"},
+	}
+	var typs []string
+	var fields [][]string
+	ast.Inspect(module, func(n ast.LlvmNode) bool {
+		var fs []string
+		switch n := n.(type) {
+		case *ast.DIArgList:
+			for _, field := range n.Fields() {
+				fs = append(fs, field.Text())
+			}
+		case *ast.DIAssignID:
+		case *ast.DICommonBlock:
+			for _, field := range n.Fields() {
+				fs = append(fs, field.Text())
+			}
+		case *ast.DIGenericSubrange:
+			for _, field := range n.Fields() {
+				fs = append(fs, field.Text())
+			}
+		case *ast.DIStringType:
+			for _, field := range n.Fields() {
+				fs = append(fs, field.Text())
+			}
+		default:
+			return true
+		}
+		typs = append(typs, fmt.Sprintf("%T", n))
+		fields = append(fields, fs)
+		return true
+	})
+	if len(typs) != len(golden) {
+		t.Fatalf("number of specialized metadata nodes mismatch; expected %d, got %d", len(golden), len(typs))
+	}
+	for i, g := range golden {
+		if typs[i] != g.typ {
+			t.Errorf("i=%d: specialized metadata node type mismatch; expected %q, got %q", i, g.typ, typs[i])
+			continue
+		}
+		if !reflect.DeepEqual(g.fields, fields[i]) {
+			t.Errorf("i=%d: fields of %s mismatch; expected %q, got %q", i, g.typ, g.fields, fields[i])
+		}
+	}
+	if err := checkMetadata(module); err != nil {
+		t.Errorf("unable to check metadata; %v", err)
+	}
+}
+
+func TestCheckMetadata(t *testing.T) {
+	golden := []struct {
+		src string
+		// Enable the legacy dialect.
+		legacy bool
+		err    bool
+	}{
+		// i=0
+		{src: "!0 = distinct !DIAssignID()\n"},
+		// i=1
+		{src: "!0 = !DIAssignID()\n", err: true},
+		// i=2
+		{src: "!0 = distinct !{!DIAssignID()}\n", err: true},
+		// i=3
+		{src: "!0 = !DICommonBlock(scope: null)\n"},
+		// i=4
+		{src: `!0 = !DICommonBlock(name: "blk", line: 3)` + "\n", err: true},
+		// i=5
+		{src: "!0 = !DIGenericSubrange(count: 10, lowerBound: 1, stride: 4)\n"},
+		// i=6
+		{src: "!0 = !DIGenericSubrange(lowerBound: 1, stride: 4)\n", err: true},
+		// i=7
+		{src: "!0 = !DIGenericSubrange(count: 10, lowerBound: 1, upperBound: 10, stride: 4)\n", err: true},
+		// i=8
+		{src: "!0 = !DIGenericSubrange(upperBound: 10, stride: 4)\n", err: true},
+		// i=9
+		{src: "!0 = !DIGenericSubrange(lowerBound: 1, upperBound: 10)\n", err: true},
+		// i=10
+		{src: "!0 = !DIStringType()\n"},
+		// i=11
+		{src: "!0 = !DIArgList(i32 1)\n", err: true},
+		// i=12
+		{src: "!0 = metadata !{}\n", err: true},
+		// i=13
+		{src: "!0 = !{metadata !\"foo\"}\n", err: true},
+		// i=14
+		{src: "!0 = metadata !{metadata !\"foo\", metadata !{metadata !0}}\n", legacy: true},
+	}
+	for i, g := range golden {
+		module, err := Parse("<stdin>", g.src)
+		if err != nil {
+			t.Errorf("i=%d: unable to parse into AST; %v", i, err)
+			continue
+		}
+		_, err = TranslateWithOptions(module, &Options{Legacy: g.legacy})
+		if g.err {
+			if err == nil {
+				t.Errorf("i=%d: expected error when translating %q", i, g.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("i=%d: unable to translate AST to IR; %v", i, err)
+		}
+	}
+}
+
+func TestMetadataDefs(t *testing.T) {
+	const src = `!llvm.ident = !{!0}
+
+!0 = metadata !{metadata !"foo", metadata !{metadata !0}, null}
+!1 = !{!"bar"}
+`
+	module, err := Parse("<stdin>", src)
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	want := []string{
+		"!llvm.ident = !{!0}",
+		`!0 = !{!"foo", !{!0}, null}`,
+		`!1 = !{!"bar"}`,
+	}
+	got := MetadataDefs(module)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("metadata definitions mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestLinker(t *testing.T) {
+	golden := []struct {
+		srcs []string
+		// Expected number of type definitions, global variables and functions.
+		ntypes, nglobals, nfuncs int
+		// Expected content type and initializer of each global variable; or nil
+		// if not checked.
+		globals []string
+		err     bool
+	}{
+		// Declarations resolved to definitions of other modules, and identical
+		// named types unified.
+		{
+			srcs: []string{
+				"%struct.foo = type { i32 }\n@x = global %struct.foo zeroinitializer\n",
+				"%struct.foo = type { i32 }\n@x = external global %struct.foo\n@y = global %struct.foo* @x\n",
+			},
+			ntypes: 1, nglobals: 2,
+		},
+		// Named types which differ are renamed.
+		{
+			srcs: []string{
+				"%struct.foo = type { i32 }\n",
+				"%struct.foo = type { i64 }\n",
+			},
+			ntypes: 2,
+		},
+		// Weak definitions overridden by strong definitions.
+		{
+			srcs: []string{
+				"@w = weak global i32 1\n",
+				"@w = global i32 2\n",
+			},
+			nglobals: 1, globals: []string{"i32 2"},
+		},
+		// Strong definitions not overridden by weak definitions.
+		{
+			srcs: []string{
+				"@w = global i32 2\n",
+				"@w = weak global i32 1\n",
+			},
+			nglobals: 1, globals: []string{"i32 2"},
+		},
+		// Linkonce definitions overridden by strong definitions.
+		{
+			srcs: []string{
+				"@l = linkonce global i32 1\n",
+				"@l = global i32 2\n",
+			},
+			nglobals: 1, globals: []string{"i32 2"},
+		},
+		// First of several linkonce definitions selected.
+		{
+			srcs: []string{
+				"@l = linkonce_odr global i32 1\n",
+				"@l = linkonce_odr global i32 2\n",
+				"@l = weak_odr global i32 3\n",
+			},
+			nglobals: 1, globals: []string{"i32 1"},
+		},
+		// Linkonce functions overridden by strong definitions.
+		{
+			srcs: []string{
+				"define linkonce i32 @f() {\n\tret i32 1\n}\n",
+				"define i32 @f() {\n\tret i32 2\n}\n",
+			},
+			nfuncs: 1,
+		},
+		// Largest common definition selected.
+		{
+			srcs: []string{
+				"@c = common global i32 0\n",
+				"@c = common global i64 0\n",
+				"@c = common global i16 0\n",
+			},
+			nglobals: 1, globals: []string{"i64 0"},
+		},
+		// First of common definitions of the same size selected.
+		{
+			srcs: []string{
+				"@c = common global float 0.0\n",
+				"@c = common global i32 0\n",
+			},
+			nglobals: 1, globals: []string{"float 0.0"},
+		},
+		// Common definitions overridden by strong definitions.
+		{
+			srcs: []string{
+				"@c = common global i32 0\n",
+				"@c = global i32 5\n",
+			},
+			nglobals: 1, globals: []string{"i32 5"},
+		},
+		// Members of comdat already selected discarded.
+		{
+			srcs: []string{
+				"$c = comdat any\n@c = global i32 1, comdat\n",
+				"$c = comdat any\n@c = global i32 2, comdat\n",
+			},
+			nglobals: 1, globals: []string{"i32 1"},
+		},
+		// Comdat with exactmatch selection kind and identical members.
+		{
+			srcs: []string{
+				"$c = comdat exactmatch\n@c = global i32 1, comdat\n",
+				"$c = comdat exactmatch\n@c = global i32 1, comdat\n",
+			},
+			nglobals: 1, globals: []string{"i32 1"},
+		},
+		// Comdat with largest selection kind.
+		{
+			srcs: []string{
+				"$c = comdat largest\n@c = global [2 x i32] zeroinitializer, comdat\n",
+				"$c = comdat largest\n@c = global [4 x i32] zeroinitializer, comdat\n",
+				"$c = comdat largest\n@c = global [3 x i32] zeroinitializer, comdat\n",
+			},
+			nglobals: 1, globals: []string{"[4 x i32] zeroinitializer"},
+		},
+		// Comdat with samesize selection kind.
+		{
+			srcs: []string{
+				"$c = comdat samesize\n@c = global i32 1, comdat\n",
+				"$c = comdat samesize\n@c = global float 2.0, comdat\n",
+			},
+			nglobals: 1, globals: []string{"i32 1"},
+		},
+		// Internal definitions renamed.
+		{
+			srcs: []string{
+				"@v = internal global i32 1\n",
+				"@v = internal global i32 2\n",
+			},
+			nglobals: 2,
+		},
+		// Duplicate strong definitions.
+		{
+			srcs: []string{
+				"@v = global i32 1\n",
+				"@v = global i32 2\n",
+			},
+			err: true,
+		},
+		// Duplicate strong function definitions.
+		{
+			srcs: []string{
+				"define void @f() {\n\tret void\n}\n",
+				"define void @f() {\n\tret void\n}\n",
+			},
+			err: true,
+		},
+		// Type mismatch.
+		{
+			srcs: []string{
+				"@v = global i32 1\n",
+				"@v = external global i64\n",
+			},
+			err: true,
+		},
+		// Type mismatch of weak definitions.
+		{
+			srcs: []string{
+				"@w = weak global i32 1\n",
+				"@w = weak global i64 1\n",
+			},
+			err: true,
+		},
+		// Function signature mismatch.
+		{
+			srcs: []string{
+				"define void @f() {\n\tret void\n}\n",
+				"declare void @f(i32)\n",
+			},
+			err: true,
+		},
+		// Global variable and function of the same name.
+		{
+			srcs: []string{
+				"@f = global i32 1\n",
+				"declare void @f()\n",
+			},
+			err: true,
+		},
+		// Comdat with noduplicates selection kind.
+		{
+			srcs: []string{
+				"$c = comdat noduplicates\n@c = global i32 1, comdat\n",
+				"$c = comdat noduplicates\n@c = global i32 1, comdat\n",
+			},
+			err: true,
+		},
+		// Comdat with exactmatch selection kind and differing members.
+		{
+			srcs: []string{
+				"$c = comdat exactmatch\n@c = global i32 1, comdat\n",
+				"$c = comdat exactmatch\n@c = global i32 2, comdat\n",
+			},
+			err: true,
+		},
+		// Comdat with samesize selection kind and differing sizes.
+		{
+			srcs: []string{
+				"$c = comdat samesize\n@c = global i32 1, comdat\n",
+				"$c = comdat samesize\n@c = global i64 1, comdat\n",
+			},
+			err: true,
+		},
+		// Comdat selection kind mismatch.
+		{
+			srcs: []string{
+				"$c = comdat any\n@c = global i32 1, comdat\n",
+				"$c = comdat largest\n@c = global i32 1, comdat\n",
+			},
+			err: true,
+		},
+	}
+	for i, g := range golden {
+		l := NewLinker()
+		var err error
+		for j, src := range g.srcs {
+			module, e := Parse(fmt.Sprintf("%d.ll", j), src)
+			if e != nil {
+				t.Fatalf("i=%d: unable to parse into AST; %v", i, e)
+			}
+			if err = l.Add(module); err != nil {
+				break
+			}
+		}
+		if g.err {
+			if err == nil {
+				t.Errorf("i=%d: expected link error, got nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("i=%d: unable to link modules; %v", i, err)
+			continue
+		}
+		m := l.Module()
+		if len(m.TypeDefs) != g.ntypes {
+			t.Errorf("i=%d: number of type definitions mismatch; expected %d, got %d", i, g.ntypes, len(m.TypeDefs))
+		}
+		if len(m.Globals) != g.nglobals {
+			t.Errorf("i=%d: number of global variables mismatch; expected %d, got %d", i, g.nglobals, len(m.Globals))
+		}
+		if len(m.Funcs) != g.nfuncs {
+			t.Errorf("i=%d: number of functions mismatch; expected %d, got %d", i, g.nfuncs, len(m.Funcs))
+		}
+		for j, want := range g.globals {
+			if j >= len(m.Globals) {
+				break
+			}
+			global := m.Globals[j]
+			got := fmt.Sprintf("%s %s", global.ContentType, global.Init.Ident())
+			if want != got {
+				t.Errorf("i=%d: global variable %q mismatch; expected %q, got %q", i, global.GlobalName, want, got)
+			}
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	const src = `define i32 @f(i32 %x) {
+	%y = add i32 %x, 1
+	%z = mul i32 %y, 2
+	ret i32 %z
+}
+`
+	module, err := Parse("<stdin>", src)
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	// Count value instructions; local variable definitions (which are also
+	// instructions) wrap the value instruction and are not counted.
+	n := 0
+	ast.Inspect(module, func(node ast.LlvmNode) bool {
+		if _, ok := node.(ast.ValueInstruction); ok {
+			n++
+		}
+		return true
+	})
+	if n != 2 {
+		t.Errorf("number of value instructions mismatch; expected 2, got %d", n)
+	}
+	// Rewrite global identifiers.
+	got, err := ast.Rewrite(src, module, func(node ast.LlvmNode) (string, bool) {
+		if _, ok := node.(*ast.GlobalIdent); ok {
+			return "@g", true
+		}
+		return "", false
+	})
+	if err != nil {
+		t.Fatalf("unable to rewrite AST; %v", err)
+	}
+	want := strings.Replace(src, "@f", "@g", 1)
+	if got != want {
+		t.Errorf("rewritten source mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	const src = `; Comment.
+source_filename = "foo.c"
+
+@x = global i32 1,
+	align 4
+@y = global i32 2 @z = global i32 3
+
+; Function.
+define i32 @f() {
+	%y = load i32, i32* @x
+	ret i32 %y
+uselistorder i32* @x, { 1, 0 }
+}
+
+uselistorder i32* @x, { 1, 0 }
+!0 = !{!"}"}
+`
+	golden := []struct {
+		// Type of top-level entity.
+		typ string
+		// Line number of top-level entity.
+		line int
+		// Source text preceding the top-level entity.
+		prefix string
+	}{
+		// i=0
+		{typ: "*ast.SourceFilename", line: 2, prefix: "; Comment.\n"},
+		// i=1
+		{typ: "*ast.GlobalDef", line: 4, prefix: "\n"},
+		// i=2
+		{typ: "*ast.GlobalDef", line: 6, prefix: "\n"},
+		// i=3
+		{typ: "*ast.GlobalDef", line: 6, prefix: " "},
+		// i=4
+		{typ: "*ast.FuncDef", line: 9, prefix: "\n; Function.\n"},
+		// i=5
+		{typ: "*ast.UseListOrder", line: 15, prefix: "\n"},
+		// i=6
+		{typ: "*ast.MetadataDef", line: 16, prefix: "\n"},
+	}
+	// Small block sizes split the input within top-level entities, quoted
+	// strings and comments.
+	defer func(blockSize int) { streamBlockSize = blockSize }(streamBlockSize)
+	for _, blockSize := range []int{1 << 20, 1, 7, 16} {
+		streamBlockSize = blockSize
+		var entities []*Entity
+		err := Stream("<stdin>", strings.NewReader(src), func(entity *Entity) error {
+			entities = append(entities, entity)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("block size %d: unable to stream top-level entities; %v", blockSize, err)
+			continue
+		}
+		if len(entities) != len(golden) {
+			t.Errorf("block size %d: number of top-level entities mismatch; expected %d, got %d", blockSize, len(golden), len(entities))
+			continue
+		}
+		for i, g := range golden {
+			entity := entities[i]
+			if typ := fmt.Sprintf("%T", entity.Node); typ != g.typ {
+				t.Errorf("block size %d, i=%d: top-level entity type mismatch; expected %q, got %q", blockSize, i, g.typ, typ)
+			}
+			if entity.Line != g.line {
+				t.Errorf("block size %d, i=%d: top-level entity line mismatch; expected %d, got %d", blockSize, i, g.line, entity.Line)
+			}
+			if !strings.HasSuffix(src[:entity.Offset], g.prefix) {
+				t.Errorf("block size %d, i=%d: top-level entity offset mismatch; expected offset following %q, got %d", blockSize, i, g.prefix, entity.Offset)
+			}
+			// Positions of AST nodes are relative to the window.
+			if got := entity.WindowOffset + entity.Node.LlvmNode().Offset(); got != entity.Offset {
+				t.Errorf("block size %d, i=%d: window offset mismatch; expected %d, got %d", blockSize, i, entity.Offset, got)
+			}
+		}
+	}
+	// Syntax errors are reported after preceding top-level entities.
+	streamBlockSize = 8
+	n := 0
+	err := Stream("<stdin>", strings.NewReader("@x = global i32 1\n@y = global i32 2\n@z = ,\n"), func(entity *Entity) error {
+		n++
+		return nil
+	})
+	if err == nil || n != 2 {
+		t.Errorf("expected syntax error after 2 top-level entities; got %d top-level entities, error %v", n, err)
+	}
+	// Streaming stops at the first error returned by f.
+	n = 0
+	err = Stream("<stdin>", strings.NewReader(src), func(entity *Entity) error {
+		n++
+		return errors.New("stop")
+	})
+	if err == nil || n != 1 {
+		t.Errorf("expected streaming to stop at first error; got %d top-level entities, error %v", n, err)
+	}
+}
+
+func TestTranslateLazy(t *testing.T) {
+	const src = `@p = global i8* blockaddress(@f, %b)
+
+define void @f() {
+	br label %b
+b:
+	ret void
+}
+`
+	module, err := Parse("<stdin>", src)
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, l, err := TranslateLazy(module)
+	if err != nil {
+		t.Fatalf("unable to translate AST to IR; %v", err)
+	}
+	f := m.Funcs[0]
+	if l.IsMaterialized(f) || len(f.Blocks) != 0 {
+		t.Fatalf("function body of %q translated before materialized", f.GlobalName)
+	}
+	if err := l.Materialize(f); err != nil {
+		t.Fatalf("unable to materialize function %q; %v", f.GlobalName, err)
+	}
+	if !l.IsMaterialized(f) || len(f.Blocks) != 2 {
+		t.Fatalf("number of basic blocks mismatch; expected 2, got %d", len(f.Blocks))
+	}
+	// Block addresses refer to basic blocks of the materialized function.
+	c, ok := m.Globals[0].Init.(*ir.ConstBlockAddress)
+	if !ok {
+		t.Fatalf("invalid initializer type; expected *ir.ConstBlockAddress, got %T", m.Globals[0].Init)
+	}
+	if c.Block != f.Blocks[1] {
+		t.Errorf("block address not resolved to basic block %q", f.Blocks[1].LocalName)
+	}
+	// Functions failing to translate remain unmaterialized.
+	module, err = Parse("<stdin>", "define i32 @g() {\n\tret i32 %x\n}\n")
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	m, l, err = TranslateLazy(module)
+	if err != nil {
+		t.Fatalf("unable to translate AST to IR; %v", err)
+	}
+	g := m.Funcs[0]
+	if err := l.Materialize(g); err == nil {
+		t.Fatalf("expected error when materializing function %q referring to undefined local", g.GlobalName)
+	}
+	if l.IsMaterialized(g) || len(g.Blocks) != 0 {
+		t.Errorf("function %q materialized despite failed translation", g.GlobalName)
+	}
+}
+
+func TestParseBitcodeFile(t *testing.T) {
+	golden := []struct {
+		path string
+		// Path of expected LLVM IR assembly output.
+		wantPath string
+	}{
+		{path: "testdata/bitcode.bc", wantPath: "testdata/bitcode.bc.golden"},
+		{path: "testdata/const_poison.bc", wantPath: "testdata/const_poison.bc.golden"},
+		{path: "testdata/inst_atomic.bc", wantPath: "testdata/inst_atomic.bc.golden"},
+		{path: "testdata/opaque_ptr.bc", wantPath: "testdata/opaque_ptr.bc.golden"},
+		{path: "testdata/types_float.bc", wantPath: "testdata/types_float.bc.golden"},
+		{path: "testdata/vector_scalable.bc", wantPath: "testdata/vector_scalable.bc.golden"},
+	}
+	for _, g := range golden {
+		buf, err := ioutil.ReadFile(g.path)
+		if err != nil {
+			t.Errorf("unable to read %q; %v", g.path, err)
+			continue
+		}
+		if !IsBitcode(buf) {
+			t.Errorf("unable to detect %q as LLVM bitcode", g.path)
+			continue
+		}
+		m, err := ParseBitcodeFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse %q into IR; %v", g.path, err)
+			continue
+		}
+		wantBuf, err := ioutil.ReadFile(g.wantPath)
+		if err != nil {
+			t.Errorf("unable to read %q; %v", g.wantPath, err)
+			continue
+		}
+		want := string(wantBuf)
+		got := m.Def()
+		if want != got {
+			t.Errorf("module mismatch; expected `%s`, got `%s`", want, got)
+			continue
+		}
+	}
+}
+
+func TestWriteBitcode(t *testing.T) {
+	golden := []struct {
+		path string
+	}{
+		{path: "testdata/bitcode.bc"},
+		{path: "testdata/const_poison.bc"},
+		{path: "testdata/inst_atomic.bc"},
+		{path: "testdata/opaque_ptr.bc"},
+		{path: "testdata/types_float.bc"},
+		{path: "testdata/vector_scalable.bc"},
+		// Round-trip of LLVM IR assembly through LLVM bitcode.
+		{path: "testdata/bitcode.ll"},
+		{path: "testdata/inst_atomic.ll"},
+		{path: "testdata/inst_binary.ll"},
+		{path: "testdata/opaque_ptr.ll"},
+		{path: "testdata/vector_scalable.ll"},
+	}
+	// The bitcode output is verified by the bitcode reader of LLVM, if llvm-dis
+	// is available.
+	llvmDis, err := exec.LookPath("llvm-dis")
+	if err != nil {
+		t.Logf("unable to locate llvm-dis; skipping verification of bitcode output by LLVM")
+	}
+	for _, g := range golden {
+		var m *ir.Module
+		var err error
+		if strings.HasSuffix(g.path, ".ll") {
+			m, err = ParseModule(g.path)
+		} else {
+			m, err = ParseBitcodeFile(g.path)
+		}
+		if err != nil {
+			t.Errorf("unable to parse %q into IR; %v", g.path, err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := WriteBitcode(buf, m); err != nil {
+			t.Errorf("unable to write %q as LLVM bitcode; %v", g.path, err)
+			continue
+		}
+		if !IsBitcode(buf.Bytes()) {
+			t.Errorf("unable to detect output of %q as LLVM bitcode", g.path)
+			continue
+		}
+		if len(llvmDis) > 0 {
+			cmd := exec.Command(llvmDis, "-o", "-", "-")
+			cmd.Stdin = bytes.NewReader(buf.Bytes())
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("invalid bitcode output of %q, as reported by llvm-dis; %v\n%s", g.path, err, out)
+				continue
+			}
+		}
+		got, err := ParseBitcode(buf.Bytes())
+		if err != nil {
+			t.Errorf("unable to parse bitcode output of %q into IR; %v", g.path, err)
+			continue
+		}
+		if m.Def() != got.Def() {
+			t.Errorf("module mismatch; expected `%s`, got `%s`", m.Def(), got.Def())
+			continue
+		}
+	}
+	// Invalid data layouts are reported as errors.
+	const path = "testdata/inst_atomic.bc"
+	m, err := ParseBitcodeFile(path)
+	if err != nil {
+		t.Fatalf("unable to parse %q into IR; %v", path, err)
+	}
+	m.DataLayout = "e-q"
+	if err := WriteBitcode(ioutil.Discard, m); err == nil {
+		t.Errorf("expected error when writing %q with invalid data layout as LLVM bitcode", path)
+	}
+}
+
+func TestParseEmbeddedFile(t *testing.T) {
+	golden := []struct {
+		path string
+		// Path of expected LLVM IR assembly output of each embedded module, with
+		// the source filename replaced by the object file name.
+		wantPath string
+		// Object file names of embedded modules.
+		objects []string
+		// Command line of embedded modules.
+		cmd []string
+	}{
+		{
+			path:     "testdata/embed.o",
+			wantPath: "testdata/bitcode.bc.golden",
+			objects:  []string{"testdata/embed.o"},
+			cmd:      []string{"-O2", "-fembed-bitcode"},
+		},
+		{
+			// Archive members without embedded bitcode are skipped.
+			path:     "testdata/embed.a",
+			wantPath: "testdata/bitcode.bc.golden",
+			objects:  []string{"testdata/embed.a(embed.o)"},
+			cmd:      []string{"-O2", "-fembed-bitcode"},
+		},
+	}
+	for _, g := range golden {
+		es, err := ParseEmbeddedFile(g.path)
+		if err != nil {
+			t.Errorf("unable to parse embedded bitcode of %q; %v", g.path, err)
+			continue
+		}
+		if len(es) != len(g.objects) {
+			t.Errorf("%q: number of embedded modules mismatch; expected %d, got %d", g.path, len(g.objects), len(es))
+			continue
+		}
+		for i, e := range es {
+			if e.Object != g.objects[i] {
+				t.Errorf("%q: object name mismatch; expected %q, got %q", g.path, g.objects[i], e.Object)
+			}
+			if strings.Join(e.Cmd, " ") != strings.Join(g.cmd, " ") {
+				t.Errorf("%q: command line mismatch; expected %q, got %q", g.path, g.cmd, e.Cmd)
+			}
+			wantBuf, err := ioutil.ReadFile(g.wantPath)
+			if err != nil {
+				t.Errorf("unable to read %q; %v", g.wantPath, err)
+				continue
+			}
+			want := strings.Replace(string(wantBuf), `source_filename = "bitcode.c"`, fmt.Sprintf("source_filename = %q", g.objects[i]), 1)
+			got := e.Module.Def()
+			if want != got {
+				t.Errorf("module mismatch; expected `%s`, got `%s`", want, got)
+			}
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	golden := []struct {
+		path string
+		// Expect an error when writing the module as JSON.
+		wantErr bool
+	}{
+		{path: "testdata/bitcode.bc"},
+		{path: "testdata/bitcode.ll"},
+		{path: "testdata/const_poison.ll"},
+		{path: "testdata/inst_binary.ll"},
+		{path: "testdata/inst_bitwise.ll"},
+		{path: "testdata/json.ll"},
+		{path: "testdata/opaque_ptr.ll"},
+		{path: "testdata/types_float.ll"},
+		{path: "testdata/vector_scalable.ll"},
+		// Instructions not represented by the JSON schema.
+		{path: "testdata/inst_atomic.ll", wantErr: true},
+		// Attributes not represented by the JSON schema.
+		{path: "testdata/param_attr.ll", wantErr: true},
+	}
+	for _, g := range golden {
+		var m *ir.Module
+		var err error
+		if strings.HasSuffix(g.path, ".bc") {
+			m, err = ParseBitcodeFile(g.path)
+		} else {
+			m, err = ParseModule(g.path)
+		}
+		if err != nil {
+			t.Errorf("unable to parse %q into IR; %v", g.path, err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		err = WriteJSON(buf, m)
+		if g.wantErr {
+			if err == nil {
+				t.Errorf("expected error when writing %q as JSON", g.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unable to write %q as JSON; %v", g.path, err)
+			continue
+		}
+		got, err := ParseJSON(buf.Bytes())
+		if err != nil {
+			t.Errorf("unable to parse JSON output of %q into IR; %v", g.path, err)
+			continue
+		}
+		if m.Def() != got.Def() {
+			t.Errorf("module mismatch; expected `%s`, got `%s`", m.Def(), got.Def())
+			continue
+		}
+	}
+}
+
+func TestASTJSON(t *testing.T) {
+	const src = `define i32 @f() {
+	ret i32 42
+}
+`
+	module, err := Parse("<stdin>", src)
+	if err != nil {
+		t.Fatalf("unable to parse into AST; %v", err)
+	}
+	root := ast.NewJSONNode(module)
+	if root.Kind != "Module" {
+		t.Errorf("root kind mismatch; expected %q, got %q", "Module", root.Kind)
+	}
+	if root.Span[0] != 0 || root.Span[1] > len(src) || root.Span[1] <= root.Span[0] {
+		t.Errorf("invalid root span %v of source with length %d", root.Span, len(src))
+	}
+	// Locate global identifier of function.
+	var texts []string
+	var visit func(n *ast.JSONNode)
+	visit = func(n *ast.JSONNode) {
+		if n.Kind == "GlobalIdent" {
+			texts = append(texts, n.Text)
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(root)
+	if len(texts) != 1 || texts[0] != "@f" {
+		t.Errorf("global identifiers mismatch; expected [@f], got %q", texts)
+	}
+}
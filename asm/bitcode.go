@@ -0,0 +1,758 @@
+package asm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// bcMagic is the magic number of LLVM bitcode files.
+var bcMagic = []byte{'B', 'C', 0xC0, 0xDE}
+
+// bcWrapperMagic is the magic number of LLVM bitcode wrapper headers (as used
+// by Darwin), stored in little-endian byte order.
+const bcWrapperMagic = 0x0B17C0DE
+
+// IsBitcode reports whether the given file contents is an LLVM bitcode file,
+// based on its magic number.
+func IsBitcode(b []byte) bool {
+	if bytes.HasPrefix(b, bcMagic) {
+		return true
+	}
+	return len(b) >= 4 && binary.LittleEndian.Uint32(b) == bcWrapperMagic
+}
+
+// ParseBitcodeFile parses the given LLVM bitcode file into an LLVM IR module.
+func ParseBitcodeFile(path string) (*ir.Module, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	m, err := ParseBitcode(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse bitcode file %q", path)
+	}
+	return m, nil
+}
+
+// ParseBitcode parses the given LLVM bitcode file contents into an LLVM IR
+// module.
+//
+// The bitcode reader produces the same IR module as Translate does for the
+// equivalent LLVM IR assembly; thus, constructs not yet handled by Translate
+// (e.g. metadata, attributes and use-list orders) are skipped.
+func ParseBitcode(buf []byte) (*ir.Module, error) {
+	// Strip bitcode wrapper header.
+	//
+	//    [magic, version, offset, size, cputype] (5 x uint32)
+	if len(buf) >= 4 && binary.LittleEndian.Uint32(buf) == bcWrapperMagic {
+		if len(buf) < 20 {
+			return nil, errors.New("invalid bitcode wrapper header; too short")
+		}
+		offset := binary.LittleEndian.Uint32(buf[8:])
+		size := binary.LittleEndian.Uint32(buf[12:])
+		if uint64(offset)+uint64(size) > uint64(len(buf)) {
+			return nil, errors.Errorf("invalid bitcode wrapper header; bitcode (offset %d, size %d) past end of file (size %d)", offset, size, len(buf))
+		}
+		buf = buf[offset : offset+size]
+	}
+	if !bytes.HasPrefix(buf, bcMagic) {
+		return nil, errors.New("invalid bitcode magic number")
+	}
+	r := newBCReader(buf[len(bcMagic):])
+	return r.read()
+}
+
+// bcReader keeps track of the type table, value table and global names when
+// reading LLVM IR modules from bitcode.
+type bcReader struct {
+	// Bitstream reader.
+	r *bitstream.Reader
+	// LLVM IR module being read.
+	m *ir.Module
+	// Indicates whether the module block has been read.
+	hasModule bool
+	// Bitcode version of the module block; version 2 and later store names of
+	// global values in the string table.
+	version uint64
+
+	// Type table; maps from type ID to IR type.
+	types []types.Type
+	// Name of the next named structure type in the type table.
+	structName string
+	// Indicates whether the module uses opaque pointers; set when reading an
+	// OPAQUE_POINTER type record.
+	opaquePointers bool
+
+	// Value table; maps from value ID to value. The module-level values
+	// (global variables, functions and constants) are followed by the values of
+	// the function being read.
+	values []*bcValue
+
+	// Names of global variables and functions stored in the string table.
+	names []bcName
+	// Initializers of global variables, resolved after reading the module block.
+	inits []bcInit
+	// Function definitions, in order of function blocks.
+	defs []*ir.Function
+	// Number of function blocks read.
+	nfuncs int
+	// Block address constants referring to functions not yet read.
+	blockAddrs []bcBlockAddr
+	// Synchronization scopes; maps from synchronization scope ID to name.
+	syncScopes []string
+}
+
+// bcValue is an entry of the value table.
+type bcValue struct {
+	// Value; or nil if not yet created.
+	v value.Value
+	// Type of the value.
+	typ types.Type
+	// Constant record of the value, pending to be created on first use; or nil
+	// if not present.
+	rec *bitstream.Record
+	// Indicates whether the pending constant is being created, to detect cyclic
+	// constants.
+	busy bool
+}
+
+// bcName records the string table location of the name of a global variable
+// or function.
+type bcName struct {
+	// Global variable or function.
+	g ir.Constant
+	// String table offset and size of the name.
+	offset, size uint64
+}
+
+// bcInit records the value ID of the initializer of a global variable.
+type bcInit struct {
+	// Global variable.
+	g *ir.Global
+	// Value ID of initializer.
+	id uint64
+}
+
+// newBCReader returns a new reader of the given bitstream (without the magic
+// number).
+func newBCReader(buf []byte) *bcReader {
+	return &bcReader{
+		r:          bitstream.NewReader(buf),
+		m:          &ir.Module{},
+		syncScopes: bcDefaultSyncScopes,
+	}
+}
+
+// read reads the top-level blocks of the bitstream, and returns the
+// corresponding LLVM IR module.
+func (br *bcReader) read() (*ir.Module, error) {
+	var strtab []byte
+	for {
+		e, err := br.r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if e.Kind != bitstream.EntrySubBlock {
+			return nil, errors.Errorf("invalid top-level bitstream entry; expected subblock, got %v", e.Kind)
+		}
+		switch e.BlockID {
+		case bcModuleBlockID:
+			if br.hasModule {
+				return nil, errors.New("support for multiple modules in bitcode file not yet implemented")
+			}
+			br.hasModule = true
+			if err := br.r.EnterBlock(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if err := br.readModule(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		case bcStrtabBlockID:
+			if err := br.r.EnterBlock(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			blob, err := br.readStrtab()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			strtab = blob
+		default:
+			// IDENTIFICATION and SYMTAB blocks.
+			if err := br.r.SkipBlock(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+	if !br.hasModule {
+		return nil, errors.New("unable to locate module block in bitcode file")
+	}
+	// Assign names of global variables and functions stored in the string
+	// table.
+	for _, name := range br.names {
+		if name.offset+name.size > uint64(len(strtab)) {
+			return nil, errors.Errorf("invalid name (offset %d, size %d) past end of string table (size %d)", name.offset, name.size, len(strtab))
+		}
+		setGlobalName(name.g, string(strtab[name.offset:name.offset+name.size]))
+	}
+	if len(br.blockAddrs) > 0 {
+		c := br.blockAddrs[0].c
+		return nil, errors.Errorf("invalid block address; unable to locate body of function %q", c.Func.GlobalName)
+	}
+	return br.m, nil
+}
+
+// readStrtab reads the string table of the current STRTAB block.
+func (br *bcReader) readStrtab() ([]byte, error) {
+	var blob []byte
+	err := br.readRecords(func(rec *bitstream.Record) error {
+		if rec.Code == bcStrtabBlob {
+			blob = rec.Blob
+		}
+		return nil
+	})
+	return blob, err
+}
+
+// readRecords invokes f for each record of the current block, skipping
+// subblocks.
+func (br *bcReader) readRecords(f func(rec *bitstream.Record) error) error {
+	for {
+		e, err := br.r.Next()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch e.Kind {
+		case bitstream.EntryEndBlock:
+			return nil
+		case bitstream.EntrySubBlock:
+			if err := br.r.SkipBlock(); err != nil {
+				return errors.WithStack(err)
+			}
+		case bitstream.EntryRecord:
+			if err := f(e.Record); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+// === [ Module ] ==============================================================
+
+// readModule reads the current MODULE block.
+func (br *bcReader) readModule() error {
+	for {
+		e, err := br.r.Next()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch e.Kind {
+		case bitstream.EntryEndBlock:
+			return nil
+		case bitstream.EntrySubBlock:
+			if err := br.readModuleSubBlock(e.BlockID); err != nil {
+				return errors.WithStack(err)
+			}
+		case bitstream.EntryRecord:
+			if err := br.readModuleRecord(e.Record); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+// readModuleSubBlock reads the subblock with the given block ID of the MODULE
+// block.
+func (br *bcReader) readModuleSubBlock(id uint64) error {
+	switch id {
+	case bcTypeBlockID:
+		if err := br.r.EnterBlock(); err != nil {
+			return errors.WithStack(err)
+		}
+		return br.readTypes()
+	case bcConstantsBlockID:
+		if err := br.r.EnterBlock(); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := br.readConsts(); err != nil {
+			return errors.WithStack(err)
+		}
+		// Global variable initializers may refer to any module-level constant.
+		return br.resolveInits()
+	case bcFunctionBlockID:
+		if err := br.resolveInits(); err != nil {
+			return errors.WithStack(err)
+		}
+		if br.nfuncs >= len(br.defs) {
+			return errors.New("function block without corresponding function definition")
+		}
+		f := br.defs[br.nfuncs]
+		br.nfuncs++
+		if err := br.r.EnterBlock(); err != nil {
+			return errors.WithStack(err)
+		}
+		return br.readFunc(f)
+	case bcValueSymtabBlockID:
+		if err := br.r.EnterBlock(); err != nil {
+			return errors.WithStack(err)
+		}
+		return br.readModuleVST()
+	case bcSyncScopeNamesBlockID:
+		if err := br.r.EnterBlock(); err != nil {
+			return errors.WithStack(err)
+		}
+		return br.readSyncScopeNames()
+	default:
+		// TODO: translate metadata, attributes, use-list orders and operand
+		// bundle tags.
+		return br.r.SkipBlock()
+	}
+}
+
+// readModuleRecord reads the given record of the MODULE block.
+func (br *bcReader) readModuleRecord(rec *bitstream.Record) error {
+	switch rec.Code {
+	case bcModuleVersion:
+		if len(rec.Ops) < 1 {
+			return errors.New("invalid VERSION record; missing version")
+		}
+		br.version = rec.Ops[0]
+		if br.version > 2 {
+			return errors.Errorf("support for bitcode version %d not yet implemented", br.version)
+		}
+	case bcModuleTriple:
+		br.m.TargetTriple = recordString(rec.Ops)
+	case bcModuleDataLayout:
+		br.m.DataLayout = recordString(rec.Ops)
+	case bcModuleSourceFilename:
+		br.m.SourceFilename = recordString(rec.Ops)
+	case bcModuleGlobalVar:
+		return br.readGlobalVar(rec.Ops)
+	case bcModuleFunction:
+		return br.readFuncDecl(rec.Ops)
+	case bcModuleAlias, bcModuleIFunc:
+		// TODO: handle alias definitions and IFuncs.
+		return errors.New("support for aliases and IFuncs not yet implemented")
+	default:
+		// TODO: handle module-level inline assembly, section names, GC names and
+		// comdats.
+	}
+	return nil
+}
+
+// readName reads the name of a global variable or function, stored as string
+// table offset and size in the first two operands of version 2 records. The
+// remaining operands are returned.
+func (br *bcReader) readName(g ir.Constant, ops []uint64) ([]uint64, error) {
+	if br.version < 2 {
+		// Names are stored in the module-level value symbol table.
+		return ops, nil
+	}
+	if len(ops) < 2 {
+		return nil, errors.New("invalid record; missing string table offset and size")
+	}
+	br.names = append(br.names, bcName{g: g, offset: ops[0], size: ops[1]})
+	return ops[2:], nil
+}
+
+// ~~~ [ Global Variable ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readGlobalVar reads the given GLOBALVAR record.
+//
+//    [strtab_offset, strtab_size, pointer type, isconst, initid, linkage,
+//     alignment, section, visibility, threadlocal, unnamed_addr,
+//     externally_initialized, dllstorageclass, comdat, attributes, preemption]
+func (br *bcReader) readGlobalVar(ops []uint64) error {
+	g := &ir.Global{}
+	ops, err := br.readName(g, ops)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 6 {
+		return errors.Errorf("invalid GLOBALVAR record; expected at least 6 operands, got %d", len(ops))
+	}
+	typ, err := br.typ(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Bit 1 of isconst indicates an explicit content type, with the address
+	// space stored in the upper bits.
+	var addrSpace types.AddrSpace
+	if ops[1]&2 != 0 {
+		g.ContentType = typ
+		addrSpace = types.AddrSpace(ops[1] >> 2)
+	} else {
+		ptr, ok := typ.(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return errors.Errorf("invalid global variable type; expected typed pointer, got %v", typ)
+		}
+		g.ContentType = ptr.ElemType
+		addrSpace = ptr.AddrSpace
+	}
+	g.Typ = br.pointerTo(g.ContentType)
+	// Address space.
+	g.Typ.AddrSpace = addrSpace
+	// Immutable (constant or global).
+	g.Immutable = ops[1]&1 != 0
+	// Initializer.
+	hasInit := ops[2] != 0
+	if hasInit {
+		br.inits = append(br.inits, bcInit{g: g, id: ops[2] - 1})
+	}
+	// Linkage.
+	linkage, err := bcLinkage(ops[3], hasInit)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	g.Linkage = linkage
+	// Alignment and section.
+	// TODO: handle GlobalAttrs.
+	if len(ops) > 6 {
+		// Visibility.
+		if g.Visibility, err = bcVisibility(ops[6]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 7 {
+		// Thread local storage model.
+		if g.TLSModel, err = bcTLSModel(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 8 {
+		// Unnamed address.
+		if g.UnnamedAddr, err = bcUnnamedAddr(ops[8]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(ops) > 9 {
+		// Externally initialized.
+		g.ExternallyInitialized = ops[9] != 0
+	}
+	if len(ops) > 10 {
+		// DLL storage class.
+		if g.DLLStorageClass, err = bcDLLStorageClass(ops[10]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Comdat and attributes.
+	// TODO: handle Comdat and FuncAttrs.
+	if len(ops) > 13 {
+		// Preemption.
+		if g.Preemption, err = bcPreemption(ops[13]); err != nil {
+			return errors.WithStack(err)
+		}
+		if bcImplicitDSOLocal(ops[3], ops[6]) {
+			g.Preemption = enum.PreemptionNone
+		}
+	}
+	br.addValue(g, g.Typ)
+	br.m.Globals = append(br.m.Globals, g)
+	return nil
+}
+
+// resolveInits resolves the initializers of global variables.
+func (br *bcReader) resolveInits() error {
+	for _, init := range br.inits {
+		c, err := br.constant(init.id)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		init.g.Init = c
+	}
+	br.inits = nil
+	return nil
+}
+
+// ~~~ [ Function ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readFuncDecl reads the given FUNCTION record.
+//
+//    [strtab_offset, strtab_size, type, callingconv, isproto, linkage,
+//     paramattrs, alignment, section, visibility, gc, unnamed_addr,
+//     prologuedata, dllstorageclass, comdat, prefixdata, personalityfn,
+//     preemption, addrspace]
+func (br *bcReader) readFuncDecl(ops []uint64) error {
+	f := &ir.Function{}
+	ops, err := br.readName(f, ops)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops) < 4 {
+		return errors.Errorf("invalid FUNCTION record; expected at least 4 operands, got %d", len(ops))
+	}
+	typ, err := br.typ(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Function types are stored as pointer to function type by older versions
+	// of LLVM.
+	var addrSpace types.AddrSpace
+	if ptr, ok := typ.(*types.PointerType); ok {
+		typ = ptr.ElemType
+		addrSpace = ptr.AddrSpace
+	}
+	sig, ok := typ.(*types.FuncType)
+	if !ok {
+		return errors.Errorf("invalid function type; expected *types.FuncType, got %T", typ)
+	}
+	f.Sig = sig
+	f.Typ = br.pointerTo(f.Sig)
+	// Calling convention.
+	// TODO: translate CallingConv.
+	isProto := ops[2] != 0
+	// Linkage; external linkage is implicit for both function declarations and
+	// definitions.
+	linkage, err := bcLinkage(ops[3], true)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.Linkage = linkage
+	// Parameter attributes, alignment and section.
+	// TODO: handle ReturnAttrs, FuncAttrs and Section.
+	if len(ops) > 7 {
+		// Visibility.
+		if f.Visibility, err = bcVisibility(ops[7]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// GC.
+	// TODO: handle GC.
+	if len(ops) > 9 {
+		// Unnamed address.
+		if f.UnnamedAddr, err = bcUnnamedAddr(ops[9]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Prologue.
+	// TODO: handle Prologue.
+	if len(ops) > 11 {
+		// DLL storage class.
+		if f.DLLStorageClass, err = bcDLLStorageClass(ops[11]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Comdat, prefix and personality.
+	// TODO: handle Comdat, Prefix and Personality.
+	if len(ops) > 15 {
+		// Preemption.
+		if f.Preemption, err = bcPreemption(ops[15]); err != nil {
+			return errors.WithStack(err)
+		}
+		if bcImplicitDSOLocal(ops[3], ops[7]) {
+			f.Preemption = enum.PreemptionNone
+		}
+	}
+	if len(ops) > 16 {
+		// Address space.
+		addrSpace = types.AddrSpace(ops[16])
+	}
+	f.Typ.AddrSpace = addrSpace
+	// Function parameters, as present in function declarations.
+	for _, param := range sig.Params {
+		f.Params = append(f.Params, ir.NewParam(param, ""))
+	}
+	if !isProto {
+		br.defs = append(br.defs, f)
+	}
+	br.addValue(f, f.Typ)
+	br.m.Funcs = append(br.m.Funcs, f)
+	return nil
+}
+
+// ~~~ [ Value Symbol Table ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readModuleVST reads the current module-level VALUE_SYMTAB block.
+func (br *bcReader) readModuleVST() error {
+	return br.readRecords(func(rec *bitstream.Record) error {
+		switch rec.Code {
+		case bcVSTEntry, bcVSTFnEntry:
+			// Version 1 stores the names of global values in the value symbol
+			// table. Version 2 only stores function offsets.
+			//
+			//    [valueid, namechar x N]
+			//    [valueid, offset, namechar x N]
+			if br.version >= 2 {
+				return nil
+			}
+			if len(rec.Ops) < 1 {
+				return errors.New("invalid VST_ENTRY record; missing value ID")
+			}
+			id, chars := rec.Ops[0], rec.Ops[1:]
+			if rec.Code == bcVSTFnEntry {
+				if len(chars) < 1 {
+					return errors.New("invalid VST_FNENTRY record; missing function offset")
+				}
+				chars = chars[1:]
+			}
+			if id >= uint64(len(br.values)) {
+				return errors.Errorf("invalid value ID %d of value symbol table entry; expected < %d", id, len(br.values))
+			}
+			g, ok := br.values[id].v.(ir.Constant)
+			if !ok {
+				return errors.Errorf("invalid value symbol table entry; expected global variable or function, got %T", br.values[id].v)
+			}
+			setGlobalName(g, recordString(chars))
+		}
+		return nil
+	})
+}
+
+// ### [ Helpers ] #############################################################
+
+// addValue adds the given value of the specified type to the value table.
+func (br *bcReader) addValue(v value.Value, typ types.Type) {
+	br.values = append(br.values, &bcValue{v: v, typ: typ})
+}
+
+// recordString returns the string of the given record operands, each holding
+// one character.
+func recordString(ops []uint64) string {
+	buf := make([]byte, len(ops))
+	for i, op := range ops {
+		buf[i] = byte(op)
+	}
+	return string(buf)
+}
+
+// decodeSignRotated decodes the given sign rotated value, as used by bitcode
+// to store signed integers (with the sign stored in the least significant
+// bit).
+func decodeSignRotated(v uint64) int64 {
+	if v&1 == 0 {
+		return int64(v >> 1)
+	}
+	if v != 1 {
+		return -int64(v >> 1)
+	}
+	// Minimum signed 64-bit integer.
+	return -1 << 63
+}
+
+// readSyncScopeNames reads the synchronization scope names of the current
+// SYNC_SCOPE_NAMES block.
+func (br *bcReader) readSyncScopeNames() error {
+	var names []string
+	err := br.readRecords(func(rec *bitstream.Record) error {
+		if rec.Code != bcSyncScopeName {
+			return errors.Errorf("invalid record code %d of SYNC_SCOPE_NAMES block", rec.Code)
+		}
+		names = append(names, recordString(rec.Ops))
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	br.syncScopes = names
+	return nil
+}
+
+// syncScope returns the synchronization scope of the given synchronization
+// scope ID.
+func (br *bcReader) syncScope(id uint64) (string, error) {
+	if id >= uint64(len(br.syncScopes)) {
+		return "", errors.Errorf("invalid synchronization scope ID %d; expected < %d", id, len(br.syncScopes))
+	}
+	return br.syncScopes[id], nil
+}
+
+// bcLinkage returns the IR linkage corresponding to the given linkage code.
+// External linkage is implicit if specified.
+func bcLinkage(code uint64, implicitExternal bool) (enum.Linkage, error) {
+	name, ok := bcLinkageNames[code]
+	if !ok {
+		return 0, errors.Errorf("support for linkage code %d not yet implemented", code)
+	}
+	if name == "external" && implicitExternal {
+		return enum.LinkageNone, nil
+	}
+	return asmenum.LinkageFromString(name), nil
+}
+
+// bcVisibility returns the IR visibility corresponding to the given visibility
+// code.
+func bcVisibility(code uint64) (enum.Visibility, error) {
+	if code >= uint64(len(bcVisibilityNames)) {
+		return 0, errors.Errorf("support for visibility code %d not yet implemented", code)
+	}
+	return asmenum.VisibilityFromString(bcVisibilityNames[code]), nil
+}
+
+// bcDLLStorageClass returns the IR DLL storage class corresponding to the given
+// DLL storage class code.
+func bcDLLStorageClass(code uint64) (enum.DLLStorageClass, error) {
+	if code >= uint64(len(bcDLLStorageClassNames)) {
+		return 0, errors.Errorf("support for DLL storage class code %d not yet implemented", code)
+	}
+	return asmenum.DLLStorageClassFromString(bcDLLStorageClassNames[code]), nil
+}
+
+// bcTLSModel returns the IR thread local storage model corresponding to the
+// given TLS model code.
+func bcTLSModel(code uint64) (enum.TLSModel, error) {
+	if code >= uint64(len(bcTLSModelNames)) {
+		return 0, errors.Errorf("support for thread local storage model code %d not yet implemented", code)
+	}
+	return asmenum.TLSModelFromString(bcTLSModelNames[code]), nil
+}
+
+// bcUnnamedAddr returns the IR unnamed address corresponding to the given
+// unnamed address code.
+func bcUnnamedAddr(code uint64) (enum.UnnamedAddr, error) {
+	if code >= uint64(len(bcUnnamedAddrNames)) {
+		return 0, errors.Errorf("support for unnamed address code %d not yet implemented", code)
+	}
+	return asmenum.UnnamedAddrFromString(bcUnnamedAddrNames[code]), nil
+}
+
+// bcPreemption returns the IR preemption corresponding to the given preemption
+// code.
+func bcPreemption(code uint64) (enum.Preemption, error) {
+	if code >= uint64(len(bcPreemptionNames)) {
+		return 0, errors.Errorf("support for preemption code %d not yet implemented", code)
+	}
+	return asmenum.PreemptionFromString(bcPreemptionNames[code]), nil
+}
+
+// bcImplicitDSOLocal reports whether dso_local preemption is implied by the
+// given linkage and visibility codes, in which case it is omitted (as done by
+// llvm-dis).
+func bcImplicitDSOLocal(linkage, visibility uint64) bool {
+	switch bcLinkageNames[linkage] {
+	case "private", "internal":
+		return true
+	case "extern_weak":
+		return false
+	}
+	return visibility != 0
+}
+
+// bcAtomicOrdering returns the IR atomic ordering corresponding to the given
+// atomic ordering code.
+func bcAtomicOrdering(code uint64) (enum.AtomicOrdering, error) {
+	if code >= uint64(len(bcAtomicOrderingNames)) {
+		return 0, errors.Errorf("support for atomic ordering code %d not yet implemented", code)
+	}
+	return asmenum.AtomicOrderingFromString(bcAtomicOrderingNames[code]), nil
+}
+
+// bcAtomicOp returns the IR atomic operation corresponding to the given atomic
+// operation code.
+func bcAtomicOp(code uint64) (enum.AtomicOp, error) {
+	if code >= uint64(len(bcAtomicOpNames)) {
+		return 0, errors.Errorf("support for atomic operation code %d not yet implemented", code)
+	}
+	return atomicOpFromString(bcAtomicOpNames[code]), nil
+}
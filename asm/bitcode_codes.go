@@ -0,0 +1,567 @@
+package asm
+
+// Block IDs, record codes and enumerations of the LLVM bitcode format.
+//
+// References:
+//
+//	https://llvm.org/docs/BitCodeFormat.html
+//	llvm/include/llvm/Bitcode/LLVMBitCodes.h
+
+// Block IDs.
+const (
+	bcModuleBlockID             = 8
+	bcParamAttrBlockID          = 9
+	bcParamAttrGroupBlockID     = 10
+	bcConstantsBlockID          = 11
+	bcFunctionBlockID           = 12
+	bcIdentificationBlockID     = 13
+	bcValueSymtabBlockID        = 14
+	bcMetadataBlockID           = 15
+	bcMetadataAttachmentBlockID = 16
+	bcTypeBlockID               = 17
+	bcUselistBlockID            = 18
+	bcModuleStrtabBlockID       = 19
+	bcGlobalValSummaryBlockID   = 20
+	bcOperandBundleTagsBlockID  = 21
+	bcMetadataKindBlockID       = 22
+	bcStrtabBlockID             = 23
+	bcFullLTOGlobalValSummaryID = 24
+	bcSymtabBlockID             = 25
+	bcSyncScopeNamesBlockID     = 26
+)
+
+// Record codes of the IDENTIFICATION block.
+const (
+	// [strchr x N]
+	bcIdentificationString = 1
+	// [epoch]
+	bcIdentificationEpoch = 2
+)
+
+// Record codes of the MODULE block.
+const (
+	// [version#]
+	bcModuleVersion = 1
+	// [strchr x N]
+	bcModuleTriple = 2
+	// [strchr x N]
+	bcModuleDataLayout = 3
+	// [strchr x N]
+	bcModuleAsm = 4
+	// [strchr x N]
+	bcModuleSectionName = 5
+	// [strchr x N]
+	bcModuleDepLib = 6
+	// [strtab_offset, strtab_size, pointer type, isconst, initid, linkage,
+	//  alignment, section, visibility, threadlocal, unnamed_addr,
+	//  externally_initialized, dllstorageclass, comdat, attributes,
+	//  preemption]
+	bcModuleGlobalVar = 7
+	// [strtab_offset, strtab_size, type, callingconv, isproto, linkage,
+	//  paramattrs, alignment, section, visibility, gc, unnamed_addr,
+	//  prologuedata, dllstorageclass, comdat, prefixdata, personalityfn,
+	//  preemption, addrspace]
+	bcModuleFunction = 8
+	// [strchr x N]
+	bcModuleGCName = 11
+	// [strtab_offset, strtab_size, selection_kind]
+	bcModuleComdat = 12
+	// [offset]
+	bcModuleVSTOffset = 13
+	// [strtab_offset, strtab_size, alias type, addrspace, aliasee val#,
+	//  linkage, visibility, dllstorageclass, threadlocal, unnamed_addr,
+	//  preemption]
+	bcModuleAlias = 14
+	// [strtab_offset, strtab_size, ifunc type, addrspace, resolver val#,
+	//  linkage, visibility]
+	bcModuleIFunc = 15
+	// [namechar x N]
+	bcModuleSourceFilename = 16
+	// [5 x i32]
+	bcModuleHash = 17
+)
+
+// Record codes of the TYPE block.
+const (
+	// [numentries]
+	bcTypeNumEntry = 1
+	// []
+	bcTypeVoid = 2
+	// []
+	bcTypeFloat = 3
+	// []
+	bcTypeDouble = 4
+	// []
+	bcTypeLabel = 5
+	// []
+	bcTypeOpaque = 6
+	// [width]
+	bcTypeInteger = 7
+	// [pointee type, addrspace]
+	bcTypePointer = 8
+	// [vararg, attrid, retty, paramty x N]
+	bcTypeFunctionOld = 9
+	// []
+	bcTypeHalf = 10
+	// [numelts, eltty]
+	bcTypeArray = 11
+	// [numelts, eltty]
+	bcTypeVector = 12
+	// []
+	bcTypeX86FP80 = 13
+	// []
+	bcTypeFP128 = 14
+	// []
+	bcTypePPCFP128 = 15
+	// []
+	bcTypeMetadata = 16
+	// []
+	bcTypeX86MMX = 17
+	// [ispacked, eltty x N]
+	bcTypeStructAnon = 18
+	// [strchr x N]
+	bcTypeStructName = 19
+	// [ispacked, eltty x N]
+	bcTypeStructNamed = 20
+	// [vararg, retty, paramty x N]
+	bcTypeFunction = 21
+	// []
+	bcTypeToken = 22
+	// []
+	bcTypeBFloat = 23
+	// []
+	bcTypeX86AMX = 24
+	// [addrspace]
+	bcTypeOpaquePointer = 25
+	// [numtys, ty x numtys, int x N]
+	bcTypeTargetType = 26
+)
+
+// Record codes of the CONSTANTS block.
+const (
+	// [typeid]
+	bcConstSetType = 1
+	// []
+	bcConstNull = 2
+	// []
+	bcConstUndef = 3
+	// [signed intval]
+	bcConstInteger = 4
+	// [n x signed intval]
+	bcConstWideInteger = 5
+	// [fpval]
+	bcConstFloat = 6
+	// [n x value number]
+	bcConstAggregate = 7
+	// [values]
+	bcConstString = 8
+	// [values]
+	bcConstCString = 9
+	// [opcode, opval, opval]
+	bcConstCEBinop = 10
+	// [opcode, opty, opval]
+	bcConstCECast = 11
+	// [[pointee type], n x operands]
+	bcConstCEGEP = 12
+	// [opval, opval, opval]
+	bcConstCESelect = 13
+	// [opty, opval, opty, opval]
+	bcConstCEExtractElt = 14
+	// [opval, opval, opty, opval]
+	bcConstCEInsertElt = 15
+	// [opval, opval, opval]
+	bcConstCEShuffleVec = 16
+	// [opty, opval, opval, pred]
+	bcConstCECmp = 17
+	// [opty, opval, opval, opval]
+	bcConstCEShufVecEx = 19
+	// [[pointee type], n x operands]
+	bcConstCEInboundsGEP = 20
+	// [fnty, fnval, bb#]
+	bcConstBlockAddress = 21
+	// [n x elements]
+	bcConstData = 22
+	// [pointee type, flags, n x operands]
+	bcConstCEGEPWithInrangeIndex = 24
+	// [opcode, opval]
+	bcConstCEUnop = 25
+	// []
+	bcConstPoison = 26
+	// [gvty, gv]
+	bcConstDSOLocalEquivalent = 27
+	// [fty, f]
+	bcConstNoCFIValue = 29
+)
+
+// Record codes of the FUNCTION block.
+const (
+	// [n]
+	bcFuncDeclareBlocks = 1
+	// [opval, ty, opval, opcode]
+	bcFuncInstBinop = 2
+	// [opval, opty, destty, castopc]
+	bcFuncInstCast = 3
+	// [n x operands]
+	bcFuncInstGEPOld = 4
+	// [opval, opval, opval]
+	bcFuncInstSelect = 5
+	// [opty, opval, opval]
+	bcFuncInstExtractElt = 6
+	// [ty, opval, opval, opval]
+	bcFuncInstInsertElt = 7
+	// [ty, opval, opval, opval]
+	bcFuncInstShuffleVec = 8
+	// [opty, opval, opval, pred]
+	bcFuncInstCmp = 9
+	// [opty, opval<both optional>]
+	bcFuncInstRet = 10
+	// [bb#, bb#, cond] or [bb#]
+	bcFuncInstBr = 11
+	// [opty, op0, op1, ...]
+	bcFuncInstSwitch = 12
+	// [attr, fnty, op0, op1, ...]
+	bcFuncInstInvoke = 13
+	// []
+	bcFuncInstUnreachable = 15
+	// [ty, val0, bb0, ...]
+	bcFuncInstPhi = 16
+	// [instty, opty, op, align]
+	bcFuncInstAlloca = 19
+	// [op, [ty], align, vol]
+	bcFuncInstLoad = 20
+	// [valistty, valist, instty]
+	bcFuncInstVAArg = 23
+	// [ptrty, ptr, val, align, vol]
+	bcFuncInstStoreOld = 24
+	// [n x operands]
+	bcFuncInstExtractVal = 26
+	// [n x operands]
+	bcFuncInstInsertVal = 27
+	// [opty, opval, opval, pred]
+	bcFuncInstCmp2 = 28
+	// [ty, opval, opval, predty, pred]
+	bcFuncInstVSelect = 29
+	// [n x operands]
+	bcFuncInstInboundsGEPOld = 30
+	// [opty, op0, op1, ...]
+	bcFuncInstIndirectBr = 31
+	// []
+	bcFuncDebugLocAgain = 33
+	// [paramattrs, cc, fmf, fnty, fnid, args...]
+	bcFuncInstCall = 34
+	// [Line, Col, ScopeVal, IAVal]
+	bcFuncDebugLoc = 35
+	// [ordering, synchscope]
+	bcFuncInstFence = 36
+	// [ptrty, ptr, val, operation, vol, ordering, synchscope, align]
+	bcFuncInstAtomicRMWOld = 38
+	// [opty, opval]
+	bcFuncInstResume = 39
+	// [op, [ty], align, vol, ordering, synchscope]
+	bcFuncInstLoadAtomic = 41
+	// [inbounds, ty, n x operands]
+	bcFuncInstGEP = 43
+	// [ptrty, ptr, val, align, vol]
+	bcFuncInstStore = 44
+	// [ptrty, ptr, val, align, vol, ordering, synchscope]
+	bcFuncInstStoreAtomic = 45
+	// [ptrty, ptr, cmp, val, vol, success_ordering, synchscope,
+	//  failure_ordering, weak, align]
+	bcFuncInstCmpXchg = 46
+	// [opcode, ty, opval]
+	bcFuncInstUnop = 56
+	// [attr, cc, norm, transfs, fnty, fnid, args]
+	bcFuncInstCallBr = 57
+	// [opty, opval]
+	bcFuncInstFreeze = 58
+	// [ptrty, ptr, valty, val, operation, align, vol, ordering, synchscope]
+	bcFuncInstAtomicRMW = 59
+)
+
+// Record codes of the VALUE_SYMTAB block.
+const (
+	// [valueid, namechar x N]
+	bcVSTEntry = 1
+	// [bbid, namechar x N]
+	bcVSTBBEntry = 2
+	// [valueid, offset, namechar x N]
+	bcVSTFnEntry = 3
+)
+
+// Record codes of the SYNC_SCOPE_NAMES block.
+const (
+	// [strchr x N]
+	bcSyncScopeName = 1
+)
+
+// Record codes of the STRTAB block.
+const (
+	// [blob]
+	bcStrtabBlob = 1
+)
+
+// Binary opcodes.
+const (
+	bcBinopAdd  = 0
+	bcBinopSub  = 1
+	bcBinopMul  = 2
+	bcBinopUDiv = 3
+	// sdiv for integer operands, fdiv for floating-point operands.
+	bcBinopSDiv = 4
+	bcBinopURem = 5
+	// srem for integer operands, frem for floating-point operands.
+	bcBinopSRem = 6
+	bcBinopShl  = 7
+	bcBinopLShr = 8
+	bcBinopAShr = 9
+	bcBinopAnd  = 10
+	bcBinopOr   = 11
+	bcBinopXor  = 12
+)
+
+// Flags of binary operations.
+const (
+	// Overflowing binary operations (add, sub, mul and shl).
+	bcOBONoUnsignedWrap = 1 << 0
+	bcOBONoSignedWrap   = 1 << 1
+	// Exact binary operations (udiv, sdiv, lshr and ashr).
+	bcPEOExact = 1 << 0
+)
+
+// Cast opcodes.
+const (
+	bcCastTrunc         = 0
+	bcCastZExt          = 1
+	bcCastSExt          = 2
+	bcCastFPToUI        = 3
+	bcCastFPToSI        = 4
+	bcCastUIToFP        = 5
+	bcCastSIToFP        = 6
+	bcCastFPTrunc       = 7
+	bcCastFPExt         = 8
+	bcCastPtrToInt      = 9
+	bcCastIntToPtr      = 10
+	bcCastBitCast       = 11
+	bcCastAddrSpaceCast = 12
+)
+
+// Flags of call instructions.
+const (
+	bcCallTail         = 1 << 0
+	bcCallCConvShift   = 1
+	bcCallCConvMask    = 0x3FF
+	bcCallMustTail     = 1 << 14
+	bcCallExplicitType = 1 << 15
+	bcCallNoTail       = 1 << 16
+	bcCallFMF          = 1 << 17
+)
+
+// Flags of alloca instructions.
+const (
+	bcAllocaAlignMask     = 0x1F
+	bcAllocaInAlloca      = 1 << 5
+	bcAllocaExplicitType  = 1 << 6
+	bcAllocaSwiftError    = 1 << 7
+	bcAllocaAlignUpperBit = 8
+)
+
+// bcLinkageNames maps from linkage code to linkage name. Codes of deprecated
+// linkage kinds are mapped to their replacement.
+var bcLinkageNames = map[uint64]string{
+	0:  "external",
+	1:  "weak",
+	2:  "appending",
+	3:  "internal",
+	4:  "linkonce",
+	5:  "external", // dllimport
+	6:  "external", // dllexport
+	7:  "extern_weak",
+	8:  "common",
+	9:  "private",
+	10: "weak_odr",
+	11: "linkonce_odr",
+	12: "available_externally",
+	13: "private",  // linker_private
+	14: "private",  // linker_private_weak
+	15: "external", // linkonce_odr_auto_hide
+	16: "weak",
+	17: "weak_odr",
+	18: "linkonce",
+	19: "linkonce_odr",
+}
+
+// bcLinkageCodes maps from linkage name to linkage code, as used when writing
+// bitcode.
+var bcLinkageCodes = map[string]uint64{
+	"external":             0,
+	"appending":            2,
+	"internal":             3,
+	"extern_weak":          7,
+	"common":               8,
+	"private":              9,
+	"available_externally": 12,
+	"weak":                 16,
+	"weak_odr":             17,
+	"linkonce":             18,
+	"linkonce_odr":         19,
+}
+
+// bcVisibilityNames maps from visibility code to visibility name.
+var bcVisibilityNames = []string{
+	0: "",
+	1: "hidden",
+	2: "protected",
+}
+
+// bcDLLStorageClassNames maps from DLL storage class code to DLL storage class
+// name.
+var bcDLLStorageClassNames = []string{
+	0: "",
+	1: "dllimport",
+	2: "dllexport",
+}
+
+// bcTLSModelNames maps from thread local storage model code to TLS model name.
+var bcTLSModelNames = []string{
+	0: "",
+	1: "thread_local",
+	2: "thread_local(localdynamic)",
+	3: "thread_local(initialexec)",
+	4: "thread_local(localexec)",
+}
+
+// bcUnnamedAddrNames maps from unnamed address code to unnamed address name.
+var bcUnnamedAddrNames = []string{
+	0: "",
+	1: "unnamed_addr",
+	2: "local_unnamed_addr",
+}
+
+// bcPreemptionNames maps from preemption code to preemption name.
+var bcPreemptionNames = []string{
+	0: "",
+	1: "dso_local",
+}
+
+// bcCallingConvNames maps from calling convention code to calling convention
+// name.
+var bcCallingConvNames = map[uint64]string{
+	0:  "",
+	8:  "fastcc",
+	9:  "coldcc",
+	10: "ghccc",
+	12: "webkit_jscc",
+	13: "anyregcc",
+	14: "preserve_mostcc",
+	15: "preserve_allcc",
+	16: "swiftcc",
+	17: "cxx_fast_tlscc",
+	64: "x86_stdcallcc",
+	65: "x86_fastcallcc",
+	66: "arm_apcscc",
+	67: "arm_aapcscc",
+	68: "arm_aapcs_vfpcc",
+	69: "msp430_intrcc",
+	70: "x86_thiscallcc",
+	71: "ptx_kernel",
+	72: "ptx_device",
+	75: "spir_func",
+	76: "spir_kernel",
+	77: "intel_ocl_bicc",
+	78: "x86_64_sysvcc",
+	79: "win64cc",
+	80: "x86_vectorcallcc",
+	81: "hhvmcc",
+	82: "hhvm_ccc",
+	83: "x86_intrcc",
+	84: "avr_intrcc",
+	85: "avr_signalcc",
+	92: "x86_regcallcc",
+}
+
+// bcFPredNames maps from floating-point comparison predicate code to predicate
+// name.
+var bcFPredNames = []string{
+	0:  "false",
+	1:  "oeq",
+	2:  "ogt",
+	3:  "oge",
+	4:  "olt",
+	5:  "ole",
+	6:  "one",
+	7:  "ord",
+	8:  "uno",
+	9:  "ueq",
+	10: "ugt",
+	11: "uge",
+	12: "ult",
+	13: "ule",
+	14: "une",
+	15: "true",
+}
+
+// bcIPredNames maps from integer comparison predicate code to predicate name.
+var bcIPredNames = map[uint64]string{
+	32: "eq",
+	33: "ne",
+	34: "ugt",
+	35: "uge",
+	36: "ult",
+	37: "ule",
+	38: "sgt",
+	39: "sge",
+	40: "slt",
+	41: "sle",
+}
+
+// bcAtomicOrderingNames maps from atomic ordering code to atomic ordering name.
+var bcAtomicOrderingNames = []string{
+	0: "none", // not atomic
+	1: "unordered",
+	2: "monotonic",
+	3: "acquire",
+	4: "release",
+	5: "acq_rel",
+	6: "seq_cst",
+}
+
+// bcAtomicOpNames maps from atomic operation code to atomic operation name.
+var bcAtomicOpNames = []string{
+	0:  "xchg",
+	1:  "add",
+	2:  "sub",
+	3:  "and",
+	4:  "nand",
+	5:  "or",
+	6:  "xor",
+	7:  "max",
+	8:  "min",
+	9:  "umax",
+	10: "umin",
+}
+
+// bcDefaultSyncScopes specifies the synchronization scopes of bitcode without a
+// SYNC_SCOPE_NAMES block, in order of synchronization scope ID. The empty
+// string denotes the default system scope.
+var bcDefaultSyncScopes = []string{
+	0: "singlethread",
+	1: "",
+}
+
+// bcFastMathFlagBits specifies the fast math flag bits and their names, in
+// order of occurrence in LLVM IR assembly.
+var bcFastMathFlagBits = []struct {
+	bit  uint64
+	name string
+}{
+	{bit: 1 << 1, name: "nnan"},
+	{bit: 1 << 2, name: "ninf"},
+	{bit: 1 << 3, name: "nsz"},
+	{bit: 1 << 4, name: "arcp"},
+	{bit: 1 << 5, name: "contract"},
+	{bit: 1 << 6, name: "afn"},
+	{bit: 1 << 7, name: "reassoc"},
+}
+
+// bcFastMathAll is the combination of fast math flags denoted by `fast`.
+const bcFastMathAll = 0xFE
@@ -0,0 +1,466 @@
+package asm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// === [ Constants ] ===========================================================
+
+// readConsts reads the constants of the current CONSTANTS block, and adds them
+// to the value table.
+//
+// Constants may refer to constants of higher value IDs (e.g. elements of
+// aggregate constants), thus the records are retained and the constants are
+// created on first use.
+func (br *bcReader) readConsts() error {
+	// The type of constants defaults to i32 until set by a SETTYPE record.
+	var typ types.Type = types.I32
+	return br.readRecords(func(rec *bitstream.Record) error {
+		if rec.Code == bcConstSetType {
+			// [typeid]
+			if len(rec.Ops) < 1 {
+				return errors.New("invalid SETTYPE record; missing type ID")
+			}
+			t, err := br.typ(rec.Ops[0])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			typ = t
+			return nil
+		}
+		br.values = append(br.values, &bcValue{typ: typ, rec: rec})
+		return nil
+	})
+}
+
+// constant returns the constant of the given value ID.
+func (br *bcReader) constant(id uint64) (ir.Constant, error) {
+	if id >= uint64(len(br.values)) {
+		return nil, errors.Errorf("invalid value ID %d of constant; expected < %d", id, len(br.values))
+	}
+	v := br.values[id]
+	if v.v == nil {
+		if v.rec == nil {
+			return nil, errors.Errorf("invalid value ID %d of constant; refers to non-constant value", id)
+		}
+		if v.busy {
+			return nil, errors.Errorf("invalid cyclic constant of value ID %d", id)
+		}
+		v.busy = true
+		c, err := br.newConst(v.typ, v.rec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to create constant of value ID %d", id)
+		}
+		v.busy = false
+		v.v = c
+		v.rec = nil
+	}
+	c, ok := v.v.(ir.Constant)
+	if !ok {
+		return nil, errors.Errorf("invalid value ID %d of constant; expected ir.Constant, got %T", id, v.v)
+	}
+	return c, nil
+}
+
+// newConst returns a new IR constant of the given type based on the given
+// CONSTANTS block record.
+func (br *bcReader) newConst(t types.Type, rec *bitstream.Record) (ir.Constant, error) {
+	ops := rec.Ops
+	switch rec.Code {
+	case bcConstNull:
+		return bcNullConst(t)
+	case bcConstUndef:
+		return ir.NewUndef(t), nil
+	case bcConstPoison:
+		return ir.NewPoison(t), nil
+	case bcConstInteger:
+		// [signed intval]
+		if len(ops) < 1 {
+			return nil, errors.New("invalid INTEGER record; missing value")
+		}
+		typ, ok := t.(*types.IntType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of integer constant; expected *types.IntType, got %T", t)
+		}
+		return bcIntConst(typ, decodeSignRotated(ops[0]))
+	case bcConstWideInteger:
+		// [n x signed intval]
+		typ, ok := t.(*types.IntType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of integer constant; expected *types.IntType, got %T", t)
+		}
+		x := new(big.Int)
+		for i := len(ops) - 1; i >= 0; i-- {
+			word := uint64(decodeSignRotated(ops[i]))
+			x.Lsh(x, 64)
+			x.Or(x, new(big.Int).SetUint64(word))
+		}
+		// Interpret as signed integer of the given bit size.
+		if x.Bit(int(typ.BitSize)-1) == 1 {
+			x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(typ.BitSize)))
+		}
+		return ir.NewIntFromString(typ, x.String())
+	case bcConstFloat:
+		// [fpval]
+		typ, ok := t.(*types.FloatType)
+		if !ok {
+			return nil, errors.Errorf("invalid type of floating-point constant; expected *types.FloatType, got %T", t)
+		}
+		return bcFloatConst(typ, ops)
+	case bcConstAggregate:
+		// [n x value number]
+		var elems []ir.Constant
+		for _, op := range ops {
+			elem, err := br.constant(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			elems = append(elems, elem)
+		}
+		switch t := t.(type) {
+		case *types.StructType:
+			return ir.NewStruct(t, elems...), nil
+		case *types.ArrayType:
+			return ir.NewArray(t, elems...), nil
+		case *types.VectorType:
+			return ir.NewVector(t, elems...), nil
+		default:
+			return nil, errors.Errorf("invalid type of aggregate constant; expected *types.StructType, *types.ArrayType or *types.VectorType, got %T", t)
+		}
+	case bcConstString, bcConstCString:
+		// [values]
+		data := make([]byte, len(ops), len(ops)+1)
+		for i, op := range ops {
+			data[i] = byte(op)
+		}
+		if rec.Code == bcConstCString {
+			// NULL-terminator is implicit.
+			data = append(data, 0)
+		}
+		// TODO: validate t against expr.Typ.
+		return ir.NewCharArray(data), nil
+	case bcConstData:
+		// [n x elements]
+		return bcDataConst(t, ops)
+	case bcConstCECast:
+		// [opcode, opty, opval]
+		if len(ops) < 3 {
+			return nil, errors.New("invalid CE_CAST record; expected 3 operands")
+		}
+		from, err := br.constant(ops[2])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return bcCastExpr(ops[0], from, t)
+	case bcConstCEGEP, bcConstCEInboundsGEP, bcConstCEGEPWithInrangeIndex:
+		return br.newGEPExpr(rec)
+	case bcConstBlockAddress:
+		// [fnty, fnval, bb#]
+		if len(ops) < 3 {
+			return nil, errors.New("invalid BLOCKADDRESS record; expected 3 operands")
+		}
+		fn, err := br.constant(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f, ok := fn.(*ir.Function)
+		if !ok {
+			return nil, errors.Errorf("invalid function of block address; expected *ir.Function, got %T", fn)
+		}
+		// Add dummy basic block to track the index of the basic block. Resolve
+		// the proper basic block after reading the function body.
+		expr := ir.NewBlockAddress(f, &ir.BasicBlock{})
+		br.blockAddrs = append(br.blockAddrs, bcBlockAddr{c: expr, index: ops[2]})
+		if len(f.Blocks) > 0 {
+			// Function body already read.
+			if err := br.fixBlockAddrs(f); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		return expr, nil
+	case bcConstDSOLocalEquivalent, bcConstNoCFIValue:
+		// [gvty, gv]
+		// [fty, f]
+		if len(ops) < 2 {
+			return nil, errors.New("invalid DSO_LOCAL_EQUIVALENT or NO_CFI_VALUE record; expected 2 operands")
+		}
+		fn, err := br.constant(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f, ok := fn.(*ir.Function)
+		if !ok {
+			return nil, errors.Errorf("invalid function of dso_local_equivalent or no_cfi constant; expected *ir.Function, got %T", fn)
+		}
+		if rec.Code == bcConstNoCFIValue {
+			return ir.NewNoCFI(f), nil
+		}
+		return ir.NewDSOLocalEquivalent(f), nil
+	default:
+		// TODO: add support for remaining constant expressions, once handled by
+		// Translate.
+		return nil, errors.Errorf("support for constant record code %d not yet implemented", rec.Code)
+	}
+}
+
+// newGEPExpr returns a new getelementptr expression based on the given
+// CE_GEP, CE_INBOUNDS_GEP or CE_GEP_WITH_INRANGE_INDEX record.
+//
+//    [[pointee type], n x (opty, opval)]
+//    [pointee type, flags, n x (opty, opval)]
+func (br *bcReader) newGEPExpr(rec *bitstream.Record) (*ir.ExprGetElementPtr, error) {
+	ops := rec.Ops
+	var elemType types.Type
+	if len(ops) < 1 {
+		return nil, errors.New("invalid getelementptr record; missing operands")
+	}
+	if rec.Code == bcConstCEGEPWithInrangeIndex || len(ops)%2 == 1 {
+		t, err := br.typ(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		elemType = t
+		ops = ops[1:]
+	}
+	inBounds := rec.Code == bcConstCEInboundsGEP
+	inRange := -1
+	if rec.Code == bcConstCEGEPWithInrangeIndex {
+		if len(ops) < 1 {
+			return nil, errors.New("invalid CE_GEP_WITH_INRANGE_INDEX record; missing flags")
+		}
+		inBounds = ops[0]&1 != 0
+		inRange = int(ops[0] >> 1)
+		ops = ops[1:]
+	}
+	if len(ops) < 2 || len(ops)%2 != 0 {
+		return nil, errors.Errorf("invalid number of getelementptr operands; expected even and non-zero, got %d", len(ops))
+	}
+	// Source.
+	src, err := br.constant(ops[1])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if elemType == nil {
+		// Element type is implicit in older versions of LLVM.
+		ptr, ok := src.Type().(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return nil, errors.Errorf("invalid source type of getelementptr expression; expected typed pointer, got %v", src.Type())
+		}
+		elemType = ptr.ElemType
+	}
+	// Indices.
+	var indices []*ir.Index
+	for i := 2; i < len(ops); i += 2 {
+		idx, err := br.constant(ops[i+1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		index := ir.NewIndex(idx)
+		index.InRange = len(indices) == inRange
+		indices = append(indices, index)
+	}
+	expr := ir.NewGetElementPtrExpr(elemType, src, indices...)
+	// The result of getelementptr on an opaque pointer is an opaque pointer in
+	// the same address space.
+	if ptr, ok := src.Type().(*types.PointerType); ok && isOpaquePointer(ptr) {
+		expr.Typ = &types.PointerType{AddrSpace: ptr.AddrSpace}
+	}
+	// In-bounds.
+	expr.InBounds = inBounds
+	return expr, nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// bcBlockAddr records the basic block index of a block address constant.
+type bcBlockAddr struct {
+	// Block address constant with dummy basic block.
+	c *ir.ConstBlockAddress
+	// Index of basic block within function.
+	index uint64
+}
+
+// fixBlockAddrs resolves the basic blocks of block address constants referring
+// to the given function.
+//
+// Pre-condition: read function body of f.
+func (br *bcReader) fixBlockAddrs(f *ir.Function) error {
+	var pending []bcBlockAddr
+	for _, b := range br.blockAddrs {
+		if b.c.Func != f {
+			pending = append(pending, b)
+			continue
+		}
+		if b.index >= uint64(len(f.Blocks)) {
+			return errors.Errorf("invalid basic block index %d of block address; expected < %d", b.index, len(f.Blocks))
+		}
+		b.c.Block = f.Blocks[b.index]
+	}
+	br.blockAddrs = pending
+	return nil
+}
+
+// bcNullConst returns the null value of the given type, as stored by NULL
+// records.
+func bcNullConst(t types.Type) (ir.Constant, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		return bcIntConst(t, 0)
+	case *types.FloatType:
+		return bcFloatConst(t, []uint64{0, 0})
+	case *types.PointerType:
+		return ir.NewNull(t), nil
+	case *types.TokenType:
+		return ir.None, nil
+	default:
+		return ir.NewZeroInitializer(t), nil
+	}
+}
+
+// bcIntConst returns the integer constant of the given type and value.
+func bcIntConst(t *types.IntType, x int64) (*ir.ConstInt, error) {
+	if t.BitSize == 1 {
+		if x != 0 {
+			return ir.True, nil
+		}
+		return ir.False, nil
+	}
+	return ir.NewIntFromString(t, strconv.FormatInt(x, 10))
+}
+
+// bcFloatConst returns the floating-point constant of the given type, based on
+// the words of its bit pattern.
+func bcFloatConst(t *types.FloatType, ops []uint64) (*ir.ConstFloat, error) {
+	if len(ops) < 1 {
+		return nil, errors.New("invalid floating-point constant; missing value")
+	}
+	var s string
+	switch t.Kind {
+	case types.FloatKindHalf:
+		s = fmt.Sprintf("0xH%04X", ops[0])
+	case types.FloatKindBFloat:
+		s = fmt.Sprintf("0xR%04X", ops[0])
+	case types.FloatKindFloat:
+		// Single precision values are represented in double precision in LLVM IR
+		// assembly.
+		x := math.Float32frombits(uint32(ops[0]))
+		s = bcDoubleString(float64(x))
+	case types.FloatKindDouble:
+		s = bcDoubleString(math.Float64frombits(ops[0]))
+	case types.FloatKindX86FP80, types.FloatKindFP128, types.FloatKindPPCFP128:
+		if len(ops) < 2 {
+			return nil, errors.Errorf("invalid %v constant; expected 2 words, got %d", t, len(ops))
+		}
+		switch t.Kind {
+		case types.FloatKindX86FP80:
+			// The first word holds the 16-bit sign and exponent followed by the
+			// upper 48 bits of the mantissa, and the second word holds the lower
+			// 16 bits of the mantissa.
+			se := ops[0] >> 48
+			mant := ops[0]<<16 | ops[1]&0xFFFF
+			s = fmt.Sprintf("0xK%04X%016X", se, mant)
+		case types.FloatKindFP128:
+			s = fmt.Sprintf("0xL%016X%016X", ops[0], ops[1])
+		default:
+			s = fmt.Sprintf("0xM%016X%016X", ops[0], ops[1])
+		}
+	default:
+		return nil, errors.Errorf("support for floating-point kind %v not yet implemented", t.Kind)
+	}
+	return ir.NewFloatFromString(t, s)
+}
+
+// bcDoubleString returns the LLVM IR assembly representation of the given
+// double precision floating-point value. As with llvm-dis, the decimal notation
+// is used if it represents the value exactly, and the hexadecimal notation is
+// used otherwise.
+func bcDoubleString(x float64) string {
+	if !math.IsInf(x, 0) && !math.IsNaN(x) {
+		s := fmt.Sprintf("%e", x)
+		if y, err := strconv.ParseFloat(s, 64); err == nil && y == x {
+			return s
+		}
+	}
+	return fmt.Sprintf("0x%016X", math.Float64bits(x))
+}
+
+// bcDataConst returns the array or vector constant of the given type, based on
+// the elements of a DATA record.
+func bcDataConst(t types.Type, ops []uint64) (ir.Constant, error) {
+	var elemType types.Type
+	switch t := t.(type) {
+	case *types.ArrayType:
+		elemType = t.ElemType
+	case *types.VectorType:
+		elemType = t.ElemType
+	default:
+		return nil, errors.Errorf("invalid type of data constant; expected *types.ArrayType or *types.VectorType, got %T", t)
+	}
+	var elems []ir.Constant
+	for _, op := range ops {
+		var elem ir.Constant
+		var err error
+		switch et := elemType.(type) {
+		case *types.IntType:
+			// Sign-extend to 64 bits.
+			shift := uint(64 - et.BitSize)
+			elem, err = bcIntConst(et, int64(op<<shift)>>shift)
+		case *types.FloatType:
+			elem, err = bcFloatConst(et, []uint64{op})
+		default:
+			return nil, errors.Errorf("invalid element type of data constant; expected *types.IntType or *types.FloatType, got %T", et)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		elems = append(elems, elem)
+	}
+	switch t := t.(type) {
+	case *types.ArrayType:
+		return ir.NewArray(t, elems...), nil
+	default:
+		return ir.NewVector(t.(*types.VectorType), elems...), nil
+	}
+}
+
+// bcCastExpr returns the conversion expression of the given cast opcode.
+func bcCastExpr(opcode uint64, from ir.Constant, to types.Type) (ir.Expression, error) {
+	switch opcode {
+	case bcCastTrunc:
+		return ir.NewTruncExpr(from, to), nil
+	case bcCastZExt:
+		return ir.NewZExtExpr(from, to), nil
+	case bcCastSExt:
+		return ir.NewSExtExpr(from, to), nil
+	case bcCastFPToUI:
+		return ir.NewFPToUIExpr(from, to), nil
+	case bcCastFPToSI:
+		return ir.NewFPToSIExpr(from, to), nil
+	case bcCastUIToFP:
+		return ir.NewUIToFPExpr(from, to), nil
+	case bcCastSIToFP:
+		return ir.NewSIToFPExpr(from, to), nil
+	case bcCastFPTrunc:
+		return ir.NewFPTruncExpr(from, to), nil
+	case bcCastFPExt:
+		return ir.NewFPExtExpr(from, to), nil
+	case bcCastPtrToInt:
+		return ir.NewPtrToIntExpr(from, to), nil
+	case bcCastIntToPtr:
+		return ir.NewIntToPtrExpr(from, to), nil
+	case bcCastBitCast:
+		return ir.NewBitCastExpr(from, to), nil
+	case bcCastAddrSpaceCast:
+		return ir.NewAddrSpaceCastExpr(from, to), nil
+	default:
+		return nil, errors.Errorf("support for cast opcode %d not yet implemented", opcode)
+	}
+}
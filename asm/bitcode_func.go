@@ -0,0 +1,1536 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// === [ Function Bodies ] =====================================================
+
+// bcFuncReader keeps track of the basic blocks and instructions when reading
+// the body of a function from bitcode.
+//
+// Instructions are read in two passes. The first pass creates instructions
+// (with type and without operands) as records are read, thus assigning value
+// IDs. The second pass fills in the operands of instructions, which may refer
+// to instructions of higher value IDs (e.g. incoming values of phi
+// instructions).
+type bcFuncReader struct {
+	br *bcReader
+	// Function being read.
+	f *ir.Function
+	// Basic blocks of the function.
+	blocks []*ir.BasicBlock
+	// Index of the current basic block.
+	cur int
+	// Functions filling in the operands of instructions, invoked after all
+	// instructions of the function have been created.
+	fills []func() error
+	// Value symbol table records of the function.
+	vst []*bitstream.Record
+}
+
+// bcMaxBlocks is the maximum number of basic blocks of a function.
+const bcMaxBlocks = 1 << 24
+
+// bcRef is a reference to a value of the value table.
+type bcRef struct {
+	// Value ID.
+	id uint64
+	// Type of the value.
+	typ types.Type
+}
+
+// readFunc reads the body of the given function from the current FUNCTION
+// block.
+func (br *bcReader) readFunc(f *ir.Function) error {
+	// Values of the function body are only valid within the function block.
+	base := len(br.values)
+	defer func() {
+		br.values = br.values[:base]
+	}()
+	fr := &bcFuncReader{br: br, f: f}
+	// Function parameters.
+	for _, param := range f.Params {
+		br.addValue(param, param.Type())
+	}
+	for {
+		e, err := br.r.Next()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if e.Kind == bitstream.EntryEndBlock {
+			break
+		}
+		if e.Kind == bitstream.EntrySubBlock {
+			switch e.BlockID {
+			case bcConstantsBlockID:
+				if err := br.r.EnterBlock(); err != nil {
+					return errors.WithStack(err)
+				}
+				if err := br.readConsts(); err != nil {
+					return errors.WithStack(err)
+				}
+			case bcValueSymtabBlockID:
+				if err := br.r.EnterBlock(); err != nil {
+					return errors.WithStack(err)
+				}
+				err := br.readRecords(func(rec *bitstream.Record) error {
+					fr.vst = append(fr.vst, rec)
+					return nil
+				})
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			default:
+				// TODO: translate metadata, metadata attachments and use-list
+				// orders.
+				if err := br.r.SkipBlock(); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			continue
+		}
+		if err := fr.readRecord(e.Record); err != nil {
+			return errors.Wrapf(err, "unable to read instruction record (code %d) of function", e.Record.Code)
+		}
+	}
+	if fr.cur != len(fr.blocks) {
+		return errors.Errorf("invalid function body; missing terminator of basic block %d", fr.cur)
+	}
+	// Fill in operands of instructions.
+	for _, fill := range fr.fills {
+		if err := fill(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Local names.
+	if err := fr.readVST(base); err != nil {
+		return errors.WithStack(err)
+	}
+	f.Blocks = fr.blocks
+	// Assign local IDs of unnamed values.
+	if err := f.AssignIDs(); err != nil {
+		return errors.WithStack(err)
+	}
+	return br.fixBlockAddrs(f)
+}
+
+// readVST assigns local names based on the value symbol table of the function
+// body. The value IDs of the function body start at base.
+func (fr *bcFuncReader) readVST(base int) error {
+	for _, rec := range fr.vst {
+		if len(rec.Ops) < 1 {
+			return errors.New("invalid value symbol table record; missing value ID")
+		}
+		id, name := rec.Ops[0], recordString(rec.Ops[1:])
+		switch rec.Code {
+		case bcVSTEntry:
+			// [valueid, namechar x N]
+			if id < uint64(base) || id >= uint64(len(fr.br.values)) {
+				return errors.Errorf("invalid value ID %d of local value symbol table entry", id)
+			}
+			switch v := fr.br.values[id].v.(type) {
+			case *ir.Param:
+				v.LocalName = name
+			case value.Named:
+				v.SetName(name)
+			default:
+				return errors.Errorf("invalid local value symbol table entry; expected named value, got %T", v)
+			}
+		case bcVSTBBEntry:
+			// [bbid, namechar x N]
+			block, err := fr.block(id)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			block.LocalName = name
+		}
+	}
+	return nil
+}
+
+// readRecord reads the given record of the FUNCTION block.
+func (fr *bcFuncReader) readRecord(rec *bitstream.Record) error {
+	switch rec.Code {
+	case bcFuncDeclareBlocks:
+		// [n]
+		if len(rec.Ops) < 1 {
+			return errors.New("invalid DECLAREBLOCKS record; missing number of basic blocks")
+		}
+		if len(fr.blocks) > 0 {
+			return errors.New("invalid DECLAREBLOCKS record; basic blocks already declared")
+		}
+		// Limit the number of basic blocks, in case of invalid input.
+		if rec.Ops[0] == 0 || rec.Ops[0] > bcMaxBlocks {
+			return errors.Errorf("invalid number of basic blocks %d; expected > 0 and <= %d", rec.Ops[0], bcMaxBlocks)
+		}
+		for i := uint64(0); i < rec.Ops[0]; i++ {
+			fr.blocks = append(fr.blocks, ir.NewBlock(""))
+		}
+		return nil
+	case bcFuncDebugLoc, bcFuncDebugLocAgain:
+		// TODO: handle debug locations.
+		return nil
+	}
+	if fr.cur >= len(fr.blocks) {
+		return errors.New("instruction outside of basic block")
+	}
+	switch rec.Code {
+	// Terminators.
+	case bcFuncInstRet:
+		return fr.readRet(rec.Ops)
+	case bcFuncInstBr:
+		return fr.readBr(rec.Ops)
+	case bcFuncInstSwitch:
+		return fr.readSwitch(rec.Ops)
+	case bcFuncInstUnreachable:
+		fr.addTerm(&ir.TermUnreachable{})
+		return nil
+	// Instructions.
+	case bcFuncInstBinop:
+		return fr.readBinop(rec.Ops)
+	case bcFuncInstCast:
+		return fr.readCast(rec.Ops)
+	case bcFuncInstExtractElt:
+		return fr.readExtractElement(rec.Ops)
+	case bcFuncInstInsertElt:
+		return fr.readInsertElement(rec.Ops)
+	case bcFuncInstShuffleVec:
+		return fr.readShuffleVector(rec.Ops)
+	case bcFuncInstExtractVal:
+		return fr.readExtractValue(rec.Ops)
+	case bcFuncInstInsertVal:
+		return fr.readInsertValue(rec.Ops)
+	case bcFuncInstAlloca:
+		return fr.readAlloca(rec.Ops)
+	case bcFuncInstLoad, bcFuncInstLoadAtomic:
+		return fr.readLoad(rec.Code, rec.Ops)
+	case bcFuncInstStore, bcFuncInstStoreOld, bcFuncInstStoreAtomic:
+		return fr.readStore(rec.Code, rec.Ops)
+	case bcFuncInstFence:
+		return fr.readFence(rec.Ops)
+	case bcFuncInstCmpXchg:
+		return fr.readCmpXchg(rec.Ops)
+	case bcFuncInstAtomicRMW, bcFuncInstAtomicRMWOld:
+		return fr.readAtomicRMW(rec.Code, rec.Ops)
+	case bcFuncInstGEP, bcFuncInstGEPOld, bcFuncInstInboundsGEPOld:
+		return fr.readGetElementPtr(rec.Code, rec.Ops)
+	case bcFuncInstCmp, bcFuncInstCmp2:
+		return fr.readCmp(rec.Ops)
+	case bcFuncInstPhi:
+		return fr.readPhi(rec.Ops)
+	case bcFuncInstVSelect:
+		return fr.readSelect(rec.Ops)
+	case bcFuncInstCall:
+		return fr.readCall(rec.Ops)
+	default:
+		// TODO: add support for remaining instructions and terminators, once
+		// handled by Translate.
+		return errors.Errorf("support for instruction record code %d not yet implemented", rec.Code)
+	}
+}
+
+// --- [ Terminators ] ---------------------------------------------------------
+
+// ~~~ [ ret ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readRet reads the given INST_RET record.
+//
+//    [opty, opval<both optional>]
+func (fr *bcFuncReader) readRet(ops []uint64) error {
+	term := &ir.TermRet{}
+	fr.addTerm(term)
+	if len(ops) == 0 {
+		// void return.
+		return nil
+	}
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fr.fill(func() error {
+		v, err := fr.value(x)
+		term.X = v
+		return err
+	})
+	return nil
+}
+
+// ~~~ [ br ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readBr reads the given INST_BR record.
+//
+//    [bb#, bb#, cond] or [bb#]
+func (fr *bcFuncReader) readBr(ops []uint64) error {
+	switch len(ops) {
+	case 1:
+		target, err := fr.block(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		fr.addTerm(&ir.TermBr{Target: target})
+		return nil
+	case 3:
+		targetTrue, err := fr.block(ops[0])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		targetFalse, err := fr.block(ops[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		i := 2
+		cond, err := fr.untypedValue(ops, &i, types.I1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		term := &ir.TermCondBr{TargetTrue: targetTrue, TargetFalse: targetFalse}
+		fr.addTerm(term)
+		fr.fill(func() error {
+			v, err := fr.value(cond)
+			term.Cond = v
+			return err
+		})
+		return nil
+	default:
+		return errors.Errorf("invalid number of INST_BR operands; expected 1 or 3, got %d", len(ops))
+	}
+}
+
+// ~~~ [ switch ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// readSwitch reads the given INST_SWITCH record.
+//
+//    [opty, cond, default, n x (caseval, bb#)]
+func (fr *bcFuncReader) readSwitch(ops []uint64) error {
+	if len(ops) < 3 || len(ops)%2 != 1 {
+		return errors.Errorf("invalid number of INST_SWITCH operands; expected odd and at least 3, got %d", len(ops))
+	}
+	if ops[0]>>16 == 0x4B5 {
+		// TODO: add support for case ranges of LLVM 3.3.
+		return errors.New("support for switch case ranges not yet implemented")
+	}
+	typ, err := fr.br.typ(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	i := 1
+	x, err := fr.untypedValue(ops, &i, typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	targetDefault, err := fr.block(ops[2])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	term := &ir.TermSwitch{TargetDefault: targetDefault}
+	fr.addTerm(term)
+	fr.fill(func() error {
+		v, err := fr.value(x)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		term.X = v
+		// Case values are stored as absolute value IDs.
+		for i := 3; i < len(ops); i += 2 {
+			c, err := fr.br.constant(ops[i])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			target, err := fr.block(ops[i+1])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			term.Cases = append(term.Cases, ir.NewCase(c, target))
+		}
+		return nil
+	})
+	return nil
+}
+
+// --- [ Binary and bitwise instructions ] -------------------------------------
+
+// readBinop reads the given INST_BINOP record.
+//
+//    [opval, opval, opcode, flags<optional>]
+func (fr *bcFuncReader) readBinop(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	y, err := fr.untypedValue(ops, &i, x.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if i >= len(ops) {
+		return errors.New("invalid INST_BINOP record; missing opcode")
+	}
+	opcode := ops[i]
+	var flags uint64
+	if i+1 < len(ops) {
+		flags = ops[i+1]
+	}
+	inst, err := newBCBinop(opcode, flags, x.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fr.addInst(inst, x.typ)
+	fr.fill(func() error {
+		xv, err := fr.value(x)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		yv, err := fr.value(y)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		setBinopOperands(inst, xv, yv)
+		return nil
+	})
+	return nil
+}
+
+// newBCBinop returns a new binary or bitwise instruction (with type and
+// without operands) based on the given binary opcode and flags.
+func newBCBinop(opcode, flags uint64, typ types.Type) (ir.Instruction, error) {
+	if isFloatOrFloatVector(typ) {
+		fmf := bcFastMathFlags(flags)
+		switch opcode {
+		case bcBinopAdd:
+			return &ir.InstFAdd{Typ: typ, FastMathFlags: fmf}, nil
+		case bcBinopSub:
+			return &ir.InstFSub{Typ: typ, FastMathFlags: fmf}, nil
+		case bcBinopMul:
+			return &ir.InstFMul{Typ: typ, FastMathFlags: fmf}, nil
+		case bcBinopSDiv:
+			return &ir.InstFDiv{Typ: typ, FastMathFlags: fmf}, nil
+		case bcBinopSRem:
+			return &ir.InstFRem{Typ: typ, FastMathFlags: fmf}, nil
+		default:
+			return nil, errors.Errorf("invalid binary opcode %d for floating-point operands", opcode)
+		}
+	}
+	overflowFlags := bcOverflowFlags(flags)
+	switch opcode {
+	case bcBinopAdd:
+		return &ir.InstAdd{Typ: typ, OverflowFlags: overflowFlags}, nil
+	case bcBinopSub:
+		return &ir.InstSub{Typ: typ, OverflowFlags: overflowFlags}, nil
+	case bcBinopMul:
+		return &ir.InstMul{Typ: typ, OverflowFlags: overflowFlags}, nil
+	case bcBinopShl:
+		return &ir.InstShl{Typ: typ, OverflowFlags: overflowFlags}, nil
+	// TODO: handle exact flag (bcPEOExact) of udiv, sdiv, lshr and ashr.
+	case bcBinopUDiv:
+		return &ir.InstUDiv{Typ: typ}, nil
+	case bcBinopSDiv:
+		return &ir.InstSDiv{Typ: typ}, nil
+	case bcBinopURem:
+		return &ir.InstURem{Typ: typ}, nil
+	case bcBinopSRem:
+		return &ir.InstSRem{Typ: typ}, nil
+	case bcBinopLShr:
+		return &ir.InstLShr{Typ: typ}, nil
+	case bcBinopAShr:
+		return &ir.InstAShr{Typ: typ}, nil
+	case bcBinopAnd:
+		return &ir.InstAnd{Typ: typ}, nil
+	case bcBinopOr:
+		return &ir.InstOr{Typ: typ}, nil
+	case bcBinopXor:
+		return &ir.InstXor{Typ: typ}, nil
+	default:
+		return nil, errors.Errorf("support for binary opcode %d not yet implemented", opcode)
+	}
+}
+
+// setBinopOperands sets the operands of the given binary or bitwise
+// instruction.
+func setBinopOperands(inst ir.Instruction, x, y value.Value) {
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		inst.X, inst.Y = x, y
+	case *ir.InstFAdd:
+		inst.X, inst.Y = x, y
+	case *ir.InstSub:
+		inst.X, inst.Y = x, y
+	case *ir.InstFSub:
+		inst.X, inst.Y = x, y
+	case *ir.InstMul:
+		inst.X, inst.Y = x, y
+	case *ir.InstFMul:
+		inst.X, inst.Y = x, y
+	case *ir.InstUDiv:
+		inst.X, inst.Y = x, y
+	case *ir.InstSDiv:
+		inst.X, inst.Y = x, y
+	case *ir.InstFDiv:
+		inst.X, inst.Y = x, y
+	case *ir.InstURem:
+		inst.X, inst.Y = x, y
+	case *ir.InstSRem:
+		inst.X, inst.Y = x, y
+	case *ir.InstFRem:
+		inst.X, inst.Y = x, y
+	case *ir.InstShl:
+		inst.X, inst.Y = x, y
+	case *ir.InstLShr:
+		inst.X, inst.Y = x, y
+	case *ir.InstAShr:
+		inst.X, inst.Y = x, y
+	case *ir.InstAnd:
+		inst.X, inst.Y = x, y
+	case *ir.InstOr:
+		inst.X, inst.Y = x, y
+	case *ir.InstXor:
+		inst.X, inst.Y = x, y
+	default:
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("support for binary instruction %T not yet implemented", inst))
+	}
+}
+
+// --- [ Vector instructions ] -------------------------------------------------
+
+// readExtractElement reads the given INST_EXTRACTELT record.
+//
+//    [opval, opval]
+func (fr *bcFuncReader) readExtractElement(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	index, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	xt, ok := x.typ.(*types.VectorType)
+	if !ok {
+		return errors.Errorf("invalid vector type; expected *types.VectorType, got %T", x.typ)
+	}
+	inst := &ir.InstExtractElement{Typ: xt.ElemType}
+	fr.addInst(inst, inst.Typ)
+	fr.fill(func() error {
+		vs, err := fr.values(x, index)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Index = vs[0], vs[1]
+		return nil
+	})
+	return nil
+}
+
+// readInsertElement reads the given INST_INSERTELT record.
+//
+//    [opval, opval, opval]
+func (fr *bcFuncReader) readInsertElement(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	xt, ok := x.typ.(*types.VectorType)
+	if !ok {
+		return errors.Errorf("invalid vector type; expected *types.VectorType, got %T", x.typ)
+	}
+	elem, err := fr.untypedValue(ops, &i, xt.ElemType)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	index, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &ir.InstInsertElement{Typ: xt}
+	fr.addInst(inst, inst.Typ)
+	fr.fill(func() error {
+		vs, err := fr.values(x, elem, index)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Elem, inst.Index = vs[0], vs[1], vs[2]
+		return nil
+	})
+	return nil
+}
+
+// readShuffleVector reads the given INST_SHUFFLEVEC record.
+//
+//    [opval, opval, opval]
+func (fr *bcFuncReader) readShuffleVector(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	y, err := fr.untypedValue(ops, &i, x.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	mask, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	xt, ok := x.typ.(*types.VectorType)
+	if !ok {
+		return errors.Errorf("invalid vector type; expected *types.VectorType, got %T", x.typ)
+	}
+	mt, ok := mask.typ.(*types.VectorType)
+	if !ok {
+		return errors.Errorf("invalid vector type; expected *types.VectorType, got %T", mask.typ)
+	}
+	inst := &ir.InstShuffleVector{Typ: vectorOf(mt, xt.ElemType)}
+	fr.addInst(inst, inst.Typ)
+	fr.fill(func() error {
+		vs, err := fr.values(x, y, mask)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Y, inst.Mask = vs[0], vs[1], vs[2]
+		return nil
+	})
+	return nil
+}
+
+// --- [ Aggregate instructions ] ----------------------------------------------
+
+// readExtractValue reads the given INST_EXTRACTVAL record.
+//
+//    [opval, n x indices]
+func (fr *bcFuncReader) readExtractValue(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	indices := ops[i:]
+	typ, err := bcAggregateElemType(x.typ, indices)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &ir.InstExtractValue{Typ: typ, Indices: indices}
+	fr.addInst(inst, typ)
+	fr.fill(func() error {
+		v, err := fr.value(x)
+		inst.X = v
+		return err
+	})
+	return nil
+}
+
+// readInsertValue reads the given INST_INSERTVAL record.
+//
+//    [opval, opval, n x indices]
+func (fr *bcFuncReader) readInsertValue(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	elem, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	indices := ops[i:]
+	if _, err := bcAggregateElemType(x.typ, indices); err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &ir.InstInsertValue{Typ: x.typ, Indices: indices}
+	fr.addInst(inst, x.typ)
+	fr.fill(func() error {
+		vs, err := fr.values(x, elem)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Elem = vs[0], vs[1]
+		return nil
+	})
+	return nil
+}
+
+// --- [ Memory instructions ] -------------------------------------------------
+
+// readAlloca reads the given INST_ALLOCA record.
+//
+//    [instty, opty, op, align]
+func (fr *bcFuncReader) readAlloca(ops []uint64) error {
+	if len(ops) < 4 {
+		return errors.Errorf("invalid number of INST_ALLOCA operands; expected 4, got %d", len(ops))
+	}
+	typ, err := fr.br.typ(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	flags := ops[3]
+	elemType := typ
+	if flags&bcAllocaExplicitType == 0 {
+		ptr, ok := typ.(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return errors.Errorf("invalid alloca type; expected typed pointer, got %v", typ)
+		}
+		elemType = ptr.ElemType
+	}
+	inst := &ir.InstAlloca{ElemType: elemType, Typ: fr.br.pointerTo(elemType)}
+	inst.InAlloca = flags&bcAllocaInAlloca != 0
+	inst.SwiftError = flags&bcAllocaSwiftError != 0
+	// The encoded alignment is split into the lower five bits and one upper bit
+	// of the flags.
+	inst.Align = bcAlign(flags&bcAllocaAlignMask | (flags>>bcAllocaAlignUpperBit&1)<<5)
+	fr.addInst(inst, inst.Typ)
+	// Number of elements; stored as absolute value ID.
+	nelems := ops[2]
+	fr.fill(func() error {
+		v, err := fr.value(bcRef{id: nelems})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// Omit the default number of elements (i.e. `i32 1`), as does LLVM IR
+		// assembly.
+		if c, ok := v.(*ir.ConstInt); ok && c.X.IsInt64() && c.X.Int64() == 1 && c.Typ.BitSize == 32 {
+			return nil
+		}
+		inst.NElems = v
+		return nil
+	})
+	return nil
+}
+
+// readLoad reads the given INST_LOAD or INST_LOADATOMIC record.
+//
+//    [op, ty<optional>, align, vol]
+//    [op, ty<optional>, align, vol, ordering, synchscope]
+func (fr *bcFuncReader) readLoad(code uint64, ops []uint64) error {
+	i := 0
+	src, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Number of trailing operands following the optional element type.
+	n := 2
+	if code == bcFuncInstLoadAtomic {
+		n = 4
+	}
+	var elemType types.Type
+	switch len(ops) - i {
+	case n + 1:
+		t, err := fr.br.typ(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		elemType = t
+		i++
+	case n:
+		// Element type is implicit in older versions of LLVM.
+		ptr, ok := src.typ.(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return errors.Errorf("invalid load source type; expected typed pointer, got %v", src.typ)
+		}
+		elemType = ptr.ElemType
+	default:
+		return errors.Errorf("invalid number of INST_LOAD operands; expected %d or %d, got %d", i+n, i+n+1, len(ops))
+	}
+	inst := &ir.InstLoad{Typ: elemType, Align: bcAlign(ops[i]), Volatile: ops[i+1] != 0}
+	if code == bcFuncInstLoadAtomic {
+		inst.Atomic = true
+		if inst.Ordering, inst.SyncScope, err = fr.atomicOrdering(ops[i+2], ops[i+3]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	fr.addInst(inst, elemType)
+	fr.fill(func() error {
+		v, err := fr.value(src)
+		inst.Src = v
+		return err
+	})
+	return nil
+}
+
+// readStore reads the given INST_STORE, INST_STORE_OLD or INST_STOREATOMIC
+// record.
+//
+//    [ptr, val, align, vol]
+//    [ptr, val, align, vol, ordering, synchscope]
+func (fr *bcFuncReader) readStore(code uint64, ops []uint64) error {
+	i := 0
+	dst, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var src bcRef
+	if code == bcFuncInstStoreOld {
+		// Type of stored value is implicit in older versions of LLVM.
+		ptr, ok := dst.typ.(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return errors.Errorf("invalid store destination type; expected typed pointer, got %v", dst.typ)
+		}
+		src, err = fr.untypedValue(ops, &i, ptr.ElemType)
+	} else {
+		src, err = fr.typedValue(ops, &i)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Number of trailing operands.
+	n := 2
+	if code == bcFuncInstStoreAtomic {
+		n = 4
+	}
+	if len(ops)-i < n {
+		return errors.Errorf("invalid number of INST_STORE operands; expected %d, got %d", i+n, len(ops))
+	}
+	inst := &ir.InstStore{Align: bcAlign(ops[i]), Volatile: ops[i+1] != 0}
+	if code == bcFuncInstStoreAtomic {
+		inst.Atomic = true
+		if inst.Ordering, inst.SyncScope, err = fr.atomicOrdering(ops[i+2], ops[i+3]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	fr.addInst(inst, types.Void)
+	fr.fill(func() error {
+		vs, err := fr.values(src, dst)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Src, inst.Dst = vs[0], vs[1]
+		return nil
+	})
+	return nil
+}
+
+// readFence reads the given INST_FENCE record.
+//
+//    [ordering, synchscope]
+func (fr *bcFuncReader) readFence(ops []uint64) error {
+	if len(ops) < 2 {
+		return errors.Errorf("invalid number of INST_FENCE operands; expected 2, got %d", len(ops))
+	}
+	inst := &ir.InstFence{}
+	var err error
+	if inst.Ordering, inst.SyncScope, err = fr.atomicOrdering(ops[0], ops[1]); err != nil {
+		return errors.WithStack(err)
+	}
+	fr.addInst(inst, types.Void)
+	return nil
+}
+
+// readCmpXchg reads the given INST_CMPXCHG record.
+//
+//    [ptrty, ptr, cmp, val, vol, success_ordering, synchscope,
+//     failure_ordering, weak, align<optional>]
+func (fr *bcFuncReader) readCmpXchg(ops []uint64) error {
+	i := 0
+	ptr, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cmp, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	newVal, err := fr.untypedValue(ops, &i, cmp.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops)-i < 5 {
+		return errors.Errorf("invalid number of INST_CMPXCHG operands; expected %d, got %d", i+5, len(ops))
+	}
+	// TODO: handle alignment.
+	inst := &ir.InstCmpXchg{Volatile: ops[i] != 0, Weak: ops[i+4] != 0}
+	if inst.SuccessOrdering, inst.SyncScope, err = fr.atomicOrdering(ops[i+1], ops[i+2]); err != nil {
+		return errors.WithStack(err)
+	}
+	if inst.FailureOrdering, err = bcAtomicOrdering(ops[i+3]); err != nil {
+		return errors.WithStack(err)
+	}
+	// The result is a pair of the original value and a success flag.
+	inst.Typ = types.NewStruct(cmp.typ, types.I1)
+	fr.addInst(inst, inst.Typ)
+	fr.fill(func() error {
+		vs, err := fr.values(ptr, cmp, newVal)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Ptr, inst.Cmp, inst.New = vs[0], vs[1], vs[2]
+		return nil
+	})
+	return nil
+}
+
+// readAtomicRMW reads the given INST_ATOMICRMW or INST_ATOMICRMW_OLD record.
+//
+//    [ptrty, ptr, valty, val, operation, vol, ordering, synchscope, align]
+//    [ptrty, ptr, val, operation, vol, ordering, synchscope, align<optional>]
+func (fr *bcFuncReader) readAtomicRMW(code uint64, ops []uint64) error {
+	i := 0
+	dst, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var x bcRef
+	if code == bcFuncInstAtomicRMWOld {
+		// Type of operand is implicit in older versions of LLVM; given by the
+		// element type of typed pointers, or by the operand itself if already
+		// defined.
+		var typ types.Type
+		if ptr, ok := dst.typ.(*types.PointerType); ok && !isOpaquePointer(ptr) {
+			typ = ptr.ElemType
+		} else if i < len(ops) {
+			if id := fr.relID(ops[i]); id < fr.instNum() {
+				typ = fr.br.values[id].typ
+			}
+		}
+		if typ == nil {
+			return errors.Errorf("unable to determine operand type of atomicrmw instruction with destination type %v", dst.typ)
+		}
+		x, err = fr.untypedValue(ops, &i, typ)
+	} else {
+		x, err = fr.typedValue(ops, &i)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ops)-i < 4 {
+		return errors.Errorf("invalid number of INST_ATOMICRMW operands; expected %d, got %d", i+4, len(ops))
+	}
+	// TODO: handle alignment.
+	inst := &ir.InstAtomicRMW{Volatile: ops[i+1] != 0}
+	if inst.Op, err = bcAtomicOp(ops[i]); err != nil {
+		return errors.WithStack(err)
+	}
+	if inst.Ordering, inst.SyncScope, err = fr.atomicOrdering(ops[i+2], ops[i+3]); err != nil {
+		return errors.WithStack(err)
+	}
+	fr.addInst(inst, x.typ)
+	fr.fill(func() error {
+		vs, err := fr.values(dst, x)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Dst, inst.X = vs[0], vs[1]
+		return nil
+	})
+	return nil
+}
+
+// atomicOrdering returns the atomic ordering and synchronization scope of the
+// given atomic ordering code and synchronization scope ID.
+func (fr *bcFuncReader) atomicOrdering(ordering, ssid uint64) (enum.AtomicOrdering, string, error) {
+	o, err := bcAtomicOrdering(ordering)
+	if err != nil {
+		return 0, "", errors.WithStack(err)
+	}
+	syncScope, err := fr.br.syncScope(ssid)
+	if err != nil {
+		return 0, "", errors.WithStack(err)
+	}
+	return o, syncScope, nil
+}
+
+// readGetElementPtr reads the given INST_GEP, INST_GEP_OLD or
+// INST_INBOUNDS_GEP_OLD record.
+//
+//    [inbounds, ty, n x operands]
+//    [n x operands]
+func (fr *bcFuncReader) readGetElementPtr(code uint64, ops []uint64) error {
+	inst := &ir.InstGetElementPtr{InBounds: code == bcFuncInstInboundsGEPOld}
+	i := 0
+	if code == bcFuncInstGEP {
+		if len(ops) < 2 {
+			return errors.New("invalid INST_GEP record; missing in-bounds flag or element type")
+		}
+		inst.InBounds = ops[0] != 0
+		elemType, err := fr.br.typ(ops[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.ElemType = elemType
+		i = 2
+	}
+	src, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if inst.ElemType == nil {
+		// Element type is implicit in older versions of LLVM.
+		ptr, ok := scalarType(src.typ).(*types.PointerType)
+		if !ok || isOpaquePointer(ptr) {
+			return errors.Errorf("invalid getelementptr source type; expected typed pointer, got %v", src.typ)
+		}
+		inst.ElemType = ptr.ElemType
+	}
+	var indices []bcRef
+	for i < len(ops) {
+		index, err := fr.typedValue(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		indices = append(indices, index)
+	}
+	typ, err := fr.gepType(inst.ElemType, src.typ, indices)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst.Typ = typ
+	fr.addInst(inst, typ)
+	fr.fill(func() error {
+		v, err := fr.value(src)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Src = v
+		inst.Indices, err = fr.values(indices...)
+		return err
+	})
+	return nil
+}
+
+// gepType returns the result type of a getelementptr instruction, based on its
+// element type, source type and indices.
+func (fr *bcFuncReader) gepType(elemType, srcType types.Type, indices []bcRef) (types.Type, error) {
+	ptr, ok := scalarType(srcType).(*types.PointerType)
+	if !ok {
+		return nil, errors.Errorf("invalid getelementptr source type; expected *types.PointerType, got %T", srcType)
+	}
+	// Vector of pointers if any operand is a vector.
+	var vec *types.VectorType
+	if t, ok := srcType.(*types.VectorType); ok {
+		vec = t
+	}
+	t := elemType
+	for j, index := range indices {
+		if it, ok := index.typ.(*types.VectorType); ok {
+			vec = it
+		}
+		if j == 0 {
+			// The first index steps through the source pointer.
+			continue
+		}
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			t = tt.ElemType
+		case *types.VectorType:
+			t = tt.ElemType
+		case *types.StructType:
+			c, err := fr.br.constant(index.id)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid structure index of getelementptr")
+			}
+			x, ok := c.(*ir.ConstInt)
+			if !ok || !x.X.IsInt64() || x.X.Int64() < 0 || x.X.Int64() >= int64(len(tt.Fields)) {
+				return nil, errors.Errorf("invalid structure index of getelementptr; expected integer constant in range [0, %d)", len(tt.Fields))
+			}
+			t = tt.Fields[x.X.Int64()]
+		default:
+			return nil, errors.Errorf("invalid indexed type of getelementptr; expected aggregate type, got %T", t)
+		}
+	}
+	typ := types.NewPointer(t)
+	if isOpaquePointer(ptr) {
+		// The result of getelementptr on an opaque pointer is an opaque pointer.
+		typ.ElemType = nil
+	}
+	typ.AddrSpace = ptr.AddrSpace
+	if vec != nil {
+		return vectorOf(vec, typ), nil
+	}
+	return typ, nil
+}
+
+// --- [ Conversion instructions ] ---------------------------------------------
+
+// readCast reads the given INST_CAST record.
+//
+//    [opval, destty, castopc]
+func (fr *bcFuncReader) readCast(ops []uint64) error {
+	i := 0
+	from, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if i+2 > len(ops) {
+		return errors.New("invalid INST_CAST record; missing destination type or opcode")
+	}
+	to, err := fr.br.typ(ops[i])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var inst ir.Instruction
+	switch opcode := ops[i+1]; opcode {
+	case bcCastTrunc:
+		inst = &ir.InstTrunc{To: to}
+	case bcCastZExt:
+		inst = &ir.InstZExt{To: to}
+	case bcCastSExt:
+		inst = &ir.InstSExt{To: to}
+	case bcCastFPToUI:
+		inst = &ir.InstFPToUI{To: to}
+	case bcCastFPToSI:
+		inst = &ir.InstFPToSI{To: to}
+	case bcCastUIToFP:
+		inst = &ir.InstUIToFP{To: to}
+	case bcCastSIToFP:
+		inst = &ir.InstSIToFP{To: to}
+	case bcCastFPTrunc:
+		inst = &ir.InstFPTrunc{To: to}
+	case bcCastFPExt:
+		inst = &ir.InstFPExt{To: to}
+	case bcCastPtrToInt:
+		inst = &ir.InstPtrToInt{To: to}
+	case bcCastIntToPtr:
+		inst = &ir.InstIntToPtr{To: to}
+	case bcCastBitCast:
+		inst = &ir.InstBitCast{To: to}
+	case bcCastAddrSpaceCast:
+		inst = &ir.InstAddrSpaceCast{To: to}
+	default:
+		return errors.Errorf("support for cast opcode %d not yet implemented", opcode)
+	}
+	fr.addInst(inst, to)
+	fr.fill(func() error {
+		v, err := fr.value(from)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch inst := inst.(type) {
+		case *ir.InstTrunc:
+			inst.From = v
+		case *ir.InstZExt:
+			inst.From = v
+		case *ir.InstSExt:
+			inst.From = v
+		case *ir.InstFPToUI:
+			inst.From = v
+		case *ir.InstFPToSI:
+			inst.From = v
+		case *ir.InstUIToFP:
+			inst.From = v
+		case *ir.InstSIToFP:
+			inst.From = v
+		case *ir.InstFPTrunc:
+			inst.From = v
+		case *ir.InstFPExt:
+			inst.From = v
+		case *ir.InstPtrToInt:
+			inst.From = v
+		case *ir.InstIntToPtr:
+			inst.From = v
+		case *ir.InstBitCast:
+			inst.From = v
+		case *ir.InstAddrSpaceCast:
+			inst.From = v
+		}
+		return nil
+	})
+	return nil
+}
+
+// --- [ Other instructions ] --------------------------------------------------
+
+// readCmp reads the given INST_CMP or INST_CMP2 record.
+//
+//    [opval, opval, pred, flags<optional>]
+func (fr *bcFuncReader) readCmp(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	y, err := fr.untypedValue(ops, &i, x.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if i >= len(ops) {
+		return errors.New("invalid INST_CMP record; missing predicate")
+	}
+	pred := ops[i]
+	// Result type; i1 or vector of i1.
+	var typ types.Type = types.I1
+	if t, ok := x.typ.(*types.VectorType); ok {
+		typ = vectorOf(t, types.I1)
+	}
+	if isFloatOrFloatVector(x.typ) {
+		if pred >= uint64(len(bcFPredNames)) {
+			return errors.Errorf("invalid floating-point comparison predicate %d", pred)
+		}
+		// TODO: handle fast math flags.
+		inst := &ir.InstFCmp{Pred: asmenum.FPredFromString(bcFPredNames[pred])}
+		fr.addInst(inst, typ)
+		fr.fill(func() error {
+			vs, err := fr.values(x, y)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			inst.X, inst.Y = vs[0], vs[1]
+			return nil
+		})
+		return nil
+	}
+	name, ok := bcIPredNames[pred]
+	if !ok {
+		return errors.Errorf("invalid integer comparison predicate %d", pred)
+	}
+	inst := &ir.InstICmp{Pred: asmenum.IPredFromString(name)}
+	fr.addInst(inst, typ)
+	fr.fill(func() error {
+		vs, err := fr.values(x, y)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Y = vs[0], vs[1]
+		return nil
+	})
+	return nil
+}
+
+// readPhi reads the given INST_PHI record.
+//
+//    [ty, n x (val, bb#), flags<optional>]
+func (fr *bcFuncReader) readPhi(ops []uint64) error {
+	if len(ops) < 1 {
+		return errors.New("invalid INST_PHI record; missing type")
+	}
+	typ, err := fr.br.typ(ops[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	incs := ops[1:]
+	if len(incs)%2 == 1 {
+		// TODO: handle fast math flags.
+		incs = incs[:len(incs)-1]
+	}
+	// Incoming values are stored as signed relative value IDs.
+	var xs []bcRef
+	for i := 0; i < len(incs); i += 2 {
+		id := uint64(int64(fr.instNum()) - decodeSignRotated(incs[i]))
+		xs = append(xs, bcRef{id: id, typ: typ})
+	}
+	inst := &ir.InstPhi{Typ: typ}
+	fr.addInst(inst, typ)
+	fr.fill(func() error {
+		for j, x := range xs {
+			v, err := fr.value(x)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			pred, err := fr.block(incs[2*j+1])
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			inst.Incs = append(inst.Incs, ir.NewIncoming(v, pred))
+		}
+		return nil
+	})
+	return nil
+}
+
+// readSelect reads the given INST_VSELECT record.
+//
+//    [opval, opval, pred]
+func (fr *bcFuncReader) readSelect(ops []uint64) error {
+	i := 0
+	x, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	y, err := fr.untypedValue(ops, &i, x.typ)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cond, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inst := &ir.InstSelect{Typ: x.typ}
+	fr.addInst(inst, x.typ)
+	fr.fill(func() error {
+		vs, err := fr.values(cond, x, y)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Cond, inst.X, inst.Y = vs[0], vs[1], vs[2]
+		return nil
+	})
+	return nil
+}
+
+// readCall reads the given INST_CALL record.
+//
+//    [paramattrs, cc, fmf<optional>, fnty<optional>, fnid, args...]
+func (fr *bcFuncReader) readCall(ops []uint64) error {
+	if len(ops) < 3 {
+		return errors.Errorf("invalid number of INST_CALL operands; expected at least 3, got %d", len(ops))
+	}
+	// TODO: handle parameter attributes.
+	cc := ops[1]
+	i := 2
+	inst := &ir.InstCall{}
+	if cc&bcCallFMF != 0 {
+		inst.FastMathFlags = bcFastMathFlags(ops[i])
+		i++
+	}
+	var sig *types.FuncType
+	if cc&bcCallExplicitType != 0 {
+		if i >= len(ops) {
+			return errors.New("invalid INST_CALL record; missing function type")
+		}
+		t, err := fr.br.typ(ops[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if sig, _ = t.(*types.FuncType); sig == nil {
+			return errors.Errorf("invalid callee type; expected *types.FuncType, got %T", t)
+		}
+		i++
+	}
+	callee, err := fr.typedValue(ops, &i)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sig == nil {
+		// Function type is implicit in older versions of LLVM.
+		if ptr, ok := callee.typ.(*types.PointerType); ok {
+			sig, _ = ptr.ElemType.(*types.FuncType)
+		}
+		if sig == nil {
+			return errors.Errorf("invalid callee type; expected pointer to *types.FuncType, got %v", callee.typ)
+		}
+	}
+	// Calling convention.
+	name, ok := bcCallingConvNames[(cc>>bcCallCConvShift)&bcCallCConvMask]
+	if !ok {
+		return errors.Errorf("support for calling convention %d not yet implemented", (cc>>bcCallCConvShift)&bcCallCConvMask)
+	}
+	inst.CallingConv = asmenum.CallingConvFromString(name)
+	// TODO: handle tail, musttail and notail.
+	// Function arguments.
+	var args []bcRef
+	for _, param := range sig.Params {
+		if _, ok := param.(*types.LabelType); ok {
+			return errors.New("support for label arguments not yet implemented")
+		}
+		arg, err := fr.untypedValue(ops, &i, param)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		args = append(args, arg)
+	}
+	// Variadic arguments.
+	for i < len(ops) {
+		if !sig.Variadic {
+			return errors.New("invalid INST_CALL record; too many arguments for non-variadic function")
+		}
+		arg, err := fr.typedValue(ops, &i)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		args = append(args, arg)
+	}
+	inst.Typ = sig.RetType
+	fr.addInst(inst, sig.RetType)
+	fr.fill(func() error {
+		v, err := fr.value(callee)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Callee = v
+		inst.Args, err = fr.values(args...)
+		return err
+	})
+	return nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// instNum returns the value ID of the next instruction.
+func (fr *bcFuncReader) instNum() uint64 {
+	return uint64(len(fr.br.values))
+}
+
+// addInst adds the given instruction of the specified type to the current
+// basic block. Non-void instructions are added to the value table.
+func (fr *bcFuncReader) addInst(inst ir.Instruction, typ types.Type) {
+	block := fr.blocks[fr.cur]
+	block.Insts = append(block.Insts, inst)
+	if !typ.Equal(types.Void) {
+		v, ok := inst.(value.Value)
+		if !ok {
+			// NOTE: panic since this would indicate a bug in the implementation.
+			panic(fmt.Errorf("invalid non-void instruction; expected value.Value, got %T", inst))
+		}
+		fr.br.addValue(v, typ)
+	}
+}
+
+// addTerm sets the terminator of the current basic block, and moves to the
+// next basic block.
+func (fr *bcFuncReader) addTerm(term ir.Terminator) {
+	fr.blocks[fr.cur].Term = term
+	fr.cur++
+}
+
+// fill adds a function filling in the operands of an instruction.
+func (fr *bcFuncReader) fill(f func() error) {
+	fr.fills = append(fr.fills, f)
+}
+
+// typedValue decodes the relative value ID at index *i of ops, and advances *i.
+// The type of forward references (i.e. value IDs not yet defined) follows the
+// value ID.
+func (fr *bcFuncReader) typedValue(ops []uint64, i *int) (bcRef, error) {
+	if *i >= len(ops) {
+		return bcRef{}, errors.New("invalid record; missing value operand")
+	}
+	id := fr.relID(ops[*i])
+	*i++
+	if id < fr.instNum() {
+		return bcRef{id: id, typ: fr.br.values[id].typ}, nil
+	}
+	if *i >= len(ops) {
+		return bcRef{}, errors.Errorf("invalid record; missing type of forward referenced value ID %d", id)
+	}
+	typ, err := fr.br.typ(ops[*i])
+	if err != nil {
+		return bcRef{}, errors.WithStack(err)
+	}
+	*i++
+	return bcRef{id: id, typ: typ}, nil
+}
+
+// untypedValue decodes the relative value ID at index *i of ops, and advances
+// *i. The type of the value is implied by the instruction.
+func (fr *bcFuncReader) untypedValue(ops []uint64, i *int, typ types.Type) (bcRef, error) {
+	if *i >= len(ops) {
+		return bcRef{}, errors.New("invalid record; missing value operand")
+	}
+	id := fr.relID(ops[*i])
+	*i++
+	return bcRef{id: id, typ: typ}, nil
+}
+
+// relID returns the absolute value ID of the given relative value ID.
+func (fr *bcFuncReader) relID(rel uint64) uint64 {
+	// Relative value IDs of forward references wrap around as unsigned 32-bit
+	// integers.
+	return uint64(uint32(fr.instNum()) - uint32(rel))
+}
+
+// value returns the value referred to by the given value reference.
+//
+// Pre-condition: all instructions of the function have been created.
+func (fr *bcFuncReader) value(ref bcRef) (value.Value, error) {
+	if ref.id >= uint64(len(fr.br.values)) {
+		return nil, errors.Errorf("invalid value ID %d; expected < %d", ref.id, len(fr.br.values))
+	}
+	if v := fr.br.values[ref.id]; v.v != nil {
+		return v.v, nil
+	}
+	return fr.br.constant(ref.id)
+}
+
+// values returns the values referred to by the given value references.
+//
+// Pre-condition: all instructions of the function have been created.
+func (fr *bcFuncReader) values(refs ...bcRef) ([]value.Value, error) {
+	var vs []value.Value
+	for _, ref := range refs {
+		v, err := fr.value(ref)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// block returns the basic block of the given index.
+func (fr *bcFuncReader) block(index uint64) (*ir.BasicBlock, error) {
+	if index >= uint64(len(fr.blocks)) {
+		return nil, errors.Errorf("invalid basic block index %d; expected < %d", index, len(fr.blocks))
+	}
+	return fr.blocks[index], nil
+}
+
+// bcAggregateElemType returns the element type of the given aggregate type,
+// based on the specified indices.
+func bcAggregateElemType(t types.Type, indices []uint64) (types.Type, error) {
+	for _, index := range indices {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			t = tt.ElemType
+		case *types.StructType:
+			if index >= uint64(len(tt.Fields)) {
+				return nil, errors.Errorf("invalid structure index %d; expected < %d", index, len(tt.Fields))
+			}
+			t = tt.Fields[index]
+		default:
+			return nil, errors.Errorf("invalid aggregate type; expected *types.ArrayType or *types.StructType, got %T", t)
+		}
+	}
+	return t, nil
+}
+
+// bcOverflowFlags returns the IR overflow flags of the given binary operation
+// flags.
+func bcOverflowFlags(flags uint64) []enum.OverflowFlag {
+	var overflowFlags []enum.OverflowFlag
+	if flags&bcOBONoUnsignedWrap != 0 {
+		overflowFlags = append(overflowFlags, asmenum.OverflowFlagFromString("nuw"))
+	}
+	if flags&bcOBONoSignedWrap != 0 {
+		overflowFlags = append(overflowFlags, asmenum.OverflowFlagFromString("nsw"))
+	}
+	return overflowFlags
+}
+
+// bcFastMathFlags returns the IR fast math flags of the given fast math flags
+// bitmask.
+func bcFastMathFlags(flags uint64) []enum.FastMathFlag {
+	// Bit 0 denotes unsafe algebra in older versions of LLVM.
+	if flags&1 != 0 || flags&bcFastMathAll == bcFastMathAll {
+		return []enum.FastMathFlag{asmenum.FastMathFlagFromString("fast")}
+	}
+	var fmf []enum.FastMathFlag
+	for _, flag := range bcFastMathFlagBits {
+		if flags&flag.bit != 0 {
+			fmf = append(fmf, asmenum.FastMathFlagFromString(flag.name))
+		}
+	}
+	return fmf
+}
+
+// bcAlign returns the alignment in bytes corresponding to the given encoded
+// alignment (log2 of the alignment in bytes, plus one).
+func bcAlign(x uint64) ir.Align {
+	if x == 0 {
+		return 0
+	}
+	return ir.Align(1) << (x - 1)
+}
+
+// isFloatOrFloatVector reports whether the given type is a floating-point type
+// or a vector of floating-point types.
+func isFloatOrFloatVector(t types.Type) bool {
+	_, ok := scalarType(t).(*types.FloatType)
+	return ok
+}
+
+// scalarType returns the element type of the given vector type, or the type
+// itself if not a vector type.
+func scalarType(t types.Type) types.Type {
+	if t, ok := t.(*types.VectorType); ok {
+		return t.ElemType
+	}
+	return t
+}
@@ -0,0 +1,250 @@
+package asm
+
+import (
+	"github.com/llir/l/ir/types"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// === [ Types ] ===============================================================
+
+// readTypes reads the type table of the current TYPE block.
+//
+// Named structure types may be referred to before being defined (e.g. by
+// pointer types of self-referential structure types), in which case a
+// placeholder structure type is added to the type table, which is later
+// filled in by the definition.
+func (br *bcReader) readTypes() error {
+	// Number of types defined.
+	n := 0
+	err := br.readRecords(func(rec *bitstream.Record) error {
+		if rec.Code == bcTypeNumEntry {
+			return nil
+		}
+		if rec.Code == bcTypeStructName {
+			br.structName = recordString(rec.Ops)
+			return nil
+		}
+		var placeholder *types.StructType
+		if n < len(br.types) && br.types[n] != nil {
+			placeholder = br.types[n].(*types.StructType)
+		}
+		t, err := br.readType(rec, placeholder)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if placeholder != nil && t != placeholder {
+			return errors.Errorf("invalid forward reference to type ID %d; expected named structure type, got %T", n, t)
+		}
+		if n < len(br.types) {
+			br.types[n] = t
+		} else {
+			br.types = append(br.types, t)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for id := n; id < len(br.types); id++ {
+		if br.types[id] != nil {
+			return errors.Errorf("invalid forward reference to undefined type ID %d", id)
+		}
+	}
+	return nil
+}
+
+// readType returns the type of the given TYPE block record. Named structure
+// types fill in the given placeholder structure type, if non-nil.
+func (br *bcReader) readType(rec *bitstream.Record, placeholder *types.StructType) (types.Type, error) {
+	ops := rec.Ops
+	switch rec.Code {
+	case bcTypeVoid:
+		return types.Void, nil
+	case bcTypeHalf:
+		return &types.FloatType{Kind: types.FloatKindHalf}, nil
+	case bcTypeBFloat:
+		return &types.FloatType{Kind: types.FloatKindBFloat}, nil
+	case bcTypeFloat:
+		return &types.FloatType{Kind: types.FloatKindFloat}, nil
+	case bcTypeDouble:
+		return &types.FloatType{Kind: types.FloatKindDouble}, nil
+	case bcTypeX86FP80:
+		return &types.FloatType{Kind: types.FloatKindX86FP80}, nil
+	case bcTypeFP128:
+		return &types.FloatType{Kind: types.FloatKindFP128}, nil
+	case bcTypePPCFP128:
+		return &types.FloatType{Kind: types.FloatKindPPCFP128}, nil
+	case bcTypeLabel:
+		return &types.LabelType{}, nil
+	case bcTypeMetadata:
+		return &types.MetadataType{}, nil
+	case bcTypeX86MMX:
+		return &types.MMXType{}, nil
+	case bcTypeX86AMX:
+		return &types.AMXType{}, nil
+	case bcTypeToken:
+		return &types.TokenType{}, nil
+	case bcTypeInteger:
+		// [width]
+		if len(ops) < 1 {
+			return nil, errors.New("invalid INTEGER type record; missing bit width")
+		}
+		return &types.IntType{BitSize: int64(ops[0])}, nil
+	case bcTypePointer:
+		// [pointee type, addrspace]
+		if len(ops) < 1 {
+			return nil, errors.New("invalid POINTER type record; missing pointee type")
+		}
+		elem, err := br.typeRef(ops[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		typ := types.NewPointer(elem)
+		if len(ops) > 1 {
+			typ.AddrSpace = types.AddrSpace(ops[1])
+		}
+		return typ, nil
+	case bcTypeArray, bcTypeVector:
+		// [numelts, eltty]
+		// [numelts, eltty, scalable]
+		if len(ops) < 2 {
+			return nil, errors.New("invalid ARRAY or VECTOR type record; missing length or element type")
+		}
+		elem, err := br.typeRef(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if rec.Code == bcTypeVector {
+			scalable := len(ops) > 2 && ops[2] != 0
+			return &types.VectorType{Len: int64(ops[0]), Scalable: scalable, ElemType: elem}, nil
+		}
+		return &types.ArrayType{Len: int64(ops[0]), ElemType: elem}, nil
+	case bcTypeFunction, bcTypeFunctionOld:
+		// [vararg, retty, paramty x N]
+		// [vararg, attrid, retty, paramty x N]
+		if rec.Code == bcTypeFunctionOld && len(ops) > 0 {
+			ops = append([]uint64{ops[0]}, ops[2:]...)
+		}
+		if len(ops) < 2 {
+			return nil, errors.New("invalid FUNCTION type record; missing return type")
+		}
+		typ := &types.FuncType{Variadic: ops[0] != 0}
+		retType, err := br.typeRef(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		typ.RetType = retType
+		for _, op := range ops[2:] {
+			param, err := br.typeRef(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			typ.Params = append(typ.Params, param)
+		}
+		return typ, nil
+	case bcTypeStructAnon, bcTypeStructNamed:
+		// [ispacked, eltty x N]
+		if len(ops) < 1 {
+			return nil, errors.New("invalid STRUCT type record; missing packed flag")
+		}
+		typ := &types.StructType{}
+		if rec.Code == bcTypeStructNamed {
+			typ = br.namedStruct(placeholder)
+		}
+		typ.Packed = ops[0] != 0
+		for _, op := range ops[1:] {
+			field, err := br.typeRef(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			typ.Fields = append(typ.Fields, field)
+		}
+		typ.Opaque = false
+		return typ, nil
+	case bcTypeOpaque:
+		typ := br.namedStruct(placeholder)
+		typ.Opaque = true
+		return typ, nil
+	case bcTypeOpaquePointer:
+		// [addrspace]
+		br.opaquePointers = true
+		typ := &types.PointerType{}
+		if len(ops) > 0 {
+			typ.AddrSpace = types.AddrSpace(ops[0])
+		}
+		return typ, nil
+	case bcTypeTargetType:
+		// [numtys, ty x numtys, int x N]
+		//
+		// The name of target extension types is given by the preceding
+		// STRUCT_NAME record.
+		if len(ops) < 1 || uint64(len(ops)-1) < ops[0] {
+			return nil, errors.New("invalid TARGET_TYPE type record; missing type parameters")
+		}
+		typ := &types.TargetExtType{TypeName: br.structName}
+		br.structName = ""
+		n := ops[0]
+		for _, op := range ops[1 : 1+n] {
+			param, err := br.typeRef(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			typ.TypeParams = append(typ.TypeParams, param)
+		}
+		typ.IntParams = append(typ.IntParams, ops[1+n:]...)
+		return typ, nil
+	default:
+		return nil, errors.Errorf("support for type record code %d not yet implemented", rec.Code)
+	}
+}
+
+// namedStruct returns the named structure type being defined, using the name
+// of the preceding STRUCT_NAME record. The type definition is added to the IR
+// module.
+func (br *bcReader) namedStruct(placeholder *types.StructType) *types.StructType {
+	typ := placeholder
+	if typ == nil {
+		typ = &types.StructType{}
+	}
+	typ.Alias = br.structName
+	br.structName = ""
+	br.m.TypeDefs = append(br.m.TypeDefs, typ)
+	return typ
+}
+
+// typeRef returns the type of the given type ID, as referred to from within the
+// TYPE block. A placeholder structure type is returned for forward references.
+func (br *bcReader) typeRef(id uint64) (types.Type, error) {
+	if id >= uint64(len(br.types)) {
+		// Limit the size of the type table, in case of invalid input.
+		if id > uint64(len(br.types))+(1<<16) {
+			return nil, errors.Errorf("invalid type ID %d; expected < %d", id, len(br.types))
+		}
+		for uint64(len(br.types)) <= id {
+			br.types = append(br.types, nil)
+		}
+	}
+	if br.types[id] == nil {
+		br.types[id] = &types.StructType{Opaque: true}
+	}
+	return br.types[id], nil
+}
+
+// typ returns the type of the given type ID.
+func (br *bcReader) typ(id uint64) (types.Type, error) {
+	if id >= uint64(len(br.types)) || br.types[id] == nil {
+		return nil, errors.Errorf("invalid type ID %d; expected < %d", id, len(br.types))
+	}
+	return br.types[id], nil
+}
+
+// pointerTo returns a pointer type with the given element type; or an opaque
+// pointer type if the module uses opaque pointers.
+func (br *bcReader) pointerTo(elem types.Type) *types.PointerType {
+	if br.opaquePointers {
+		return &types.PointerType{}
+	}
+	return types.NewPointer(elem)
+}
@@ -0,0 +1,554 @@
+package asm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/mewmew/l-tm/asm/datalayout"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// WriteBitcodeFile writes the given LLVM IR module to the specified LLVM
+// bitcode file.
+func WriteBitcodeFile(path string, m *ir.Module) error {
+	buf := &bytes.Buffer{}
+	if err := WriteBitcode(buf, m); err != nil {
+		return errors.Wrapf(err, "unable to write bitcode file %q", path)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// WriteBitcode writes the given LLVM IR module to w, as LLVM bitcode.
+//
+// The bitcode writer handles the same constructs as the bitcode reader; thus,
+// constructs not yet handled by ParseBitcode (e.g. metadata, attributes and
+// use-list orders) are omitted. An error is returned if the data layout of the
+// module is invalid.
+func WriteBitcode(w io.Writer, m *ir.Module) error {
+	bw := newBCWriter(m)
+	// The data layout is used to compute the alignment of atomic loads and
+	// stores without explicit alignment.
+	dl, err := datalayout.Parse(m.DataLayout)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	bw.dl = dl
+	buf, err := bw.write()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := w.Write(bcMagic); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := w.Write(buf); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// bcWriter keeps track of the type table, value table and string table when
+// writing LLVM IR modules to bitcode.
+type bcWriter struct {
+	// Bitstream writer.
+	w *bitstream.Writer
+	// LLVM IR module being written.
+	m *ir.Module
+	// Data layout of the module.
+	dl *datalayout.DataLayout
+
+	// Type table; maps from type ID to IR type.
+	types []types.Type
+	// Type IDs; maps from type (in LLVM IR assembly notation) to type ID.
+	typeIDs map[string]uint64
+	// Named structure types, in order of occurrence; type IDs are assigned to
+	// named structure types after all other types.
+	structs []*types.StructType
+	// Tracks visited named structure types; maps from type name to true.
+	visited map[string]bool
+
+	// Value table of module-level values (global variables, functions and
+	// constants).
+	values *bcValueTable
+
+	// String table holding the names of global variables and functions.
+	strtab []byte
+	// Number of elements (i.e. `i32 1`) of alloca instructions without
+	// explicit number of elements.
+	allocaOne ir.Constant
+	// Synchronization scopes; maps from synchronization scope ID to name.
+	syncScopes []string
+}
+
+// newBCWriter returns a new writer of the given LLVM IR module.
+func newBCWriter(m *ir.Module) *bcWriter {
+	one, err := ir.NewIntFromString(types.I32, "1")
+	if err != nil {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("unable to create integer constant; %v", err))
+	}
+	return &bcWriter{
+		w:         bitstream.NewWriter(),
+		m:         m,
+		typeIDs:   make(map[string]uint64),
+		visited:   make(map[string]bool),
+		values:    newBCValueTable(nil),
+		allocaOne: one,
+		// The default synchronization scopes are always present, as with LLVM.
+		syncScopes: append([]string(nil), bcDefaultSyncScopes...),
+	}
+}
+
+// write writes the top-level blocks of the bitstream, and returns the contents
+// of the bitstream (without the magic number).
+func (bw *bcWriter) write() ([]byte, error) {
+	// Type table.
+	if err := bw.enumTypes(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Value table; global variables, followed by functions and constants.
+	for _, g := range bw.m.Globals {
+		bw.values.add(g)
+	}
+	for _, f := range bw.m.Funcs {
+		bw.values.add(f)
+	}
+	for _, g := range bw.m.Globals {
+		if g.Init == nil {
+			continue
+		}
+		if err := bw.values.enumConst(g.Init); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := bw.writeIdentification(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := bw.w.EnterBlock(bcModuleBlockID, 3); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := bw.writeModule(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := bw.w.ExitBlock(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := bw.writeStrtab(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return bw.w.Bytes()
+}
+
+// writeIdentification writes the IDENTIFICATION block, specifying the producer
+// of the bitcode.
+func (bw *bcWriter) writeIdentification() error {
+	if err := bw.w.EnterBlock(bcIdentificationBlockID, 5); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.writeRecord(bcIdentificationString, stringOps("l-tm")...); err != nil {
+		return errors.WithStack(err)
+	}
+	// Bitcode epoch.
+	if err := bw.writeRecord(bcIdentificationEpoch, 0); err != nil {
+		return errors.WithStack(err)
+	}
+	return bw.w.ExitBlock()
+}
+
+// writeStrtab writes the STRTAB block, holding the names of global variables
+// and functions.
+func (bw *bcWriter) writeStrtab() error {
+	if err := bw.w.EnterBlock(bcStrtabBlockID, 3); err != nil {
+		return errors.WithStack(err)
+	}
+	// The string table is stored as blob, which requires an abbreviation.
+	//
+	//    [STRTAB_BLOB, blob]
+	abbrev := &bitstream.Abbrev{
+		Ops: []bitstream.AbbrevOp{bitstream.Literal(bcStrtabBlob), bitstream.Blob()},
+	}
+	abbrevID, err := bw.w.DefineAbbrev(abbrev)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	rec := &bitstream.Record{Code: bcStrtabBlob, Blob: bw.strtab}
+	if rec.Blob == nil {
+		rec.Blob = []byte{}
+	}
+	if err := bw.w.WriteAbbrevRecord(abbrevID, rec); err != nil {
+		return errors.WithStack(err)
+	}
+	return bw.w.ExitBlock()
+}
+
+// === [ Module ] ==============================================================
+
+// writeModule writes the contents of the current MODULE block.
+func (bw *bcWriter) writeModule() error {
+	// Version 2 stores names of global values in the string table.
+	if err := bw.writeRecord(bcModuleVersion, 2); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.writeTypes(); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(bw.m.TargetTriple) > 0 {
+		if err := bw.writeRecord(bcModuleTriple, stringOps(bw.m.TargetTriple)...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(bw.m.DataLayout) > 0 {
+		if err := bw.writeRecord(bcModuleDataLayout, stringOps(bw.m.DataLayout)...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(bw.m.SourceFilename) > 0 {
+		if err := bw.writeRecord(bcModuleSourceFilename, stringOps(bw.m.SourceFilename)...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, g := range bw.m.Globals {
+		if err := bw.writeGlobalVar(g); err != nil {
+			return errors.Wrapf(err, "unable to write global variable %q", g.GlobalName)
+		}
+	}
+	for _, f := range bw.m.Funcs {
+		if err := bw.writeFuncDecl(f); err != nil {
+			return errors.Wrapf(err, "unable to write function %q", f.GlobalName)
+		}
+	}
+	// TODO: handle aliases, IFuncs, comdats, module-level inline assembly,
+	// attributes and metadata.
+	if err := bw.writeConsts(bw.values); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.writeSyncScopeNames(); err != nil {
+		return errors.WithStack(err)
+	}
+	// Function bodies, in order of function definitions.
+	for _, f := range bw.m.Funcs {
+		if len(f.Blocks) == 0 {
+			continue
+		}
+		if err := bw.writeFunc(f); err != nil {
+			return errors.Wrapf(err, "unable to write body of function %q", f.GlobalName)
+		}
+	}
+	return nil
+}
+
+// writeSyncScopeNames writes the synchronization scope names as a
+// SYNC_SCOPE_NAMES block, in order of synchronization scope ID.
+func (bw *bcWriter) writeSyncScopeNames() error {
+	// Synchronization scopes of atomic instructions.
+	for _, f := range bw.m.Funcs {
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if syncScope, ok := instSyncScope(inst); ok {
+					bw.syncScopeID(syncScope)
+				}
+			}
+		}
+	}
+	if err := bw.w.EnterBlock(bcSyncScopeNamesBlockID, 2); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, name := range bw.syncScopes {
+		// [strchr x N]
+		if err := bw.writeRecord(bcSyncScopeName, stringOps(name)...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return bw.w.ExitBlock()
+}
+
+// syncScopeID returns the synchronization scope ID of the given
+// synchronization scope, adding it to the synchronization scopes if not yet
+// present.
+func (bw *bcWriter) syncScopeID(syncScope string) uint64 {
+	for id, name := range bw.syncScopes {
+		if name == syncScope {
+			return uint64(id)
+		}
+	}
+	bw.syncScopes = append(bw.syncScopes, syncScope)
+	return uint64(len(bw.syncScopes) - 1)
+}
+
+// ~~~ [ Global Variable ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writeGlobalVar writes the GLOBALVAR record of the given global variable.
+//
+//    [strtab_offset, strtab_size, pointer type, isconst, initid, linkage,
+//     alignment, section, visibility, threadlocal, unnamed_addr,
+//     externally_initialized, dllstorageclass, comdat, attributes, preemption]
+func (bw *bcWriter) writeGlobalVar(g *ir.Global) error {
+	offset, size := bw.addName(g.GlobalName)
+	// Bit 1 of isconst indicates an explicit content type, with the address
+	// space stored in the upper bits.
+	isConst := uint64(g.Typ.AddrSpace)<<2 | 2
+	if g.Immutable {
+		isConst |= 1
+	}
+	// Initializer; stored as value ID + 1, with 0 denoting a declaration.
+	var initID uint64
+	if g.Init != nil {
+		id, err := bw.values.id(g.Init)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		initID = id + 1
+	}
+	linkage, err := bcLinkageCode(g.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	visibility, err := bcVisibilityCode(g.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tlsModel, err := bcTLSModelCode(g.TLSModel)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	unnamedAddr, err := bcUnnamedAddrCode(g.UnnamedAddr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var externallyInitialized uint64
+	if g.ExternallyInitialized {
+		externallyInitialized = 1
+	}
+	dllStorageClass, err := bcDLLStorageClassCode(g.DLLStorageClass)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// TODO: handle GlobalAttrs, Section, Comdat and FuncAttrs.
+	ops := []uint64{
+		offset, size,
+		bw.typeID(g.ContentType),
+		isConst,
+		initID,
+		linkage,
+		0, // alignment
+		0, // section
+		visibility,
+		tlsModel,
+		unnamedAddr,
+		externallyInitialized,
+		dllStorageClass,
+		0, // comdat
+		0, // attributes
+		bcPreemptionCode(g.Preemption, linkage, visibility),
+	}
+	return bw.writeRecord(bcModuleGlobalVar, ops...)
+}
+
+// ~~~ [ Function ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+// writeFuncDecl writes the FUNCTION record of the given function.
+//
+//    [strtab_offset, strtab_size, type, callingconv, isproto, linkage,
+//     paramattrs, alignment, section, visibility, gc, unnamed_addr,
+//     prologuedata, dllstorageclass, comdat, prefixdata, personalityfn,
+//     preemption, addrspace]
+func (bw *bcWriter) writeFuncDecl(f *ir.Function) error {
+	offset, size := bw.addName(f.GlobalName)
+	callingConv, err := bcCallingConvCode(f.CallingConv)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var isProto uint64
+	if len(f.Blocks) == 0 {
+		isProto = 1
+	}
+	linkage, err := bcLinkageCode(f.Linkage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	visibility, err := bcVisibilityCode(f.Visibility)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	unnamedAddr, err := bcUnnamedAddrCode(f.UnnamedAddr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dllStorageClass, err := bcDLLStorageClassCode(f.DLLStorageClass)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// TODO: handle ReturnAttrs, FuncAttrs, Section, GC, Prologue, Comdat,
+	// Prefix and Personality.
+	ops := []uint64{
+		offset, size,
+		bw.typeID(f.Sig),
+		callingConv,
+		isProto,
+		linkage,
+		0, // paramattrs
+		0, // alignment
+		0, // section
+		visibility,
+		0, // gc
+		unnamedAddr,
+		0, // prologuedata
+		dllStorageClass,
+		0, // comdat
+		0, // prefixdata
+		0, // personalityfn
+		bcPreemptionCode(f.Preemption, linkage, visibility),
+		uint64(f.Typ.AddrSpace),
+	}
+	return bw.writeRecord(bcModuleFunction, ops...)
+}
+
+// ### [ Helpers ] #############################################################
+
+// writeRecord writes an unabbreviated record of the given code and operands.
+func (bw *bcWriter) writeRecord(code uint64, ops ...uint64) error {
+	return bw.w.WriteRecord(&bitstream.Record{Code: code, Ops: ops})
+}
+
+// addName adds the given name of a global variable or function to the string
+// table, and returns its string table offset and size.
+func (bw *bcWriter) addName(name string) (offset, size uint64) {
+	offset = uint64(len(bw.strtab))
+	bw.strtab = append(bw.strtab, name...)
+	return offset, uint64(len(name))
+}
+
+// stringOps returns the record operands of the given string, each holding one
+// character.
+func stringOps(s string) []uint64 {
+	ops := make([]uint64, len(s))
+	for i := 0; i < len(s); i++ {
+		ops[i] = uint64(s[i])
+	}
+	return ops
+}
+
+// encodeSignRotated encodes the given signed integer as a sign rotated value,
+// as used by bitcode to store signed integers (with the sign stored in the
+// least significant bit).
+func encodeSignRotated(x int64) uint64 {
+	if x >= 0 {
+		return uint64(x) << 1
+	}
+	// The minimum signed 64-bit integer is encoded as 1 (i.e. negative zero).
+	return uint64(-x)<<1 | 1
+}
+
+// bcLinkageCode returns the linkage code of the given IR linkage.
+func bcLinkageCode(linkage enum.Linkage) (uint64, error) {
+	if linkage == enum.LinkageNone {
+		return bcLinkageCodes["external"], nil
+	}
+	for name, code := range bcLinkageCodes {
+		if asmenum.LinkageFromString(name) == linkage {
+			return code, nil
+		}
+	}
+	return 0, errors.Errorf("support for linkage %v not yet implemented", linkage)
+}
+
+// bcVisibilityCode returns the visibility code of the given IR visibility.
+func bcVisibilityCode(visibility enum.Visibility) (uint64, error) {
+	if visibility == asmenum.VisibilityFromString("default") {
+		return 0, nil
+	}
+	for code, name := range bcVisibilityNames {
+		if asmenum.VisibilityFromString(name) == visibility {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for visibility %v not yet implemented", visibility)
+}
+
+// bcDLLStorageClassCode returns the DLL storage class code of the given IR DLL
+// storage class.
+func bcDLLStorageClassCode(dllStorageClass enum.DLLStorageClass) (uint64, error) {
+	for code, name := range bcDLLStorageClassNames {
+		if asmenum.DLLStorageClassFromString(name) == dllStorageClass {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for DLL storage class %v not yet implemented", dllStorageClass)
+}
+
+// bcTLSModelCode returns the thread local storage model code of the given IR
+// TLS model.
+func bcTLSModelCode(tlsModel enum.TLSModel) (uint64, error) {
+	for code, name := range bcTLSModelNames {
+		if asmenum.TLSModelFromString(name) == tlsModel {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for thread local storage model %v not yet implemented", tlsModel)
+}
+
+// bcUnnamedAddrCode returns the unnamed address code of the given IR unnamed
+// address.
+func bcUnnamedAddrCode(unnamedAddr enum.UnnamedAddr) (uint64, error) {
+	for code, name := range bcUnnamedAddrNames {
+		if asmenum.UnnamedAddrFromString(name) == unnamedAddr {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for unnamed address %v not yet implemented", unnamedAddr)
+}
+
+// bcPreemptionCode returns the preemption code of the given IR preemption.
+// dso_local preemption is implied by the given linkage and visibility codes, as
+// required by LLVM.
+func bcPreemptionCode(preemption enum.Preemption, linkage, visibility uint64) uint64 {
+	if preemption == asmenum.PreemptionFromString("dso_local") || bcImplicitDSOLocal(linkage, visibility) {
+		return 1
+	}
+	return 0
+}
+
+// bcCallingConvCode returns the calling convention code of the given IR calling
+// convention.
+func bcCallingConvCode(callingConv enum.CallingConv) (uint64, error) {
+	if callingConv == asmenum.CallingConvFromString("ccc") {
+		return 0, nil
+	}
+	for code, name := range bcCallingConvNames {
+		if asmenum.CallingConvFromString(name) == callingConv {
+			return code, nil
+		}
+	}
+	return 0, errors.Errorf("support for calling convention %v not yet implemented", callingConv)
+}
+
+// bcAtomicOrderingCode returns the atomic ordering code of the given IR atomic
+// ordering.
+func bcAtomicOrderingCode(ordering enum.AtomicOrdering) (uint64, error) {
+	for code, name := range bcAtomicOrderingNames {
+		if asmenum.AtomicOrderingFromString(name) == ordering {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for atomic ordering %v not yet implemented", ordering)
+}
+
+// bcAtomicOpCode returns the atomic operation code of the given IR atomic
+// operation.
+func bcAtomicOpCode(op enum.AtomicOp) (uint64, error) {
+	for code, name := range bcAtomicOpNames {
+		if atomicOpFromString(name) == op {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for atomic operation %v not yet implemented", op)
+}
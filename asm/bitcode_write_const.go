@@ -0,0 +1,445 @@
+package asm
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Constants ] ===========================================================
+
+// bcValueTable is a value table, assigning value IDs to values when writing
+// bitcode. The value table of a function body extends the module-level value
+// table.
+type bcValueTable struct {
+	// Parent value table; or nil if module-level value table.
+	parent *bcValueTable
+	// Value IDs; maps from value to value ID.
+	ids map[value.Value]uint64
+	// Constants of the value table, in order of value ID.
+	consts []ir.Constant
+	// Value ID of the next value.
+	next uint64
+}
+
+// newBCValueTable returns a new value table, extending the given parent value
+// table (if non-nil).
+func newBCValueTable(parent *bcValueTable) *bcValueTable {
+	vt := &bcValueTable{
+		parent: parent,
+		ids:    make(map[value.Value]uint64),
+	}
+	if parent != nil {
+		vt.next = parent.next
+	}
+	return vt
+}
+
+// add adds the given value to the value table.
+func (vt *bcValueTable) add(v value.Value) {
+	vt.ids[v] = vt.next
+	vt.next++
+}
+
+// lookup returns the value ID of the given value, and a boolean indicating
+// whether the value is present in the value table (or its parent).
+func (vt *bcValueTable) lookup(v value.Value) (uint64, bool) {
+	for ; vt != nil; vt = vt.parent {
+		if id, ok := vt.ids[v]; ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// id returns the value ID of the given value.
+func (vt *bcValueTable) id(v value.Value) (uint64, error) {
+	id, ok := vt.lookup(v)
+	if !ok {
+		return 0, errors.Errorf("unable to locate value ID of %q", v.Ident())
+	}
+	return id, nil
+}
+
+// enumConst adds the given constant to the value table, preceded by its
+// operands (in post-order). Constants already present in the value table (or
+// its parent) are skipped.
+func (vt *bcValueTable) enumConst(c ir.Constant) error {
+	if _, ok := vt.lookup(c); ok {
+		return nil
+	}
+	switch c.(type) {
+	case *ir.Global, *ir.Function:
+		// Global variables and functions are added to the value table by
+		// bcWriter.write.
+		return errors.Errorf("unable to locate value ID of %q", c.Ident())
+	}
+	ops, err := constOperands(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, op := range ops {
+		if err := vt.enumConst(op); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	vt.add(c)
+	vt.consts = append(vt.consts, c)
+	return nil
+}
+
+// writeConsts writes the constants of the given value table as a CONSTANTS
+// block. No block is written if the value table has no constants.
+func (bw *bcWriter) writeConsts(vt *bcValueTable) error {
+	if len(vt.consts) == 0 {
+		return nil
+	}
+	if err := bw.w.EnterBlock(bcConstantsBlockID, 4); err != nil {
+		return errors.WithStack(err)
+	}
+	// The type of constants is specified by SETTYPE records, preceding
+	// constants of a different type than the previous constant.
+	prevType := -1
+	for _, c := range vt.consts {
+		typ := bw.typeID(c.Type())
+		if int(typ) != prevType {
+			// [typeid]
+			if err := bw.writeRecord(bcConstSetType, typ); err != nil {
+				return errors.WithStack(err)
+			}
+			prevType = int(typ)
+		}
+		code, ops, err := bw.constRecord(c, vt)
+		if err != nil {
+			return errors.Wrapf(err, "unable to write constant %q", c.Ident())
+		}
+		if err := bw.writeRecord(code, ops...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return bw.w.ExitBlock()
+}
+
+// constRecord returns the record code and operands of the CONSTANTS block
+// record of the given constant. Constant operands are stored as absolute value
+// IDs of the given value table.
+func (bw *bcWriter) constRecord(c ir.Constant, vt *bcValueTable) (uint64, []uint64, error) {
+	switch c := c.(type) {
+	case *ir.ConstNull, *ir.ConstZeroInitializer, *ir.ConstNone:
+		return bcConstNull, nil, nil
+	case *ir.ConstUndef:
+		return bcConstUndef, nil, nil
+	case *ir.ConstPoison:
+		return bcConstPoison, nil, nil
+	case *ir.ConstInt:
+		// [signed intval]
+		// [n x signed intval]
+		return bcIntRecord(c)
+	case *ir.ConstFloat:
+		// [fpval]
+		ops, err := bcFloatOps(c)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcConstFloat, ops, nil
+	case *ir.ConstCharArray:
+		// [values]
+		data := c.X
+		if n := len(data); n > 0 && data[n-1] == 0 && bytes.IndexByte(data[:n-1], 0) == -1 {
+			// NULL-terminator is implicit.
+			return bcConstCString, stringOps(string(data[:n-1])), nil
+		}
+		return bcConstString, stringOps(string(data)), nil
+	case *ir.ConstStruct, *ir.ConstArray, *ir.ConstVector:
+		// [n x value number]
+		elems, err := constOperands(c)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		var ops []uint64
+		for _, elem := range elems {
+			id, err := vt.id(elem)
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			ops = append(ops, id)
+		}
+		return bcConstAggregate, ops, nil
+	case *ir.ExprGetElementPtr:
+		return bw.gepExprRecord(c, vt)
+	case *ir.ConstBlockAddress:
+		// [fnty, fnval, bb#]
+		fn, err := vt.id(c.Func)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		index, err := blockIndex(c.Func, c.Block)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcConstBlockAddress, []uint64{bw.typeID(c.Func.Typ), fn, index}, nil
+	case *ir.ConstDSOLocalEquivalent:
+		// [gvty, gv]
+		fn, err := vt.id(c.Func)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcConstDSOLocalEquivalent, []uint64{bw.typeID(c.Func.Typ), fn}, nil
+	case *ir.ConstNoCFI:
+		// [fty, f]
+		fn, err := vt.id(c.Func)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcConstNoCFIValue, []uint64{bw.typeID(c.Func.Typ), fn}, nil
+	}
+	if opcode, from, ok := bcCastExprOpcode(c); ok {
+		// [opcode, opty, opval]
+		id, err := vt.id(from)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcConstCECast, []uint64{opcode, bw.typeID(from.Type()), id}, nil
+	}
+	// TODO: add support for remaining constant expressions, once handled by
+	// the bitcode reader.
+	return 0, nil, errors.Errorf("support for constant %T not yet implemented", c)
+}
+
+// gepExprRecord returns the record code and operands of the CE_GEP,
+// CE_INBOUNDS_GEP or CE_GEP_WITH_INRANGE_INDEX record of the given
+// getelementptr expression.
+//
+//    [pointee type, n x (opty, opval)]
+//    [pointee type, flags, n x (opty, opval)]
+func (bw *bcWriter) gepExprRecord(expr *ir.ExprGetElementPtr, vt *bcValueTable) (uint64, []uint64, error) {
+	code := uint64(bcConstCEGEP)
+	if expr.InBounds {
+		code = bcConstCEInboundsGEP
+	}
+	ops := []uint64{bw.typeID(expr.ElemType)}
+	for i, index := range expr.Indices {
+		if !index.InRange {
+			continue
+		}
+		var inBounds uint64
+		if expr.InBounds {
+			inBounds = 1
+		}
+		code = bcConstCEGEPWithInrangeIndex
+		ops = append(ops, uint64(i)<<1|inBounds)
+		break
+	}
+	operands, err := constOperands(expr)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	for _, op := range operands {
+		id, err := vt.id(op)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops = append(ops, bw.typeID(op.Type()), id)
+	}
+	return code, ops, nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// constOperands returns the constant operands of the given constant. Global
+// variables, functions and simple constants have no operands.
+func constOperands(c ir.Constant) ([]ir.Constant, error) {
+	switch c := c.(type) {
+	case *ir.Global, *ir.Function:
+		return nil, nil
+	case *ir.ConstInt, *ir.ConstFloat, *ir.ConstNull, *ir.ConstNone, *ir.ConstZeroInitializer, *ir.ConstUndef, *ir.ConstPoison, *ir.ConstCharArray:
+		return nil, nil
+	case *ir.ConstStruct:
+		return c.Fields, nil
+	case *ir.ConstArray:
+		return c.Elems, nil
+	case *ir.ConstVector:
+		return c.Elems, nil
+	case *ir.ConstBlockAddress:
+		return []ir.Constant{c.Func}, nil
+	case *ir.ConstDSOLocalEquivalent:
+		return []ir.Constant{c.Func}, nil
+	case *ir.ConstNoCFI:
+		return []ir.Constant{c.Func}, nil
+	case *ir.ExprGetElementPtr:
+		ops := []ir.Constant{c.Src}
+		for _, index := range c.Indices {
+			ops = append(ops, index.Constant)
+		}
+		return ops, nil
+	}
+	if _, from, ok := bcCastExprOpcode(c); ok {
+		return []ir.Constant{from}, nil
+	}
+	return nil, errors.Errorf("support for constant %T not yet implemented", c)
+}
+
+// bcCastExprOpcode returns the cast opcode and source operand of the given
+// conversion expression, and a boolean indicating whether c is a conversion
+// expression.
+func bcCastExprOpcode(c ir.Constant) (uint64, ir.Constant, bool) {
+	switch c := c.(type) {
+	case *ir.ExprTrunc:
+		return bcCastTrunc, c.From, true
+	case *ir.ExprZExt:
+		return bcCastZExt, c.From, true
+	case *ir.ExprSExt:
+		return bcCastSExt, c.From, true
+	case *ir.ExprFPToUI:
+		return bcCastFPToUI, c.From, true
+	case *ir.ExprFPToSI:
+		return bcCastFPToSI, c.From, true
+	case *ir.ExprUIToFP:
+		return bcCastUIToFP, c.From, true
+	case *ir.ExprSIToFP:
+		return bcCastSIToFP, c.From, true
+	case *ir.ExprFPTrunc:
+		return bcCastFPTrunc, c.From, true
+	case *ir.ExprFPExt:
+		return bcCastFPExt, c.From, true
+	case *ir.ExprPtrToInt:
+		return bcCastPtrToInt, c.From, true
+	case *ir.ExprIntToPtr:
+		return bcCastIntToPtr, c.From, true
+	case *ir.ExprBitCast:
+		return bcCastBitCast, c.From, true
+	case *ir.ExprAddrSpaceCast:
+		return bcCastAddrSpaceCast, c.From, true
+	default:
+		return 0, nil, false
+	}
+}
+
+// bcIntRecord returns the record code and operands of the INTEGER or
+// WIDE_INTEGER record of the given integer constant.
+func bcIntRecord(c *ir.ConstInt) (uint64, []uint64, error) {
+	bitSize := c.Typ.BitSize
+	if bitSize < 1 {
+		return 0, nil, errors.Errorf("invalid bit size %d of integer constant", bitSize)
+	}
+	// Two's complement representation of the given bit size.
+	x := new(big.Int).Mod(c.X, new(big.Int).Lsh(big.NewInt(1), uint(bitSize)))
+	if bitSize <= 64 {
+		// Sign-extend to 64 bits.
+		shift := uint(64 - bitSize)
+		v := int64(x.Uint64()<<shift) >> shift
+		return bcConstInteger, []uint64{encodeSignRotated(v)}, nil
+	}
+	// Words in little-endian order.
+	var ops []uint64
+	mask := new(big.Int).SetUint64(math.MaxUint64)
+	for i := int64(0); i < bitSize; i += 64 {
+		word := new(big.Int).And(x, mask).Uint64()
+		ops = append(ops, encodeSignRotated(int64(word)))
+		x.Rsh(x, 64)
+	}
+	return bcConstWideInteger, ops, nil
+}
+
+// bcFloatOps returns the words of the bit pattern of the given floating-point
+// constant, as stored by FLOAT records.
+func bcFloatOps(c *ir.ConstFloat) ([]uint64, error) {
+	s := c.Ident()
+	// Hexadecimal notation of the bit pattern.
+	switch {
+	case strings.HasPrefix(s, "0xH"), strings.HasPrefix(s, "0xR"):
+		// half and bfloat.
+		bits, err := strconv.ParseUint(s[len("0xH"):], 16, 16)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []uint64{bits}, nil
+	case strings.HasPrefix(s, "0xK"):
+		// x86_fp80; 16-bit sign and exponent followed by 64-bit mantissa. The
+		// first word holds the sign and exponent followed by the upper 48 bits of
+		// the mantissa, and the second word holds the lower 16 bits of the
+		// mantissa.
+		hex := s[len("0xK"):]
+		if len(hex) != 20 {
+			return nil, errors.Errorf("invalid x86_fp80 constant %q; expected 20 hexadecimal digits", s)
+		}
+		se, err := strconv.ParseUint(hex[:4], 16, 16)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		mant, err := strconv.ParseUint(hex[4:], 16, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []uint64{se<<48 | mant>>16, mant & 0xFFFF}, nil
+	case strings.HasPrefix(s, "0xL"), strings.HasPrefix(s, "0xM"):
+		// fp128 and ppc_fp128.
+		hex := s[len("0xL"):]
+		if len(hex) != 32 {
+			return nil, errors.Errorf("invalid %v constant %q; expected 32 hexadecimal digits", c.Typ, s)
+		}
+		hi, err := strconv.ParseUint(hex[:16], 16, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		lo, err := strconv.ParseUint(hex[16:], 16, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []uint64{hi, lo}, nil
+	}
+	// Double precision value, in hexadecimal or decimal notation.
+	var x float64
+	if strings.HasPrefix(s, "0x") {
+		bits, err := strconv.ParseUint(s[len("0x"):], 16, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		x = math.Float64frombits(bits)
+	} else {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		x = v
+	}
+	switch c.Typ.Kind {
+	case types.FloatKindFloat:
+		return []uint64{uint64(math.Float32bits(float32(x)))}, nil
+	case types.FloatKindDouble:
+		return []uint64{math.Float64bits(x)}, nil
+	case types.FloatKindBFloat:
+		// The upper 16 bits of the single precision representation, which must
+		// represent the value exactly.
+		bits := math.Float32bits(float32(x))
+		if float64(float32(x)) != x || bits&0xFFFF != 0 {
+			return nil, errors.Errorf("invalid %v constant %q; unable to represent value exactly", c.Typ, s)
+		}
+		return []uint64{uint64(bits >> 16)}, nil
+	case types.FloatKindPPCFP128:
+		// Pair of double precision values, the sum of which is the value. The
+		// high-order double holds the value, and the low-order double is zero.
+		return []uint64{math.Float64bits(x), 0}, nil
+	default:
+		// TODO: add support for decimal notation of half, x86_fp80 and fp128
+		// constants.
+		return nil, errors.Errorf("support for %v constant %q not yet implemented", c.Typ, s)
+	}
+}
+
+// blockIndex returns the index of the given basic block within the function.
+func blockIndex(f *ir.Function, block *ir.BasicBlock) (uint64, error) {
+	for i, b := range f.Blocks {
+		if b == block {
+			return uint64(i), nil
+		}
+	}
+	return 0, errors.Errorf("unable to locate basic block %q in function %q", block.Ident(), f.GlobalName)
+}
@@ -0,0 +1,974 @@
+package asm
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/mewmew/l-tm/asm/datalayout"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
+	"github.com/pkg/errors"
+)
+
+// === [ Function Bodies ] =====================================================
+
+// bcFuncWriter keeps track of the value table and basic blocks when writing the
+// body of a function to bitcode.
+type bcFuncWriter struct {
+	bw *bcWriter
+	// Function being written.
+	f *ir.Function
+	// Value table of the function body; parameters, followed by constants and
+	// instructions.
+	values *bcValueTable
+	// Basic block indices; maps from basic block to index within function.
+	blocks map[*ir.BasicBlock]uint64
+	// Value ID of the next instruction.
+	instNum uint64
+}
+
+// writeFunc writes the body of the given function as a FUNCTION block.
+func (bw *bcWriter) writeFunc(f *ir.Function) error {
+	fw := &bcFuncWriter{
+		bw:     bw,
+		f:      f,
+		values: newBCValueTable(bw.values),
+		blocks: make(map[*ir.BasicBlock]uint64),
+	}
+	// Function parameters.
+	for _, param := range f.Params {
+		fw.values.add(param)
+	}
+	// Constants used by instructions and terminators, not already present in
+	// the module-level value table.
+	for i, block := range f.Blocks {
+		fw.blocks[block] = uint64(i)
+		for _, inst := range block.Insts {
+			ops, err := bw.instOperands(inst)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := fw.enumConsts(ops); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		ops, err := termOperands(block.Term)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := fw.enumConsts(ops); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Non-void instructions; value IDs are assigned before writing the
+	// instructions, as instructions may refer to instructions of higher value
+	// IDs (e.g. incoming values of phi instructions).
+	fw.instNum = fw.values.next
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if v, ok := isValueInst(inst); ok {
+				fw.values.add(v)
+			}
+		}
+	}
+	if err := bw.w.EnterBlock(bcFunctionBlockID, 4); err != nil {
+		return errors.WithStack(err)
+	}
+	// [n]
+	if err := bw.writeRecord(bcFuncDeclareBlocks, uint64(len(f.Blocks))); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.writeConsts(fw.values); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, block := range f.Blocks {
+		for _, inst := range block.Insts {
+			if err := fw.writeInst(inst); err != nil {
+				return errors.Wrapf(err, "unable to write instruction of basic block %q", block.Ident())
+			}
+		}
+		if block.Term == nil {
+			return errors.Errorf("invalid basic block %q; missing terminator", block.Ident())
+		}
+		if err := fw.writeTerm(block.Term); err != nil {
+			return errors.Wrapf(err, "unable to write terminator of basic block %q", block.Ident())
+		}
+	}
+	if err := fw.writeVST(); err != nil {
+		return errors.WithStack(err)
+	}
+	// TODO: handle metadata, metadata attachments and use-list orders.
+	return bw.w.ExitBlock()
+}
+
+// enumConsts adds the constants of the given operands to the value table of the
+// function body.
+func (fw *bcFuncWriter) enumConsts(ops []value.Value) error {
+	for _, op := range ops {
+		c, ok := op.(ir.Constant)
+		if !ok {
+			continue
+		}
+		if err := fw.values.enumConst(c); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// writeVST writes the local names of the function body as a VALUE_SYMTAB
+// block. Unnamed values (i.e. local IDs) are omitted.
+func (fw *bcFuncWriter) writeVST() error {
+	type entry struct {
+		code uint64
+		id   uint64
+		name string
+	}
+	var entries []entry
+	for _, param := range fw.f.Params {
+		if !isUnnamedLocal(param.LocalName) {
+			id, err := fw.values.id(param)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			entries = append(entries, entry{code: bcVSTEntry, id: id, name: param.LocalName})
+		}
+	}
+	for i, block := range fw.f.Blocks {
+		if !isUnnamedLocal(block.LocalName) {
+			entries = append(entries, entry{code: bcVSTBBEntry, id: uint64(i), name: block.LocalName})
+		}
+		for _, inst := range block.Insts {
+			v, ok := isValueInst(inst)
+			if !ok {
+				continue
+			}
+			n, ok := v.(value.Named)
+			if !ok || isUnnamedLocal(n.Name()) {
+				continue
+			}
+			id, err := fw.values.id(v)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			entries = append(entries, entry{code: bcVSTEntry, id: id, name: n.Name()})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := fw.bw.w.EnterBlock(bcValueSymtabBlockID, 4); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, e := range entries {
+		// [valueid, namechar x N]
+		// [bbid, namechar x N]
+		ops := append([]uint64{e.id}, stringOps(e.name)...)
+		if err := fw.bw.writeRecord(e.code, ops...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return fw.bw.w.ExitBlock()
+}
+
+// writeInst writes the record of the given instruction.
+func (fw *bcFuncWriter) writeInst(inst ir.Instruction) error {
+	code, ops, err := fw.instRecord(inst)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fw.bw.writeRecord(code, ops...); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, ok := isValueInst(inst); ok {
+		fw.instNum++
+	}
+	return nil
+}
+
+// instRecord returns the record code and operands of the given instruction.
+func (fw *bcFuncWriter) instRecord(inst ir.Instruction) (uint64, []uint64, error) {
+	if opcode, x, y, flags, ok := bcBinopOpcode(inst); ok {
+		// [opval, opval, opcode, flags<optional>]
+		ops, err := fw.pushValueAndType(nil, x)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, y); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops = append(ops, opcode)
+		if flags != 0 {
+			ops = append(ops, flags)
+		}
+		return bcFuncInstBinop, ops, nil
+	}
+	if opcode, from, to, ok := bcCastInstOpcode(inst); ok {
+		// [opval, destty, castopc]
+		ops, err := fw.pushValueAndType(nil, from)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return bcFuncInstCast, append(ops, fw.bw.typeID(to), opcode), nil
+	}
+	switch inst := inst.(type) {
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		// [opval, opval]
+		ops, err := fw.pushValuesAndTypes(inst.X, inst.Index)
+		return bcFuncInstExtractElt, ops, err
+	case *ir.InstInsertElement:
+		// [opval, opval, opval]
+		ops, err := fw.pushValueAndType(nil, inst.X)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, inst.Elem); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops, err = fw.pushValueAndType(ops, inst.Index)
+		return bcFuncInstInsertElt, ops, err
+	case *ir.InstShuffleVector:
+		// [opval, opval, opval]
+		ops, err := fw.pushValueAndType(nil, inst.X)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, inst.Y); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops, err = fw.pushValueAndType(ops, inst.Mask)
+		return bcFuncInstShuffleVec, ops, err
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		// [opval, n x indices]
+		ops, err := fw.pushValueAndType(nil, inst.X)
+		return bcFuncInstExtractVal, append(ops, inst.Indices...), err
+	case *ir.InstInsertValue:
+		// [opval, opval, n x indices]
+		ops, err := fw.pushValuesAndTypes(inst.X, inst.Elem)
+		return bcFuncInstInsertVal, append(ops, inst.Indices...), err
+	// Memory instructions.
+	case *ir.InstAlloca:
+		// [instty, opty, op, align]
+		//
+		// The number of elements is stored as absolute value ID.
+		nelems := fw.bw.allocaNElems(inst)
+		id, err := fw.values.id(nelems)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		// The encoded alignment is split into the lower five bits and one upper
+		// bit of the flags.
+		align := bcEncodeAlign(inst.Align)
+		flags := uint64(bcAllocaExplicitType) | align&bcAllocaAlignMask | (align>>5)<<bcAllocaAlignUpperBit
+		if inst.InAlloca {
+			flags |= bcAllocaInAlloca
+		}
+		if inst.SwiftError {
+			flags |= bcAllocaSwiftError
+		}
+		ops := []uint64{fw.bw.typeID(inst.ElemType), fw.bw.typeID(nelems.Type()), id, flags}
+		return bcFuncInstAlloca, ops, nil
+	case *ir.InstLoad:
+		// [op, ty, align, vol]
+		// [op, ty, align, vol, ordering, synchscope]
+		ops, err := fw.pushValueAndType(nil, inst.Src)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		align := bcEncodeAlign(inst.Align)
+		if !inst.Atomic {
+			return bcFuncInstLoad, append(ops, fw.bw.typeID(inst.Type()), align, bcBool(inst.Volatile)), nil
+		}
+		// Atomic loads require explicit alignment.
+		if align == 0 {
+			if align, err = bcNaturalAlign(fw.bw.dl, inst.Type()); err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+		}
+		ops = append(ops, fw.bw.typeID(inst.Type()), align, bcBool(inst.Volatile))
+		ops, err = fw.pushOrdering(ops, inst.Ordering, inst.SyncScope)
+		return bcFuncInstLoadAtomic, ops, err
+	case *ir.InstStore:
+		// [ptr, val, align, vol]
+		// [ptr, val, align, vol, ordering, synchscope]
+		ops, err := fw.pushValuesAndTypes(inst.Dst, inst.Src)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		align := bcEncodeAlign(inst.Align)
+		if !inst.Atomic {
+			return bcFuncInstStore, append(ops, align, bcBool(inst.Volatile)), nil
+		}
+		// Atomic stores require explicit alignment.
+		if align == 0 {
+			if align, err = bcNaturalAlign(fw.bw.dl, inst.Src.Type()); err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+		}
+		ops = append(ops, align, bcBool(inst.Volatile))
+		ops, err = fw.pushOrdering(ops, inst.Ordering, inst.SyncScope)
+		return bcFuncInstStoreAtomic, ops, err
+	case *ir.InstFence:
+		// [ordering, synchscope]
+		ops, err := fw.pushOrdering(nil, inst.Ordering, inst.SyncScope)
+		return bcFuncInstFence, ops, err
+	case *ir.InstCmpXchg:
+		// [ptrty, ptr, cmp, val, vol, success_ordering, synchscope,
+		//  failure_ordering, weak]
+		ops, err := fw.pushValuesAndTypes(inst.Ptr, inst.Cmp)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, inst.New); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops = append(ops, bcBool(inst.Volatile))
+		if ops, err = fw.pushOrdering(ops, inst.SuccessOrdering, inst.SyncScope); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		failure, err := bcAtomicOrderingCode(inst.FailureOrdering)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		// TODO: handle alignment.
+		return bcFuncInstCmpXchg, append(ops, failure, bcBool(inst.Weak)), nil
+	case *ir.InstAtomicRMW:
+		// [ptrty, ptr, val, operation, vol, ordering, synchscope]
+		//
+		// The INST_ATOMICRMW_OLD record is used, as supported by all versions of
+		// LLVM; the type of val is given by the instruction.
+		ops, err := fw.pushValueAndType(nil, inst.Dst)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, inst.X); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		op, err := bcAtomicOpCode(inst.Op)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops = append(ops, op, bcBool(inst.Volatile))
+		// TODO: handle alignment.
+		ops, err = fw.pushOrdering(ops, inst.Ordering, inst.SyncScope)
+		return bcFuncInstAtomicRMWOld, ops, err
+	case *ir.InstGetElementPtr:
+		// [inbounds, ty, n x operands]
+		var inBounds uint64
+		if inst.InBounds {
+			inBounds = 1
+		}
+		ops, err := fw.pushValuesAndTypes(append([]value.Value{inst.Src}, inst.Indices...)...)
+		return bcFuncInstGEP, append([]uint64{inBounds, fw.bw.typeID(inst.ElemType)}, ops...), err
+	// Other instructions.
+	case *ir.InstICmp:
+		// [opval, opval, pred]
+		pred, err := bcIPredCode(inst.Pred)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return fw.cmpRecord(inst.X, inst.Y, pred, 0)
+	case *ir.InstFCmp:
+		// [opval, opval, pred, flags<optional>]
+		pred, err := bcFPredCode(inst.Pred)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return fw.cmpRecord(inst.X, inst.Y, pred, bcFastMathFlagsCode(inst.FastMathFlags))
+	case *ir.InstPhi:
+		// [ty, n x (val, bb#)]
+		ops := []uint64{fw.bw.typeID(inst.Typ)}
+		for _, inc := range inst.Incs {
+			id, err := fw.values.id(inc.X)
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			pred, ok := inc.Pred.(*ir.BasicBlock)
+			if !ok {
+				return 0, nil, errors.Errorf("invalid incoming predecessor of phi instruction; expected *ir.BasicBlock, got %T", inc.Pred)
+			}
+			index, err := fw.block(pred)
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			// Incoming values are stored as signed relative value IDs.
+			ops = append(ops, encodeSignRotated(int64(fw.instNum)-int64(id)), index)
+		}
+		return bcFuncInstPhi, ops, nil
+	case *ir.InstSelect:
+		// [opval, opval, pred]
+		ops, err := fw.pushValueAndType(nil, inst.X)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		if ops, err = fw.pushValue(ops, inst.Y); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops, err = fw.pushValueAndType(ops, inst.Cond)
+		return bcFuncInstVSelect, ops, err
+	case *ir.InstCall:
+		return fw.callRecord(inst)
+	default:
+		// TODO: add support for remaining instructions, once handled by the
+		// bitcode reader.
+		return 0, nil, errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+}
+
+// cmpRecord returns the record code and operands of the INST_CMP2 record of a
+// comparison instruction.
+//
+//    [opval, opval, pred, flags<optional>]
+func (fw *bcFuncWriter) cmpRecord(x, y value.Value, pred, flags uint64) (uint64, []uint64, error) {
+	ops, err := fw.pushValueAndType(nil, x)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	if ops, err = fw.pushValue(ops, y); err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	ops = append(ops, pred)
+	if flags != 0 {
+		ops = append(ops, flags)
+	}
+	return bcFuncInstCmp2, ops, nil
+}
+
+// callRecord returns the record code and operands of the INST_CALL record of
+// the given call instruction.
+//
+//    [paramattrs, cc, fmf<optional>, fnty, fnid, args...]
+func (fw *bcFuncWriter) callRecord(inst *ir.InstCall) (uint64, []uint64, error) {
+	sig, err := callSig(inst)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	callingConv, err := bcCallingConvCode(inst.CallingConv)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	cc := callingConv<<bcCallCConvShift | bcCallExplicitType
+	switch inst.Tail {
+	case asmenum.TailFromString("tail"):
+		cc |= bcCallTail
+	case asmenum.TailFromString("musttail"):
+		cc |= bcCallMustTail
+	case asmenum.TailFromString("notail"):
+		cc |= bcCallNoTail
+	}
+	// TODO: handle parameter attributes.
+	ops := []uint64{0, cc}
+	if fmf := bcFastMathFlagsCode(inst.FastMathFlags); fmf != 0 {
+		ops[1] |= bcCallFMF
+		ops = append(ops, fmf)
+	}
+	ops = append(ops, fw.bw.typeID(sig))
+	if ops, err = fw.pushValueAndType(ops, inst.Callee); err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	if len(inst.Args) < len(sig.Params) || (!sig.Variadic && len(inst.Args) > len(sig.Params)) {
+		return 0, nil, errors.Errorf("invalid number of call arguments; expected %d, got %d", len(sig.Params), len(inst.Args))
+	}
+	// Function arguments; the type of fixed arguments is implied by the function
+	// type.
+	for _, arg := range inst.Args[:len(sig.Params)] {
+		if ops, err = fw.pushValue(ops, arg); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+	}
+	// Variadic arguments.
+	for _, arg := range inst.Args[len(sig.Params):] {
+		if ops, err = fw.pushValueAndType(ops, arg); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+	}
+	return bcFuncInstCall, ops, nil
+}
+
+// --- [ Terminators ] ---------------------------------------------------------
+
+// writeTerm writes the record of the given terminator.
+func (fw *bcFuncWriter) writeTerm(term ir.Terminator) error {
+	code, ops, err := fw.termRecord(term)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return fw.bw.writeRecord(code, ops...)
+}
+
+// termRecord returns the record code and operands of the given terminator.
+func (fw *bcFuncWriter) termRecord(term ir.Terminator) (uint64, []uint64, error) {
+	switch term := term.(type) {
+	case *ir.TermRet:
+		// [opty, opval<both optional>]
+		if term.X == nil {
+			// void return.
+			return bcFuncInstRet, nil, nil
+		}
+		ops, err := fw.pushValueAndType(nil, term.X)
+		return bcFuncInstRet, ops, err
+	case *ir.TermBr:
+		// [bb#]
+		target, err := fw.block(term.Target)
+		return bcFuncInstBr, []uint64{target}, err
+	case *ir.TermCondBr:
+		// [bb#, bb#, cond]
+		targetTrue, err := fw.block(term.TargetTrue)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		targetFalse, err := fw.block(term.TargetFalse)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops, err := fw.pushValue([]uint64{targetTrue, targetFalse}, term.Cond)
+		return bcFuncInstBr, ops, err
+	case *ir.TermSwitch:
+		// [opty, cond, default, n x (caseval, bb#)]
+		ops, err := fw.pushValue([]uint64{fw.bw.typeID(term.X.Type())}, term.X)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		targetDefault, err := fw.block(term.TargetDefault)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		ops = append(ops, targetDefault)
+		// Case values are stored as absolute value IDs.
+		for _, c := range term.Cases {
+			id, err := fw.values.id(c.X)
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			target, err := fw.block(c.Target)
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			ops = append(ops, id, target)
+		}
+		return bcFuncInstSwitch, ops, nil
+	case *ir.TermUnreachable:
+		// []
+		return bcFuncInstUnreachable, nil, nil
+	default:
+		// TODO: add support for remaining terminators, once handled by the
+		// bitcode reader.
+		return 0, nil, errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+}
+
+// ### [ Helpers ] #############################################################
+
+// pushValueAndType appends the relative value ID of the given value to ops.
+// The type of forward references (i.e. value IDs not yet defined) follows the
+// value ID.
+func (fw *bcFuncWriter) pushValueAndType(ops []uint64, v value.Value) ([]uint64, error) {
+	id, err := fw.values.id(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ops = append(ops, fw.relID(id))
+	if id >= fw.instNum {
+		ops = append(ops, fw.bw.typeID(v.Type()))
+	}
+	return ops, nil
+}
+
+// pushValuesAndTypes returns the relative value IDs of the given values, each
+// followed by its type for forward references.
+func (fw *bcFuncWriter) pushValuesAndTypes(vs ...value.Value) ([]uint64, error) {
+	var ops []uint64
+	for _, v := range vs {
+		var err error
+		if ops, err = fw.pushValueAndType(ops, v); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return ops, nil
+}
+
+// pushValue appends the relative value ID of the given value to ops. The type
+// of the value is implied by the instruction.
+func (fw *bcFuncWriter) pushValue(ops []uint64, v value.Value) ([]uint64, error) {
+	id, err := fw.values.id(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(ops, fw.relID(id)), nil
+}
+
+// pushOrdering appends the atomic ordering code and synchronization scope ID of
+// the given atomic ordering and synchronization scope to ops.
+func (fw *bcFuncWriter) pushOrdering(ops []uint64, ordering enum.AtomicOrdering, syncScope string) ([]uint64, error) {
+	code, err := bcAtomicOrderingCode(ordering)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(ops, code, fw.bw.syncScopeID(syncScope)), nil
+}
+
+// relID returns the relative value ID of the given absolute value ID.
+func (fw *bcFuncWriter) relID(id uint64) uint64 {
+	// Relative value IDs of forward references wrap around as unsigned 32-bit
+	// integers.
+	return uint64(uint32(fw.instNum) - uint32(id))
+}
+
+// block returns the index of the given basic block.
+func (fw *bcFuncWriter) block(block *ir.BasicBlock) (uint64, error) {
+	index, ok := fw.blocks[block]
+	if !ok {
+		return 0, errors.Errorf("unable to locate basic block %q in function %q", block.Ident(), fw.f.GlobalName)
+	}
+	return index, nil
+}
+
+// allocaNElems returns the number of elements of the given alloca instruction;
+// `i32 1` if not explicitly specified.
+func (bw *bcWriter) allocaNElems(inst *ir.InstAlloca) value.Value {
+	if inst.NElems != nil {
+		return inst.NElems
+	}
+	return bw.allocaOne
+}
+
+// instOperands returns the operands of the given instruction, excluding basic
+// blocks.
+func (bw *bcWriter) instOperands(inst ir.Instruction) ([]value.Value, error) {
+	if _, x, y, _, ok := bcBinopOpcode(inst); ok {
+		return []value.Value{x, y}, nil
+	}
+	if _, from, _, ok := bcCastInstOpcode(inst); ok {
+		return []value.Value{from}, nil
+	}
+	switch inst := inst.(type) {
+	case *ir.InstExtractElement:
+		return []value.Value{inst.X, inst.Index}, nil
+	case *ir.InstInsertElement:
+		return []value.Value{inst.X, inst.Elem, inst.Index}, nil
+	case *ir.InstShuffleVector:
+		return []value.Value{inst.X, inst.Y, inst.Mask}, nil
+	case *ir.InstExtractValue:
+		return []value.Value{inst.X}, nil
+	case *ir.InstInsertValue:
+		return []value.Value{inst.X, inst.Elem}, nil
+	case *ir.InstAlloca:
+		return []value.Value{bw.allocaNElems(inst)}, nil
+	case *ir.InstLoad:
+		return []value.Value{inst.Src}, nil
+	case *ir.InstStore:
+		return []value.Value{inst.Src, inst.Dst}, nil
+	case *ir.InstFence:
+		return nil, nil
+	case *ir.InstCmpXchg:
+		return []value.Value{inst.Ptr, inst.Cmp, inst.New}, nil
+	case *ir.InstAtomicRMW:
+		return []value.Value{inst.Dst, inst.X}, nil
+	case *ir.InstGetElementPtr:
+		return append([]value.Value{inst.Src}, inst.Indices...), nil
+	case *ir.InstICmp:
+		return []value.Value{inst.X, inst.Y}, nil
+	case *ir.InstFCmp:
+		return []value.Value{inst.X, inst.Y}, nil
+	case *ir.InstPhi:
+		var ops []value.Value
+		for _, inc := range inst.Incs {
+			ops = append(ops, inc.X)
+		}
+		return ops, nil
+	case *ir.InstSelect:
+		return []value.Value{inst.Cond, inst.X, inst.Y}, nil
+	case *ir.InstCall:
+		return append([]value.Value{inst.Callee}, inst.Args...), nil
+	default:
+		// TODO: add support for remaining instructions, once handled by the
+		// bitcode reader.
+		return nil, errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+}
+
+// termOperands returns the operands of the given terminator, excluding basic
+// blocks.
+func termOperands(term ir.Terminator) ([]value.Value, error) {
+	switch term := term.(type) {
+	case *ir.TermRet:
+		if term.X == nil {
+			return nil, nil
+		}
+		return []value.Value{term.X}, nil
+	case *ir.TermBr, *ir.TermUnreachable:
+		return nil, nil
+	case *ir.TermCondBr:
+		return []value.Value{term.Cond}, nil
+	case *ir.TermSwitch:
+		ops := []value.Value{term.X}
+		for _, c := range term.Cases {
+			ops = append(ops, c.X)
+		}
+		return ops, nil
+	case nil:
+		return nil, nil
+	default:
+		// TODO: add support for remaining terminators, once handled by the
+		// bitcode reader.
+		return nil, errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+}
+
+// instSyncScope returns the synchronization scope of the given instruction, and
+// a boolean indicating whether inst is an atomic instruction.
+func instSyncScope(inst ir.Instruction) (string, bool) {
+	switch inst := inst.(type) {
+	case *ir.InstLoad:
+		return inst.SyncScope, inst.Atomic
+	case *ir.InstStore:
+		return inst.SyncScope, inst.Atomic
+	case *ir.InstFence:
+		return inst.SyncScope, true
+	case *ir.InstCmpXchg:
+		return inst.SyncScope, true
+	case *ir.InstAtomicRMW:
+		return inst.SyncScope, true
+	default:
+		return "", false
+	}
+}
+
+// isValueInst returns the value of the given instruction, and a boolean
+// indicating whether the instruction produces a (non-void) value.
+func isValueInst(inst ir.Instruction) (value.Value, bool) {
+	v, ok := inst.(value.Value)
+	if !ok || v.Type().Equal(types.Void) {
+		return nil, false
+	}
+	return v, true
+}
+
+// isUnnamedLocal reports whether the given local name denotes an unnamed local
+// value (i.e. a local ID).
+func isUnnamedLocal(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// bcBinopOpcode returns the binary opcode, operands and flags of the given
+// binary or bitwise instruction, and a boolean indicating whether inst is a
+// binary or bitwise instruction.
+func bcBinopOpcode(inst ir.Instruction) (opcode uint64, x, y value.Value, flags uint64, ok bool) {
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		return bcBinopAdd, inst.X, inst.Y, bcOverflowFlagsCode(inst.OverflowFlags), true
+	case *ir.InstFAdd:
+		return bcBinopAdd, inst.X, inst.Y, bcFastMathFlagsCode(inst.FastMathFlags), true
+	case *ir.InstSub:
+		return bcBinopSub, inst.X, inst.Y, bcOverflowFlagsCode(inst.OverflowFlags), true
+	case *ir.InstFSub:
+		return bcBinopSub, inst.X, inst.Y, bcFastMathFlagsCode(inst.FastMathFlags), true
+	case *ir.InstMul:
+		return bcBinopMul, inst.X, inst.Y, bcOverflowFlagsCode(inst.OverflowFlags), true
+	case *ir.InstFMul:
+		return bcBinopMul, inst.X, inst.Y, bcFastMathFlagsCode(inst.FastMathFlags), true
+	case *ir.InstUDiv:
+		return bcBinopUDiv, inst.X, inst.Y, bcExactCode(inst.Exact), true
+	case *ir.InstSDiv:
+		return bcBinopSDiv, inst.X, inst.Y, bcExactCode(inst.Exact), true
+	case *ir.InstFDiv:
+		return bcBinopSDiv, inst.X, inst.Y, bcFastMathFlagsCode(inst.FastMathFlags), true
+	case *ir.InstURem:
+		return bcBinopURem, inst.X, inst.Y, 0, true
+	case *ir.InstSRem:
+		return bcBinopSRem, inst.X, inst.Y, 0, true
+	case *ir.InstFRem:
+		return bcBinopSRem, inst.X, inst.Y, bcFastMathFlagsCode(inst.FastMathFlags), true
+	case *ir.InstShl:
+		return bcBinopShl, inst.X, inst.Y, bcOverflowFlagsCode(inst.OverflowFlags), true
+	case *ir.InstLShr:
+		return bcBinopLShr, inst.X, inst.Y, bcExactCode(inst.Exact), true
+	case *ir.InstAShr:
+		return bcBinopAShr, inst.X, inst.Y, bcExactCode(inst.Exact), true
+	case *ir.InstAnd:
+		return bcBinopAnd, inst.X, inst.Y, 0, true
+	case *ir.InstOr:
+		return bcBinopOr, inst.X, inst.Y, 0, true
+	case *ir.InstXor:
+		return bcBinopXor, inst.X, inst.Y, 0, true
+	default:
+		return 0, nil, nil, 0, false
+	}
+}
+
+// bcCastInstOpcode returns the cast opcode, operand and destination type of the
+// given conversion instruction, and a boolean indicating whether inst is a
+// conversion instruction.
+func bcCastInstOpcode(inst ir.Instruction) (opcode uint64, from value.Value, to types.Type, ok bool) {
+	switch inst := inst.(type) {
+	case *ir.InstTrunc:
+		return bcCastTrunc, inst.From, inst.To, true
+	case *ir.InstZExt:
+		return bcCastZExt, inst.From, inst.To, true
+	case *ir.InstSExt:
+		return bcCastSExt, inst.From, inst.To, true
+	case *ir.InstFPToUI:
+		return bcCastFPToUI, inst.From, inst.To, true
+	case *ir.InstFPToSI:
+		return bcCastFPToSI, inst.From, inst.To, true
+	case *ir.InstUIToFP:
+		return bcCastUIToFP, inst.From, inst.To, true
+	case *ir.InstSIToFP:
+		return bcCastSIToFP, inst.From, inst.To, true
+	case *ir.InstFPTrunc:
+		return bcCastFPTrunc, inst.From, inst.To, true
+	case *ir.InstFPExt:
+		return bcCastFPExt, inst.From, inst.To, true
+	case *ir.InstPtrToInt:
+		return bcCastPtrToInt, inst.From, inst.To, true
+	case *ir.InstIntToPtr:
+		return bcCastIntToPtr, inst.From, inst.To, true
+	case *ir.InstBitCast:
+		return bcCastBitCast, inst.From, inst.To, true
+	case *ir.InstAddrSpaceCast:
+		return bcCastAddrSpaceCast, inst.From, inst.To, true
+	default:
+		return 0, nil, nil, false
+	}
+}
+
+// bcOverflowFlagsCode returns the binary operation flags of the given IR
+// overflow flags.
+func bcOverflowFlagsCode(overflowFlags []enum.OverflowFlag) uint64 {
+	var flags uint64
+	for _, flag := range overflowFlags {
+		switch flag {
+		case asmenum.OverflowFlagFromString("nuw"):
+			flags |= bcOBONoUnsignedWrap
+		case asmenum.OverflowFlagFromString("nsw"):
+			flags |= bcOBONoSignedWrap
+		}
+	}
+	return flags
+}
+
+// bcExactCode returns the binary operation flags of the given exact flag.
+func bcExactCode(exact bool) uint64 {
+	if exact {
+		return bcPEOExact
+	}
+	return 0
+}
+
+// bcNaturalAlign returns the encoded alignment (log2 of the alignment in bytes,
+// plus one) of the ABI alignment of the given integer, floating-point or
+// pointer type in the given data layout, as used for atomic loads and stores
+// without explicit alignment.
+func bcNaturalAlign(dl *datalayout.DataLayout, t types.Type) (uint64, error) {
+	switch t.(type) {
+	case *types.IntType, *types.FloatType, *types.PointerType:
+		// valid type.
+	default:
+		return 0, errors.Errorf("invalid type of atomic load or store; expected integer, floating-point or pointer type, got %v", t)
+	}
+	align, err := dl.AlignOf(t)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return bcEncodeAlign(ir.Align(align)), nil
+}
+
+// bcEncodeAlign returns the encoded alignment (log2 of the alignment in bytes,
+// plus one) of the given alignment, or 0 if no alignment is specified.
+func bcEncodeAlign(align ir.Align) uint64 {
+	if align == 0 {
+		return 0
+	}
+	var log uint64
+	for ir.Align(1)<<log < align {
+		log++
+	}
+	return log + 1
+}
+
+// bcBool returns the bitcode representation of the given boolean.
+func bcBool(x bool) uint64 {
+	if x {
+		return 1
+	}
+	return 0
+}
+
+// bcFastMathFlagsCode returns the fast math flags bitmask of the given IR fast
+// math flags.
+func bcFastMathFlagsCode(fmf []enum.FastMathFlag) uint64 {
+	var flags uint64
+	for _, flag := range fmf {
+		if flag == asmenum.FastMathFlagFromString("fast") {
+			flags |= bcFastMathAll
+			continue
+		}
+		for _, f := range bcFastMathFlagBits {
+			if flag == asmenum.FastMathFlagFromString(f.name) {
+				flags |= f.bit
+			}
+		}
+	}
+	return flags
+}
+
+// bcIPredCode returns the integer comparison predicate code of the given IR
+// predicate.
+func bcIPredCode(pred enum.IPred) (uint64, error) {
+	for code, name := range bcIPredNames {
+		if asmenum.IPredFromString(name) == pred {
+			return code, nil
+		}
+	}
+	return 0, errors.Errorf("support for integer comparison predicate %v not yet implemented", pred)
+}
+
+// bcFPredCode returns the floating-point comparison predicate code of the
+// given IR predicate.
+func bcFPredCode(pred enum.FPred) (uint64, error) {
+	for code, name := range bcFPredNames {
+		if asmenum.FPredFromString(name) == pred {
+			return uint64(code), nil
+		}
+	}
+	return 0, errors.Errorf("support for floating-point comparison predicate %v not yet implemented", pred)
+}
+
+// callSig returns the function type of the callee of the given call
+// instruction. The function type of callees of opaque pointer type is given by
+// the callee function, or otherwise derived from the return type and arguments
+// of the call instruction.
+func callSig(inst *ir.InstCall) (*types.FuncType, error) {
+	ptr, ok := inst.Callee.Type().(*types.PointerType)
+	if !ok {
+		return nil, errors.Errorf("invalid callee type; expected *types.PointerType, got %T", inst.Callee.Type())
+	}
+	if !isOpaquePointer(ptr) {
+		sig, ok := ptr.ElemType.(*types.FuncType)
+		if !ok {
+			return nil, errors.Errorf("invalid callee type; expected pointer to *types.FuncType, got %v", ptr)
+		}
+		return sig, nil
+	}
+	if f, ok := inst.Callee.(*ir.Function); ok {
+		return f.Sig, nil
+	}
+	if sig, ok := inst.Typ.(*types.FuncType); ok {
+		// Explicit function type of call instruction (e.g. variadic callee).
+		return sig, nil
+	}
+	// TODO: handle indirect calls of variadic functions through opaque pointers,
+	// which cannot be distinguished from non-variadic calls based on arguments.
+	sig := &types.FuncType{RetType: inst.Typ}
+	for _, arg := range inst.Args {
+		sig.Params = append(sig.Params, arg.Type())
+	}
+	return sig, nil
+}
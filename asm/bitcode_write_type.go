@@ -0,0 +1,309 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ Types ] ===============================================================
+
+// enumTypes assigns type IDs to the types used by the module.
+//
+// Only named structure types may be referred to before being defined in the
+// type table. Therefore, type IDs are assigned to the remaining types in
+// post-order, and to named structure types thereafter (in order of type
+// definition).
+func (bw *bcWriter) enumTypes() error {
+	for _, t := range bw.m.TypeDefs {
+		if t, ok := t.(*types.StructType); ok && isNamedStruct(t) && !bw.visited[t.Alias] {
+			bw.visited[t.Alias] = true
+			bw.structs = append(bw.structs, t)
+		}
+	}
+	for _, t := range bw.m.TypeDefs {
+		if t, ok := t.(*types.StructType); ok && isNamedStruct(t) {
+			bw.enumSubtypes(t)
+			continue
+		}
+		bw.enumType(t)
+	}
+	for _, g := range bw.m.Globals {
+		bw.enumType(g.Typ)
+		bw.enumType(g.ContentType)
+		if g.Init != nil {
+			if err := bw.enumConstTypes(g.Init); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	for _, f := range bw.m.Funcs {
+		bw.enumType(f.Typ)
+		bw.enumType(f.Sig)
+		for _, block := range f.Blocks {
+			for _, inst := range block.Insts {
+				if v, ok := inst.(value.Value); ok {
+					bw.enumType(v.Type())
+				}
+				ops, err := bw.instOperands(inst)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if err := bw.enumOperandTypes(ops); err != nil {
+					return errors.WithStack(err)
+				}
+				// Explicit types of instructions; not implied by the types of
+				// operands of opaque pointer type.
+				switch inst := inst.(type) {
+				case *ir.InstAlloca:
+					bw.enumType(inst.ElemType)
+				case *ir.InstGetElementPtr:
+					bw.enumType(inst.ElemType)
+				case *ir.InstCall:
+					sig, err := callSig(inst)
+					if err != nil {
+						return errors.WithStack(err)
+					}
+					bw.enumType(sig)
+				}
+			}
+			ops, err := termOperands(block.Term)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := bw.enumOperandTypes(ops); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	// Named structure types.
+	for _, t := range bw.structs {
+		bw.addType(t)
+	}
+	return nil
+}
+
+// enumOperandTypes assigns type IDs to the types of the given operands.
+func (bw *bcWriter) enumOperandTypes(ops []value.Value) error {
+	for _, op := range ops {
+		bw.enumType(op.Type())
+		if c, ok := op.(ir.Constant); ok {
+			if err := bw.enumConstTypes(c); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// enumConstTypes assigns type IDs to the types of the given constant and its
+// operands.
+func (bw *bcWriter) enumConstTypes(c ir.Constant) error {
+	bw.enumType(c.Type())
+	ops, err := constOperands(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, op := range ops {
+		if err := bw.enumConstTypes(op); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Element type of getelementptr expressions.
+	if expr, ok := c.(*ir.ExprGetElementPtr); ok {
+		bw.enumType(expr.ElemType)
+	}
+	return nil
+}
+
+// enumType assigns type IDs to the given type and its subtypes. Named structure
+// types are recorded, to be assigned type IDs after all other types.
+func (bw *bcWriter) enumType(t types.Type) {
+	if _, ok := bw.typeIDs[t.String()]; ok {
+		return
+	}
+	if t, ok := t.(*types.StructType); ok && isNamedStruct(t) {
+		if bw.visited[t.Alias] {
+			return
+		}
+		bw.visited[t.Alias] = true
+		bw.structs = append(bw.structs, t)
+		bw.enumSubtypes(t)
+		return
+	}
+	bw.enumSubtypes(t)
+	bw.addType(t)
+}
+
+// enumSubtypes assigns type IDs to the subtypes of the given type.
+func (bw *bcWriter) enumSubtypes(t types.Type) {
+	switch t := t.(type) {
+	case *types.PointerType:
+		if !isOpaquePointer(t) {
+			bw.enumType(t.ElemType)
+		}
+	case *types.VectorType:
+		bw.enumType(t.ElemType)
+	case *types.ArrayType:
+		bw.enumType(t.ElemType)
+	case *types.StructType:
+		for _, field := range t.Fields {
+			bw.enumType(field)
+		}
+	case *types.FuncType:
+		bw.enumType(t.RetType)
+		for _, param := range t.Params {
+			bw.enumType(param)
+		}
+	case *types.TargetExtType:
+		for _, param := range t.TypeParams {
+			bw.enumType(param)
+		}
+	}
+}
+
+// addType adds the given type to the type table.
+func (bw *bcWriter) addType(t types.Type) {
+	bw.typeIDs[t.String()] = uint64(len(bw.types))
+	bw.types = append(bw.types, t)
+}
+
+// typeID returns the type ID of the given type.
+//
+// Pre-condition: assigned type IDs by enumTypes.
+func (bw *bcWriter) typeID(t types.Type) uint64 {
+	id, ok := bw.typeIDs[t.String()]
+	if !ok {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("unable to locate type ID of %q", t))
+	}
+	return id
+}
+
+// writeTypes writes the type table as a TYPE block.
+func (bw *bcWriter) writeTypes() error {
+	if err := bw.w.EnterBlock(bcTypeBlockID, 4); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := bw.writeRecord(bcTypeNumEntry, uint64(len(bw.types))); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, t := range bw.types {
+		if err := bw.writeType(t); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return bw.w.ExitBlock()
+}
+
+// writeType writes the TYPE block record of the given type.
+func (bw *bcWriter) writeType(t types.Type) error {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return bw.writeRecord(bcTypeVoid)
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf:
+			return bw.writeRecord(bcTypeHalf)
+		case types.FloatKindBFloat:
+			return bw.writeRecord(bcTypeBFloat)
+		case types.FloatKindFloat:
+			return bw.writeRecord(bcTypeFloat)
+		case types.FloatKindDouble:
+			return bw.writeRecord(bcTypeDouble)
+		case types.FloatKindX86FP80:
+			return bw.writeRecord(bcTypeX86FP80)
+		case types.FloatKindFP128:
+			return bw.writeRecord(bcTypeFP128)
+		case types.FloatKindPPCFP128:
+			return bw.writeRecord(bcTypePPCFP128)
+		default:
+			return errors.Errorf("support for floating-point kind %v not yet implemented", t.Kind)
+		}
+	case *types.LabelType:
+		return bw.writeRecord(bcTypeLabel)
+	case *types.MetadataType:
+		return bw.writeRecord(bcTypeMetadata)
+	case *types.MMXType:
+		return bw.writeRecord(bcTypeX86MMX)
+	case *types.AMXType:
+		return bw.writeRecord(bcTypeX86AMX)
+	case *types.TokenType:
+		return bw.writeRecord(bcTypeToken)
+	case *types.IntType:
+		// [width]
+		return bw.writeRecord(bcTypeInteger, uint64(t.BitSize))
+	case *types.PointerType:
+		if isOpaquePointer(t) {
+			// [addrspace]
+			return bw.writeRecord(bcTypeOpaquePointer, uint64(t.AddrSpace))
+		}
+		// [pointee type, addrspace]
+		return bw.writeRecord(bcTypePointer, bw.typeID(t.ElemType), uint64(t.AddrSpace))
+	case *types.ArrayType:
+		// [numelts, eltty]
+		return bw.writeRecord(bcTypeArray, uint64(t.Len), bw.typeID(t.ElemType))
+	case *types.VectorType:
+		if t.Scalable {
+			// [numelts, eltty, scalable]
+			return bw.writeRecord(bcTypeVector, uint64(t.Len), bw.typeID(t.ElemType), 1)
+		}
+		// [numelts, eltty]
+		return bw.writeRecord(bcTypeVector, uint64(t.Len), bw.typeID(t.ElemType))
+	case *types.FuncType:
+		// [vararg, retty, paramty x N]
+		var variadic uint64
+		if t.Variadic {
+			variadic = 1
+		}
+		ops := []uint64{variadic, bw.typeID(t.RetType)}
+		for _, param := range t.Params {
+			ops = append(ops, bw.typeID(param))
+		}
+		return bw.writeRecord(bcTypeFunction, ops...)
+	case *types.StructType:
+		// [ispacked, eltty x N]
+		var packed uint64
+		if t.Packed {
+			packed = 1
+		}
+		ops := []uint64{packed}
+		for _, field := range t.Fields {
+			ops = append(ops, bw.typeID(field))
+		}
+		if !isNamedStruct(t) {
+			return bw.writeRecord(bcTypeStructAnon, ops...)
+		}
+		// The name of named structure types precedes their definition.
+		if err := bw.writeRecord(bcTypeStructName, stringOps(t.Alias)...); err != nil {
+			return errors.WithStack(err)
+		}
+		if t.Opaque {
+			return bw.writeRecord(bcTypeOpaque, 0)
+		}
+		return bw.writeRecord(bcTypeStructNamed, ops...)
+	case *types.TargetExtType:
+		// The name of target extension types precedes their definition.
+		if err := bw.writeRecord(bcTypeStructName, stringOps(t.TypeName)...); err != nil {
+			return errors.WithStack(err)
+		}
+		// [numtys, ty x numtys, int x N]
+		ops := []uint64{uint64(len(t.TypeParams))}
+		for _, param := range t.TypeParams {
+			ops = append(ops, bw.typeID(param))
+		}
+		ops = append(ops, t.IntParams...)
+		return bw.writeRecord(bcTypeTargetType, ops...)
+	default:
+		return errors.Errorf("support for type %T not yet implemented", t)
+	}
+}
+
+// isNamedStruct reports whether the given structure type is a named (i.e.
+// identified) structure type.
+func isNamedStruct(t *types.StructType) bool {
+	return len(t.Alias) > 0
+}
@@ -2,6 +2,7 @@ package asm
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/llir/l/ir"
 	"github.com/llir/l/ir/types"
@@ -11,7 +12,23 @@ import (
 
 // === [ Constants ] ===========================================================
 
+// irConstant returns the IR constant corresponding to the given AST constant.
 func (gen *generator) irConstant(t types.Type, old ast.Constant) (ir.Constant, error) {
+	c, err := gen.astToIRConstant(t, old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, ok := old.(*ast.GlobalIdent); !ok {
+		// Global identifiers are recorded by their global declarations and
+		// definitions.
+		gen.record(c, old)
+	}
+	return c, nil
+}
+
+// astToIRConstant translates the given AST constant into an equivalent IR
+// constant.
+func (gen *generator) astToIRConstant(t types.Type, old ast.Constant) (ir.Constant, error) {
 	switch old := old.(type) {
 	case *ast.BoolConst:
 		return gen.irBoolConst(t, old)
@@ -35,15 +52,17 @@ func (gen *generator) irConstant(t types.Type, old ast.Constant) (ir.Constant, e
 		return gen.irZeroInitializerConst(t, old)
 	case *ast.UndefConst:
 		return gen.irUndefConst(t, old)
+	case *ast.PoisonConst:
+		return gen.irPoisonConst(t, old)
 	case *ast.BlockAddressConst:
 		return gen.irBlockAddressConst(t, old)
+	case *ast.DSOLocalEquivalentConst:
+		return gen.irDSOLocalEquivalentConst(t, old)
+	case *ast.NoCFIConst:
+		return gen.irNoCFIConst(t, old)
 	case *ast.GlobalIdent:
 		name := global(*old)
-		v, ok := gen.gs[name]
-		if !ok {
-			return nil, errors.Errorf("unable to locate global identifier %q", name)
-		}
-		return v, nil
+		return gen.lookupGlobal(name)
 	case ast.ConstantExpr:
 		return gen.irConstantExpr(t, old)
 	default:
@@ -97,9 +116,26 @@ func (gen *generator) irFloatConst(t types.Type, old *ast.FloatConst) (*ir.Const
 		return nil, errors.Errorf("invalid type of floating-point constant; expected *types.FloatType, got %T", t)
 	}
 	s := old.FloatLit().Text()
+	// The hexadecimal notation with a kind prefix (e.g. 0xR for bfloat) is only
+	// valid for constants of the given floating-point kind.
+	if len(s) > len("0x") && strings.HasPrefix(s, "0x") {
+		if kind, ok := floatHexPrefixKind[s[len("0x")]]; ok && kind != typ.Kind {
+			return nil, errors.Errorf("invalid floating-point constant %q for type %v", s, typ)
+		}
+	}
 	return ir.NewFloatFromString(typ, s)
 }
 
+// floatHexPrefixKind maps from the prefix of hexadecimal floating-point
+// literals to the floating-point kind denoted by the prefix.
+var floatHexPrefixKind = map[byte]types.FloatKind{
+	'H': types.FloatKindHalf,
+	'R': types.FloatKindBFloat,
+	'K': types.FloatKindX86FP80,
+	'L': types.FloatKindFP128,
+	'M': types.FloatKindPPCFP128,
+}
+
 // --- [ Null Pointer Constants ] ----------------------------------------------
 
 func (gen *generator) irNullConst(t types.Type, old *ast.NullConst) (*ir.ConstNull, error) {
@@ -169,6 +205,9 @@ func (gen *generator) irVectorConst(t types.Type, old *ast.VectorConst) (*ir.Con
 	if !ok {
 		return nil, errors.Errorf("invalid type of vector constant; expected *types.VectorType, got %T", t)
 	}
+	if typ.Scalable {
+		return nil, errors.Errorf("invalid type of vector constant; scalable vector type %v has no fixed number of elements", typ)
+	}
 	var elems []ir.Constant
 	for _, e := range old.Elems() {
 		elem, err := gen.irTypeConst(e)
@@ -192,6 +231,12 @@ func (gen *generator) irUndefConst(t types.Type, old *ast.UndefConst) (*ir.Const
 	return ir.NewUndef(t), nil
 }
 
+// --- [ Poison Values ] -------------------------------------------------------
+
+func (gen *generator) irPoisonConst(t types.Type, old *ast.PoisonConst) (*ir.ConstPoison, error) {
+	return ir.NewPoison(t), nil
+}
+
 // --- [ Addresses of Basic Blocks ] -------------------------------------------
 
 func (gen *generator) irBlockAddressConst(t types.Type, old *ast.BlockAddressConst) (*ir.ConstBlockAddress, error) {
@@ -215,6 +260,38 @@ func (gen *generator) irBlockAddressConst(t types.Type, old *ast.BlockAddressCon
 	return expr, nil
 }
 
+// --- [ DSO Local Equivalent ] ------------------------------------------------
+
+func (gen *generator) irDSOLocalEquivalentConst(t types.Type, old *ast.DSOLocalEquivalentConst) (*ir.ConstDSOLocalEquivalent, error) {
+	// Function.
+	funcName := global(old.Func())
+	f, err := gen.function(funcName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := ir.NewDSOLocalEquivalent(f)
+	if !c.Type().Equal(t) {
+		return nil, errors.Errorf("type mismatch of dso_local_equivalent constant; expected %v, got %v", t, c.Type())
+	}
+	return c, nil
+}
+
+// --- [ No CFI ] --------------------------------------------------------------
+
+func (gen *generator) irNoCFIConst(t types.Type, old *ast.NoCFIConst) (*ir.ConstNoCFI, error) {
+	// Function.
+	funcName := global(old.Func())
+	f, err := gen.function(funcName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := ir.NewNoCFI(f)
+	if !c.Type().Equal(t) {
+		return nil, errors.Errorf("type mismatch of no_cfi constant; expected %v, got %v", t, c.Type())
+	}
+	return c, nil
+}
+
 // Pre-condition: translate function body and assign local IDs of c.Func.
 func fixBlockAddressConst(c *ir.ConstBlockAddress) error {
 	f := c.Func
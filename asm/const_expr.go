@@ -257,7 +257,7 @@ func (gen *generator) irInsertValueExpr(t types.Type, old *ast.InsertValueExpr)
 
 func (gen *generator) irGetElementPtrExpr(t types.Type, old *ast.GetElementPtrExpr) (*ir.ExprGetElementPtr, error) {
 	// Element type.
-	elemType, err := gen.irType(old.ElemType())
+	elemType, err := gen.irElemType(old.ElemType(), old.Src().Typ())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -276,6 +276,11 @@ func (gen *generator) irGetElementPtrExpr(t types.Type, old *ast.GetElementPtrEx
 		indices = append(indices, index)
 	}
 	expr := ir.NewGetElementPtrExpr(elemType, src, indices...)
+	// The result of getelementptr on an opaque pointer is an opaque pointer in
+	// the same address space.
+	if ptr, ok := src.Type().(*types.PointerType); ok && isOpaquePointer(ptr) {
+		expr.Typ = &types.PointerType{AddrSpace: ptr.AddrSpace}
+	}
 	// TODO: validate type t against expr.Typ.
 	// In-bounds.
 	expr.InBounds = irOptInBounds(old.InBounds())
@@ -0,0 +1,465 @@
+// Package datalayout implements parsing of LLVM IR target data layouts, and
+// queries of the size, alignment and memory layout of types.
+//
+// ref: https://llvm.org/docs/LangRef.html#data-layout
+package datalayout
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// DataLayout specifies how data is laid out in memory for a given target.
+//
+// All sizes are in bits and all alignments are in bytes.
+type DataLayout struct {
+	// Big-endian byte order if set; little-endian otherwise.
+	BigEndian bool
+	// Natural alignment of the stack; 0 if unspecified.
+	StackAlign int64
+	// Address space of program memory (e.g. functions).
+	ProgramAddrSpace types.AddrSpace
+	// Address space of objects created by alloca.
+	AllocaAddrSpace types.AddrSpace
+	// Default address space of global variables.
+	GlobalsAddrSpace types.AddrSpace
+	// Alignment of function pointers; 0 if unspecified.
+	FuncPtrAlign int64
+	// Specifies how the alignment of function pointers relates to the alignment
+	// of functions.
+	FuncPtrAlignKind FuncPtrAlignKind
+	// Name mangling of symbols in the output object file.
+	Mangling Mangling
+	// Native integer widths of the target CPU.
+	NativeIntWidths []int64
+	// Address spaces with non-integral pointer types.
+	NonIntegralAddrSpaces []types.AddrSpace
+	// Pointer specifications, in order of address space.
+	Pointers []PointerSpec
+	// Alignment specifications of integer, floating-point, vector and aggregate
+	// types, in order of kind and size.
+	Aligns []AlignSpec
+}
+
+// PointerSpec specifies the size and alignment of pointers in a given address
+// space.
+//
+//    p[n]:<size>:<abi>[:<pref>[:<idx>]]
+type PointerSpec struct {
+	// Address space.
+	AddrSpace types.AddrSpace
+	// Size of pointers in bits.
+	Size int64
+	// ABI alignment of pointers in bytes.
+	ABIAlign int64
+	// Preferred alignment of pointers in bytes.
+	PrefAlign int64
+	// Size of indices used for address calculation in bits.
+	IndexSize int64
+}
+
+// AlignSpec specifies the alignment of integer, floating-point, vector or
+// aggregate types of a given size.
+//
+//    i<size>:<abi>[:<pref>]
+//    f<size>:<abi>[:<pref>]
+//    v<size>:<abi>[:<pref>]
+//    a:<abi>[:<pref>]
+type AlignSpec struct {
+	// Kind of types.
+	Kind AlignKind
+	// Size of types in bits; 0 for aggregate types.
+	Size int64
+	// ABI alignment in bytes.
+	ABIAlign int64
+	// Preferred alignment in bytes.
+	PrefAlign int64
+}
+
+// AlignKind specifies the kind of types of an alignment specification.
+type AlignKind uint8
+
+// Alignment specification kinds.
+const (
+	AlignKindInt       AlignKind = iota // i
+	AlignKindVector                     // v
+	AlignKindFloat                      // f
+	AlignKindAggregate                  // a
+)
+
+// FuncPtrAlignKind specifies how the alignment of function pointers relates to
+// the alignment of functions.
+type FuncPtrAlignKind uint8
+
+// Function pointer alignment kinds.
+const (
+	// The alignment of function pointers is independent of the alignment of
+	// functions.
+	FuncPtrAlignIndependent FuncPtrAlignKind = iota // Fi
+	// The alignment of function pointers is a multiple of the explicit
+	// alignment of functions.
+	FuncPtrAlignMultiple // Fn
+)
+
+// Mangling specifies the name mangling of symbols in the output object file.
+type Mangling uint8
+
+// Name manglings.
+const (
+	ManglingNone       Mangling = iota
+	ManglingELF                 // m:e
+	ManglingGOFF                // m:l
+	ManglingMips                // m:m
+	ManglingMachO               // m:o
+	ManglingWinCOFF             // m:w
+	ManglingWinCOFFX86          // m:x
+	ManglingXCOFF               // m:a
+)
+
+// Default returns the default data layout, as used for the parts of a data
+// layout not explicitly specified.
+func Default() *DataLayout {
+	return &DataLayout{
+		Pointers: []PointerSpec{
+			{AddrSpace: 0, Size: 64, ABIAlign: 8, PrefAlign: 8, IndexSize: 64},
+		},
+		Aligns: []AlignSpec{
+			{Kind: AlignKindInt, Size: 1, ABIAlign: 1, PrefAlign: 1},
+			{Kind: AlignKindInt, Size: 8, ABIAlign: 1, PrefAlign: 1},
+			{Kind: AlignKindInt, Size: 16, ABIAlign: 2, PrefAlign: 2},
+			{Kind: AlignKindInt, Size: 32, ABIAlign: 4, PrefAlign: 4},
+			{Kind: AlignKindInt, Size: 64, ABIAlign: 4, PrefAlign: 8},
+			{Kind: AlignKindVector, Size: 64, ABIAlign: 8, PrefAlign: 8},
+			{Kind: AlignKindVector, Size: 128, ABIAlign: 16, PrefAlign: 16},
+			{Kind: AlignKindFloat, Size: 16, ABIAlign: 2, PrefAlign: 2},
+			{Kind: AlignKindFloat, Size: 32, ABIAlign: 4, PrefAlign: 4},
+			{Kind: AlignKindFloat, Size: 64, ABIAlign: 8, PrefAlign: 8},
+			{Kind: AlignKindFloat, Size: 128, ABIAlign: 16, PrefAlign: 16},
+			{Kind: AlignKindAggregate, Size: 0, ABIAlign: 1, PrefAlign: 8},
+		},
+	}
+}
+
+// Parse parses the given data layout string (e.g. the value of `target
+// datalayout`). Parts of the data layout not explicitly specified are given by
+// the default data layout.
+func Parse(s string) (*DataLayout, error) {
+	dl := Default()
+	if len(s) == 0 {
+		return dl, nil
+	}
+	for _, spec := range strings.Split(s, "-") {
+		if err := dl.parseSpec(spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid data layout %q", s)
+		}
+	}
+	return dl, nil
+}
+
+// parseSpec parses the given data layout specification.
+func (dl *DataLayout) parseSpec(spec string) error {
+	if len(spec) == 0 {
+		return errors.New("empty specification")
+	}
+	fields := strings.Split(spec, ":")
+	tok, args := fields[0], fields[1:]
+	// Non-integral address spaces.
+	//
+	//    ni:<as>[:<as>]*
+	if tok == "ni" {
+		for _, arg := range args {
+			addrSpace, err := parseAddrSpace(arg)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if addrSpace == 0 {
+				return errors.Errorf("invalid specification %q; address space 0 can never be non-integral", spec)
+			}
+			dl.NonIntegralAddrSpaces = append(dl.NonIntegralAddrSpaces, addrSpace)
+		}
+		return nil
+	}
+	kind, rest := tok[0], tok[1:]
+	switch kind {
+	case 's':
+		// Deprecated; ignored.
+		return nil
+	case 'e':
+		dl.BigEndian = false
+	case 'E':
+		dl.BigEndian = true
+	case 'p':
+		return dl.parsePointerSpec(spec, rest, args)
+	case 'i', 'v', 'f', 'a':
+		return dl.parseAlignSpec(spec, kind, rest, args)
+	case 'n':
+		for _, arg := range append([]string{rest}, args...) {
+			width, err := parseInt(arg)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if width == 0 {
+				return errors.Errorf("invalid specification %q; zero width native integer type", spec)
+			}
+			dl.NativeIntWidths = append(dl.NativeIntWidths, width)
+		}
+	case 'S':
+		align, err := parseAlign(rest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dl.StackAlign = align
+	case 'F':
+		if len(rest) == 0 {
+			return errors.Errorf("invalid specification %q; missing function pointer alignment type", spec)
+		}
+		switch rest[0] {
+		case 'i':
+			dl.FuncPtrAlignKind = FuncPtrAlignIndependent
+		case 'n':
+			dl.FuncPtrAlignKind = FuncPtrAlignMultiple
+		default:
+			return errors.Errorf("invalid specification %q; unknown function pointer alignment type %q", spec, rest[:1])
+		}
+		align, err := parseAlign(rest[1:])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dl.FuncPtrAlign = align
+	case 'P', 'A', 'G':
+		addrSpace, err := parseAddrSpace(rest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch kind {
+		case 'P':
+			dl.ProgramAddrSpace = addrSpace
+		case 'A':
+			dl.AllocaAddrSpace = addrSpace
+		case 'G':
+			dl.GlobalsAddrSpace = addrSpace
+		}
+	case 'm':
+		if len(rest) > 0 {
+			return errors.Errorf("invalid specification %q; unexpected trailing characters after mangling specifier", spec)
+		}
+		if len(args) != 1 || len(args[0]) != 1 {
+			return errors.Errorf("invalid specification %q; expected single character mangling", spec)
+		}
+		switch args[0] {
+		case "e":
+			dl.Mangling = ManglingELF
+		case "l":
+			dl.Mangling = ManglingGOFF
+		case "m":
+			dl.Mangling = ManglingMips
+		case "o":
+			dl.Mangling = ManglingMachO
+		case "w":
+			dl.Mangling = ManglingWinCOFF
+		case "x":
+			dl.Mangling = ManglingWinCOFFX86
+		case "a":
+			dl.Mangling = ManglingXCOFF
+		default:
+			return errors.Errorf("invalid specification %q; unknown mangling %q", spec, args[0])
+		}
+		return nil
+	default:
+		return errors.Errorf("invalid specification %q; unknown specifier %q", spec, kind)
+	}
+	if kind != 'n' && len(args) > 0 {
+		return errors.Errorf("invalid specification %q; unexpected trailing fields", spec)
+	}
+	return nil
+}
+
+// parsePointerSpec parses the given pointer specification.
+//
+//    p[n]:<size>:<abi>[:<pref>[:<idx>]]
+func (dl *DataLayout) parsePointerSpec(spec, rest string, args []string) error {
+	addrSpace, err := parseAddrSpace(rest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(args) < 1 {
+		return errors.Errorf("invalid specification %q; missing pointer size", spec)
+	}
+	if len(args) < 2 {
+		return errors.Errorf("invalid specification %q; missing pointer alignment", spec)
+	}
+	if len(args) > 4 {
+		return errors.Errorf("invalid specification %q; unexpected trailing fields", spec)
+	}
+	size, err := parseInt(args[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if size == 0 {
+		return errors.Errorf("invalid specification %q; invalid pointer size of 0 bits", spec)
+	}
+	p := PointerSpec{AddrSpace: addrSpace, Size: size, IndexSize: size}
+	if p.ABIAlign, err = parseAlign(args[1]); err != nil {
+		return errors.WithStack(err)
+	}
+	p.PrefAlign = p.ABIAlign
+	if len(args) >= 3 {
+		if p.PrefAlign, err = parseAlign(args[2]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(args) >= 4 {
+		if p.IndexSize, err = parseInt(args[3]); err != nil {
+			return errors.WithStack(err)
+		}
+		if p.IndexSize == 0 {
+			return errors.Errorf("invalid specification %q; invalid index size of 0 bits", spec)
+		}
+		if p.IndexSize > p.Size {
+			return errors.Errorf("invalid specification %q; index size (%d) larger than pointer size (%d)", spec, p.IndexSize, p.Size)
+		}
+	}
+	p.ABIAlign, p.PrefAlign = atLeastOne(p.ABIAlign), atLeastOne(p.PrefAlign)
+	if p.PrefAlign < p.ABIAlign {
+		return errors.Errorf("invalid specification %q; preferred alignment less than ABI alignment", spec)
+	}
+	for i, prev := range dl.Pointers {
+		if prev.AddrSpace == addrSpace {
+			dl.Pointers[i] = p
+			return nil
+		}
+		if prev.AddrSpace > addrSpace {
+			dl.Pointers = append(dl.Pointers[:i], append([]PointerSpec{p}, dl.Pointers[i:]...)...)
+			return nil
+		}
+	}
+	dl.Pointers = append(dl.Pointers, p)
+	return nil
+}
+
+// parseAlignSpec parses the given alignment specification of integer,
+// floating-point, vector or aggregate types.
+//
+//    i<size>:<abi>[:<pref>]
+//    f<size>:<abi>[:<pref>]
+//    v<size>:<abi>[:<pref>]
+//    a:<abi>[:<pref>]
+func (dl *DataLayout) parseAlignSpec(spec string, kind byte, rest string, args []string) error {
+	a := AlignSpec{}
+	switch kind {
+	case 'i':
+		a.Kind = AlignKindInt
+	case 'v':
+		a.Kind = AlignKindVector
+	case 'f':
+		a.Kind = AlignKindFloat
+	case 'a':
+		a.Kind = AlignKindAggregate
+	}
+	if len(rest) > 0 {
+		size, err := parseInt(rest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		a.Size = size
+	}
+	if a.Kind == AlignKindAggregate && a.Size != 0 {
+		return errors.Errorf("invalid specification %q; sized aggregate specification", spec)
+	}
+	if a.Kind != AlignKindAggregate && a.Size == 0 {
+		return errors.Errorf("invalid specification %q; missing bit width", spec)
+	}
+	if len(args) < 1 {
+		return errors.Errorf("invalid specification %q; missing alignment", spec)
+	}
+	if len(args) > 2 {
+		return errors.Errorf("invalid specification %q; unexpected trailing fields", spec)
+	}
+	var err error
+	if a.ABIAlign, err = parseAlign(args[0]); err != nil {
+		return errors.WithStack(err)
+	}
+	if a.Kind != AlignKindAggregate && a.ABIAlign == 0 {
+		return errors.Errorf("invalid specification %q; ABI alignment must be greater than 0 for non-aggregate types", spec)
+	}
+	a.PrefAlign = a.ABIAlign
+	if len(args) >= 2 {
+		if a.PrefAlign, err = parseAlign(args[1]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	a.ABIAlign, a.PrefAlign = atLeastOne(a.ABIAlign), atLeastOne(a.PrefAlign)
+	if a.PrefAlign < a.ABIAlign {
+		return errors.Errorf("invalid specification %q; preferred alignment less than ABI alignment", spec)
+	}
+	if a.Kind == AlignKindInt && a.Size == 8 && a.ABIAlign != 1 {
+		return errors.Errorf("invalid specification %q; i8 must be naturally aligned", spec)
+	}
+	for i, prev := range dl.Aligns {
+		if prev.Kind == a.Kind && prev.Size == a.Size {
+			dl.Aligns[i] = a
+			return nil
+		}
+		if prev.Kind > a.Kind || (prev.Kind == a.Kind && prev.Size > a.Size) {
+			dl.Aligns = append(dl.Aligns[:i], append([]AlignSpec{a}, dl.Aligns[i:]...)...)
+			return nil
+		}
+	}
+	dl.Aligns = append(dl.Aligns, a)
+	return nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// parseInt parses the given unsigned 32-bit integer.
+func parseInt(s string) (int64, error) {
+	x, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid integer %q; expected unsigned 32-bit integer", s)
+	}
+	return int64(x), nil
+}
+
+// parseAddrSpace parses the given address space; an empty string denotes the
+// default address space.
+func parseAddrSpace(s string) (types.AddrSpace, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+	x, err := parseInt(s)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if x >= 1<<24 {
+		return 0, errors.Errorf("invalid address space %d; must be a 24-bit integer", x)
+	}
+	return types.AddrSpace(x), nil
+}
+
+// parseAlign parses the given alignment in bits, and returns the alignment in
+// bytes. The alignment must be 0 or a power of two multiple of 8.
+func parseAlign(s string) (int64, error) {
+	bits, err := parseInt(s)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if bits%8 != 0 {
+		return 0, errors.Errorf("invalid alignment %d; number of bits must be a byte width multiple", bits)
+	}
+	align := bits / 8
+	if align != 0 && align&(align-1) != 0 {
+		return 0, errors.Errorf("invalid alignment %d; must be a power of two", bits)
+	}
+	return align, nil
+}
+
+// atLeastOne returns the given alignment, or 1 if the alignment is 0.
+func atLeastOne(align int64) int64 {
+	if align == 0 {
+		return 1
+	}
+	return align
+}
@@ -0,0 +1,172 @@
+package datalayout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/llir/l/ir/types"
+)
+
+// Data layout of x86-64 Linux.
+const linuxAMD64 = "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"
+
+func TestParse(t *testing.T) {
+	dl, err := Parse(linuxAMD64)
+	if err != nil {
+		t.Fatalf("unable to parse data layout %q; %v", linuxAMD64, err)
+	}
+	if dl.BigEndian || dl.Mangling != ManglingELF || dl.StackAlign != 16 {
+		t.Errorf("data layout mismatch; expected little-endian ELF mangling with 16 byte stack alignment, got %+v", dl)
+	}
+	if want := []int64{8, 16, 32, 64}; !reflect.DeepEqual(dl.NativeIntWidths, want) {
+		t.Errorf("native integer widths mismatch; expected %v, got %v", want, dl.NativeIntWidths)
+	}
+	if want := (PointerSpec{AddrSpace: 270, Size: 32, ABIAlign: 4, PrefAlign: 4, IndexSize: 32}); dl.Pointer(270) != want {
+		t.Errorf("pointer specification mismatch of address space 270; expected %+v, got %+v", want, dl.Pointer(270))
+	}
+	if p := dl.Pointer(1); p.AddrSpace != 0 {
+		t.Errorf("pointer specification mismatch of address space 1; expected default address space, got %+v", p)
+	}
+	dl, err = Parse("E-p:32:32:64:16-ni:1:2-Fn32-P1-A5-G1-m:o")
+	if err != nil {
+		t.Fatalf("unable to parse data layout; %v", err)
+	}
+	if want := (PointerSpec{AddrSpace: 0, Size: 32, ABIAlign: 4, PrefAlign: 8, IndexSize: 16}); dl.Pointer(0) != want {
+		t.Errorf("pointer specification mismatch; expected %+v, got %+v", want, dl.Pointer(0))
+	}
+	if !dl.BigEndian || dl.FuncPtrAlignKind != FuncPtrAlignMultiple || dl.FuncPtrAlign != 4 || dl.ProgramAddrSpace != 1 || dl.AllocaAddrSpace != 5 || dl.GlobalsAddrSpace != 1 || dl.Mangling != ManglingMachO {
+		t.Errorf("data layout mismatch; got %+v", dl)
+	}
+	if want := []types.AddrSpace{1, 2}; !reflect.DeepEqual(dl.NonIntegralAddrSpaces, want) {
+		t.Errorf("non-integral address spaces mismatch; expected %v, got %v", want, dl.NonIntegralAddrSpaces)
+	}
+	// Invalid data layouts.
+	for _, s := range []string{
+		"e--i64:64",
+		"i64:63",
+		"i64:24",
+		"i8:16",
+		"a8:64",
+		"p:0:64",
+		"p:64",
+		"p:64:64:32",
+		"ni:0",
+		"m:q",
+		"q",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("expected error when parsing invalid data layout %q", s)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	p270 := types.NewPointer(types.I8)
+	p270.AddrSpace = 270
+	golden := []struct {
+		layout string
+		t      types.Type
+		// Size in bytes.
+		size int64
+		// Store size in bytes.
+		storeSize int64
+		// ABI alignment in bytes.
+		align int64
+	}{
+		// i=0
+		{layout: linuxAMD64, t: types.NewStruct(types.I8, types.I32, types.I64), size: 16, storeSize: 16, align: 8},
+		// i=1
+		{layout: linuxAMD64, t: types.NewStruct(types.I8, types.X86_FP80), size: 32, storeSize: 32, align: 16},
+		// i=2
+		{layout: linuxAMD64, t: &types.StructType{Packed: true, Fields: []types.Type{types.I8, types.I32}}, size: 5, storeSize: 5, align: 1},
+		// i=3
+		{layout: linuxAMD64, t: types.NewArray(3, types.NewStruct(types.I8, types.I16)), size: 12, storeSize: 12, align: 2},
+		// i=4
+		{layout: linuxAMD64, t: types.NewStruct(types.I8, p270), size: 8, storeSize: 8, align: 4},
+		// i=5
+		{layout: linuxAMD64, t: types.NewVector(3, types.I32), size: 16, storeSize: 12, align: 16},
+		// i=6
+		{layout: linuxAMD64, t: types.NewInt(36), size: 8, storeSize: 5, align: 8},
+		// i=7
+		{layout: linuxAMD64, t: types.I128, size: 16, storeSize: 16, align: 8},
+		// i=8
+		{layout: "", t: types.NewInt(36), size: 8, storeSize: 5, align: 4},
+		// i=9
+		{layout: "", t: types.NewStruct(types.I8, types.I64), size: 12, storeSize: 12, align: 4},
+		// i=10
+		{layout: "", t: types.NewStruct(types.I8, p270), size: 16, storeSize: 16, align: 8},
+		// i=11
+		{layout: "", t: types.X86_FP80, size: 16, storeSize: 10, align: 16},
+	}
+	for i, g := range golden {
+		dl, err := Parse(g.layout)
+		if err != nil {
+			t.Errorf("i=%d: unable to parse data layout %q; %v", i, g.layout, err)
+			continue
+		}
+		size, err := dl.SizeOf(g.t)
+		if err != nil {
+			t.Errorf("i=%d: unable to compute size of %v; %v", i, g.t, err)
+			continue
+		}
+		if g.size != size {
+			t.Errorf("i=%d: size mismatch of %v; expected %d, got %d", i, g.t, g.size, size)
+		}
+		storeSize, err := dl.StoreSize(g.t)
+		if err != nil {
+			t.Errorf("i=%d: unable to compute store size of %v; %v", i, g.t, err)
+			continue
+		}
+		if g.storeSize != storeSize {
+			t.Errorf("i=%d: store size mismatch of %v; expected %d, got %d", i, g.t, g.storeSize, storeSize)
+		}
+		align, err := dl.AlignOf(g.t)
+		if err != nil {
+			t.Errorf("i=%d: unable to compute alignment of %v; %v", i, g.t, err)
+			continue
+		}
+		if g.align != align {
+			t.Errorf("i=%d: alignment mismatch of %v; expected %d, got %d", i, g.t, g.align, align)
+		}
+	}
+	// Unsized types.
+	dl := Default()
+	for _, typ := range []types.Type{types.Void, types.NewFunc(types.Void), &types.StructType{Opaque: true}} {
+		if _, err := dl.SizeOf(typ); err == nil {
+			t.Errorf("expected error when computing size of unsized type %v", typ)
+		}
+	}
+}
+
+func TestStructLayout(t *testing.T) {
+	dl, err := Parse(linuxAMD64)
+	if err != nil {
+		t.Fatalf("unable to parse data layout %q; %v", linuxAMD64, err)
+	}
+	typ := types.NewStruct(types.I8, types.I32, types.I64, types.I16)
+	layout, err := dl.StructLayout(typ)
+	if err != nil {
+		t.Fatalf("unable to compute layout of %v; %v", typ, err)
+	}
+	if want := []int64{0, 4, 8, 16}; !reflect.DeepEqual(layout.Offsets, want) {
+		t.Errorf("field offsets mismatch of %v; expected %v, got %v", typ, want, layout.Offsets)
+	}
+	if layout.Size != 24 || layout.Align != 8 || !layout.Padded {
+		t.Errorf("layout mismatch of %v; expected padded size 24 and alignment 8, got %+v", typ, layout)
+	}
+	for offset, want := range map[int64]int{0: 0, 3: 0, 4: 1, 15: 2, 17: 3, 23: 3, 24: -1} {
+		if got := layout.FieldAt(offset); got != want {
+			t.Errorf("field mismatch at offset %d of %v; expected %d, got %d", offset, typ, want, got)
+		}
+	}
+	offset, err := dl.FieldOffset(typ, 2)
+	if err != nil {
+		t.Fatalf("unable to compute offset of field 2 of %v; %v", typ, err)
+	}
+	if offset != 8 {
+		t.Errorf("field offset mismatch of field 2 of %v; expected 8, got %d", typ, offset)
+	}
+	if _, err := dl.FieldOffset(typ, 4); err == nil {
+		t.Errorf("expected error when computing offset of out of bounds field of %v", typ)
+	}
+}
@@ -0,0 +1,334 @@
+package datalayout
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir/types"
+	"github.com/pkg/errors"
+)
+
+// === [ Sizes ] ===============================================================
+
+// SizeInBits returns the size in bits of the given type. The size of scalable
+// vector types is the minimum size (i.e. for vscale of 1).
+func (dl *DataLayout) SizeInBits(t types.Type) (int64, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		return t.BitSize, nil
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindHalf, types.FloatKindBFloat:
+			return 16, nil
+		case types.FloatKindFloat:
+			return 32, nil
+		case types.FloatKindDouble:
+			return 64, nil
+		case types.FloatKindX86FP80:
+			return 80, nil
+		case types.FloatKindFP128, types.FloatKindPPCFP128:
+			return 128, nil
+		default:
+			panic(fmt.Errorf("support for floating-point kind %v not yet implemented", t.Kind))
+		}
+	case *types.MMXType:
+		return 64, nil
+	case *types.AMXType:
+		return 8192, nil
+	case *types.PointerType:
+		return dl.Pointer(t.AddrSpace).Size, nil
+	case *types.LabelType:
+		return dl.Pointer(0).Size, nil
+	case *types.VectorType:
+		elemSize, err := dl.SizeInBits(t.ElemType)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return t.Len * elemSize, nil
+	case *types.ArrayType:
+		elemSize, err := dl.SizeOf(t.ElemType)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return 8 * t.Len * elemSize, nil
+	case *types.StructType:
+		layout, err := dl.StructLayout(t)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return 8 * layout.Size, nil
+	case *types.VoidType, *types.FuncType, *types.MetadataType, *types.TokenType:
+		return 0, errors.Errorf("unable to compute size of unsized type %v", t)
+	case *types.TargetExtType:
+		// TODO: add support for the layout types of target extension types.
+		return 0, errors.Errorf("support for size of target extension type %v not yet implemented", t)
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+}
+
+// StoreSize returns the maximum number of bytes that may be overwritten by
+// storing a value of the given type.
+func (dl *DataLayout) StoreSize(t types.Type) (int64, error) {
+	bits, err := dl.SizeInBits(t)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return (bits + 7) / 8, nil
+}
+
+// SizeOf returns the size in bytes of the given type, including alignment
+// padding; i.e. the offset in bytes between successive values of the type in
+// an array.
+func (dl *DataLayout) SizeOf(t types.Type) (int64, error) {
+	size, err := dl.StoreSize(t)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	align, err := dl.AlignOf(t)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return alignTo(size, align), nil
+}
+
+// === [ Alignments ] ==========================================================
+
+// AlignOf returns the ABI alignment in bytes of the given type.
+func (dl *DataLayout) AlignOf(t types.Type) (int64, error) {
+	return dl.align(t, true)
+}
+
+// PrefAlignOf returns the preferred alignment in bytes of the given type.
+func (dl *DataLayout) PrefAlignOf(t types.Type) (int64, error) {
+	return dl.align(t, false)
+}
+
+// align returns the ABI alignment (if abi is set) or preferred alignment in
+// bytes of the given type.
+func (dl *DataLayout) align(t types.Type, abi bool) (int64, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		a, ok := dl.intAlign(t.BitSize)
+		if !ok {
+			return 1, nil
+		}
+		return a.pick(abi), nil
+	case *types.FloatType:
+		bits, err := dl.SizeInBits(t)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if a, ok := dl.lookupAlign(AlignKindFloat, bits); ok {
+			return a.pick(abi), nil
+		}
+		// Fall back to the store size rounded up to the nearest power of two.
+		return powerOf2Ceil((bits + 7) / 8), nil
+	case *types.MMXType, *types.VectorType:
+		bits, err := dl.SizeInBits(t)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if a, ok := dl.lookupAlign(AlignKindVector, bits); ok {
+			return a.pick(abi), nil
+		}
+		// Vectors are naturally aligned by default.
+		return powerOf2Ceil((bits + 7) / 8), nil
+	case *types.AMXType:
+		return 64, nil
+	case *types.PointerType:
+		p := dl.Pointer(t.AddrSpace)
+		return p.pick(abi), nil
+	case *types.LabelType:
+		p := dl.Pointer(0)
+		return p.pick(abi), nil
+	case *types.ArrayType:
+		return dl.align(t.ElemType, abi)
+	case *types.StructType:
+		// Packed structure types always have an ABI alignment of one.
+		if t.Packed && abi {
+			return 1, nil
+		}
+		layout, err := dl.StructLayout(t)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		a, _ := dl.lookupAlign(AlignKindAggregate, 0)
+		return max(a.pick(abi), layout.Align), nil
+	case *types.VoidType, *types.FuncType, *types.MetadataType, *types.TokenType:
+		return 0, errors.Errorf("unable to compute alignment of unsized type %v", t)
+	case *types.TargetExtType:
+		// TODO: add support for the layout types of target extension types.
+		return 0, errors.Errorf("support for alignment of target extension type %v not yet implemented", t)
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+}
+
+// Pointer returns the pointer specification of the given address space. The
+// specification of the default address space is used for address spaces
+// without explicit pointer specification.
+func (dl *DataLayout) Pointer(addrSpace types.AddrSpace) PointerSpec {
+	var def PointerSpec
+	for _, p := range dl.Pointers {
+		if p.AddrSpace == addrSpace {
+			return p
+		}
+		if p.AddrSpace == 0 {
+			def = p
+		}
+	}
+	return def
+}
+
+// intAlign returns the alignment specification of integer types of the given
+// bit size. Integer types without exact alignment specification use the
+// alignment of the next larger integer type, or of the largest integer type if
+// none is larger.
+func (dl *DataLayout) intAlign(bits int64) (AlignSpec, bool) {
+	var largest AlignSpec
+	found := false
+	for _, a := range dl.Aligns {
+		if a.Kind != AlignKindInt {
+			continue
+		}
+		if a.Size >= bits {
+			return a, true
+		}
+		largest, found = a, true
+	}
+	return largest, found
+}
+
+// lookupAlign returns the alignment specification of the given kind and exact
+// bit size.
+func (dl *DataLayout) lookupAlign(kind AlignKind, bits int64) (AlignSpec, bool) {
+	for _, a := range dl.Aligns {
+		if a.Kind == kind && a.Size == bits {
+			return a, true
+		}
+	}
+	return AlignSpec{ABIAlign: 1, PrefAlign: 1}, false
+}
+
+// pick returns the ABI alignment (if abi is set) or the preferred alignment of
+// the alignment specification.
+func (a AlignSpec) pick(abi bool) int64 {
+	if abi {
+		return a.ABIAlign
+	}
+	return a.PrefAlign
+}
+
+// pick returns the ABI alignment (if abi is set) or the preferred alignment of
+// the pointer specification.
+func (p PointerSpec) pick(abi bool) int64 {
+	if abi {
+		return p.ABIAlign
+	}
+	return p.PrefAlign
+}
+
+// === [ Structure layouts ] ===================================================
+
+// StructLayout is the memory layout of a structure type.
+type StructLayout struct {
+	// Size in bytes, including padding.
+	Size int64
+	// Alignment in bytes of the fields; the ABI alignment of the structure type
+	// is at least the alignment of aggregate types.
+	Align int64
+	// Offsets in bytes of the fields.
+	Offsets []int64
+	// Indicates whether the structure type contains padding between or after
+	// fields.
+	Padded bool
+}
+
+// StructLayout returns the memory layout of the given structure type.
+func (dl *DataLayout) StructLayout(t *types.StructType) (*StructLayout, error) {
+	if t.Opaque {
+		return nil, errors.Errorf("unable to compute layout of opaque structure type %v", t)
+	}
+	layout := &StructLayout{Align: 1}
+	for _, field := range t.Fields {
+		align := int64(1)
+		if !t.Packed {
+			var err error
+			if align, err = dl.AlignOf(field); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		// Add padding to align the field.
+		if layout.Size%align != 0 {
+			layout.Padded = true
+			layout.Size = alignTo(layout.Size, align)
+		}
+		layout.Align = max(layout.Align, align)
+		layout.Offsets = append(layout.Offsets, layout.Size)
+		size, err := dl.SizeOf(field)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		layout.Size += size
+	}
+	// Add padding to the end of the structure so that each element of an array
+	// of the structure type is aligned.
+	if layout.Size%layout.Align != 0 {
+		layout.Padded = true
+		layout.Size = alignTo(layout.Size, layout.Align)
+	}
+	return layout, nil
+}
+
+// FieldOffset returns the offset in bytes of the i:th field of the given
+// structure type.
+func (dl *DataLayout) FieldOffset(t *types.StructType, i int) (int64, error) {
+	if i < 0 || i >= len(t.Fields) {
+		return 0, errors.Errorf("invalid field index %d of structure type %v with %d fields", i, t, len(t.Fields))
+	}
+	layout, err := dl.StructLayout(t)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return layout.Offsets[i], nil
+}
+
+// FieldAt returns the index of the field containing the given byte offset, or
+// -1 if the offset is outside of the structure.
+func (l *StructLayout) FieldAt(offset int64) int {
+	if offset < 0 || offset >= l.Size {
+		return -1
+	}
+	field := -1
+	for i, off := range l.Offsets {
+		if off > offset {
+			break
+		}
+		field = i
+	}
+	return field
+}
+
+// ### [ Helpers ] #############################################################
+
+// alignTo returns x rounded up to the nearest multiple of align.
+func alignTo(x, align int64) int64 {
+	return (x + align - 1) / align * align
+}
+
+// powerOf2Ceil returns x rounded up to the nearest power of two.
+func powerOf2Ceil(x int64) int64 {
+	p := int64(1)
+	for p < x {
+		p <<= 1
+	}
+	return p
+}
+
+// max returns the maximum of x and y.
+func max(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
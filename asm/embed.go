@@ -0,0 +1,329 @@
+package asm
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/mewmew/l-tm/internal/bitstream"
+	"github.com/pkg/errors"
+)
+
+// Sections of ELF object files containing embedded LLVM bitcode and the
+// command line used to compile it (as produced by clang -fembed-bitcode).
+const (
+	elfBitcodeSection = ".llvmbc"
+	elfCmdSection     = ".llvmcmd"
+)
+
+// elfMagic is the magic number of ELF files.
+var elfMagic = []byte{0x7F, 'E', 'L', 'F'}
+
+// arMagic is the magic number of ar archives.
+var arMagic = []byte("!<arch>\n")
+
+// EmbeddedModule is an LLVM IR module embedded in an object file.
+type EmbeddedModule struct {
+	// Name of the object file containing the module. Members of archives are
+	// named "archive.a(member.o)".
+	Object string
+	// Command line used to compile the module, as stored in the .llvmcmd
+	// section; or nil if not present.
+	Cmd []string
+	// LLVM IR module.
+	Module *ir.Module
+}
+
+// IsObject reports whether the given file contents is an ELF object file or an
+// ar archive, based on its magic number.
+func IsObject(b []byte) bool {
+	return bytes.HasPrefix(b, elfMagic) || bytes.HasPrefix(b, arMagic)
+}
+
+// ParseEmbeddedFile parses the LLVM bitcode embedded in the given ELF object
+// file or archive into LLVM IR modules, one per embedded module.
+func ParseEmbeddedFile(path string) ([]*EmbeddedModule, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ms, err := ParseEmbedded(path, buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse embedded bitcode of %q", path)
+	}
+	return ms, nil
+}
+
+// ParseEmbedded parses the LLVM bitcode embedded in the given ELF object file
+// or archive contents into LLVM IR modules, one per embedded module. The
+// source filename of each module is set to the name of the object file
+// containing it, based on the given file name.
+//
+// Bitcode is embedded in the .llvmbc section of ELF object files compiled with
+// -fembed-bitcode, and stored directly as archive members by -flto.
+func ParseEmbedded(name string, buf []byte) ([]*EmbeddedModule, error) {
+	switch {
+	case bytes.HasPrefix(buf, arMagic):
+		return parseArchive(name, buf)
+	case bytes.HasPrefix(buf, elfMagic):
+		ms, err := parseELF(name, buf)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(ms) == 0 {
+			return nil, errors.Errorf("unable to locate %s section in ELF object file %q", elfBitcodeSection, name)
+		}
+		return ms, nil
+	default:
+		return nil, errors.Errorf("invalid object file %q; expected ELF object file or ar archive", name)
+	}
+}
+
+// parseArchive parses the LLVM bitcode of the members of the given ar archive.
+// Members without bitcode (e.g. symbol tables and native object files) are
+// skipped.
+func parseArchive(name string, buf []byte) ([]*EmbeddedModule, error) {
+	members, err := arMembers(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse archive %q", name)
+	}
+	var ms []*EmbeddedModule
+	for _, member := range members {
+		object := fmt.Sprintf("%s(%s)", name, member.name)
+		switch {
+		case bytes.HasPrefix(member.data, elfMagic):
+			objMs, err := parseELF(object, member.data)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			ms = append(ms, objMs...)
+		case IsBitcode(member.data):
+			// LLVM bitcode object file (as produced by -flto).
+			m, err := parseEmbeddedBitcode(object, member.data)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			ms = append(ms, &EmbeddedModule{Object: object, Module: m})
+		}
+	}
+	return ms, nil
+}
+
+// parseELF parses the LLVM bitcode embedded in the given ELF object file. The
+// .llvmbc section contains one or more concatenated bitcode modules, as
+// sections of several object files are concatenated by relocatable links.
+func parseELF(name string, buf []byte) ([]*EmbeddedModule, error) {
+	f, err := elf.NewFile(bytes.NewReader(buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse ELF object file %q", name)
+	}
+	defer f.Close()
+	bcSect := f.Section(elfBitcodeSection)
+	if bcSect == nil {
+		return nil, nil
+	}
+	data, err := bcSect.Data()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s section of %q", elfBitcodeSection, name)
+	}
+	var cmd []string
+	if cmdSect := f.Section(elfCmdSection); cmdSect != nil {
+		cmdData, err := cmdSect.Data()
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read %s section of %q", elfCmdSection, name)
+		}
+		cmd = splitCmd(cmdData)
+	}
+	modules, err := splitBitcode(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to locate bitcode modules of %s section of %q", elfBitcodeSection, name)
+	}
+	var ms []*EmbeddedModule
+	for _, module := range modules {
+		m, err := parseEmbeddedBitcode(name, module)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// TODO: associate command lines with modules once the .llvmcmd sections
+		// of several object files have been concatenated; for now, the command
+		// line is only recorded for object files containing a single module.
+		e := &EmbeddedModule{Object: name, Module: m}
+		if len(modules) == 1 {
+			e.Cmd = cmd
+		}
+		ms = append(ms, e)
+	}
+	return ms, nil
+}
+
+// parseEmbeddedBitcode parses the given bitcode module embedded in the
+// specified object file, recording the object file name as source filename.
+func parseEmbeddedBitcode(object string, buf []byte) (*ir.Module, error) {
+	m, err := ParseBitcode(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse bitcode embedded in %q", object)
+	}
+	m.SourceFilename = object
+	return m, nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// splitBitcode splits the given contents of concatenated bitcode modules into
+// separate modules. Modules may be separated by zero padding (used to align
+// sections).
+func splitBitcode(buf []byte) ([][]byte, error) {
+	var modules [][]byte
+	for {
+		buf = trimPadding(buf)
+		if len(buf) == 0 {
+			return modules, nil
+		}
+		n, err := bitcodeSize(buf)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		modules = append(modules, buf[:n])
+		buf = buf[n:]
+	}
+}
+
+// bitcodeSize returns the size in bytes of the first bitcode module of the
+// given contents, including wrapper header or magic number.
+func bitcodeSize(buf []byte) (int, error) {
+	if !IsBitcode(buf) {
+		return 0, errors.New("invalid bitcode magic number")
+	}
+	if !bytes.HasPrefix(buf, bcMagic) {
+		// Bitcode wrapper header.
+		//
+		//    [magic, version, offset, size, cputype] (5 x uint32)
+		if len(buf) < 20 {
+			return 0, errors.New("invalid bitcode wrapper header; too short")
+		}
+		end := uint64(binary.LittleEndian.Uint32(buf[8:])) + uint64(binary.LittleEndian.Uint32(buf[12:]))
+		if end > uint64(len(buf)) {
+			return 0, errors.Errorf("invalid bitcode wrapper header; bitcode (end %d) past end of file (size %d)", end, len(buf))
+		}
+		return int(end), nil
+	}
+	// Skip top-level blocks (identification, module, string table, symbol
+	// table, ...) until the start of the next module or the end of contents.
+	body := buf[len(bcMagic):]
+	r := bitstream.NewReader(body)
+	for {
+		rest := trimPadding(body[r.Offset():])
+		if len(rest) == 0 || IsBitcode(rest) {
+			return len(bcMagic) + int(r.Offset()), nil
+		}
+		entry, err := r.Next()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if entry.Kind != bitstream.EntrySubBlock {
+			return 0, errors.Errorf("invalid top-level bitstream entry kind; expected subblock, got %d", entry.Kind)
+		}
+		if err := r.SkipBlock(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+}
+
+// trimPadding returns the given contents with leading zero padding removed.
+func trimPadding(buf []byte) []byte {
+	return bytes.TrimLeft(buf, "\x00")
+}
+
+// splitCmd returns the arguments of the given .llvmcmd section contents, which
+// are separated by NUL characters.
+func splitCmd(data []byte) []string {
+	s := strings.TrimRight(string(data), "\x00")
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// arMember is a member of an ar archive.
+type arMember struct {
+	// Member name.
+	name string
+	// Member contents.
+	data []byte
+}
+
+// arMembers returns the members of the given ar archive contents. Both the GNU
+// and BSD variants of long member names are supported.
+//
+// Each member is preceded by a 60 byte header.
+//
+//    name[16] date[12] uid[6] gid[6] mode[8] size[10] fmag[2]
+func arMembers(buf []byte) ([]arMember, error) {
+	const headerSize = 60
+	var (
+		members []arMember
+		// GNU long member names table.
+		longNames []byte
+	)
+	buf = buf[len(arMagic):]
+	for len(buf) > 0 {
+		if len(buf) < headerSize {
+			return nil, errors.Errorf("invalid archive member header; expected %d bytes, got %d", headerSize, len(buf))
+		}
+		hdr := buf[:headerSize]
+		if string(hdr[58:60]) != "`\n" {
+			return nil, errors.Errorf("invalid archive member header terminator %q", hdr[58:60])
+		}
+		size, err := strconv.ParseUint(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		buf = buf[headerSize:]
+		if size > uint64(len(buf)) {
+			return nil, errors.Errorf("invalid archive member size; member (size %d) past end of archive (%d bytes remaining)", size, len(buf))
+		}
+		data := buf[:size]
+		// Members are aligned to even offsets.
+		if size%2 == 1 && size < uint64(len(buf)) {
+			size++
+		}
+		buf = buf[size:]
+		name := strings.TrimRight(string(hdr[:16]), " ")
+		switch {
+		case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+			// Symbol table.
+			continue
+		case name == "//":
+			longNames = data
+			continue
+		case strings.HasPrefix(name, "#1/"):
+			// BSD long member name, stored at the start of the member contents.
+			n, err := strconv.Atoi(name[len("#1/"):])
+			if err != nil || n > len(data) {
+				return nil, errors.Errorf("invalid BSD long member name %q", name)
+			}
+			name = strings.TrimRight(string(data[:n]), "\x00")
+			data = data[n:]
+		case strings.HasPrefix(name, "/"):
+			// GNU long member name; offset into long member names table.
+			offset, err := strconv.Atoi(name[len("/"):])
+			if err != nil || offset > len(longNames) {
+				return nil, errors.Errorf("invalid GNU long member name %q", name)
+			}
+			name = string(longNames[offset:])
+			if end := strings.Index(name, "/\n"); end != -1 {
+				name = name[:end]
+			}
+		default:
+			// GNU short member names are terminated by '/'.
+			name = strings.TrimSuffix(name, "/")
+		}
+		members = append(members, arMember{name: name, data: data})
+	}
+	return members, nil
+}
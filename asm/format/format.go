@@ -0,0 +1,470 @@
+// Package format implements canonical formatting of LLVM IR assembly files.
+//
+// In contrast to the assembly printed by ir.Module.Def, formatting is lossless
+// in that comments and the grouping of lines (separated by blank lines) are
+// preserved.
+package format
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mewmew/l-tm/asm"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// Source formats the given LLVM IR assembly source in canonical layout. An
+// optional path to the source file may be specified for error reporting.
+//
+// The canonical layout places each top-level entity on a separate line (with
+// blank lines surrounding function definitions), indents instructions of
+// function bodies by two spaces, aligns attribute group definitions, and
+// orders attribute group and metadata definitions by ID at the end of the
+// module.
+func Source(path string, src []byte) ([]byte, error) {
+	content := string(src)
+	module, err := asm.Parse(path, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f := &formatter{}
+	f.formatModule(module, tokens(content))
+	return f.buf.Bytes(), nil
+}
+
+// formatter formats LLVM IR assembly.
+type formatter struct {
+	// Output buffer.
+	buf bytes.Buffer
+	// Indicates whether a line has been written.
+	started bool
+}
+
+// span is a span of source code.
+type span struct {
+	// Start and end byte offsets of the span.
+	start, end int
+}
+
+// nodeSpan returns the source span of the given AST node.
+func nodeSpan(n ast.LlvmNode) span {
+	node := n.LlvmNode()
+	return span{start: node.Offset(), end: node.Endoffset()}
+}
+
+// entity is a top-level entity of a module, together with its comments.
+type entity struct {
+	// AST node of top-level entity.
+	node ast.TopLevelEntity
+	// Comments preceding the top-level entity.
+	lead []token
+	// Tokens of the top-level entity.
+	toks []token
+	// Comment following the top-level entity on the same line; or nil if not
+	// present.
+	trail *token
+}
+
+// blank reports whether the top-level entity (including leading comments) is
+// preceded by a blank line in the source.
+func (e *entity) blank() bool {
+	if len(e.lead) > 0 {
+		return e.lead[0].newlines >= 2
+	}
+	return e.toks[0].newlines >= 2
+}
+
+// id returns the numeric ID of the attribute group or metadata definition.
+func (e *entity) id() int {
+	var s string
+	switch e.node.(type) {
+	case *ast.AttrGroupDef:
+		// attributes #0 = { ... }
+		s = e.toks[1].text
+	case *ast.MetadataDef:
+		// !0 = !{ ... }
+		s = e.toks[0].text
+	}
+	id, _ := strconv.Atoi(s[1:])
+	return id
+}
+
+// formatModule formats the given module, based on the tokens of its source.
+func (f *formatter) formatModule(module *ast.Module, toks []token) {
+	// Split tokens into top-level entities and comments.
+	var (
+		entities []*entity
+		lead     []token
+		i        int
+	)
+	for _, node := range module.TopLevelEntities() {
+		s := nodeSpan(node)
+		for ; i < len(toks) && toks[i].start < s.start; i++ {
+			t := toks[i]
+			if n := len(entities); n > 0 && len(lead) == 0 && t.newlines == 0 && entities[n-1].trail == nil {
+				entities[n-1].trail = &toks[i]
+				continue
+			}
+			lead = append(lead, t)
+		}
+		e := &entity{node: node, lead: lead}
+		lead = nil
+		for ; i < len(toks) && toks[i].start < s.end; i++ {
+			e.toks = append(e.toks, toks[i])
+		}
+		entities = append(entities, e)
+	}
+	var tail []token
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		if n := len(entities); n > 0 && len(tail) == 0 && t.newlines == 0 && entities[n-1].trail == nil {
+			entities[n-1].trail = &toks[i]
+			continue
+		}
+		tail = append(tail, t)
+	}
+	// Order attribute group and metadata definitions at the end of the module.
+	var other, attrs, namedMDs, mds []*entity
+	for _, e := range entities {
+		switch e.node.(type) {
+		case *ast.AttrGroupDef:
+			attrs = append(attrs, e)
+		case *ast.NamedMetadataDef:
+			namedMDs = append(namedMDs, e)
+		case *ast.MetadataDef:
+			mds = append(mds, e)
+		default:
+			other = append(other, e)
+		}
+	}
+	sort.SliceStable(attrs, func(i, j int) bool { return attrs[i].id() < attrs[j].id() })
+	sort.SliceStable(mds, func(i, j int) bool { return mds[i].id() < mds[j].id() })
+	// Width of attribute group IDs, used for alignment.
+	width := 0
+	for _, e := range attrs {
+		if n := len(e.toks[1].text); n > width {
+			width = n
+		}
+	}
+	prevFunc := false
+	for _, section := range [][]*entity{other, attrs, append(namedMDs, mds...)} {
+		for i, e := range section {
+			_, isFunc := e.node.(*ast.FuncDef)
+			blank := e.blank() || isFunc || prevFunc || i == 0
+			if len(e.lead) > 0 {
+				f.writeComments("", e.lead, blank)
+				blank = e.toks[0].newlines >= 2
+			}
+			f.newline("", blank)
+			switch node := e.node.(type) {
+			case *ast.FuncDef:
+				f.writeFuncDef(node, e.toks)
+			case *ast.AttrGroupDef:
+				// attributes #0 = { ... }
+				name := e.toks[1].text
+				f.buf.WriteString("attributes ")
+				f.buf.WriteString(name)
+				f.buf.WriteString(strings.Repeat(" ", width-len(name)+1))
+				f.writeTokens("", e.toks[2:])
+			default:
+				f.writeTokens("", e.toks)
+			}
+			if e.trail != nil {
+				f.writeTrail(*e.trail)
+			}
+			prevFunc = isFunc
+		}
+	}
+	if len(tail) > 0 {
+		f.writeComments("", tail, tail[0].newlines >= 2)
+	}
+	if f.started {
+		f.buf.WriteString("\n")
+	}
+}
+
+// writeFuncDef writes the given function definition, based on its tokens.
+func (f *formatter) writeFuncDef(def *ast.FuncDef, toks []token) {
+	body := def.Body()
+	// Instructions, terminators and use-list orders of the function body; each
+	// written on a separate line.
+	var items []span
+	for _, block := range body.Blocks() {
+		for _, inst := range block.Insts() {
+			items = append(items, nodeSpan(inst))
+		}
+		items = append(items, nodeSpan(block.Term()))
+	}
+	for _, useList := range body.UseListOrders() {
+		items = append(items, nodeSpan(useList))
+	}
+	// Function header and '{'.
+	bodyStart := nodeSpan(body).start
+	i := 0
+	for i < len(toks) && toks[i].start < bodyStart {
+		i++
+	}
+	f.writeTokens("", toks[:i+1])
+	// Basic blocks; excluding '}'.
+	const indent = "  "
+	afterBrace := true
+	bodyToks := toks[i+1 : len(toks)-1]
+	for i := 0; i < len(bodyToks); {
+		t := bodyToks[i]
+		if t.kind == kindComment && t.newlines == 0 {
+			f.writeTrail(t)
+			i++
+			continue
+		}
+		// Leading comments, followed by a label, item or '}'.
+		j := i
+		for j < len(bodyToks) && bodyToks[j].kind == kindComment {
+			j++
+		}
+		isLabel := j < len(bodyToks) && !(len(items) > 0 && bodyToks[j].start >= items[0].start)
+		lineIndent := indent
+		if isLabel {
+			lineIndent = ""
+		}
+		blank := t.newlines >= 2 || (isLabel && !afterBrace)
+		if j > i {
+			f.writeComments(lineIndent, bodyToks[i:j], blank)
+			if j == len(bodyToks) {
+				break
+			}
+			blank = bodyToks[j].newlines >= 2
+		}
+		f.newline(lineIndent, blank)
+		if isLabel {
+			f.buf.WriteString(bodyToks[j].text)
+			i = j + 1
+		} else {
+			k := j
+			for k < len(bodyToks) && bodyToks[k].start < items[0].end {
+				k++
+			}
+			f.writeTokens(indent, bodyToks[j:k])
+			items = items[1:]
+			i = k
+		}
+		afterBrace = false
+	}
+	f.newline("", false)
+	f.buf.WriteString(toks[len(toks)-1].text)
+}
+
+// writeComments writes the given comments on separate lines, with the given
+// indentation. If blank is set, a blank line is written before the first
+// comment.
+func (f *formatter) writeComments(indent string, comments []token, blank bool) {
+	for i, c := range comments {
+		if i > 0 {
+			blank = c.newlines >= 2
+		}
+		f.newline(indent, blank)
+		f.buf.WriteString(comment(c))
+	}
+}
+
+// writeTrail writes the given comment at the end of the current line.
+func (f *formatter) writeTrail(c token) {
+	f.buf.WriteString(" ")
+	f.buf.WriteString(comment(c))
+}
+
+// newline starts a new line with the given indentation. If blank is set, a
+// blank line is written before the new line. No line break is written before
+// the first line.
+func (f *formatter) newline(indent string, blank bool) {
+	if f.started {
+		f.buf.WriteString("\n")
+		if blank {
+			f.buf.WriteString("\n")
+		}
+	}
+	f.started = true
+	f.buf.WriteString(indent)
+}
+
+// writeTokens writes the given tokens on the current line, separated by
+// canonical spacing. Lines broken by comments are continued with the given
+// indentation plus one extra level.
+func (f *formatter) writeTokens(indent string, toks []token) {
+	// Stack of '{' and '[' delimiters; true if the delimiter has inner spacing
+	// (e.g. `{ i32 }`), false otherwise (e.g. `!{!0}`).
+	var delims []bool
+	top := func() bool {
+		return len(delims) > 0 && delims[len(delims)-1]
+	}
+	// Switch terminators are written with one case per line.
+	isSwitch := len(toks) > 0 && toks[0].text == "switch"
+	// Phi instructions are written with inner spacing of incoming values.
+	isPhi := false
+	for _, t := range toks {
+		if t.text == "phi" {
+			isPhi = true
+			break
+		}
+		if t.kind != kindWord && t.text != "=" {
+			break
+		}
+	}
+	inCases, caseStart := false, false
+	for i, t := range toks {
+		switch {
+		case i == 0:
+		case toks[i-1].kind == kindComment:
+			f.buf.WriteString("\n")
+			f.buf.WriteString(indent)
+			f.buf.WriteString("    ")
+		case inCases && t.text == "]":
+			f.buf.WriteString("\n")
+			f.buf.WriteString(indent)
+		case caseStart:
+			f.buf.WriteString("\n")
+			f.buf.WriteString(indent)
+			f.buf.WriteString("  ")
+		case t.kind == kindComment:
+			f.buf.WriteString(" ")
+		case t.text == "}" || t.text == "]":
+			if top() {
+				f.buf.WriteString(" ")
+			}
+		case toks[i-1].text == "{" || toks[i-1].text == "[":
+			if top() {
+				f.buf.WriteString(" ")
+			}
+		case t.text == "=" && isAttrKey(toks[i-1]), toks[i-1].text == "=" && i >= 2 && isAttrKey(toks[i-2]):
+			// String attributes (e.g. `"no-frame-pointer-elim"="true"`).
+		default:
+			if spaceBetween(toks[i-1], t) {
+				f.buf.WriteString(" ")
+			}
+		}
+		caseStart = false
+		if t.kind == kindComment {
+			f.buf.WriteString(comment(t))
+		} else {
+			f.buf.WriteString(t.text)
+		}
+		switch t.text {
+		case "{":
+			delims = append(delims, i == 0 || toks[i-1].text != "!")
+		case "[":
+			delims = append(delims, isPhi)
+			if isSwitch {
+				inCases, caseStart = true, true
+			}
+		case "}", "]":
+			if len(delims) > 0 {
+				delims = delims[:len(delims)-1]
+			}
+			inCases = false
+		default:
+			// Each case of a switch terminator ends with its target branch
+			// (e.g. `i32 1, label %foo`).
+			if inCases && i > 0 && toks[i-1].text == "label" {
+				caseStart = true
+			}
+		}
+	}
+}
+
+// spaceBetween reports whether a space separates the given adjacent tokens
+// (other than '{', '}', '[' and ']' delimiters).
+func spaceBetween(prev, next token) bool {
+	switch next.text {
+	case ",", ")", "*", ">":
+		return false
+	case "(":
+		if prev.text == "target" {
+			// Parameters of target extension types (e.g. `target("foo", i8)`).
+			return false
+		}
+		return isType(prev) || constExprKeywords[prev.text]
+	}
+	switch prev.text {
+	case "(", "<", "!":
+		return false
+	case "c":
+		// Character array constants (e.g. `c"foo"`).
+		return next.kind != kindString
+	}
+	return true
+}
+
+// isAttrKey reports whether the given token is the key of a string attribute.
+func isAttrKey(t token) bool {
+	return t.kind == kindString && strings.HasPrefix(t.text, `"`) && !strings.HasSuffix(t.text, ":")
+}
+
+// isType reports whether the given token is a type keyword or ends a type.
+func isType(t token) bool {
+	if typeKeywords[t.text] {
+		return true
+	}
+	switch t.text {
+	case "*", "]", "}", ">":
+		return true
+	}
+	if len(t.text) > 1 && t.text[0] == 'i' {
+		if _, err := strconv.Atoi(t.text[1:]); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// typeKeywords specifies the keywords of types, as defined by the LLVM IR
+// grammar (see asm/ll/ll.tm).
+var typeKeywords = map[string]bool{
+	// Void type.
+	"void": true,
+	// Floating-point types.
+	"half": true, "bfloat": true, "float": true, "double": true,
+	"x86_fp80": true, "fp128": true, "ppc_fp128": true,
+	// MMX and AMX types.
+	"x86_mmx": true, "x86_amx": true,
+	// Pointer types.
+	"ptr": true,
+	// Vector types.
+	"vscale": true,
+	// Target extension types.
+	"target": true,
+	// Label, metadata and token types.
+	"label": true, "metadata": true, "token": true,
+}
+
+// constExprKeywords specifies the keywords which are followed by a space
+// before the parenthesized operands of constant expressions (e.g.
+// `bitcast (i8* @x to i32*)`).
+var constExprKeywords = map[string]bool{
+	// Binary and bitwise expressions.
+	"add": true, "fadd": true, "sub": true, "fsub": true, "mul": true, "fmul": true,
+	"udiv": true, "sdiv": true, "fdiv": true, "urem": true, "srem": true, "frem": true,
+	"shl": true, "lshr": true, "ashr": true, "and": true, "or": true, "xor": true,
+	"nuw": true, "nsw": true, "exact": true,
+	// Vector and aggregate expressions.
+	"extractelement": true, "insertelement": true, "shufflevector": true,
+	"extractvalue": true, "insertvalue": true,
+	// Memory expressions.
+	"getelementptr": true, "inbounds": true,
+	// Conversion expressions.
+	"trunc": true, "zext": true, "sext": true, "fptrunc": true, "fpext": true,
+	"fptoui": true, "fptosi": true, "uitofp": true, "sitofp": true, "ptrtoint": true,
+	"inttoptr": true, "bitcast": true, "addrspacecast": true,
+	// Other expressions.
+	"select": true, "icmp": true, "fcmp": true,
+	"eq": true, "ne": true, "ugt": true, "uge": true, "ult": true, "ule": true,
+	"sgt": true, "sge": true, "slt": true, "sle": true,
+	"oeq": true, "ogt": true, "oge": true, "olt": true, "ole": true, "one": true,
+	"ord": true, "ueq": true, "une": true, "uno": true, "true": true, "false": true,
+}
+
+// comment returns the text of the given comment, without trailing whitespace.
+func comment(c token) string {
+	return strings.TrimRight(c.text, " \t\r")
+}
@@ -0,0 +1,106 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Comments and blank lines.
+		{
+			in: `; Header comment.
+source_filename   =  "foo.c"
+
+!0 = !{ i32 1 }
+attributes #10 = { nounwind }
+attributes #0 = {"a"="b"}
+
+define i32 @f(i32 %x) #0 { ; trailing
+entry:
+    %y = add i32 %x, 1 ; inc
+  ; Return.
+  ret i32 %y
+}
+!llvm.ident = !{!0}
+`,
+			want: `; Header comment.
+source_filename = "foo.c"
+
+define i32 @f(i32 %x) #0 { ; trailing
+entry:
+  %y = add i32 %x, 1 ; inc
+  ; Return.
+  ret i32 %y
+}
+
+attributes #0  = { "a"="b" }
+attributes #10 = { nounwind }
+
+!llvm.ident = !{!0}
+!0 = !{i32 1}
+`,
+		},
+		// Types, constant expressions and switch cases.
+		{
+			in: `%T = type {i32,<2 x i8>, [4 x i8]*}
+@s = global [4 x i8] c"foo\00"
+@p = global i8* getelementptr ([4 x i8], [4 x i8]* @s, i64 0, i64 0)
+define void @g(i32 %x) {
+  switch i32 %x, label %a [ i32 0, label %a  i32 1, label %b ]
+a:
+  %y = phi i32 [0, %0], [1, %b]
+  ret void
+b:
+  br label %a
+}
+`,
+			want: `%T = type { i32, <2 x i8>, [4 x i8]* }
+@s = global [4 x i8] c"foo\00"
+@p = global i8* getelementptr ([4 x i8], [4 x i8]* @s, i64 0, i64 0)
+
+define void @g(i32 %x) {
+  switch i32 %x, label %a [
+    i32 0, label %a
+    i32 1, label %b
+  ]
+
+a:
+  %y = phi i32 [ 0, %0 ], [ 1, %b ]
+  ret void
+
+b:
+  br label %a
+}
+`,
+		},
+		// Function types of pointer, floating-point, AMX and target extension
+		// types.
+		{
+			in: `define void @k(ptr %p, bfloat %b, x86_amx %a, target("x",i8) %t, <vscale x 4 x i32> %v) {
+  %q = call ptr(ptr) %p(ptr null)
+  %c = call bfloat(bfloat) %p(bfloat %b)
+  %d = call x86_amx(x86_amx) %p(x86_amx %a)
+  ret void
+}
+`,
+			want: `define void @k(ptr %p, bfloat %b, x86_amx %a, target("x", i8) %t, <vscale x 4 x i32> %v) {
+  %q = call ptr (ptr) %p(ptr null)
+  %c = call bfloat (bfloat) %p(bfloat %b)
+  %d = call x86_amx (x86_amx) %p(x86_amx %a)
+  ret void
+}
+`,
+		},
+	}
+	for i, g := range golden {
+		got, err := Source("", []byte(g.in))
+		if err != nil {
+			t.Errorf("i=%d: unable to format source; %v", i, err)
+			continue
+		}
+		if string(got) != g.want {
+			t.Errorf("i=%d: formatted source mismatch; expected %q, got %q", i, g.want, string(got))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/mewmew/l-tm/asm/ll"
+)
+
+// tokenKind specifies the kind of a source token.
+type tokenKind uint8
+
+// Source token kinds.
+const (
+	// ; comment
+	kindComment tokenKind = iota + 1
+	// "foo", "foo":
+	kindString
+	// Punctuation: , = * | ( ) [ ] { } < > !
+	kindPunct
+	// Keywords, types, identifiers and literals.
+	kindWord
+)
+
+// token is a source token of an LLVM IR assembly file, together with the
+// trivia (whitespace) preceding it.
+type token struct {
+	// Token kind.
+	kind tokenKind
+	// Token text.
+	text string
+	// Start and end byte offsets of the token.
+	start, end int
+	// Number of line breaks preceding the token.
+	newlines int
+}
+
+// tokens splits the given LLVM IR assembly source into tokens, retaining
+// comments and the number of line breaks between tokens.
+//
+// Tokens are produced by the lexer generated from ll.tm. As comments and
+// whitespace are (space) tokens skipped by the lexer, they are recovered from
+// the source text between consecutive tokens, which consists solely of
+// comments and whitespace.
+func tokens(src string) []token {
+	var toks []token
+	newlines := 0
+	// trivia records the comments and line breaks of src[start:end].
+	trivia := func(start, end int) {
+		for i := start; i < end; i++ {
+			switch src[i] {
+			case '\n':
+				newlines++
+			case ';':
+				j := i
+				for j < end && src[j] != '\n' && src[j] != '\r' {
+					j++
+				}
+				toks = append(toks, token{kind: kindComment, text: src[i:j], start: i, end: j, newlines: newlines})
+				newlines = 0
+				i = j - 1
+			}
+		}
+	}
+	var l ll.Lexer
+	l.Init(src)
+	prev := 0
+	for tok := l.Next(); tok != ll.EOI; tok = l.Next() {
+		start, end := l.Pos()
+		trivia(prev, start)
+		text := src[start:end]
+		toks = append(toks, token{kind: kindOf(text), text: text, start: start, end: end, newlines: newlines})
+		newlines = 0
+		prev = end
+	}
+	trivia(prev, len(src))
+	return toks
+}
+
+// kindOf returns the kind of the given token text.
+func kindOf(text string) tokenKind {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		// String literals (e.g. `"foo"`) and quoted labels (e.g. `"foo":`).
+		return kindString
+	case len(text) == 1 && strings.IndexByte(",=*|()[]{}<>!", text[0]) != -1:
+		return kindPunct
+	}
+	return kindWord
+}
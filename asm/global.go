@@ -61,27 +61,72 @@ func (gen *generator) resolveGlobals(module *ast.Module) (map[string]ir.Constant
 
 	// Create corresponding IR global variables and functions (without bodies but
 	// with type).
-	gen.gs = make(map[string]ir.Constant)
+	//
+	// existing records global variables and functions already present in the IR
+	// module being translated into.
+	existing := make(map[string]bool)
+	// redefined records existing global variables and functions defined by the
+	// translated module.
+	redefined := make(map[string]bool)
 	for name, old := range index {
+		if prev, ok := gen.gs[name]; ok {
+			redefine, err := gen.redefGlobal(name, prev, old)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			existing[name] = true
+			redefined[name] = redefine
+			continue
+		}
 		g, err := gen.newGlobal(name, old)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		if gen.linker != nil {
+			gen.linker.claimName(g, old)
+		}
 		gen.gs[name] = g
+		gen.record(g, old)
 	}
-
 	// Translate global variables and functions (including bodies).
 	for name, old := range index {
 		g := gen.gs[name]
+		if existing[name] {
+			if !redefined[name] {
+				// Refers to the existing global variable or function.
+				continue
+			}
+			// Definition of existing global variable or function; function
+			// parameters and basic blocks are translated from the definition.
+			if f, ok := g.(*ir.Function); ok {
+				f.Params = nil
+				f.Blocks = nil
+			}
+		}
 		_, err := gen.astToIRGlobal(g, old)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 	}
 
+	// Global variables and functions are of opaque pointer type in modules using
+	// opaque pointers. As opaque pointer types are detected while translating
+	// types, and may first occur within function bodies, pointer types are
+	// updated once translated.
+	if gen.opaquePointers {
+		for name := range index {
+			if !existing[name] || redefined[name] {
+				setOpaquePointer(gen.gs[name])
+			}
+		}
+	}
+
 	// Add global variable declarations and definitions to IR module in order of
 	// occurrence in input.
 	for _, key := range globalOrder {
+		if existing[key] {
+			continue
+		}
 		g, err := gen.global(key)
 		if err != nil {
 			// NOTE: panic since this would indicate a bug in the implementation.
@@ -93,6 +138,9 @@ func (gen *generator) resolveGlobals(module *ast.Module) (map[string]ir.Constant
 	// Add function declarations and definitions to IR module in order of
 	// occurrence in input.
 	for _, key := range funcOrder {
+		if existing[key] {
+			continue
+		}
 		f, err := gen.function(key)
 		if err != nil {
 			// NOTE: panic since this would indicate a bug in the implementation.
@@ -286,7 +334,7 @@ func (gen *generator) astToIRFuncDecl(g ir.Constant, old *ast.FuncDecl) (*ir.Fun
 
 func (gen *generator) astToIRFuncHeader(f *ir.Function, hdr ast.FuncHeader) error {
 	// Linkage.
-	f.Linkage = irOptLinkage(hdr.ExternLinkage())
+	f.Linkage = irFuncLinkage(hdr)
 	// Preemption.
 	f.Preemption = irOptPreemption(hdr.Preemption())
 	// Visibility.
@@ -308,9 +356,14 @@ func (gen *generator) astToIRFuncHeader(f *ir.Function, hdr ast.FuncHeader) erro
 			return errors.WithStack(err)
 		}
 		// Parameter attributes.
-		// TODO: handle Attrs.
+		attrs, err := gen.irParamAttrs(p.Attrs())
+		if err != nil {
+			return errors.WithStack(err)
+		}
 		name := optLocal(p.Name())
 		param := ir.NewParam(typ, name)
+		param.Attrs = attrs
+		gen.record(param, p)
 		f.Params = append(f.Params, param)
 	}
 
@@ -348,6 +401,11 @@ func (gen *generator) astToIRFuncDef(g ir.Constant, old *ast.FuncDef) (*ir.Funct
 	// Metadata.
 	// TODO: translate function metadata.
 	// Basic blocks.
+	if gen.bodies != nil {
+		// Translation of function body deferred until materialized.
+		gen.bodies[f] = old
+		return f, nil
+	}
 	fgen := newFuncGen(gen, f)
 	_, err := fgen.resolveLocals(old.Body())
 	if err != nil {
@@ -360,6 +418,104 @@ func (gen *generator) astToIRFuncDef(g ir.Constant, old *ast.FuncDef) (*ir.Funct
 
 // ### [ Helper functions ] ####################################################
 
+// redefGlobal checks that the AST global variable or function of the given name
+// may refer to the existing IR global variable or function prev, and reports
+// whether the existing global variable or function should be (re)defined by the
+// AST definition.
+func (gen *generator) redefGlobal(name string, prev ir.Constant, old ast.LlvmNode) (bool, error) {
+	if gen.linker != nil {
+		return gen.linker.redefGlobal(gen, name, prev, old)
+	}
+	if err := checkGlobalRedef(name, prev, old); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return !isDecl(old), nil
+}
+
+// checkGlobalRedef checks that the AST global variable or function of the given
+// name may refer to the existing IR global variable or function prev.
+// Declarations may refer to existing global variables and functions of the
+// same kind, and definitions may define existing declarations.
+func checkGlobalRedef(name string, prev ir.Constant, old ast.LlvmNode) error {
+	switch old.(type) {
+	case *ast.GlobalDecl:
+		if _, ok := prev.(*ir.Global); ok {
+			return nil
+		}
+	case *ast.GlobalDef:
+		if g, ok := prev.(*ir.Global); ok && g.Init == nil {
+			return nil
+		}
+	case *ast.FuncDecl:
+		if _, ok := prev.(*ir.Function); ok {
+			return nil
+		}
+	case *ast.FuncDef:
+		if f, ok := prev.(*ir.Function); ok && len(f.Blocks) == 0 {
+			return nil
+		}
+	}
+	return errors.Errorf("global identifier %q already present; prev `%s`, new `%s`", enc.Global(name), prev, text(old))
+}
+
+// globalName returns the global identifier (without '@' prefix) of the given
+// IR global variable or function.
+func globalName(g ir.Constant) string {
+	switch g := g.(type) {
+	case *ir.Global:
+		return g.GlobalName
+	case *ir.Function:
+		return g.GlobalName
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", g))
+	}
+}
+
+// setGlobalName sets the global identifier (without '@' prefix) of the given IR
+// global variable or function.
+func setGlobalName(g ir.Constant, name string) {
+	switch g := g.(type) {
+	case *ir.Global:
+		g.GlobalName = name
+	case *ir.Function:
+		g.GlobalName = name
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", g))
+	}
+}
+
+// setOpaquePointer sets the type of the given IR global variable or function to
+// an opaque pointer type, retaining its address space. The result types of
+// alloca instructions of the function body are set to opaque pointer types.
+func setOpaquePointer(g ir.Constant) {
+	switch g := g.(type) {
+	case *ir.Global:
+		g.Typ.ElemType = nil
+	case *ir.Function:
+		g.Typ.ElemType = nil
+		// Alloca instructions translated before the first opaque pointer type.
+		for _, block := range g.Blocks {
+			for _, inst := range block.Insts {
+				if inst, ok := inst.(*ir.InstAlloca); ok && !isOpaquePointer(inst.Type()) {
+					inst.Typ = &types.PointerType{}
+				}
+			}
+		}
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", g))
+	}
+}
+
+// isDecl reports whether the given AST global variable or function is a
+// declaration.
+func isDecl(old ast.LlvmNode) bool {
+	switch old.(type) {
+	case *ast.GlobalDecl, *ast.FuncDecl:
+		return true
+	}
+	return false
+}
+
 // text returns the text of the given node.
 func text(n ast.LlvmNode) string {
 	if n := n.LlvmNode(); n != nil {
@@ -8,6 +8,7 @@ import (
 	"github.com/llir/l/ir"
 	"github.com/llir/l/ir/enum"
 	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
 	asmenum "github.com/mewmew/l-tm/asm/enum"
 	"github.com/mewmew/l-tm/asm/ll/ast"
 	"github.com/mewmew/l-tm/internal/enc"
@@ -83,8 +84,43 @@ func optLabel(n *ast.LabelIdent) string {
 
 // --- [ Comdat Identifiers ] --------------------------------------------------
 
+// comdat returns the name (without '$' prefix) of the given comdat name.
+func comdat(n ast.ComdatName) string {
+	text := n.Text()
+	const prefix = "$"
+	if !strings.HasPrefix(text, prefix) {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible given the grammar.
+		panic(fmt.Errorf("invalid comdat name %q; missing '%s' prefix", text, prefix))
+	}
+	text = text[len(prefix):]
+	return unquote(text)
+}
+
 // --- [ Metadata Identifiers ] ------------------------------------------------
 
+// --- [ Summary Identifiers ] -------------------------------------------------
+
+// summaryID returns the ID (without '^' prefix) of the given summary
+// identifier.
+func summaryID(n ast.SummaryID) int64 {
+	text := n.Text()
+	const prefix = "^"
+	if !strings.HasPrefix(text, prefix) {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible given the grammar.
+		panic(fmt.Errorf("invalid summary identifier %q; missing '%s' prefix", text, prefix))
+	}
+	text = text[len(prefix):]
+	id, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible given the grammar.
+		panic(fmt.Errorf("unable to parse summary ID %q; %v", text, err))
+	}
+	return id
+}
+
 // === [ Literals ] ============================================================
 
 // --- [ Integer literals ] ----------------------------------------------------
@@ -164,6 +200,16 @@ func irOptAddrSpace(n *ast.AddrSpace) types.AddrSpace {
 	return types.AddrSpace(x)
 }
 
+// irOptAlign returns the IR alignment corresponding to the given optional AST
+// alignment.
+func irOptAlign(n *ast.Alignment) ir.Align {
+	if n == nil {
+		return 0
+	}
+	x := uintLit(n.N())
+	return ir.Align(x)
+}
+
 // irCase returns the IR switch case corresponding to the given AST switch case.
 func (fgen *funcGen) irCase(n ast.Case) (*ir.Case, error) {
 	x, err := fgen.gen.irTypeConst(n.X())
@@ -177,6 +223,124 @@ func (fgen *funcGen) irCase(n ast.Case) (*ir.Case, error) {
 	return ir.NewCase(x, target), nil
 }
 
+// irArgs returns the IR function arguments corresponding to the given AST
+// function arguments.
+func (fgen *funcGen) irArgs(n ast.Args) ([]value.Value, error) {
+	var args []value.Value
+	for _, oldArg := range n.Args() {
+		typ, err := fgen.gen.irType(oldArg.Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Parameter attributes.
+		attrs, err := fgen.gen.irParamAttrs(oldArg.Attrs())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		oldVal, ok := oldArg.Val().(ast.Value)
+		if !ok {
+			// TODO: add support for metadata arguments.
+			return nil, errors.Errorf("support for metadata argument %T not yet implemented", oldArg.Val())
+		}
+		arg, err := fgen.astToIRValue(typ, oldVal)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Record call-site parameter attributes of function arguments.
+		if len(attrs) > 0 {
+			arg = &ir.Arg{Value: arg, Attrs: attrs}
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// irCallee returns the IR callee corresponding to the given AST callee, based
+// on the function type or return type of the call and the function arguments.
+func (fgen *funcGen) irCallee(typ types.Type, n ast.Value, args []value.Value) (value.Value, error) {
+	sig, ok := typ.(*types.FuncType)
+	if !ok {
+		// The function type of non-variadic callees is implied by the return
+		// type and the types of the function arguments.
+		sig = &types.FuncType{RetType: typ}
+		for _, arg := range args {
+			sig.Params = append(sig.Params, arg.Type())
+		}
+	}
+	return fgen.astToIRValue(types.NewPointer(sig), n)
+}
+
+// irCallType returns the IR function type or return type of a call, based on
+// the given AST type of the call. In the legacy dialect, the type of the call
+// may also be specified as a pointer to the function type (e.g. `call i32
+// (i8*, ...)* @printf(...)`), in which case the function type is returned.
+func (gen *generator) irCallType(old ast.LlvmNode) (types.Type, error) {
+	typ, err := gen.irType(old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !gen.legacy {
+		return typ, nil
+	}
+	if ptr, ok := typ.(*types.PointerType); ok {
+		if sig, ok := ptr.ElemType.(*types.FuncType); ok {
+			return sig, nil
+		}
+	}
+	return typ, nil
+}
+
+// irElemType returns the IR element type of a load or getelementptr, based on
+// the given optional AST element type and the AST type of the source address.
+//
+// The explicit element type is only optional in the legacy dialect of LLVM IR
+// (pre LLVM 3.7), where it is implied by the typed pointer of the source
+// address (e.g. `load i32* %p`).
+func (gen *generator) irElemType(elemType ast.Type, srcType ast.LlvmNode) (types.Type, error) {
+	if elemType != nil {
+		return gen.irType(elemType)
+	}
+	if !gen.legacy {
+		return nil, errors.New("missing explicit element type; implicit element types are only supported by the legacy dialect (see Options.Legacy)")
+	}
+	typ, err := gen.irType(srcType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ptr, ok := scalarType(typ).(*types.PointerType)
+	if !ok || isOpaquePointer(ptr) {
+		return nil, errors.Errorf("invalid source address type; expected typed pointer, got %v", typ)
+	}
+	return ptr.ElemType, nil
+}
+
+// irOptAtomic returns the atomic boolean corresponding to the given optional
+// AST atomic.
+func irOptAtomic(n *ast.Atomic) bool {
+	return n != nil
+}
+
+// irAtomicOp returns the IR atomic operation corresponding to the given AST
+// atomic operation.
+func irAtomicOp(n ast.AtomicOp) enum.AtomicOp {
+	return atomicOpFromString(n.Text())
+}
+
+// irAtomicOrdering returns the IR atomic ordering corresponding to the given
+// AST atomic ordering.
+func irAtomicOrdering(n ast.AtomicOrdering) enum.AtomicOrdering {
+	return asmenum.AtomicOrderingFromString(n.Text())
+}
+
+// irOptAtomicOrdering returns the IR atomic ordering corresponding to the given
+// optional AST atomic ordering.
+func irOptAtomicOrdering(n *ast.AtomicOrdering) enum.AtomicOrdering {
+	if n == nil {
+		return enum.AtomicOrderingNone
+	}
+	return irAtomicOrdering(*n)
+}
+
 // irOptCallingConv returns the IR calling convention corresponding to the given
 // optional AST calling convention.
 func irOptCallingConv(n ast.CallingConv) enum.CallingConv {
@@ -290,6 +454,15 @@ func irOptLinkage(n ast.LlvmNode) enum.Linkage {
 	return asmenum.LinkageFromString(n.LlvmNode().Text())
 }
 
+// irFuncLinkage returns the IR linkage corresponding to the linkage of the
+// given AST function header.
+func irFuncLinkage(hdr ast.FuncHeader) enum.Linkage {
+	if n := hdr.Linkage(); n != nil {
+		return irOptLinkage(n)
+	}
+	return irOptLinkage(hdr.ExternLinkage())
+}
+
 // irOverflowFlags returns the IR overflow flags corresponding to the given AST
 // overflow flags.
 func irOverflowFlags(ns []ast.OverflowFlag) []enum.OverflowFlag {
@@ -301,6 +474,73 @@ func irOverflowFlags(ns []ast.OverflowFlag) []enum.OverflowFlag {
 	return flags
 }
 
+// irParamAttrs returns the IR parameter attributes corresponding to the given
+// AST parameter attributes.
+func (gen *generator) irParamAttrs(ns []ast.ParamAttr) ([]ir.ParamAttribute, error) {
+	var attrs []ir.ParamAttribute
+	for _, n := range ns {
+		attr, err := gen.irParamAttr(n)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// irParamAttr returns the IR parameter attribute corresponding to the given AST
+// parameter attribute.
+func (gen *generator) irParamAttr(n ast.ParamAttr) (ir.ParamAttribute, error) {
+	switch n := n.(type) {
+	case *ast.AttrString:
+		return ir.AttrString(stringLit(n.Val())), nil
+	case *ast.AttrPair:
+		return ir.AttrPair{Key: stringLit(n.Key()), Value: stringLit(n.Val())}, nil
+	case *ast.Alignment:
+		return ir.Align(uintLit(n.N())), nil
+	case *ast.Dereferenceable:
+		return ir.Dereferenceable{
+			N:           uintLit(n.N()),
+			DerefOrNull: strings.HasPrefix(n.Text(), "dereferenceable_or_null"),
+		}, nil
+	case *ast.ParamAttribute:
+		return paramAttrFromString(n.Text()), nil
+	}
+	// Resolve the type operand through irType, so that named types only
+	// referred to by parameter attributes are recorded in gen.ts.
+	var typ ast.Type
+	switch n := n.(type) {
+	case *ast.ByRefAttr:
+		typ = n.Typ()
+	case *ast.ByvalAttr:
+		typ = n.Typ()
+	case *ast.ElementTypeAttr:
+		typ = n.Typ()
+	case *ast.InAllocaAttr:
+		typ = n.Typ()
+	case *ast.SRetAttr:
+		typ = n.Typ()
+	default:
+		panic(fmt.Errorf("support for parameter attribute %T not yet implemented", n))
+	}
+	t, err := gen.irType(typ)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch n.(type) {
+	case *ast.ByRefAttr:
+		return ir.ByRef{Typ: t}, nil
+	case *ast.ByvalAttr:
+		return ir.Byval{Typ: t}, nil
+	case *ast.ElementTypeAttr:
+		return ir.ElementType{Typ: t}, nil
+	case *ast.InAllocaAttr:
+		return ir.InAlloca{Typ: t}, nil
+	default:
+		return ir.SRet{Typ: t}, nil
+	}
+}
+
 // irOptPreemption returns the IR preemption corresponding to the given optional
 // AST preemption.
 func irOptPreemption(n *ast.Preemption) enum.Preemption {
@@ -319,6 +559,16 @@ func irOptSelectionKind(n *ast.SelectionKind) enum.SelectionKind {
 	return asmenum.SelectionKindFromString(n.Text())
 }
 
+// irOptSyncScope returns the synchronization scope corresponding to the given
+// optional AST synchronization scope, or the empty string for the default
+// system scope.
+func irOptSyncScope(n *ast.SyncScope) string {
+	if n == nil {
+		return ""
+	}
+	return stringLit(n.Scope())
+}
+
 // irOptTLSModelFromThreadLocal returns the IR TLS model corresponding to the
 // given optional AST thread local storage.
 func irOptTLSModelFromThreadLocal(n *ast.ThreadLocal) enum.TLSModel {
@@ -368,8 +618,92 @@ func irOptVisibility(n *ast.Visibility) enum.Visibility {
 	return asmenum.VisibilityFromString(n.Text())
 }
 
+// irOptVolatile returns the volatile boolean corresponding to the given optional
+// AST volatile.
+func irOptVolatile(n *ast.Volatile) bool {
+	return n != nil
+}
+
+// irOptWeak returns the weak boolean corresponding to the given optional AST
+// weak.
+func irOptWeak(n *ast.Weak) bool {
+	return n != nil
+}
+
 // ### [ Helpers ] #############################################################
 
+// atomicOpFromString returns the IR atomic operation corresponding to the given
+// atomic operation name.
+func atomicOpFromString(s string) enum.AtomicOp {
+	switch s {
+	case "add":
+		return enum.AtomicOpAdd
+	case "and":
+		return enum.AtomicOpAnd
+	case "max":
+		return enum.AtomicOpMax
+	case "min":
+		return enum.AtomicOpMin
+	case "nand":
+		return enum.AtomicOpNAnd
+	case "or":
+		return enum.AtomicOpOr
+	case "sub":
+		return enum.AtomicOpSub
+	case "umax":
+		return enum.AtomicOpUMax
+	case "umin":
+		return enum.AtomicOpUMin
+	case "xchg":
+		return enum.AtomicOpXChg
+	case "xor":
+		return enum.AtomicOpXor
+	default:
+		panic(fmt.Errorf("unable to locate AtomicOp enum corresponding to %q", s))
+	}
+}
+
+// paramAttrFromString returns the IR parameter attribute corresponding to the
+// given parameter attribute name.
+func paramAttrFromString(s string) enum.ParamAttr {
+	switch s {
+	case "byval":
+		return enum.ParamAttrByval
+	case "inalloca":
+		return enum.ParamAttrInAlloca
+	case "inreg":
+		return enum.ParamAttrInReg
+	case "nest":
+		return enum.ParamAttrNest
+	case "noalias":
+		return enum.ParamAttrNoAlias
+	case "nocapture":
+		return enum.ParamAttrNoCapture
+	case "nonnull":
+		return enum.ParamAttrNonNull
+	case "readnone":
+		return enum.ParamAttrReadNone
+	case "readonly":
+		return enum.ParamAttrReadOnly
+	case "returned":
+		return enum.ParamAttrReturned
+	case "signext":
+		return enum.ParamAttrSExt
+	case "sret":
+		return enum.ParamAttrSRet
+	case "swifterror":
+		return enum.ParamAttrSwiftError
+	case "swiftself":
+		return enum.ParamAttrSwiftSelf
+	case "writeonly":
+		return enum.ParamAttrWriteOnly
+	case "zeroext":
+		return enum.ParamAttrZExt
+	default:
+		panic(fmt.Errorf("unable to locate ParamAttr enum corresponding to %q", s))
+	}
+}
+
 // unquote returns the unquoted version of s if quoted, and the original string
 // otherwise.
 func unquote(s string) string {
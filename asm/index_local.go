@@ -29,6 +29,14 @@ func (fgen *funcGen) indexLocals(oldBlocks []ast.BasicBlock) error {
 		return errors.WithStack(err)
 	}
 	// Index local identifiers.
+	return fgen.addLocals()
+}
+
+// addLocals indexes the function parameters, basic blocks and local variables
+// (produced by instructions and terminators) of fgen.f, which must have local
+// IDs assigned.
+func (fgen *funcGen) addLocals() error {
+	f := fgen.f
 	for _, param := range f.Params {
 		if err := fgen.addLocal(param.LocalName, param); err != nil {
 			return errors.WithStack(err)
@@ -70,17 +78,21 @@ func (fgen *funcGen) newLocals(oldBlocks []ast.BasicBlock) error {
 	for _, oldBlock := range oldBlocks {
 		blockName := optLabel(oldBlock.Name())
 		block := ir.NewBlock(blockName)
+		fgen.gen.record(block, oldBlock)
 		for _, oldInst := range oldBlock.Insts() {
 			inst, err := fgen.newIRInst(oldInst)
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			fgen.gen.record(inst, oldInst)
 			block.Insts = append(block.Insts, inst)
 		}
-		term, err := fgen.newIRTerm(oldBlock.Term())
+		oldTerm := oldBlock.Term()
+		term, err := fgen.newIRTerm(oldTerm)
 		if err != nil {
 			return errors.WithStack(err)
 		}
+		fgen.gen.record(term, oldTerm)
 		block.Term = term
 		f.Blocks = append(f.Blocks, block)
 	}
@@ -100,10 +112,10 @@ func (fgen *funcGen) addLocal(name string, v value.Value) error {
 }
 
 // isVoidValue reports whether the given named value is a non-value (i.e. a call
-// instruction or invoke terminator with void-return type).
+// instruction or invoke or callbr terminator with void-return type).
 func isVoidValue(n value.Named) bool {
 	switch n.(type) {
-	case *ir.InstCall, *ir.TermInvoke:
+	case *ir.InstCall, *ir.TermInvoke, *ir.TermCallBr:
 		return n.Type().Equal(types.Void)
 	}
 	return false
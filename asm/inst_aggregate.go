@@ -5,6 +5,7 @@ import (
 
 	"github.com/llir/l/ir"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 // --- [ Aggregate instructions ] ----------------------------------------------
@@ -17,7 +18,14 @@ func (fgen *funcGen) astToIRInstExtractValue(inst ir.Instruction, old *ast.Extra
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstExtractValue, got %T", inst))
 	}
-	// TODO: implement
+	// Aggregate value.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Element indices.
+	i.Indices = uintSlice(old.Indices())
 	return i, nil
 }
 
@@ -29,6 +37,19 @@ func (fgen *funcGen) astToIRInstInsertValue(inst ir.Instruction, old *ast.Insert
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstInsertValue, got %T", inst))
 	}
-	// TODO: implement
+	// Aggregate value.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Element to insert.
+	elem, err := fgen.astToIRTypeValue(old.Elem())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Elem = elem
+	// Element indices.
+	i.Indices = uintSlice(old.Indices())
 	return i, nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/llir/l/ir"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 // --- [ Conversion instructions ] ---------------------------------------------
@@ -17,7 +18,12 @@ func (fgen *funcGen) astToIRInstTrunc(inst ir.Instruction, old *ast.TruncInst) (
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstTrunc, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -29,7 +35,12 @@ func (fgen *funcGen) astToIRInstZExt(inst ir.Instruction, old *ast.ZExtInst) (*i
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstZExt, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -41,7 +52,12 @@ func (fgen *funcGen) astToIRInstSExt(inst ir.Instruction, old *ast.SExtInst) (*i
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstSExt, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -53,7 +69,12 @@ func (fgen *funcGen) astToIRInstFPTrunc(inst ir.Instruction, old *ast.FPTruncIns
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFPTrunc, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -65,7 +86,12 @@ func (fgen *funcGen) astToIRInstFPExt(inst ir.Instruction, old *ast.FPExtInst) (
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFPExt, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -77,7 +103,12 @@ func (fgen *funcGen) astToIRInstFPToUI(inst ir.Instruction, old *ast.FPToUIInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFPToUI, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -89,7 +120,12 @@ func (fgen *funcGen) astToIRInstFPToSI(inst ir.Instruction, old *ast.FPToSIInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFPToSI, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -101,7 +137,12 @@ func (fgen *funcGen) astToIRInstUIToFP(inst ir.Instruction, old *ast.UIToFPInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstUIToFP, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -113,7 +154,12 @@ func (fgen *funcGen) astToIRInstSIToFP(inst ir.Instruction, old *ast.SIToFPInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstSIToFP, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -125,7 +171,12 @@ func (fgen *funcGen) astToIRInstPtrToInt(inst ir.Instruction, old *ast.PtrToIntI
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstPtrToInt, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -137,7 +188,12 @@ func (fgen *funcGen) astToIRInstIntToPtr(inst ir.Instruction, old *ast.IntToPtrI
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstIntToPtr, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -149,7 +205,12 @@ func (fgen *funcGen) astToIRInstBitCast(inst ir.Instruction, old *ast.BitCastIns
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstBitCast, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
 
@@ -161,6 +222,11 @@ func (fgen *funcGen) astToIRInstAddrSpaceCast(inst ir.Instruction, old *ast.Addr
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstAddrSpaceCast, got %T", inst))
 	}
-	// TODO: implement
+	// Value before conversion.
+	from, err := fgen.astToIRTypeValue(old.From())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.From = from
 	return i, nil
 }
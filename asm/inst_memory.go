@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 // --- [ Memory instructions ] -------------------------------------------------
@@ -17,7 +19,21 @@ func (fgen *funcGen) astToIRInstAlloca(inst ir.Instruction, old *ast.AllocaInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstAlloca, got %T", inst))
 	}
-	// TODO: implement
+	// In-alloca.
+	i.InAlloca = old.InAlloca() != nil
+	// Swift error.
+	i.SwiftError = old.SwiftError() != nil
+	// Number of elements.
+	if n := old.NElems(); n != nil {
+		nelems, err := fgen.astToIRTypeValue(*n)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		i.NElems = nelems
+	}
+	// Alignment.
+	i.Align = irOptAlign(old.Alignment())
+	// TODO: handle address space.
 	return i, nil
 }
 
@@ -29,7 +45,26 @@ func (fgen *funcGen) astToIRInstLoad(inst ir.Instruction, old *ast.LoadInst) (*i
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstLoad, got %T", inst))
 	}
-	// TODO: implement
+	// Atomic.
+	i.Atomic = irOptAtomic(old.Atomic())
+	// Volatile.
+	i.Volatile = irOptVolatile(old.Volatile())
+	// Source address.
+	src, err := fgen.astToIRTypeValue(old.Src())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Src = src
+	// Synchronization scope.
+	i.SyncScope = irOptSyncScope(old.SyncScope())
+	// Atomic memory ordering constraints.
+	i.Ordering = irOptAtomicOrdering(old.AtomicOrdering())
+	switch i.Ordering {
+	case enum.AtomicOrderingRelease, enum.AtomicOrderingAcqRel:
+		return nil, errors.Errorf("invalid atomic ordering of load instruction; %v not allowed", i.Ordering)
+	}
+	// Alignment.
+	i.Align = irOptAlign(old.Alignment())
 	return i, nil
 }
 
@@ -41,7 +76,32 @@ func (fgen *funcGen) astToIRInstStore(inst ir.Instruction, old *ast.StoreInst) (
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstStore, got %T", inst))
 	}
-	// TODO: implement
+	// Atomic.
+	i.Atomic = irOptAtomic(old.Atomic())
+	// Volatile.
+	i.Volatile = irOptVolatile(old.Volatile())
+	// Source value.
+	src, err := fgen.astToIRTypeValue(old.Src())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Src = src
+	// Destination address.
+	dst, err := fgen.astToIRTypeValue(old.Dst())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Dst = dst
+	// Synchronization scope.
+	i.SyncScope = irOptSyncScope(old.SyncScope())
+	// Atomic memory ordering constraints.
+	i.Ordering = irOptAtomicOrdering(old.AtomicOrdering())
+	switch i.Ordering {
+	case enum.AtomicOrderingAcquire, enum.AtomicOrderingAcqRel:
+		return nil, errors.Errorf("invalid atomic ordering of store instruction; %v not allowed", i.Ordering)
+	}
+	// Alignment.
+	i.Align = irOptAlign(old.Alignment())
 	return i, nil
 }
 
@@ -53,7 +113,14 @@ func (fgen *funcGen) astToIRInstFence(inst ir.Instruction, old *ast.FenceInst) (
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFence, got %T", inst))
 	}
-	// TODO: implement
+	// Synchronization scope.
+	i.SyncScope = irOptSyncScope(old.SyncScope())
+	// Atomic memory ordering constraints.
+	i.Ordering = irAtomicOrdering(old.AtomicOrdering())
+	switch i.Ordering {
+	case enum.AtomicOrderingUnordered, enum.AtomicOrderingMonotonic:
+		return nil, errors.Errorf("invalid atomic ordering of fence instruction; %v not allowed", i.Ordering)
+	}
 	return i, nil
 }
 
@@ -65,7 +132,44 @@ func (fgen *funcGen) astToIRInstCmpXchg(inst ir.Instruction, old *ast.CmpXchgIns
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstCmpXchg, got %T", inst))
 	}
-	// TODO: implement
+	// Weak.
+	i.Weak = irOptWeak(old.Weak())
+	// Volatile.
+	i.Volatile = irOptVolatile(old.Volatile())
+	// Address to read from, compare against and store to.
+	ptr, err := fgen.astToIRTypeValue(old.Ptr())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Ptr = ptr
+	// Value to compare against.
+	cmp, err := fgen.astToIRTypeValue(old.Cmp())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Cmp = cmp
+	// New value to store.
+	newVal, err := fgen.astToIRTypeValue(old.New())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.New = newVal
+	// Synchronization scope.
+	i.SyncScope = irOptSyncScope(old.SyncScope())
+	// Atomic memory ordering constraints on success.
+	i.SuccessOrdering = irAtomicOrdering(old.Success())
+	// Atomic memory ordering constraints on failure.
+	i.FailureOrdering = irAtomicOrdering(old.Failure())
+	if i.SuccessOrdering == enum.AtomicOrderingUnordered || i.FailureOrdering == enum.AtomicOrderingUnordered {
+		return nil, errors.New("invalid atomic ordering of cmpxchg instruction; unordered not allowed")
+	}
+	switch i.FailureOrdering {
+	case enum.AtomicOrderingRelease, enum.AtomicOrderingAcqRel:
+		return nil, errors.Errorf("invalid failure ordering of cmpxchg instruction; %v not allowed", i.FailureOrdering)
+	}
+	if isStrongerOrdering(i.FailureOrdering, i.SuccessOrdering) {
+		return nil, errors.Errorf("invalid failure ordering of cmpxchg instruction; failure ordering %v stronger than success ordering %v", i.FailureOrdering, i.SuccessOrdering)
+	}
 	return i, nil
 }
 
@@ -77,7 +181,29 @@ func (fgen *funcGen) astToIRInstAtomicRMW(inst ir.Instruction, old *ast.AtomicRM
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstAtomicRMW, got %T", inst))
 	}
-	// TODO: implement
+	// Volatile.
+	i.Volatile = irOptVolatile(old.Volatile())
+	// Atomic operation.
+	i.Op = irAtomicOp(old.Op())
+	// Destination address.
+	dst, err := fgen.astToIRTypeValue(old.Dst())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Dst = dst
+	// Operand.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Synchronization scope.
+	i.SyncScope = irOptSyncScope(old.SyncScope())
+	// Atomic memory ordering constraints.
+	i.Ordering = irAtomicOrdering(old.AtomicOrdering())
+	if i.Ordering == enum.AtomicOrderingUnordered {
+		return nil, errors.New("invalid atomic ordering of atomicrmw instruction; unordered not allowed")
+	}
 	return i, nil
 }
 
@@ -89,6 +215,41 @@ func (fgen *funcGen) astToIRInstGetElementPtr(inst ir.Instruction, old *ast.GetE
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstGetElementPtr, got %T", inst))
 	}
-	// TODO: implement
+	// In-bounds.
+	i.InBounds = irOptInBounds(old.InBounds())
+	// Source.
+	src, err := fgen.astToIRTypeValue(old.Src())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Src = src
+	// Indices.
+	for _, idx := range old.Indices() {
+		index, err := fgen.astToIRTypeValue(idx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		i.Indices = append(i.Indices, index)
+	}
 	return i, nil
 }
+
+// ### [ Helpers ] #############################################################
+
+// isStrongerOrdering reports whether the atomic ordering a is strictly stronger
+// than the atomic ordering b. The acquire and release orderings are
+// incomparable.
+func isStrongerOrdering(a, b enum.AtomicOrdering) bool {
+	return orderingRank[a] > orderingRank[b]
+}
+
+// orderingRank maps from atomic ordering to its relative strength.
+var orderingRank = map[enum.AtomicOrdering]int{
+	enum.AtomicOrderingNone:      0,
+	enum.AtomicOrderingUnordered: 1,
+	enum.AtomicOrderingMonotonic: 2,
+	enum.AtomicOrderingAcquire:   3,
+	enum.AtomicOrderingRelease:   3,
+	enum.AtomicOrderingAcqRel:    4,
+	enum.AtomicOrderingSeqCst:    5,
+}
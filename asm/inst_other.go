@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/llir/l/ir"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 // --- [ Other instructions ] --------------------------------------------------
@@ -17,7 +19,20 @@ func (fgen *funcGen) astToIRInstICmp(inst ir.Instruction, old *ast.ICmpInst) (*i
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstICmp, got %T", inst))
 	}
-	// TODO: implement
+	// Integer comparison predicate.
+	i.Pred = asmenum.IPredFromString(old.Pred().Text())
+	// X operand.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Y operand.
+	y, err := fgen.astToIRValue(x.Type(), old.Y())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Y = y
 	return i, nil
 }
 
@@ -31,7 +46,20 @@ func (fgen *funcGen) astToIRInstFCmp(inst ir.Instruction, old *ast.FCmpInst) (*i
 	}
 	// Fast math flags.
 	i.FastMathFlags = irFastMathFlags(old.FastMathFlags())
-	// TODO: implement
+	// Floating-point comparison predicate.
+	i.Pred = asmenum.FPredFromString(old.Pred().Text())
+	// X operand.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Y operand.
+	y, err := fgen.astToIRValue(x.Type(), old.Y())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Y = y
 	return i, nil
 }
 
@@ -43,7 +71,23 @@ func (fgen *funcGen) astToIRInstPhi(inst ir.Instruction, old *ast.PhiInst) (*ir.
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstPhi, got %T", inst))
 	}
-	// TODO: implement
+	// Incoming values.
+	for _, oldInc := range old.Incs() {
+		x, err := fgen.astToIRValue(i.Typ, oldInc.X())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		name := local(oldInc.Pred())
+		v, ok := fgen.ls[name]
+		if !ok {
+			return nil, errors.Errorf("unable to locate local identifier %q", name)
+		}
+		pred, ok := v.(*ir.BasicBlock)
+		if !ok {
+			return nil, errors.Errorf("invalid basic block type of incoming predecessor %q; expected *ir.BasicBlock, got %T", name, v)
+		}
+		i.Incs = append(i.Incs, ir.NewIncoming(x, pred))
+	}
 	return i, nil
 }
 
@@ -55,7 +99,41 @@ func (fgen *funcGen) astToIRInstSelect(inst ir.Instruction, old *ast.SelectInst)
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstSelect, got %T", inst))
 	}
-	// TODO: implement
+	// Selection condition.
+	cond, err := fgen.astToIRTypeValue(old.Cond())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Cond = cond
+	// X operand.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
+	// Y operand.
+	y, err := fgen.astToIRTypeValue(old.Y())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Y = y
+	return i, nil
+}
+
+// ~~~ [ freeze ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+func (fgen *funcGen) astToIRInstFreeze(inst ir.Instruction, old *ast.FreezeInst) (*ir.InstFreeze, error) {
+	i, ok := inst.(*ir.InstFreeze)
+	if !ok {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstFreeze, got %T", inst))
+	}
+	// X operand.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.X = x
 	return i, nil
 }
 
@@ -67,10 +145,35 @@ func (fgen *funcGen) astToIRInstCall(inst ir.Instruction, old *ast.CallInst) (*i
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstCall, got %T", inst))
 	}
+	// Tail.
+	if n := old.Tail(); n != nil {
+		i.Tail = asmenum.TailFromString(n.Text())
+	}
 	// Fast math flags.
 	i.FastMathFlags = irFastMathFlags(old.FastMathFlags())
 	// Calling convention.
 	i.CallingConv = irOptCallingConv(old.CallingConv())
+	// TODO: handle return attributes and address space.
+	// Function arguments.
+	args, err := fgen.irArgs(old.Args())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Args = args
+	// Callee.
+	//
+	// NOTE: the type of the call instruction records the return type; the
+	// function type (if explicitly specified) is retrieved from the AST.
+	typ, err := fgen.gen.irCallType(old.Typ())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	callee, err := fgen.irCallee(typ, old.Callee(), args)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Callee = callee
+	// TODO: handle function attributes and operand bundles.
 	return i, nil
 }
 
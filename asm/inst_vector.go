@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
 )
 
 // --- [ Vector instructions ] -------------------------------------------------
@@ -17,7 +19,21 @@ func (fgen *funcGen) astToIRInstExtractElement(inst ir.Instruction, old *ast.Ext
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstExtractElement, got %T", inst))
 	}
-	// TODO: implement
+	// Vector.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, ok := x.Type().(*types.VectorType); !ok {
+		return nil, errors.Errorf("invalid vector type of extractelement; expected *types.VectorType, got %T", x.Type())
+	}
+	i.X = x
+	// Element index.
+	index, err := fgen.astToIRTypeValue(old.Index())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Index = index
 	return i, nil
 }
 
@@ -29,7 +45,31 @@ func (fgen *funcGen) astToIRInstInsertElement(inst ir.Instruction, old *ast.Inse
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstInsertElement, got %T", inst))
 	}
-	// TODO: implement
+	// Vector.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	xt, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid vector type of insertelement; expected *types.VectorType, got %T", x.Type())
+	}
+	i.X = x
+	// Element to insert.
+	elem, err := fgen.astToIRTypeValue(old.Elem())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !elem.Type().Equal(xt.ElemType) {
+		return nil, errors.Errorf("element type mismatch of insertelement; vector element type %v, got %v", xt.ElemType, elem.Type())
+	}
+	i.Elem = elem
+	// Element index.
+	index, err := fgen.astToIRTypeValue(old.Index())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	i.Index = index
 	return i, nil
 }
 
@@ -41,6 +81,47 @@ func (fgen *funcGen) astToIRInstShuffleVector(inst ir.Instruction, old *ast.Shuf
 		// NOTE: panic since this would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR instruction for AST instruction; expected *ir.InstShuffleVector, got %T", inst))
 	}
-	// TODO: implement
+	// X vector.
+	x, err := fgen.astToIRTypeValue(old.X())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	xt, ok := x.Type().(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid vector type of shufflevector; expected *types.VectorType, got %T", x.Type())
+	}
+	i.X = x
+	// Y vector.
+	y, err := fgen.astToIRTypeValue(old.Y())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !y.Type().Equal(xt) {
+		return nil, errors.Errorf("vector type mismatch of shufflevector; x of type %v, y of type %v", xt, y.Type())
+	}
+	i.Y = y
+	// Shuffle mask.
+	mask, err := fgen.astToIRTypeValue(old.Mask())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	mt, ok := mask.Type().(*types.VectorType)
+	if !ok || !mt.ElemType.Equal(types.I32) {
+		return nil, errors.Errorf("invalid mask type of shufflevector; expected vector of i32, got %v", mask.Type())
+	}
+	// The mask of scalable vectors has an unknown number of elements, and is
+	// thus restricted to zeroinitializer, undef and poison.
+	if mt.Scalable != xt.Scalable {
+		return nil, errors.Errorf("scalable mismatch of shufflevector; vector of type %v, mask of type %v", xt, mt)
+	}
+	if mt.Scalable {
+		switch mask.(type) {
+		case *ir.ConstZeroInitializer, *ir.ConstUndef, *ir.ConstPoison:
+			// valid scalable mask.
+		default:
+			return nil, errors.Errorf("invalid mask of scalable shufflevector; expected zeroinitializer, undef or poison, got %v", mask)
+		}
+	}
+	i.Mask = mask
 	return i, nil
 }
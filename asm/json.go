@@ -0,0 +1,694 @@
+package asm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/pkg/errors"
+)
+
+// === [ JSON schema ] =========================================================
+
+// JSONVersion is the version of the JSON schema of LLVM IR modules. The
+// version is incremented on incompatible changes to the schema.
+const JSONVersion = 1
+
+// JSONModule is the JSON representation of an LLVM IR module.
+//
+// Types are represented in LLVM IR assembly notation (e.g. "{ i32, %list* }"),
+// as are constants, prefixed by their type (e.g. "i32 42"). Global variables,
+// functions, function parameters, basic blocks and instructions are assigned
+// unique (positive) reference IDs, by which they are referred to as operands.
+//
+// Modules with data not represented by the schema (e.g. comdats, attributes
+// and metadata attachments) are rejected by the encoder, rather than silently
+// dropping the data.
+//
+//	{
+//		"version": 1,
+//		"typeDefs": [{"name": "list", "def": "{ i32, %list* }"}],
+//		"globals": [{"id": 1, "name": "x", "contentType": "i32", "init": "i32 42"}],
+//		"funcs": [{"id": 2, "name": "f", "sig": "i32 ()", "blocks": [
+//			{"id": 3, "insts": [
+//				{"id": 4, "op": "load", "type": "i32", "operands": [{"ref": 1}]}
+//			], "term": {"op": "ret", "operands": [{"ref": 4}]}}
+//		]}]
+//	}
+type JSONModule struct {
+	// Version of the JSON schema.
+	Version int `json:"version"`
+	// Source filename of the module.
+	SourceFilename string `json:"sourceFilename,omitempty"`
+	// Data layout of the module.
+	DataLayout string `json:"dataLayout,omitempty"`
+	// Target triple of the module.
+	TargetTriple string `json:"targetTriple,omitempty"`
+	// Type definitions of the module.
+	TypeDefs []*JSONTypeDef `json:"typeDefs,omitempty"`
+	// Global variable declarations and definitions of the module.
+	Globals []*JSONGlobal `json:"globals,omitempty"`
+	// Function declarations and definitions of the module.
+	Funcs []*JSONFunc `json:"funcs,omitempty"`
+}
+
+// JSONTypeDef is the JSON representation of a type definition.
+type JSONTypeDef struct {
+	// Type name (without '%' prefix).
+	Name string `json:"name"`
+	// Type definition (e.g. "{ i32, %list* }" or "opaque").
+	Def string `json:"def"`
+}
+
+// JSONGlobal is the JSON representation of a global variable.
+type JSONGlobal struct {
+	// Reference ID.
+	ID int `json:"id"`
+	// Global name (without '@' prefix).
+	Name string `json:"name"`
+	// Content type.
+	ContentType string `json:"contentType"`
+	// Initial value; or empty if declaration.
+	Init string `json:"init,omitempty"`
+	// Constant (true) or variable (false).
+	Immutable bool `json:"immutable,omitempty"`
+	// Externally initialized.
+	ExternallyInitialized bool `json:"externallyInitialized,omitempty"`
+	// Linkage type (e.g. "internal"); or empty if not present.
+	Linkage string `json:"linkage,omitempty"`
+	// Preemption specifier (e.g. "dso_local"); or empty if not present.
+	Preemption string `json:"preemption,omitempty"`
+	// Visibility style (e.g. "hidden"); or empty if not present.
+	Visibility string `json:"visibility,omitempty"`
+	// DLL storage class (e.g. "dllexport"); or empty if not present.
+	DLLStorageClass string `json:"dllStorageClass,omitempty"`
+	// Thread local storage model (e.g. "thread_local"); or empty if not
+	// present.
+	TLSModel string `json:"tlsModel,omitempty"`
+	// Unnamed address (e.g. "unnamed_addr"); or empty if not present.
+	UnnamedAddr string `json:"unnamedAddr,omitempty"`
+	// Address space; or 0 if default address space.
+	AddrSpace uint64 `json:"addrSpace,omitempty"`
+	// Section name; or empty if not present.
+	Section string `json:"section,omitempty"`
+	// Alignment in bytes; or 0 if not present.
+	Align uint64 `json:"align,omitempty"`
+}
+
+// JSONFunc is the JSON representation of a function.
+type JSONFunc struct {
+	// Reference ID.
+	ID int `json:"id"`
+	// Global name (without '@' prefix).
+	Name string `json:"name"`
+	// Function signature (e.g. "i32 (i8*, ...)").
+	Sig string `json:"sig"`
+	// Function parameters.
+	Params []*JSONParam `json:"params,omitempty"`
+	// Basic blocks; or empty if declaration.
+	Blocks []*JSONBlock `json:"blocks,omitempty"`
+	// Linkage type (e.g. "internal"); or empty if not present.
+	Linkage string `json:"linkage,omitempty"`
+	// Preemption specifier (e.g. "dso_local"); or empty if not present.
+	Preemption string `json:"preemption,omitempty"`
+	// Visibility style (e.g. "hidden"); or empty if not present.
+	Visibility string `json:"visibility,omitempty"`
+	// DLL storage class (e.g. "dllexport"); or empty if not present.
+	DLLStorageClass string `json:"dllStorageClass,omitempty"`
+	// Calling convention (e.g. "fastcc"); or empty if not present.
+	CallingConv string `json:"callingConv,omitempty"`
+	// Unnamed address (e.g. "unnamed_addr"); or empty if not present.
+	UnnamedAddr string `json:"unnamedAddr,omitempty"`
+	// Address space; or 0 if default address space.
+	AddrSpace uint64 `json:"addrSpace,omitempty"`
+	// Section name; or empty if not present.
+	Section string `json:"section,omitempty"`
+	// Alignment in bytes; or 0 if not present.
+	Align uint64 `json:"align,omitempty"`
+	// Garbage collector name; or empty if not present.
+	GC string `json:"gc,omitempty"`
+}
+
+// JSONParam is the JSON representation of a function parameter.
+type JSONParam struct {
+	// Reference ID.
+	ID int `json:"id"`
+	// Local name (without '%' prefix); or empty if unnamed.
+	Name string `json:"name,omitempty"`
+	// Parameter type.
+	Type string `json:"type"`
+}
+
+// JSONBlock is the JSON representation of a basic block.
+type JSONBlock struct {
+	// Reference ID.
+	ID int `json:"id"`
+	// Local name (without '%' prefix); or empty if unnamed.
+	Name string `json:"name,omitempty"`
+	// Non-terminator instructions.
+	Insts []*JSONInst `json:"insts,omitempty"`
+	// Terminator.
+	Term *JSONInst `json:"term"`
+}
+
+// JSONInst is the JSON representation of an instruction or terminator.
+//
+// The operands of each instruction are listed in order of LLVM IR assembly
+// notation. Basic blocks are operands of terminators and phi instructions
+// (e.g. "br" has the operands [cond, targetTrue, targetFalse], "switch" has
+// the operands [x, targetDefault, (caseX, caseTarget)...] and "phi" has the
+// operands [(x, pred)...]).
+type JSONInst struct {
+	// Reference ID; or 0 if the instruction does not produce a value.
+	ID int `json:"id,omitempty"`
+	// Local name (without '%' prefix); or empty if unnamed.
+	Name string `json:"name,omitempty"`
+	// Opcode (e.g. "add" or "getelementptr").
+	Op string `json:"op"`
+	// Result type; or empty if the instruction does not produce a value.
+	Type string `json:"type,omitempty"`
+	// Operands.
+	Operands []*JSONOperand `json:"operands,omitempty"`
+	// Flags; overflow flags (e.g. "nsw"), fast math flags (e.g. "nnan"),
+	// "exact", "inbounds", "inalloca", "swifterror", "atomic" and "volatile".
+	Flags []string `json:"flags,omitempty"`
+	// Comparison predicate (e.g. "slt") of icmp and fcmp instructions.
+	Pred string `json:"pred,omitempty"`
+	// Element type of alloca and getelementptr instructions.
+	ElemType string `json:"elemType,omitempty"`
+	// Aggregate indices of extractvalue and insertvalue instructions.
+	Indices []uint64 `json:"indices,omitempty"`
+	// Tail call marker (e.g. "tail") of call instructions.
+	Tail string `json:"tail,omitempty"`
+	// Calling convention (e.g. "fastcc") of call instructions.
+	CallingConv string `json:"callingConv,omitempty"`
+	// Alignment in bytes of alloca, load and store instructions; or 0 if not
+	// present.
+	Align uint64 `json:"align,omitempty"`
+	// Atomic memory ordering (e.g. "seq_cst") of atomic load and store
+	// instructions.
+	Ordering string `json:"ordering,omitempty"`
+	// Synchronization scope (e.g. "singlethread") of atomic load and store
+	// instructions; or empty if system scope.
+	SyncScope string `json:"syncScope,omitempty"`
+}
+
+// JSONOperand is the JSON representation of an operand; either a reference to
+// a global variable, function, function parameter, basic block or instruction,
+// or a constant.
+type JSONOperand struct {
+	// Reference ID; or 0 if constant.
+	Ref int `json:"ref,omitempty"`
+	// Constant, prefixed by its type (e.g. "i32 42"); or empty if reference.
+	Const string `json:"const,omitempty"`
+}
+
+// === [ JSON encoder ] ========================================================
+
+// WriteJSON writes the JSON representation of the given LLVM IR module to w.
+func WriteJSON(w io.Writer, m *ir.Module) error {
+	jm, err := NewJSONModule(m)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(jm); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// NewJSONModule returns the JSON representation of the given LLVM IR module.
+func NewJSONModule(m *ir.Module) (*JSONModule, error) {
+	enc := &jsonEncoder{ids: make(map[value.Value]int)}
+	return enc.encode(m)
+}
+
+// jsonEncoder keeps track of the reference IDs of values when encoding LLVM IR
+// modules to JSON.
+type jsonEncoder struct {
+	// Reference IDs; maps from value to reference ID.
+	ids map[value.Value]int
+	// Last assigned reference ID.
+	last int
+}
+
+// encode returns the JSON representation of the given LLVM IR module.
+func (enc *jsonEncoder) encode(m *ir.Module) (*JSONModule, error) {
+	jm := &JSONModule{
+		Version:        JSONVersion,
+		SourceFilename: m.SourceFilename,
+		DataLayout:     m.DataLayout,
+		TargetTriple:   m.TargetTriple,
+	}
+	for _, t := range m.TypeDefs {
+		jm.TypeDefs = append(jm.TypeDefs, &JSONTypeDef{Name: typeAlias(t), Def: typeDefString(t)})
+	}
+	// Reference IDs of global variables and functions are assigned before
+	// encoding, as initializers and function bodies may refer to global
+	// variables and functions defined later on.
+	for _, g := range m.Globals {
+		enc.add(g)
+	}
+	for _, f := range m.Funcs {
+		enc.add(f)
+	}
+	for _, g := range m.Globals {
+		if err := jsonCheckGlobal(g); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		jg := &JSONGlobal{
+			ID:                    enc.ids[g],
+			Name:                  g.GlobalName,
+			ContentType:           g.ContentType.String(),
+			Immutable:             g.Immutable,
+			ExternallyInitialized: g.ExternallyInitialized,
+			Linkage:               jsonEnum(g.Linkage),
+			Preemption:            jsonEnum(g.Preemption),
+			Visibility:            jsonEnum(g.Visibility),
+			DLLStorageClass:       jsonEnum(g.DLLStorageClass),
+			TLSModel:              jsonEnum(g.TLSModel),
+			UnnamedAddr:           jsonEnum(g.UnnamedAddr),
+			AddrSpace:             uint64(g.Typ.AddrSpace),
+			Section:               g.Section,
+			Align:                 uint64(g.Align),
+		}
+		if g.Init != nil {
+			jg.Init = g.Init.String()
+		}
+		jm.Globals = append(jm.Globals, jg)
+	}
+	for _, f := range m.Funcs {
+		jf, err := enc.encodeFunc(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to encode function %q", f.Ident())
+		}
+		jm.Funcs = append(jm.Funcs, jf)
+	}
+	return jm, nil
+}
+
+// encodeFunc returns the JSON representation of the given function.
+func (enc *jsonEncoder) encodeFunc(f *ir.Function) (*JSONFunc, error) {
+	if err := jsonCheckFunc(f); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	jf := &JSONFunc{
+		ID:              enc.ids[f],
+		Name:            f.GlobalName,
+		Sig:             f.Sig.String(),
+		Linkage:         jsonEnum(f.Linkage),
+		Preemption:      jsonEnum(f.Preemption),
+		Visibility:      jsonEnum(f.Visibility),
+		DLLStorageClass: jsonEnum(f.DLLStorageClass),
+		CallingConv:     jsonEnum(f.CallingConv),
+		UnnamedAddr:     jsonEnum(f.UnnamedAddr),
+		AddrSpace:       uint64(f.Typ.AddrSpace),
+		Section:         f.Section,
+		Align:           uint64(f.Align),
+		GC:              f.GC,
+	}
+	for _, param := range f.Params {
+		if len(param.Attrs) > 0 {
+			return nil, errors.Errorf("support for attributes of function parameter %q not yet implemented", param.Ident())
+		}
+		jf.Params = append(jf.Params, &JSONParam{ID: enc.add(param), Name: jsonLocalName(param.LocalName), Type: param.Typ.String()})
+	}
+	// Reference IDs of basic blocks and instructions are assigned before
+	// encoding, as instructions may refer to instructions and basic blocks
+	// defined later on.
+	for _, block := range f.Blocks {
+		enc.add(block)
+		for _, inst := range block.Insts {
+			if v, ok := isValueInst(inst); ok {
+				enc.add(v)
+			}
+		}
+	}
+	for _, block := range f.Blocks {
+		jb := &JSONBlock{ID: enc.ids[block], Name: jsonLocalName(block.LocalName)}
+		for _, inst := range block.Insts {
+			ji, err := enc.encodeInst(inst)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			jb.Insts = append(jb.Insts, ji)
+		}
+		if block.Term == nil {
+			return nil, errors.Errorf("invalid basic block %q; missing terminator", block.Ident())
+		}
+		jt, err := enc.encodeTerm(block.Term)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		jb.Term = jt
+		jf.Blocks = append(jf.Blocks, jb)
+	}
+	return jf, nil
+}
+
+// encodeInst returns the JSON representation of the given instruction.
+func (enc *jsonEncoder) encodeInst(inst ir.Instruction) (*JSONInst, error) {
+	ji := &JSONInst{}
+	if v, ok := isValueInst(inst); ok {
+		ji.ID = enc.ids[v]
+		ji.Type = v.Type().String()
+		if n, ok := v.(value.Named); ok {
+			ji.Name = jsonLocalName(n.Name())
+		}
+	}
+	var ops []value.Value
+	switch inst := inst.(type) {
+	// Binary instructions.
+	case *ir.InstAdd:
+		ji.Op, ops, ji.Flags = "add", []value.Value{inst.X, inst.Y}, jsonOverflowFlags(inst.OverflowFlags)
+	case *ir.InstFAdd:
+		ji.Op, ops, ji.Flags = "fadd", []value.Value{inst.X, inst.Y}, jsonFastMathFlags(inst.FastMathFlags)
+	case *ir.InstSub:
+		ji.Op, ops, ji.Flags = "sub", []value.Value{inst.X, inst.Y}, jsonOverflowFlags(inst.OverflowFlags)
+	case *ir.InstFSub:
+		ji.Op, ops, ji.Flags = "fsub", []value.Value{inst.X, inst.Y}, jsonFastMathFlags(inst.FastMathFlags)
+	case *ir.InstMul:
+		ji.Op, ops, ji.Flags = "mul", []value.Value{inst.X, inst.Y}, jsonOverflowFlags(inst.OverflowFlags)
+	case *ir.InstFMul:
+		ji.Op, ops, ji.Flags = "fmul", []value.Value{inst.X, inst.Y}, jsonFastMathFlags(inst.FastMathFlags)
+	case *ir.InstUDiv:
+		ji.Op, ops, ji.Flags = "udiv", []value.Value{inst.X, inst.Y}, jsonExact(inst.Exact)
+	case *ir.InstSDiv:
+		ji.Op, ops, ji.Flags = "sdiv", []value.Value{inst.X, inst.Y}, jsonExact(inst.Exact)
+	case *ir.InstFDiv:
+		ji.Op, ops, ji.Flags = "fdiv", []value.Value{inst.X, inst.Y}, jsonFastMathFlags(inst.FastMathFlags)
+	case *ir.InstURem:
+		ji.Op, ops = "urem", []value.Value{inst.X, inst.Y}
+	case *ir.InstSRem:
+		ji.Op, ops = "srem", []value.Value{inst.X, inst.Y}
+	case *ir.InstFRem:
+		ji.Op, ops, ji.Flags = "frem", []value.Value{inst.X, inst.Y}, jsonFastMathFlags(inst.FastMathFlags)
+	// Bitwise instructions.
+	case *ir.InstShl:
+		ji.Op, ops, ji.Flags = "shl", []value.Value{inst.X, inst.Y}, jsonOverflowFlags(inst.OverflowFlags)
+	case *ir.InstLShr:
+		ji.Op, ops, ji.Flags = "lshr", []value.Value{inst.X, inst.Y}, jsonExact(inst.Exact)
+	case *ir.InstAShr:
+		ji.Op, ops, ji.Flags = "ashr", []value.Value{inst.X, inst.Y}, jsonExact(inst.Exact)
+	case *ir.InstAnd:
+		ji.Op, ops = "and", []value.Value{inst.X, inst.Y}
+	case *ir.InstOr:
+		ji.Op, ops = "or", []value.Value{inst.X, inst.Y}
+	case *ir.InstXor:
+		ji.Op, ops = "xor", []value.Value{inst.X, inst.Y}
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		ji.Op, ops = "extractelement", []value.Value{inst.X, inst.Index}
+	case *ir.InstInsertElement:
+		ji.Op, ops = "insertelement", []value.Value{inst.X, inst.Elem, inst.Index}
+	case *ir.InstShuffleVector:
+		ji.Op, ops = "shufflevector", []value.Value{inst.X, inst.Y, inst.Mask}
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		ji.Op, ops, ji.Indices = "extractvalue", []value.Value{inst.X}, inst.Indices
+	case *ir.InstInsertValue:
+		ji.Op, ops, ji.Indices = "insertvalue", []value.Value{inst.X, inst.Elem}, inst.Indices
+	// Memory instructions.
+	case *ir.InstAlloca:
+		ji.Op, ji.ElemType, ji.Align = "alloca", inst.ElemType.String(), uint64(inst.Align)
+		if inst.NElems != nil {
+			ops = []value.Value{inst.NElems}
+		}
+		if inst.InAlloca {
+			ji.Flags = append(ji.Flags, "inalloca")
+		}
+		if inst.SwiftError {
+			ji.Flags = append(ji.Flags, "swifterror")
+		}
+	case *ir.InstLoad:
+		ji.Op, ops, ji.Align = "load", []value.Value{inst.Src}, uint64(inst.Align)
+		ji.Flags = jsonAtomic(inst.Atomic, inst.Volatile)
+		ji.Ordering, ji.SyncScope = jsonEnum(inst.Ordering), inst.SyncScope
+	case *ir.InstStore:
+		ji.Op, ops, ji.Align = "store", []value.Value{inst.Src, inst.Dst}, uint64(inst.Align)
+		ji.Flags = jsonAtomic(inst.Atomic, inst.Volatile)
+		ji.Ordering, ji.SyncScope = jsonEnum(inst.Ordering), inst.SyncScope
+	case *ir.InstGetElementPtr:
+		ji.Op, ji.ElemType = "getelementptr", inst.ElemType.String()
+		ops = append([]value.Value{inst.Src}, inst.Indices...)
+		if inst.InBounds {
+			ji.Flags = []string{"inbounds"}
+		}
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		ji.Op, ops = "trunc", []value.Value{inst.From}
+	case *ir.InstZExt:
+		ji.Op, ops = "zext", []value.Value{inst.From}
+	case *ir.InstSExt:
+		ji.Op, ops = "sext", []value.Value{inst.From}
+	case *ir.InstFPTrunc:
+		ji.Op, ops = "fptrunc", []value.Value{inst.From}
+	case *ir.InstFPExt:
+		ji.Op, ops = "fpext", []value.Value{inst.From}
+	case *ir.InstFPToUI:
+		ji.Op, ops = "fptoui", []value.Value{inst.From}
+	case *ir.InstFPToSI:
+		ji.Op, ops = "fptosi", []value.Value{inst.From}
+	case *ir.InstUIToFP:
+		ji.Op, ops = "uitofp", []value.Value{inst.From}
+	case *ir.InstSIToFP:
+		ji.Op, ops = "sitofp", []value.Value{inst.From}
+	case *ir.InstPtrToInt:
+		ji.Op, ops = "ptrtoint", []value.Value{inst.From}
+	case *ir.InstIntToPtr:
+		ji.Op, ops = "inttoptr", []value.Value{inst.From}
+	case *ir.InstBitCast:
+		ji.Op, ops = "bitcast", []value.Value{inst.From}
+	case *ir.InstAddrSpaceCast:
+		ji.Op, ops = "addrspacecast", []value.Value{inst.From}
+	// Other instructions.
+	case *ir.InstICmp:
+		ji.Op, ops, ji.Pred = "icmp", []value.Value{inst.X, inst.Y}, inst.Pred.String()
+	case *ir.InstFCmp:
+		ji.Op, ops, ji.Pred = "fcmp", []value.Value{inst.X, inst.Y}, inst.Pred.String()
+		ji.Flags = jsonFastMathFlags(inst.FastMathFlags)
+	case *ir.InstPhi:
+		ji.Op = "phi"
+		for _, inc := range inst.Incs {
+			ops = append(ops, inc.X, inc.Pred)
+		}
+	case *ir.InstSelect:
+		ji.Op, ops = "select", []value.Value{inst.Cond, inst.X, inst.Y}
+	case *ir.InstCall:
+		if len(inst.ReturnAttrs) > 0 || len(inst.FuncAttrs) > 0 {
+			return nil, errors.Errorf("support for attributes of call instruction %q not yet implemented", inst.Ident())
+		}
+		for _, arg := range inst.Args {
+			if _, ok := arg.(*ir.Arg); ok {
+				return nil, errors.Errorf("support for attributes of call argument %q not yet implemented", arg.Ident())
+			}
+		}
+		ji.Op, ops = "call", append([]value.Value{inst.Callee}, inst.Args...)
+		ji.Flags = jsonFastMathFlags(inst.FastMathFlags)
+		ji.Tail = jsonEnum(inst.Tail)
+		ji.CallingConv = jsonEnum(inst.CallingConv)
+	default:
+		// TODO: add support for remaining instructions (e.g. memory ordering
+		// and exception handling instructions).
+		return nil, errors.Errorf("support for instruction %T not yet implemented", inst)
+	}
+	operands, err := enc.operands(ops)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ji.Operands = operands
+	return ji, nil
+}
+
+// encodeTerm returns the JSON representation of the given terminator.
+func (enc *jsonEncoder) encodeTerm(term ir.Terminator) (*JSONInst, error) {
+	jt := &JSONInst{}
+	var ops []value.Value
+	switch term := term.(type) {
+	case *ir.TermRet:
+		jt.Op = "ret"
+		if term.X != nil {
+			ops = []value.Value{term.X}
+		}
+	case *ir.TermBr:
+		jt.Op, ops = "br", []value.Value{term.Target}
+	case *ir.TermCondBr:
+		jt.Op, ops = "br", []value.Value{term.Cond, term.TargetTrue, term.TargetFalse}
+	case *ir.TermSwitch:
+		jt.Op, ops = "switch", []value.Value{term.X, term.TargetDefault}
+		for _, c := range term.Cases {
+			ops = append(ops, c.X, c.Target)
+		}
+	case *ir.TermUnreachable:
+		jt.Op = "unreachable"
+	default:
+		// TODO: add support for remaining terminators (e.g. invoke and exception
+		// handling terminators).
+		return nil, errors.Errorf("support for terminator %T not yet implemented", term)
+	}
+	operands, err := enc.operands(ops)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	jt.Operands = operands
+	return jt, nil
+}
+
+// add assigns a reference ID to the given value.
+func (enc *jsonEncoder) add(v value.Value) int {
+	enc.last++
+	enc.ids[v] = enc.last
+	return enc.last
+}
+
+// operands returns the JSON representation of the given operands.
+func (enc *jsonEncoder) operands(ops []value.Value) ([]*JSONOperand, error) {
+	var operands []*JSONOperand
+	for _, op := range ops {
+		if id, ok := enc.ids[op]; ok {
+			operands = append(operands, &JSONOperand{Ref: id})
+			continue
+		}
+		c, ok := op.(ir.Constant)
+		if !ok {
+			return nil, errors.Errorf("unable to locate reference ID of operand %q", op.Ident())
+		}
+		operands = append(operands, &JSONOperand{Const: c.String()})
+	}
+	return operands, nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// jsonCheckGlobal reports an error if the given global variable has data not
+// represented by the JSON schema.
+func jsonCheckGlobal(g *ir.Global) error {
+	switch {
+	case g.Comdat != nil:
+		return errors.Errorf("support for comdat of global variable %q not yet implemented", g.Ident())
+	case len(g.FuncAttrs) > 0:
+		return errors.Errorf("support for attributes of global variable %q not yet implemented", g.Ident())
+	case len(g.Metadata) > 0:
+		return errors.Errorf("support for metadata attachments of global variable %q not yet implemented", g.Ident())
+	}
+	return nil
+}
+
+// jsonCheckFunc reports an error if the given function has data not
+// represented by the JSON schema.
+func jsonCheckFunc(f *ir.Function) error {
+	switch {
+	case f.Comdat != nil:
+		return errors.Errorf("support for comdat of function %q not yet implemented", f.Ident())
+	case len(f.ReturnAttrs) > 0 || len(f.FuncAttrs) > 0:
+		return errors.Errorf("support for attributes of function %q not yet implemented", f.Ident())
+	case f.Prefix != nil || f.Prologue != nil || f.Personality != nil:
+		return errors.Errorf("support for prefix, prologue and personality of function %q not yet implemented", f.Ident())
+	case len(f.Metadata) > 0:
+		return errors.Errorf("support for metadata attachments of function %q not yet implemented", f.Ident())
+	}
+	return nil
+}
+
+// typeDefString returns the type definition of the given named type in LLVM IR
+// assembly notation.
+func typeDefString(t types.Type) string {
+	if t, ok := t.(*types.StructType); ok {
+		// The fields of structure types may refer to the structure type itself;
+		// thus, the definition is given in terms of field types.
+		if t.Opaque {
+			return "opaque"
+		}
+		buf := &strings.Builder{}
+		if t.Packed {
+			buf.WriteString("<")
+		}
+		buf.WriteString("{")
+		for i, field := range t.Fields {
+			if i != 0 {
+				buf.WriteString(",")
+			}
+			fmt.Fprintf(buf, " %v", field)
+		}
+		if len(t.Fields) > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString("}")
+		if t.Packed {
+			buf.WriteString(">")
+		}
+		return buf.String()
+	}
+	alias := typeAlias(t)
+	setTypeAlias(t, "")
+	def := t.String()
+	setTypeAlias(t, alias)
+	return def
+}
+
+// jsonEnum returns the string representation of the given enum; or an empty
+// string if not present.
+func jsonEnum(v fmt.Stringer) string {
+	if s := v.String(); s != "none" {
+		return s
+	}
+	return ""
+}
+
+// jsonLocalName returns the local name of a value; or an empty string if the
+// value is unnamed (i.e. identified by local ID).
+func jsonLocalName(name string) string {
+	if isUnnamedLocal(name) {
+		return ""
+	}
+	return name
+}
+
+// jsonOverflowFlags returns the string representation of the given overflow
+// flags.
+func jsonOverflowFlags(flags []enum.OverflowFlag) []string {
+	var ss []string
+	for _, flag := range flags {
+		ss = append(ss, flag.String())
+	}
+	return ss
+}
+
+// jsonFastMathFlags returns the string representation of the given fast math
+// flags.
+func jsonFastMathFlags(flags []enum.FastMathFlag) []string {
+	var ss []string
+	for _, flag := range flags {
+		ss = append(ss, flag.String())
+	}
+	return ss
+}
+
+// jsonAtomic returns the string representation of the given atomic and
+// volatile flags.
+func jsonAtomic(atomic, volatile bool) []string {
+	var ss []string
+	if atomic {
+		ss = append(ss, "atomic")
+	}
+	if volatile {
+		ss = append(ss, "volatile")
+	}
+	return ss
+}
+
+// jsonExact returns the string representation of the given exact flag.
+func jsonExact(exact bool) []string {
+	if exact {
+		return []string{"exact"}
+	}
+	return nil
+}
@@ -0,0 +1,762 @@
+package asm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	asmenum "github.com/mewmew/l-tm/asm/enum"
+	"github.com/pkg/errors"
+)
+
+// === [ JSON decoder ] ========================================================
+
+// ParseJSON parses the given JSON representation of an LLVM IR module (as
+// written by WriteJSON) into an LLVM IR module.
+func ParseJSON(buf []byte) (*ir.Module, error) {
+	jm := &JSONModule{}
+	if err := json.Unmarshal(buf, jm); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return jm.IRModule()
+}
+
+// IRModule returns the LLVM IR module of the given JSON representation.
+func (jm *JSONModule) IRModule() (*ir.Module, error) {
+	if jm.Version != JSONVersion {
+		return nil, errors.Errorf("support for JSON schema version %d not yet implemented; expected version %d", jm.Version, JSONVersion)
+	}
+	dec := &jsonDecoder{
+		m:     &ir.Module{},
+		refs:  make(map[int]value.Value),
+		types: make(map[string]types.Type),
+	}
+	if err := dec.decode(jm); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dec.m, nil
+}
+
+// jsonDecoder keeps track of the values of reference IDs when decoding LLVM IR
+// modules from JSON.
+type jsonDecoder struct {
+	// LLVM IR module being decoded.
+	m *ir.Module
+	// Values of reference IDs; maps from reference ID to value.
+	refs map[int]value.Value
+	// Parsed types; maps from type (in LLVM IR assembly notation) to type.
+	types map[string]types.Type
+}
+
+// decode decodes the given JSON representation into the LLVM IR module of the
+// decoder.
+func (dec *jsonDecoder) decode(jm *JSONModule) error {
+	dec.m.SourceFilename = jm.SourceFilename
+	dec.m.DataLayout = jm.DataLayout
+	dec.m.TargetTriple = jm.TargetTriple
+	// Type definitions; named structure types are created before their bodies
+	// are parsed, as type definitions may refer to each other.
+	structs := make(map[*JSONTypeDef]*types.StructType)
+	for _, jt := range jm.TypeDefs {
+		if !isStructDef(jt.Def) {
+			// TODO: add support for type definitions of non-structure types.
+			return errors.Errorf("support for type definition %q of non-structure type not yet implemented", jt.Name)
+		}
+		t := &types.StructType{Alias: jt.Name}
+		structs[jt] = t
+		dec.m.TypeDefs = append(dec.m.TypeDefs, t)
+	}
+	for _, jt := range jm.TypeDefs {
+		t := structs[jt]
+		if jt.Def == "opaque" {
+			t.Opaque = true
+			continue
+		}
+		body, err := dec.typ(jt.Def)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse type definition %q", jt.Name)
+		}
+		st, ok := body.(*types.StructType)
+		if !ok {
+			return errors.Errorf("invalid type definition %q; expected *types.StructType, got %T", jt.Name, body)
+		}
+		t.Packed = st.Packed
+		t.Fields = st.Fields
+	}
+	// Global variables and functions are created before initializers and
+	// function bodies are decoded, as these may refer to global variables and
+	// functions defined later on.
+	for _, jg := range jm.Globals {
+		g, err := dec.newGlobal(jg)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decode global variable %q", jg.Name)
+		}
+		dec.m.Globals = append(dec.m.Globals, g)
+	}
+	for _, jf := range jm.Funcs {
+		f, err := dec.newFunc(jf)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decode function %q", jf.Name)
+		}
+		dec.m.Funcs = append(dec.m.Funcs, f)
+	}
+	for i, jg := range jm.Globals {
+		if len(jg.Init) == 0 {
+			continue
+		}
+		init, err := ParseConstant(jg.Init, dec.m)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse initializer of global variable %q", jg.Name)
+		}
+		dec.m.Globals[i].Init = init
+	}
+	for i, jf := range jm.Funcs {
+		if err := dec.decodeBody(dec.m.Funcs[i], jf); err != nil {
+			return errors.Wrapf(err, "unable to decode body of function %q", jf.Name)
+		}
+	}
+	return nil
+}
+
+// newGlobal returns a new global variable (without initializer) based on the
+// given JSON representation.
+func (dec *jsonDecoder) newGlobal(jg *JSONGlobal) (*ir.Global, error) {
+	contentType, err := dec.typ(jg.ContentType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	g := &ir.Global{
+		GlobalName:            jg.Name,
+		ContentType:           contentType,
+		Typ:                   types.NewPointer(contentType),
+		Immutable:             jg.Immutable,
+		ExternallyInitialized: jg.ExternallyInitialized,
+		Linkage:               asmenum.LinkageFromString(jg.Linkage),
+		Preemption:            asmenum.PreemptionFromString(jg.Preemption),
+		Visibility:            asmenum.VisibilityFromString(jg.Visibility),
+		DLLStorageClass:       asmenum.DLLStorageClassFromString(jg.DLLStorageClass),
+		TLSModel:              asmenum.TLSModelFromString(jg.TLSModel),
+		UnnamedAddr:           asmenum.UnnamedAddrFromString(jg.UnnamedAddr),
+		Section:               jg.Section,
+		Align:                 ir.Align(jg.Align),
+	}
+	g.Typ.AddrSpace = types.AddrSpace(jg.AddrSpace)
+	if err := dec.addRef(jg.ID, g); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return g, nil
+}
+
+// newFunc returns a new function (without body) based on the given JSON
+// representation.
+func (dec *jsonDecoder) newFunc(jf *JSONFunc) (*ir.Function, error) {
+	t, err := dec.typ(jf.Sig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sig, ok := t.(*types.FuncType)
+	if !ok {
+		return nil, errors.Errorf("invalid function signature; expected *types.FuncType, got %T", t)
+	}
+	if len(jf.Params) != len(sig.Params) {
+		return nil, errors.Errorf("function parameter count mismatch; expected %d, got %d", len(sig.Params), len(jf.Params))
+	}
+	f := &ir.Function{
+		GlobalName:      jf.Name,
+		Sig:             sig,
+		Typ:             types.NewPointer(sig),
+		Linkage:         asmenum.LinkageFromString(jf.Linkage),
+		Preemption:      asmenum.PreemptionFromString(jf.Preemption),
+		Visibility:      asmenum.VisibilityFromString(jf.Visibility),
+		DLLStorageClass: asmenum.DLLStorageClassFromString(jf.DLLStorageClass),
+		CallingConv:     asmenum.CallingConvFromString(jf.CallingConv),
+		UnnamedAddr:     asmenum.UnnamedAddrFromString(jf.UnnamedAddr),
+		Section:         jf.Section,
+		Align:           ir.Align(jf.Align),
+		GC:              jf.GC,
+	}
+	f.Typ.AddrSpace = types.AddrSpace(jf.AddrSpace)
+	for i, jp := range jf.Params {
+		param := ir.NewParam(sig.Params[i], jp.Name)
+		if err := dec.addRef(jp.ID, param); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f.Params = append(f.Params, param)
+	}
+	if err := dec.addRef(jf.ID, f); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return f, nil
+}
+
+// decodeBody decodes the body of the given function.
+//
+// Instructions are decoded in two passes. The first pass creates basic blocks
+// and instructions (without operands), thus assigning values to reference
+// IDs. The second pass fills in the operands of instructions, which may refer
+// to instructions defined later on (e.g. incoming values of phi instructions).
+func (dec *jsonDecoder) decodeBody(f *ir.Function, jf *JSONFunc) error {
+	if len(jf.Blocks) == 0 {
+		return nil
+	}
+	var insts [][]ir.Instruction
+	for _, jb := range jf.Blocks {
+		block := ir.NewBlock(jb.Name)
+		if err := dec.addRef(jb.ID, block); err != nil {
+			return errors.WithStack(err)
+		}
+		var blockInsts []ir.Instruction
+		for _, ji := range jb.Insts {
+			inst, err := newJSONInst(ji.Op)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if ji.ID != 0 {
+				v, ok := inst.(value.Named)
+				if !ok {
+					return errors.Errorf("invalid instruction %q with reference ID; expected value.Named, got %T", ji.Op, inst)
+				}
+				v.SetName(ji.Name)
+				if err := dec.addRef(ji.ID, v); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			blockInsts = append(blockInsts, inst)
+		}
+		insts = append(insts, blockInsts)
+		f.Blocks = append(f.Blocks, block)
+	}
+	for i, jb := range jf.Blocks {
+		block := f.Blocks[i]
+		for j, ji := range jb.Insts {
+			inst := insts[i][j]
+			if err := dec.fillInst(inst, ji); err != nil {
+				return errors.Wrapf(err, "unable to decode %q instruction", ji.Op)
+			}
+			block.Insts = append(block.Insts, inst)
+		}
+		if jb.Term == nil {
+			return errors.Errorf("invalid basic block %q; missing terminator", block.Ident())
+		}
+		term, err := dec.term(jb.Term)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decode %q terminator", jb.Term.Op)
+		}
+		block.Term = term
+	}
+	// Assign local IDs of unnamed values.
+	return f.AssignIDs()
+}
+
+// newJSONInst returns a new instruction (without operands) of the given opcode.
+func newJSONInst(op string) (ir.Instruction, error) {
+	switch op {
+	// Binary instructions.
+	case "add":
+		return &ir.InstAdd{}, nil
+	case "fadd":
+		return &ir.InstFAdd{}, nil
+	case "sub":
+		return &ir.InstSub{}, nil
+	case "fsub":
+		return &ir.InstFSub{}, nil
+	case "mul":
+		return &ir.InstMul{}, nil
+	case "fmul":
+		return &ir.InstFMul{}, nil
+	case "udiv":
+		return &ir.InstUDiv{}, nil
+	case "sdiv":
+		return &ir.InstSDiv{}, nil
+	case "fdiv":
+		return &ir.InstFDiv{}, nil
+	case "urem":
+		return &ir.InstURem{}, nil
+	case "srem":
+		return &ir.InstSRem{}, nil
+	case "frem":
+		return &ir.InstFRem{}, nil
+	// Bitwise instructions.
+	case "shl":
+		return &ir.InstShl{}, nil
+	case "lshr":
+		return &ir.InstLShr{}, nil
+	case "ashr":
+		return &ir.InstAShr{}, nil
+	case "and":
+		return &ir.InstAnd{}, nil
+	case "or":
+		return &ir.InstOr{}, nil
+	case "xor":
+		return &ir.InstXor{}, nil
+	// Vector instructions.
+	case "extractelement":
+		return &ir.InstExtractElement{}, nil
+	case "insertelement":
+		return &ir.InstInsertElement{}, nil
+	case "shufflevector":
+		return &ir.InstShuffleVector{}, nil
+	// Aggregate instructions.
+	case "extractvalue":
+		return &ir.InstExtractValue{}, nil
+	case "insertvalue":
+		return &ir.InstInsertValue{}, nil
+	// Memory instructions.
+	case "alloca":
+		return &ir.InstAlloca{}, nil
+	case "load":
+		return &ir.InstLoad{}, nil
+	case "store":
+		return &ir.InstStore{}, nil
+	case "getelementptr":
+		return &ir.InstGetElementPtr{}, nil
+	// Conversion instructions.
+	case "trunc":
+		return &ir.InstTrunc{}, nil
+	case "zext":
+		return &ir.InstZExt{}, nil
+	case "sext":
+		return &ir.InstSExt{}, nil
+	case "fptrunc":
+		return &ir.InstFPTrunc{}, nil
+	case "fpext":
+		return &ir.InstFPExt{}, nil
+	case "fptoui":
+		return &ir.InstFPToUI{}, nil
+	case "fptosi":
+		return &ir.InstFPToSI{}, nil
+	case "uitofp":
+		return &ir.InstUIToFP{}, nil
+	case "sitofp":
+		return &ir.InstSIToFP{}, nil
+	case "ptrtoint":
+		return &ir.InstPtrToInt{}, nil
+	case "inttoptr":
+		return &ir.InstIntToPtr{}, nil
+	case "bitcast":
+		return &ir.InstBitCast{}, nil
+	case "addrspacecast":
+		return &ir.InstAddrSpaceCast{}, nil
+	// Other instructions.
+	case "icmp":
+		return &ir.InstICmp{}, nil
+	case "fcmp":
+		return &ir.InstFCmp{}, nil
+	case "phi":
+		return &ir.InstPhi{}, nil
+	case "select":
+		return &ir.InstSelect{}, nil
+	case "call":
+		return &ir.InstCall{}, nil
+	default:
+		// TODO: add support for remaining instructions (e.g. memory ordering
+		// and exception handling instructions).
+		return nil, errors.Errorf("support for instruction %q not yet implemented", op)
+	}
+}
+
+// fillInst fills in the operands and attributes of the given instruction based
+// on its JSON representation.
+func (dec *jsonDecoder) fillInst(inst ir.Instruction, ji *JSONInst) error {
+	ops, err := dec.operands(ji.Operands)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var typ types.Type
+	if len(ji.Type) > 0 {
+		if typ, err = dec.typ(ji.Type); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// want reports an error if the instruction does not have n operands.
+	want := func(n int) error {
+		if len(ops) != n {
+			return errors.Errorf("invalid number of operands; expected %d, got %d", n, len(ops))
+		}
+		return nil
+	}
+	switch inst := inst.(type) {
+	// Binary instructions.
+	case *ir.InstAdd:
+		inst.Typ, inst.OverflowFlags = typ, jsonToOverflowFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstFAdd:
+		inst.Typ, inst.FastMathFlags = typ, jsonToFastMathFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstSub:
+		inst.Typ, inst.OverflowFlags = typ, jsonToOverflowFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstFSub:
+		inst.Typ, inst.FastMathFlags = typ, jsonToFastMathFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstMul:
+		inst.Typ, inst.OverflowFlags = typ, jsonToOverflowFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstFMul:
+		inst.Typ, inst.FastMathFlags = typ, jsonToFastMathFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstUDiv:
+		inst.Typ, inst.Exact = typ, hasFlag(ji.Flags, "exact")
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstSDiv:
+		inst.Typ, inst.Exact = typ, hasFlag(ji.Flags, "exact")
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstFDiv:
+		inst.Typ, inst.FastMathFlags = typ, jsonToFastMathFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstURem:
+		inst.Typ = typ
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstSRem:
+		inst.Typ = typ
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstFRem:
+		inst.Typ, inst.FastMathFlags = typ, jsonToFastMathFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	// Bitwise instructions.
+	case *ir.InstShl:
+		inst.Typ, inst.OverflowFlags = typ, jsonToOverflowFlags(ji.Flags)
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstLShr:
+		inst.Typ, inst.Exact = typ, hasFlag(ji.Flags, "exact")
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstAShr:
+		inst.Typ, inst.Exact = typ, hasFlag(ji.Flags, "exact")
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstAnd:
+		inst.Typ = typ
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstOr:
+		inst.Typ = typ
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	case *ir.InstXor:
+		inst.Typ = typ
+		return jsonBinop(ops, &inst.X, &inst.Y)
+	// Vector instructions.
+	case *ir.InstExtractElement:
+		if err := want(2); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Index, inst.Typ = ops[0], ops[1], typ
+	case *ir.InstInsertElement:
+		if err := want(3); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Elem, inst.Index, inst.Typ = ops[0], ops[1], ops[2], typ
+	case *ir.InstShuffleVector:
+		if err := want(3); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Y, inst.Mask, inst.Typ = ops[0], ops[1], ops[2], typ
+	// Aggregate instructions.
+	case *ir.InstExtractValue:
+		if err := want(1); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Indices, inst.Typ = ops[0], ji.Indices, typ
+	case *ir.InstInsertValue:
+		if err := want(2); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.X, inst.Elem, inst.Indices, inst.Typ = ops[0], ops[1], ji.Indices, typ
+	// Memory instructions.
+	case *ir.InstAlloca:
+		elemType, err := dec.typ(ji.ElemType)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.ElemType, inst.Align = elemType, ir.Align(ji.Align)
+		inst.InAlloca = hasFlag(ji.Flags, "inalloca")
+		inst.SwiftError = hasFlag(ji.Flags, "swifterror")
+		if len(ops) > 0 {
+			if err := want(1); err != nil {
+				return errors.WithStack(err)
+			}
+			inst.NElems = ops[0]
+		}
+	case *ir.InstLoad:
+		if err := want(1); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Src, inst.Typ, inst.Align = ops[0], typ, ir.Align(ji.Align)
+		inst.Atomic, inst.Volatile = hasFlag(ji.Flags, "atomic"), hasFlag(ji.Flags, "volatile")
+		inst.Ordering, inst.SyncScope = jsonToOrdering(ji.Ordering), ji.SyncScope
+	case *ir.InstStore:
+		if err := want(2); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Src, inst.Dst, inst.Align = ops[0], ops[1], ir.Align(ji.Align)
+		inst.Atomic, inst.Volatile = hasFlag(ji.Flags, "atomic"), hasFlag(ji.Flags, "volatile")
+		inst.Ordering, inst.SyncScope = jsonToOrdering(ji.Ordering), ji.SyncScope
+	case *ir.InstGetElementPtr:
+		if len(ops) < 1 {
+			return errors.New("invalid number of operands; expected at least 1, got 0")
+		}
+		elemType, err := dec.typ(ji.ElemType)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		inst.ElemType, inst.Src, inst.Indices = elemType, ops[0], ops[1:]
+		inst.InBounds = hasFlag(ji.Flags, "inbounds")
+	// Conversion instructions.
+	case *ir.InstTrunc:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstZExt:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstSExt:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstFPTrunc:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstFPExt:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstFPToUI:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstFPToSI:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstUIToFP:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstSIToFP:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstPtrToInt:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstIntToPtr:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstBitCast:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	case *ir.InstAddrSpaceCast:
+		return jsonCast(ops, typ, &inst.From, &inst.To)
+	// Other instructions.
+	case *ir.InstICmp:
+		if err := want(2); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Pred, inst.X, inst.Y = asmenum.IPredFromString(ji.Pred), ops[0], ops[1]
+	case *ir.InstFCmp:
+		if err := want(2); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Pred, inst.X, inst.Y = asmenum.FPredFromString(ji.Pred), ops[0], ops[1]
+		inst.FastMathFlags = jsonToFastMathFlags(ji.Flags)
+	case *ir.InstPhi:
+		if len(ops)%2 != 0 {
+			return errors.Errorf("invalid number of operands; expected (x, pred) pairs, got %d operands", len(ops))
+		}
+		inst.Typ = typ
+		for i := 0; i < len(ops); i += 2 {
+			inst.Incs = append(inst.Incs, ir.NewIncoming(ops[i], ops[i+1]))
+		}
+	case *ir.InstSelect:
+		if err := want(3); err != nil {
+			return errors.WithStack(err)
+		}
+		inst.Cond, inst.X, inst.Y, inst.Typ = ops[0], ops[1], ops[2], typ
+	case *ir.InstCall:
+		if len(ops) < 1 {
+			return errors.New("invalid number of operands; expected at least 1, got 0")
+		}
+		inst.Callee, inst.Args = ops[0], ops[1:]
+		if typ == nil {
+			typ = types.Void
+		}
+		inst.Typ = typ
+		inst.FastMathFlags = jsonToFastMathFlags(ji.Flags)
+		inst.Tail = asmenum.TailFromString(ji.Tail)
+		inst.CallingConv = asmenum.CallingConvFromString(ji.CallingConv)
+	default:
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("support for instruction %T not yet implemented", inst))
+	}
+	return nil
+}
+
+// term returns the terminator of the given JSON representation.
+func (dec *jsonDecoder) term(jt *JSONInst) (ir.Terminator, error) {
+	ops, err := dec.operands(jt.Operands)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch jt.Op {
+	case "ret":
+		switch len(ops) {
+		case 0:
+			return &ir.TermRet{}, nil
+		case 1:
+			return &ir.TermRet{X: ops[0]}, nil
+		}
+	case "br":
+		switch len(ops) {
+		case 1:
+			target, err := jsonBlock(ops[0])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &ir.TermBr{Target: target}, nil
+		case 3:
+			targetTrue, err := jsonBlock(ops[1])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			targetFalse, err := jsonBlock(ops[2])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &ir.TermCondBr{Cond: ops[0], TargetTrue: targetTrue, TargetFalse: targetFalse}, nil
+		}
+	case "switch":
+		if len(ops) < 2 || len(ops)%2 != 0 {
+			break
+		}
+		targetDefault, err := jsonBlock(ops[1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		term := &ir.TermSwitch{X: ops[0], TargetDefault: targetDefault}
+		for i := 2; i < len(ops); i += 2 {
+			x, ok := ops[i].(ir.Constant)
+			if !ok {
+				return nil, errors.Errorf("invalid switch case value; expected ir.Constant, got %T", ops[i])
+			}
+			target, err := jsonBlock(ops[i+1])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			term.Cases = append(term.Cases, ir.NewCase(x, target))
+		}
+		return term, nil
+	case "unreachable":
+		if len(ops) == 0 {
+			return &ir.TermUnreachable{}, nil
+		}
+	default:
+		// TODO: add support for remaining terminators (e.g. invoke and exception
+		// handling terminators).
+		return nil, errors.Errorf("support for terminator %q not yet implemented", jt.Op)
+	}
+	return nil, errors.Errorf("invalid number of operands (%d) of %q terminator", len(ops), jt.Op)
+}
+
+// ### [ Helpers ] #############################################################
+
+// addRef associates the given reference ID with the given value.
+func (dec *jsonDecoder) addRef(id int, v value.Value) error {
+	if id <= 0 {
+		return errors.Errorf("invalid reference ID %d of %q; expected positive integer", id, v.Ident())
+	}
+	if prev, ok := dec.refs[id]; ok {
+		return errors.Errorf("reference ID %d of %q already used by %q", id, v.Ident(), prev.Ident())
+	}
+	dec.refs[id] = v
+	return nil
+}
+
+// typ returns the type of the given LLVM IR type in assembly notation.
+func (dec *jsonDecoder) typ(s string) (types.Type, error) {
+	if t, ok := dec.types[s]; ok {
+		return t, nil
+	}
+	t, err := ParseType(s, dec.m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse type %q", s)
+	}
+	dec.types[s] = t
+	return t, nil
+}
+
+// operands returns the values of the given JSON operands.
+func (dec *jsonDecoder) operands(jops []*JSONOperand) ([]value.Value, error) {
+	var ops []value.Value
+	for _, jop := range jops {
+		if jop.Ref != 0 {
+			v, ok := dec.refs[jop.Ref]
+			if !ok {
+				return nil, errors.Errorf("unable to locate value of reference ID %d", jop.Ref)
+			}
+			ops = append(ops, v)
+			continue
+		}
+		c, err := ParseConstant(jop.Const, dec.m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse constant %q", jop.Const)
+		}
+		ops = append(ops, c)
+	}
+	return ops, nil
+}
+
+// jsonBinop fills in the operands x and y of a binary or bitwise instruction.
+func jsonBinop(ops []value.Value, x, y *value.Value) error {
+	if len(ops) != 2 {
+		return errors.Errorf("invalid number of operands; expected 2, got %d", len(ops))
+	}
+	*x, *y = ops[0], ops[1]
+	return nil
+}
+
+// jsonCast fills in the operand and destination type of a conversion
+// instruction.
+func jsonCast(ops []value.Value, typ types.Type, from *value.Value, to *types.Type) error {
+	if len(ops) != 1 {
+		return errors.Errorf("invalid number of operands; expected 1, got %d", len(ops))
+	}
+	if typ == nil {
+		return errors.New("invalid conversion instruction; missing destination type")
+	}
+	*from, *to = ops[0], typ
+	return nil
+}
+
+// jsonBlock returns the basic block of the given operand.
+func jsonBlock(v value.Value) (*ir.BasicBlock, error) {
+	block, ok := v.(*ir.BasicBlock)
+	if !ok {
+		return nil, errors.Errorf("invalid basic block operand; expected *ir.BasicBlock, got %T", v)
+	}
+	return block, nil
+}
+
+// isStructDef reports whether the given type definition (in LLVM IR assembly
+// notation) defines a structure type.
+func isStructDef(def string) bool {
+	return def == "opaque" || strings.HasPrefix(def, "{") || strings.HasPrefix(def, "<{")
+}
+
+// hasFlag reports whether the given flags contain flag.
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonToOverflowFlags returns the overflow flags of the given flags.
+func jsonToOverflowFlags(flags []string) []enum.OverflowFlag {
+	var overflowFlags []enum.OverflowFlag
+	for _, flag := range flags {
+		overflowFlags = append(overflowFlags, asmenum.OverflowFlagFromString(flag))
+	}
+	return overflowFlags
+}
+
+// jsonToFastMathFlags returns the fast math flags of the given flags.
+func jsonToFastMathFlags(flags []string) []enum.FastMathFlag {
+	var fmf []enum.FastMathFlag
+	for _, flag := range flags {
+		fmf = append(fmf, asmenum.FastMathFlagFromString(flag))
+	}
+	return fmf
+}
+
+// jsonToOrdering returns the atomic memory ordering of the given string; or
+// none if empty.
+func jsonToOrdering(s string) enum.AtomicOrdering {
+	if len(s) == 0 {
+		return enum.AtomicOrderingNone
+	}
+	return asmenum.AtomicOrderingFromString(s)
+}
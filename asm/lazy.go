@@ -0,0 +1,95 @@
+package asm
+
+import (
+	"github.com/llir/l/ir"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// TranslateLazy translates the AST of the given module to an equivalent LLVM
+// IR module, deferring the translation of function bodies. Functions are
+// translated with their headers (i.e. signatures, parameters and attributes),
+// but without basic blocks until materialized by the returned loader.
+//
+// Lazy translation is useful for tools which only inspect type definitions,
+// global variables and function signatures.
+//
+// Note, as functions not yet materialized have no basic blocks, they are
+// indistinguishable from function declarations when printed (e.g. by Def) or
+// otherwise encoded; use Loader.MaterializeAll before encoding the module.
+func TranslateLazy(module *ast.Module) (*ir.Module, *Loader, error) {
+	gen := newGenerator()
+	gen.bodies = make(map[*ir.Function]*ast.FuncDef)
+	m, err := gen.translate(module)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return m, &Loader{gen: gen}, nil
+}
+
+// Loader translates the bodies of functions on demand, for modules translated
+// by TranslateLazy. A loader retains the AST of function bodies not yet
+// materialized.
+//
+// A loader is not safe for concurrent use.
+type Loader struct {
+	// Generator of the lazily translated module.
+	gen *generator
+}
+
+// Materialize translates the body of the given function, if not already
+// materialized. Materializing a function not defined by the lazily translated
+// module is a no-op.
+//
+// If translation fails, the function is left without basic blocks and remains
+// unmaterialized.
+func (l *Loader) Materialize(f *ir.Function) error {
+	old, ok := l.gen.bodies[f]
+	if !ok {
+		return nil
+	}
+	opaquePointers := l.gen.opaquePointers
+	fgen := newFuncGen(l.gen, f)
+	if _, err := fgen.resolveLocals(old.Body()); err != nil {
+		f.Blocks = nil
+		return errors.WithStack(err)
+	}
+	delete(l.gen.bodies, f)
+	// Update pointer types if the function body contains the first opaque
+	// pointer type of the module.
+	switch {
+	case l.gen.opaquePointers && !opaquePointers:
+		for _, g := range l.gen.m.Globals {
+			setOpaquePointer(g)
+		}
+		for _, g := range l.gen.m.Funcs {
+			setOpaquePointer(g)
+		}
+	case l.gen.opaquePointers:
+		setOpaquePointer(f)
+	}
+	// Fix dummy values referring to the materialized function.
+	if err := l.gen.fixTodo(); err != nil {
+		f.Blocks = nil
+		l.gen.bodies[f] = old
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// MaterializeAll translates the bodies of all functions not yet materialized.
+func (l *Loader) MaterializeAll() error {
+	for _, f := range l.gen.m.Funcs {
+		if err := l.Materialize(f); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// IsMaterialized reports whether the body of the given function has been
+// translated.
+func (l *Loader) IsMaterialized(f *ir.Function) bool {
+	_, ok := l.gen.bodies[f]
+	return !ok
+}
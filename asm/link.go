@@ -0,0 +1,783 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/enum"
+	"github.com/llir/l/ir/types"
+	"github.com/mewmew/l-tm/asm/datalayout"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/mewmew/l-tm/internal/enc"
+	"github.com/pkg/errors"
+)
+
+// LinkFiles parses the given LLVM IR assembly files and links them into a
+// single LLVM IR module.
+func LinkFiles(paths ...string) (*ir.Module, error) {
+	l := NewLinker()
+	for _, path := range paths {
+		module, err := ParseFile(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := l.Add(module); err != nil {
+			return nil, errors.Wrapf(err, "unable to link %q", path)
+		}
+	}
+	return l.Module(), nil
+}
+
+// Linker links several modules into a single LLVM IR module, in the spirit of
+// llvm-link.
+//
+// Modules are linked during translation from AST to IR, so that references to
+// global variables and functions declared by one module are resolved directly
+// to the definitions provided by other modules.
+//
+// Linking follows these rules.
+//
+//   - declarations refer to definitions (or declarations) of other modules.
+//   - definitions with internal or private linkage never conflict; they are
+//     renamed as needed.
+//   - weak, linkonce, common and available_externally definitions are
+//     overridden by other definitions, and otherwise the first definition is
+//     selected.
+//   - of two common definitions, the largest is selected; common definitions
+//     may thus differ in type.
+//   - only one module defining a comdat contributes its members, following the
+//     comdat selection kind; the module with the largest key global variable
+//     for selection kind largest, and otherwise the first module.
+//   - structurally identical named types (e.g. %struct.foo and %struct.foo.0)
+//     are unified, and named types which differ are renamed.
+//
+// An error is reported for ODR-style conflicts, such as duplicate strong
+// definitions and global variables or functions whose types differ between
+// modules.
+type Linker struct {
+	// Linked LLVM IR module.
+	m *ir.Module
+
+	// types maps from base type name (without '%' prefix and numeric suffix) to
+	// type definitions of the linked module.
+	types map[string][]types.Type
+	// typeNames records the type names (without '%' prefix) of the linked
+	// module.
+	typeNames map[string]bool
+
+	// public maps from global identifier (without '@' prefix) to global
+	// variables and functions of the linked module which are visible to other
+	// modules (i.e. not internal or private).
+	public map[string]ir.Constant
+	// globalNames records the global identifiers (without '@' prefix) of the
+	// linked module.
+	globalNames map[string]bool
+
+	// comdats maps from comdat name (without '$' prefix) to selected comdat of
+	// the linked module.
+	comdats map[string]*linkComdat
+	// discard records global identifiers (without '@' prefix) of the module
+	// being linked, the definitions of which are discarded as their comdat has
+	// already been selected.
+	discard map[string]bool
+	// override records global identifiers (without '@' prefix) of the module
+	// being linked, the definitions of which override those of the linked
+	// module as their comdat replaces the comdat previously selected.
+	override map[string]bool
+}
+
+// linkComdat is a comdat selected by the linker.
+type linkComdat struct {
+	// Comdat selection kind.
+	kind enum.SelectionKind
+	// texts maps from global identifier (without '@' prefix) to the text of each
+	// member of the comdat.
+	texts map[string]string
+	// Size in bytes of the key global variable of the comdat (i.e. the global
+	// variable of the same name as the comdat); or -1 if not present.
+	size int64
+}
+
+// NewLinker returns a new linker for linking modules into an empty LLVM IR
+// module.
+func NewLinker() *Linker {
+	return &Linker{
+		m:           &ir.Module{},
+		types:       make(map[string][]types.Type),
+		typeNames:   make(map[string]bool),
+		public:      make(map[string]ir.Constant),
+		globalNames: make(map[string]bool),
+		comdats:     make(map[string]*linkComdat),
+	}
+}
+
+// Module returns the linked LLVM IR module.
+func (l *Linker) Module() *ir.Module {
+	return l.m
+}
+
+// Add links the AST of the given module into the linked LLVM IR module.
+func (l *Linker) Add(module *ast.Module) error {
+	gen := newGenerator()
+	// Resolve types of the module, and unify them with the type definitions of
+	// the linked module.
+	if _, err := gen.resolveTypeDefs(module); err != nil {
+		return errors.WithStack(err)
+	}
+	l.linkTypeDefs(gen)
+	// Select comdats.
+	if err := l.selectComdats(gen, module); err != nil {
+		return errors.WithStack(err)
+	}
+	// Resolve globals of the module into the linked module.
+	gen.m = l.m
+	gen.linker = l
+	for name, g := range l.public {
+		gen.gs[name] = g
+	}
+	// Global variables and functions with internal or private linkage shadow
+	// those of the linked module.
+	for _, entity := range module.TopLevelEntities() {
+		switch entity := entity.(type) {
+		case *ast.GlobalDecl, *ast.GlobalDef, *ast.FuncDecl, *ast.FuncDef:
+			if isLocalLinkage(astLinkage(entity)) {
+				delete(gen.gs, astGlobalName(entity))
+			}
+		}
+	}
+	if _, err := gen.resolveGlobals(module); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := gen.fixTodo(); err != nil {
+		return errors.WithStack(err)
+	}
+	l.indexGlobals()
+	return nil
+}
+
+// --- [ Type definitions ] ----------------------------------------------------
+
+// linkTypeDefs unifies the type definitions resolved by the given generator
+// with the structurally identical type definitions of the linked module, and
+// adds the remaining type definitions to the linked module; renaming them as
+// needed.
+//
+// Post-condition: gen.ts maps to type definitions of the linked module.
+func (l *Linker) linkTypeDefs(gen *generator) {
+	// mapping maps from type definition of the generator to identical type
+	// definition of the linked module.
+	mapping := make(map[types.Type]types.Type)
+	// updated records type definitions with references to update; i.e. type
+	// definitions to add to the linked module and opaque struct types of the
+	// linked module given a body.
+	var added, updated []types.Type
+	for _, t := range gen.m.TypeDefs {
+		alias := typeAlias(t)
+		base := baseName(alias)
+		if prev, filled := l.identicalTypeDef(base, t); prev != nil {
+			mapping[t] = prev
+			if filled {
+				updated = append(updated, prev)
+			}
+			continue
+		}
+		added = append(added, t)
+		updated = append(updated, t)
+	}
+	// Update references to unified type definitions.
+	visited := make(map[types.Type]bool)
+	for _, t := range updated {
+		replaceTypes(t, mapping, visited)
+	}
+	for alias, t := range gen.ts {
+		if prev, ok := mapping[t]; ok {
+			gen.ts[alias] = prev
+		}
+	}
+	// Add remaining type definitions to linked module.
+	for _, t := range added {
+		alias := typeAlias(t)
+		if l.typeNames[alias] {
+			alias = uniqueName(baseName(alias), l.typeNames)
+			setTypeAlias(t, alias)
+		}
+		l.typeNames[alias] = true
+		base := baseName(alias)
+		l.types[base] = append(l.types[base], t)
+		l.m.TypeDefs = append(l.m.TypeDefs, t)
+	}
+}
+
+// identicalTypeDef returns the type definition of the linked module with the
+// given base type name which is structurally identical to t, or nil if not
+// present.
+//
+// Opaque struct types are identical to struct types of the same base name, in
+// which case the body of the opaque struct type of the linked module may be
+// filled by t, as reported by filled.
+func (l *Linker) identicalTypeDef(base string, t types.Type) (types.Type, bool) {
+	for _, prev := range l.types[base] {
+		if isOpaque(t) {
+			return prev, false
+		}
+		if isOpaque(prev) {
+			if t, ok := t.(*types.StructType); ok {
+				p := prev.(*types.StructType)
+				p.Packed = t.Packed
+				p.Fields = t.Fields
+				p.Opaque = false
+				return prev, true
+			}
+			continue
+		}
+		if identical(prev, t, make(map[[2]types.Type]bool)) {
+			return prev, false
+		}
+	}
+	return nil, false
+}
+
+// --- [ Comdats ] -------------------------------------------------------------
+
+// selectComdats selects the comdats defined by the given module, and records
+// the global variables and functions of the module to discard or override
+// with, based on the comdats already selected by the linker.
+//
+// Pre-condition: type definitions of gen have been resolved.
+func (l *Linker) selectComdats(gen *generator, module *ast.Module) error {
+	l.discard = make(map[string]bool)
+	l.override = make(map[string]bool)
+	// kinds maps from comdat name to selection kind of the comdats defined by
+	// the module.
+	kinds := make(map[string]enum.SelectionKind)
+	// members maps from comdat name to the text of each member of the comdat,
+	// by global identifier.
+	members := make(map[string]map[string]string)
+	// keys maps from comdat name to the key global variable of the comdats
+	// defined by the module; i.e. the member global variable of the same name
+	// as the comdat.
+	keys := make(map[string]*ast.GlobalDef)
+	addMember := func(name string, c *ast.Comdat, old ast.LlvmNode) {
+		if c == nil {
+			return
+		}
+		// Comdat name defaults to the name of the global.
+		comdatName := name
+		if n := c.Name(); n != nil {
+			comdatName = comdat(*n)
+		}
+		if members[comdatName] == nil {
+			members[comdatName] = make(map[string]string)
+		}
+		members[comdatName][name] = text(old)
+	}
+	for _, entity := range module.TopLevelEntities() {
+		switch entity := entity.(type) {
+		case *ast.ComdatDef:
+			kind := entity.Kind()
+			kinds[comdat(entity.Name())] = irOptSelectionKind(&kind)
+		case *ast.GlobalDef:
+			name := global(entity.Name())
+			for _, attr := range entity.GlobalAttrs() {
+				if c, ok := attr.(*ast.Comdat); ok {
+					addMember(name, c, entity)
+					keys[name] = entity
+				}
+			}
+		case *ast.FuncDef:
+			hdr := entity.Header()
+			name := global(hdr.Name())
+			addMember(name, hdr.Comdat(), entity)
+		}
+	}
+	for comdatName, kind := range kinds {
+		c := &linkComdat{kind: kind, texts: members[comdatName], size: -1}
+		if key, ok := keys[comdatName]; ok && members[comdatName][comdatName] != "" {
+			switch kind {
+			case enum.SelectionKindLargest, enum.SelectionKindSameSize:
+				contentType, err := gen.irType(key.ContentType())
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if c.size, err = l.sizeOf(contentType); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+		prev, ok := l.comdats[comdatName]
+		if !ok {
+			l.comdats[comdatName] = c
+			continue
+		}
+		if prev.kind != kind {
+			return errors.Errorf("comdat %q selection kind mismatch; prev %v, new %v", enc.Comdat(comdatName), prev.kind, kind)
+		}
+		switch kind {
+		case enum.SelectionKindNoDuplicates:
+			return errors.Errorf("comdat %q with selection kind noduplicates already present", enc.Comdat(comdatName))
+		case enum.SelectionKindExactMatch:
+			if !sameMembers(prev.texts, members[comdatName]) {
+				return errors.Errorf("comdat %q with selection kind exactmatch differs between modules", enc.Comdat(comdatName))
+			}
+		case enum.SelectionKindLargest, enum.SelectionKindSameSize:
+			if prev.size == -1 || c.size == -1 {
+				return errors.Errorf("comdat %q with selection kind %v lacks key global variable", enc.Comdat(comdatName), kind)
+			}
+			if kind == enum.SelectionKindSameSize && prev.size != c.size {
+				return errors.Errorf("comdat %q with selection kind samesize differs in size between modules; prev %d, new %d", enc.Comdat(comdatName), prev.size, c.size)
+			}
+			if kind == enum.SelectionKindLargest && c.size > prev.size {
+				// Replace the comdat previously selected; members of the comdat
+				// override those of the linked module.
+				l.comdats[comdatName] = c
+				for name := range members[comdatName] {
+					l.override[name] = true
+				}
+				continue
+			}
+		}
+		// Discard members of the comdat, as the comdat has already been
+		// selected.
+		for name := range members[comdatName] {
+			l.discard[name] = true
+		}
+	}
+	return nil
+}
+
+// sameMembers reports whether the given comdat members are identical.
+func sameMembers(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, s := range a {
+		if b[name] != s {
+			return false
+		}
+	}
+	return true
+}
+
+// --- [ Global variables and functions ] --------------------------------------
+
+// redefGlobal checks that the AST global variable or function of the given name
+// may refer to the global variable or function prev of the linked module, and
+// reports whether prev should be (re)defined by the AST definition; following
+// the linkage rules of the linker.
+func (l *Linker) redefGlobal(gen *generator, name string, prev ir.Constant, old ast.LlvmNode) (bool, error) {
+	if l.discard[name] {
+		// Refer to the existing member of the comdat already selected, which may
+		// differ in type.
+		return false, nil
+	}
+	// Check for ODR-style type conflicts.
+	g, err := gen.newGlobal(name, old)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	prevLinkage := linkageOf(prev)
+	newLinkage := astLinkage(old)
+	// Common definitions and members of comdats selected by size may differ in
+	// type.
+	_, isGlobal := g.(*ir.Global)
+	sized := isGlobal && (l.override[name] || (prevLinkage == enum.LinkageCommon && newLinkage == enum.LinkageCommon))
+	if !sameKind(prev, g) || (!sized && !identical(contentType(prev), contentType(g), make(map[[2]types.Type]bool))) {
+		return false, errors.Errorf("type mismatch of global identifier %q; prev `%s`, new `%s`", enc.Global(name), contentType(prev), contentType(g))
+	}
+	if isDecl(old) {
+		// Refer to the existing global variable or function.
+		return false, nil
+	}
+	if l.override[name] {
+		// Override the existing member of the replaced comdat.
+		retype(prev, g)
+		return true, nil
+	}
+	if isDeclValue(prev) {
+		// Define the existing declaration.
+		return true, nil
+	}
+	// Both are definitions.
+	switch {
+	case prevLinkage == enum.LinkageCommon && newLinkage == enum.LinkageCommon:
+		// Select the largest common definition, and otherwise the first.
+		prevSize, err := l.sizeOf(contentType(prev))
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		newSize, err := l.sizeOf(contentType(g))
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if newSize <= prevSize {
+			return false, nil
+		}
+		retype(prev, g)
+		return true, nil
+	case isWeakForLinker(newLinkage):
+		// Keep existing definition.
+		return false, nil
+	case isWeakForLinker(prevLinkage):
+		// Override existing definition.
+		return true, nil
+	case prevLinkage == enum.LinkageAppending && newLinkage == enum.LinkageAppending:
+		// TODO: concatenate the arrays of global variables with appending
+		// linkage.
+		return false, errors.Errorf("support for linking global variable %q with appending linkage not yet implemented", enc.Global(name))
+	default:
+		return false, errors.Errorf("duplicate definition of global identifier %q", enc.Global(name))
+	}
+}
+
+// claimName claims the global identifier of the given new global variable or
+// function within the linked module. Global variables and functions with
+// internal or private linkage are renamed if their name is already in use.
+func (l *Linker) claimName(g ir.Constant, old ast.LlvmNode) {
+	name := globalName(g)
+	if !l.globalNames[name] {
+		l.globalNames[name] = true
+		return
+	}
+	newName := uniqueName(name, l.globalNames)
+	l.globalNames[newName] = true
+	if isLocalLinkage(astLinkage(old)) {
+		setGlobalName(g, newName)
+		return
+	}
+	// The global identifier is in use by a global variable or function with
+	// internal or private linkage of the linked module, as other global
+	// identifiers are visible to the module being linked. Rename the existing
+	// global variable or function.
+	for _, prev := range l.m.Globals {
+		if prev.GlobalName == name {
+			prev.GlobalName = newName
+		}
+	}
+	for _, prev := range l.m.Funcs {
+		if prev.GlobalName == name {
+			prev.GlobalName = newName
+		}
+	}
+}
+
+// indexGlobals indexes the global variables and functions of the linked
+// module.
+func (l *Linker) indexGlobals() {
+	l.public = make(map[string]ir.Constant)
+	l.globalNames = make(map[string]bool)
+	for _, g := range l.m.Globals {
+		l.globalNames[g.GlobalName] = true
+		if !isLocalLinkage(g.Linkage) {
+			l.public[g.GlobalName] = g
+		}
+	}
+	for _, f := range l.m.Funcs {
+		l.globalNames[f.GlobalName] = true
+		if !isLocalLinkage(f.Linkage) {
+			l.public[f.GlobalName] = f
+		}
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// astLinkage returns the IR linkage of the given AST global variable or
+// function.
+func astLinkage(old ast.LlvmNode) enum.Linkage {
+	switch old := old.(type) {
+	case *ast.GlobalDecl:
+		return irOptLinkage(old.ExternLinkage())
+	case *ast.GlobalDef:
+		return irOptLinkage(old.Linkage())
+	case *ast.FuncDecl:
+		return irFuncLinkage(old.Header())
+	case *ast.FuncDef:
+		return irFuncLinkage(old.Header())
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", old))
+	}
+}
+
+// astGlobalName returns the global identifier (without '@' prefix) of the given
+// AST global variable or function.
+func astGlobalName(old ast.LlvmNode) string {
+	switch old := old.(type) {
+	case *ast.GlobalDecl:
+		return global(old.Name())
+	case *ast.GlobalDef:
+		return global(old.Name())
+	case *ast.FuncDecl:
+		return global(old.Header().Name())
+	case *ast.FuncDef:
+		return global(old.Header().Name())
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", old))
+	}
+}
+
+// retype sets the content type of the given IR global variable prev to that of
+// the new IR global variable g; as used when a definition of different type is
+// selected. Functions are never retyped, as their signatures must match.
+//
+// NOTE: existing uses of prev are not updated to the new type.
+func retype(prev, g ir.Constant) {
+	p, ok := prev.(*ir.Global)
+	if !ok {
+		return
+	}
+	p.ContentType = g.(*ir.Global).ContentType
+	if p.Typ.ElemType != nil {
+		p.Typ.ElemType = p.ContentType
+	}
+}
+
+// sizeOf returns the size in bytes of the given type, based on the data layout
+// of the linked module.
+func (l *Linker) sizeOf(t types.Type) (int64, error) {
+	dl, err := datalayout.Parse(l.m.DataLayout)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return dl.SizeOf(t)
+}
+
+// contentType returns the content type of the given IR global variable, or the
+// function signature of the given IR function.
+func contentType(g ir.Constant) types.Type {
+	switch g := g.(type) {
+	case *ir.Global:
+		return g.ContentType
+	case *ir.Function:
+		return g.Sig
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", g))
+	}
+}
+
+// linkageOf returns the linkage of the given IR global variable or function.
+func linkageOf(g ir.Constant) enum.Linkage {
+	switch g := g.(type) {
+	case *ir.Global:
+		return g.Linkage
+	case *ir.Function:
+		return g.Linkage
+	default:
+		panic(fmt.Errorf("support for global variable or function %T not yet implemented", g))
+	}
+}
+
+// sameKind reports whether the given IR values are both global variables or
+// both functions.
+func sameKind(a, b ir.Constant) bool {
+	switch a.(type) {
+	case *ir.Global:
+		_, ok := b.(*ir.Global)
+		return ok
+	case *ir.Function:
+		_, ok := b.(*ir.Function)
+		return ok
+	}
+	return false
+}
+
+// isDeclValue reports whether the given IR global variable or function is a
+// declaration.
+func isDeclValue(g ir.Constant) bool {
+	switch g := g.(type) {
+	case *ir.Global:
+		return g.Init == nil
+	case *ir.Function:
+		return len(g.Blocks) == 0
+	}
+	return false
+}
+
+// isLocalLinkage reports whether the given linkage is local to the module.
+func isLocalLinkage(linkage enum.Linkage) bool {
+	switch linkage {
+	case enum.LinkageInternal, enum.LinkagePrivate:
+		return true
+	}
+	return false
+}
+
+// isWeakForLinker reports whether definitions of the given linkage may be
+// overridden by other definitions when linking.
+func isWeakForLinker(linkage enum.Linkage) bool {
+	switch linkage {
+	case enum.LinkageWeak, enum.LinkageWeakODR, enum.LinkageLinkOnce, enum.LinkageLinkOnceODR, enum.LinkageCommon, enum.LinkageExternWeak, enum.LinkageAvailableExternally:
+		return true
+	}
+	return false
+}
+
+// isOpaque reports whether the given type is an opaque struct type.
+func isOpaque(t types.Type) bool {
+	if t, ok := t.(*types.StructType); ok {
+		return t.Opaque
+	}
+	return false
+}
+
+// baseName returns the given name without numeric suffix (e.g. "struct.foo.0"
+// -> "struct.foo").
+func baseName(name string) string {
+	pos := strings.LastIndex(name, ".")
+	if pos == -1 {
+		return name
+	}
+	if _, err := strconv.ParseUint(name[pos+1:], 10, 64); err != nil {
+		return name
+	}
+	return name[:pos]
+}
+
+// uniqueName returns a unique name based on the given name, which is not
+// present in taken.
+func uniqueName(name string, taken map[string]bool) string {
+	for i := 0; ; i++ {
+		newName := fmt.Sprintf("%s.%d", name, i)
+		if !taken[newName] {
+			return newName
+		}
+	}
+}
+
+// identical reports whether the given types are structurally identical. The
+// pairs of types assumed to be identical are tracked by assumed, to handle
+// recursive types.
+func identical(a, b types.Type, assumed map[[2]types.Type]bool) bool {
+	if a == b {
+		return true
+	}
+	key := [2]types.Type{a, b}
+	if assumed[key] {
+		return true
+	}
+	assumed[key] = true
+	switch a := a.(type) {
+	case *types.VoidType:
+		_, ok := b.(*types.VoidType)
+		return ok
+	case *types.FuncType:
+		b, ok := b.(*types.FuncType)
+		if !ok || a.Variadic != b.Variadic || len(a.Params) != len(b.Params) {
+			return false
+		}
+		if !identical(a.RetType, b.RetType, assumed) {
+			return false
+		}
+		for i := range a.Params {
+			if !identical(a.Params[i], b.Params[i], assumed) {
+				return false
+			}
+		}
+		return true
+	case *types.IntType:
+		b, ok := b.(*types.IntType)
+		return ok && a.BitSize == b.BitSize
+	case *types.FloatType:
+		b, ok := b.(*types.FloatType)
+		return ok && a.Kind == b.Kind
+	case *types.MMXType:
+		_, ok := b.(*types.MMXType)
+		return ok
+	case *types.PointerType:
+		b, ok := b.(*types.PointerType)
+		if !ok || a.AddrSpace != b.AddrSpace || isOpaquePointer(a) != isOpaquePointer(b) {
+			return false
+		}
+		return isOpaquePointer(a) || identical(a.ElemType, b.ElemType, assumed)
+	case *types.VectorType:
+		b, ok := b.(*types.VectorType)
+		return ok && a.Len == b.Len && a.Scalable == b.Scalable && identical(a.ElemType, b.ElemType, assumed)
+	case *types.LabelType:
+		_, ok := b.(*types.LabelType)
+		return ok
+	case *types.AMXType:
+		_, ok := b.(*types.AMXType)
+		return ok
+	case *types.TokenType:
+		_, ok := b.(*types.TokenType)
+		return ok
+	case *types.TargetExtType:
+		b, ok := b.(*types.TargetExtType)
+		if !ok || a.TypeName != b.TypeName || len(a.TypeParams) != len(b.TypeParams) || len(a.IntParams) != len(b.IntParams) {
+			return false
+		}
+		for i := range a.IntParams {
+			if a.IntParams[i] != b.IntParams[i] {
+				return false
+			}
+		}
+		for i := range a.TypeParams {
+			if !identical(a.TypeParams[i], b.TypeParams[i], assumed) {
+				return false
+			}
+		}
+		return true
+	case *types.MetadataType:
+		_, ok := b.(*types.MetadataType)
+		return ok
+	case *types.ArrayType:
+		b, ok := b.(*types.ArrayType)
+		return ok && a.Len == b.Len && identical(a.ElemType, b.ElemType, assumed)
+	case *types.StructType:
+		b, ok := b.(*types.StructType)
+		if !ok || (a.Alias == "") != (b.Alias == "") {
+			return false
+		}
+		if a.Packed != b.Packed || a.Opaque != b.Opaque || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for i := range a.Fields {
+			if !identical(a.Fields[i], b.Fields[i], assumed) {
+				return false
+			}
+		}
+		return true
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", a))
+	}
+}
+
+// replaceTypes replaces the types referenced by t (recursively) based on the
+// given mapping. Types already visited are tracked by visited.
+func replaceTypes(t types.Type, mapping map[types.Type]types.Type, visited map[types.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	replace := func(u types.Type) types.Type {
+		if v, ok := mapping[u]; ok {
+			return v
+		}
+		replaceTypes(u, mapping, visited)
+		return u
+	}
+	switch t := t.(type) {
+	case *types.FuncType:
+		t.RetType = replace(t.RetType)
+		for i := range t.Params {
+			t.Params[i] = replace(t.Params[i])
+		}
+	case *types.PointerType:
+		if !isOpaquePointer(t) {
+			t.ElemType = replace(t.ElemType)
+		}
+	case *types.VectorType:
+		t.ElemType = replace(t.ElemType)
+	case *types.ArrayType:
+		t.ElemType = replace(t.ElemType)
+	case *types.StructType:
+		for i := range t.Fields {
+			t.Fields[i] = replace(t.Fields[i])
+		}
+	case *types.TargetExtType:
+		for i := range t.TypeParams {
+			t.TypeParams[i] = replace(t.TypeParams[i])
+		}
+	}
+}
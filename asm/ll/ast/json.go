@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/mewmew/l-tm/asm/ll/selector"
+	"github.com/pkg/errors"
+)
+
+// NOTE: json.go is not generated by Textmapper.
+
+// JSONNode is the JSON representation of an AST node.
+//
+// Each node records its kind (the name of its node type, e.g. "GlobalDecl"),
+// its source span as byte offsets [start, end) of the source text, and its
+// children in source order. The source text of leaf nodes (e.g. identifiers
+// and literals) is recorded as text.
+//
+//	{"kind": "TypeDef", "span": [0, 24], "children": [
+//		{"kind": "LocalIdent", "span": [0, 4], "text": "%foo"},
+//		...
+//	]}
+type JSONNode struct {
+	// Kind of the node.
+	Kind string `json:"kind"`
+	// Source span of the node; start and end byte offsets.
+	Span [2]int `json:"span"`
+	// Source text of leaf nodes; or empty if the node has children.
+	Text string `json:"text,omitempty"`
+	// Children of the node, in source order.
+	Children []*JSONNode `json:"children,omitempty"`
+}
+
+// NewJSONNode returns the JSON representation of the given AST node and its
+// descendants.
+func NewJSONNode(node LlvmNode) *JSONNode {
+	n := node.LlvmNode()
+	j := &JSONNode{
+		Kind: n.Type().String(),
+		Span: [2]int{n.Offset(), n.Endoffset()},
+	}
+	for _, child := range n.Children(selector.Any) {
+		j.Children = append(j.Children, NewJSONNode(ToLlvmNode(child)))
+	}
+	if len(j.Children) == 0 {
+		j.Text = n.Text()
+	}
+	return j
+}
+
+// WriteJSON writes the JSON representation of the given AST node and its
+// descendants to w.
+func WriteJSON(w io.Writer, node LlvmNode) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(NewJSONNode(node)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
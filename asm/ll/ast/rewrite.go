@@ -0,0 +1,99 @@
+package ast
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Rewriter records edits of the source text of an AST, replacing, deleting or
+// inserting text at the source spans of AST nodes. The edited source text is
+// regenerated by Text, leaving the text outside of edited spans (including
+// comments and whitespace) untouched.
+type Rewriter struct {
+	// Source text of the AST.
+	src string
+	// Recorded edits.
+	edits []edit
+}
+
+// edit is an edit of source text, replacing the text within the span
+// [start, end) with text.
+type edit struct {
+	// Start and end byte offsets of the edited span.
+	start, end int
+	// Replacement text.
+	text string
+}
+
+// NewRewriter returns a new rewriter of the given source text, from which the
+// AST was parsed.
+func NewRewriter(src string) *Rewriter {
+	return &Rewriter{src: src}
+}
+
+// Replace replaces the source text of the given node with text.
+func (r *Rewriter) Replace(node LlvmNode, text string) {
+	n := node.LlvmNode()
+	r.edits = append(r.edits, edit{start: n.Offset(), end: n.Endoffset(), text: text})
+}
+
+// Delete deletes the source text of the given node.
+func (r *Rewriter) Delete(node LlvmNode) {
+	r.Replace(node, "")
+}
+
+// InsertBefore inserts text before the source text of the given node.
+func (r *Rewriter) InsertBefore(node LlvmNode, text string) {
+	n := node.LlvmNode()
+	r.edits = append(r.edits, edit{start: n.Offset(), end: n.Offset(), text: text})
+}
+
+// InsertAfter inserts text after the source text of the given node.
+func (r *Rewriter) InsertAfter(node LlvmNode, text string) {
+	n := node.LlvmNode()
+	r.edits = append(r.edits, edit{start: n.Endoffset(), end: n.Endoffset(), text: text})
+}
+
+// Text returns the source text with all recorded edits applied. An error is
+// returned if the spans of two edits overlap.
+func (r *Rewriter) Text() (string, error) {
+	edits := make([]edit, len(r.edits))
+	copy(edits, r.edits)
+	// Insertions at the same offset are applied in the order recorded.
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	buf := &strings.Builder{}
+	pos := 0
+	for _, e := range edits {
+		if e.start < pos {
+			return "", errors.Errorf("overlapping edit of source span [%d, %d)", e.start, e.end)
+		}
+		buf.WriteString(r.src[pos:e.start])
+		buf.WriteString(e.text)
+		pos = e.end
+	}
+	buf.WriteString(r.src[pos:])
+	return buf.String(), nil
+}
+
+// Rewrite rewrites the given source text, from which the AST of root was
+// parsed. The AST is traversed in depth-first order, and f is invoked for each
+// node; if f returns true, the source text of the node is replaced with text
+// and the children of the node are not traversed.
+func Rewrite(src string, root LlvmNode, f func(node LlvmNode) (text string, ok bool)) (string, error) {
+	r := NewRewriter(src)
+	Inspect(root, func(node LlvmNode) bool {
+		if node == nil {
+			return false
+		}
+		if text, ok := f(node); ok {
+			r.Replace(node, text)
+			return false
+		}
+		return true
+	})
+	return r.Text()
+}
@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"github.com/mewmew/l-tm/asm/ll"
+	"github.com/mewmew/l-tm/asm/ll/selector"
+)
+
+// NOTE: stream.go is not generated by Textmapper; it builds upon the AST
+// builder of the generated parser.
+
+// ParseEntities parses the given LLVM IR assembly source text, invoking f with
+// the AST of each top-level entity in order of occurrence.
+//
+// The AST of each top-level entity is built from the node events of the
+// event-based parser, and handed to f as soon as the parser has reported the
+// top-level entity; thus the AST of the entire module is never materialized.
+// Top-level entities reported before a syntax error are handed to f before the
+// error is returned.
+//
+// As uselistorder directives may also occur within function bodies, top-level
+// uselistorder directives are handed to f once the succeeding top-level entity
+// (or the end of the module) has been reported.
+func ParseEntities(path, content string, f func(entity TopLevelEntity)) error {
+	b := newBuilder(path, content)
+	// flush hands the nodes of the builder stack to f; at the level of the
+	// module, the builder stack holds top-level entities only.
+	flush := func() {
+		for _, n := range b.stack {
+			f(ToLlvmNode(n).(TopLevelEntity))
+		}
+		b.stack = b.stack[:0]
+	}
+	listener := func(t ll.NodeType, offset, endoffset int) {
+		if t == ll.Module {
+			// The top-level entities have already been handed to f, and are
+			// therefore not added as children of the module.
+			flush()
+			return
+		}
+		b.addNode(t, offset, endoffset)
+		if t != ll.UseListOrder && selector.TopLevelEntity(t) {
+			flush()
+		}
+	}
+	var l ll.Lexer
+	l.Init(content)
+	var p ll.Parser
+	p.Init(listener)
+	return p.ParseModule(&l)
+}
@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"github.com/mewmew/l-tm/asm/ll/selector"
+)
+
+// NOTE: walk.go and rewrite.go are not generated by Textmapper.
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If the
+// result visitor w is not nil, Walk visits each of the children of node with
+// the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node LlvmNode) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for each
+// of the non-nil children of node, followed by a call of w.Visit(nil).
+func Walk(node LlvmNode, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	if n := node.LlvmNode(); n != nil {
+		for _, child := range n.Children(selector.Any) {
+			Walk(ToLlvmNode(child), v)
+		}
+	}
+	v.Visit(nil)
+}
+
+// inspector implements the Visitor interface for a function.
+type inspector func(LlvmNode) bool
+
+// Visit invokes the inspector function for the given node.
+func (f inspector) Visit(node LlvmNode) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call of
+// f(nil).
+//
+// For instance, the following counts the value instructions of a module:
+//
+//	n := 0
+//	ast.Inspect(module, func(node ast.LlvmNode) bool {
+//		if _, ok := node.(ast.ValueInstruction); ok {
+//			n++
+//		}
+//		return true
+//	})
+func Inspect(node LlvmNode, f func(LlvmNode) bool) {
+	Walk(node, inspector(f))
+}
@@ -76,6 +76,10 @@ metadata_name_tok : /[!]{_escape_name}/   (class)
 
 metadata_id_tok : /[!]{_id}/
 
+# --- [ Summary identifiers ] --------------------------------------------------
+
+summary_id_tok : /[\^]{_id}/
+
 # DW_TAG_foo
 dwarf_tag_tok : /DW_TAG_({_ascii_letter}|{_decimal_digit}|[_])*/
 
@@ -85,6 +89,9 @@ dwarf_att_encoding_tok : /DW_ATE_({_ascii_letter}|{_decimal_digit}|[_])*/
 # DIFlagFoo
 di_flag_tok : /DIFlag({_ascii_letter}|{_decimal_digit}|[_])*/
 
+# DISPFlagFoo
+disp_flag_tok : /DISPFlag({_ascii_letter}|{_decimal_digit}|[_])*/
+
 # DW_LANG_foo
 dwarf_lang_tok : /DW_LANG_({_ascii_letter}|{_decimal_digit}|[_])*/
 
@@ -138,8 +145,9 @@ _sci_lit = /{_frac_lit}[eE]{_sign}?{_decimals}/
 #   HexFP128Constant  0xL[0-9A-Fa-f]+    // 32 hex digits
 #   HexPPC128Constant 0xM[0-9A-Fa-f]+    // 32 hex digits
 #   HexHalfConstant   0xH[0-9A-Fa-f]+    // 4 hex digits
+#   HexBFloatConstant 0xR[0-9A-Fa-f]+    // 4 hex digits
 
-_float_hex_lit = /0x[KLMH]?[0-9A-Fa-f]+/
+_float_hex_lit = /0x[KLMHR]?[0-9A-Fa-f]+/
 
 # === [ String literals ] ======================================================
 
@@ -151,13 +159,17 @@ _quoted_string = /["][^"]*["]/
 
 int_type_tok : /i[0-9]+/
 
+'!DIArgList' : /!DIArgList/
+'!DIAssignID' : /!DIAssignID/
 '!DIBasicType' : /!DIBasicType/
+'!DICommonBlock' : /!DICommonBlock/
 '!DICompileUnit' : /!DICompileUnit/
 '!DICompositeType' : /!DICompositeType/
 '!DIDerivedType' : /!DIDerivedType/
 '!DIEnumerator' : /!DIEnumerator/
 '!DIExpression' : /!DIExpression/
 '!DIFile' : /!DIFile/
+'!DIGenericSubrange' : /!DIGenericSubrange/
 '!DIGlobalVariable' : /!DIGlobalVariable/
 '!DIGlobalVariableExpression' : /!DIGlobalVariableExpression/
 '!DIImportedEntity' : /!DIImportedEntity/
@@ -171,6 +183,7 @@ int_type_tok : /i[0-9]+/
 '!DIModule' : /!DIModule/
 '!DINamespace' : /!DINamespace/
 '!DIObjCProperty' : /!DIObjCProperty/
+'!DIStringType' : /!DIStringType/
 '!DISubprogram' : /!DISubprogram/
 '!DISubrange' : /!DISubrange/
 '!DISubroutineType' : /!DISubroutineType/
@@ -185,11 +198,16 @@ int_type_tok : /i[0-9]+/
 'addrspacecast' : /addrspacecast/
 'afn' : /afn/
 'alias' : /alias/
+'alias:' : /alias:/
+'aliasee:' : /aliasee:/
 'align:' : /align:/
 'align' : /align/
+'alignLog2:' : /alignLog2:/
 'alignstack' : /alignstack/
+'allOnes' : /allOnes/
 'alloca' : /alloca/
 'allocsize' : /allocsize/
+'alwaysInline:' : /alwaysInline:/
 'alwaysinline' : /alwaysinline/
 'amdgpu_cs' : /amdgpu_cs/
 'amdgpu_es' : /amdgpu_es/
@@ -206,6 +224,7 @@ int_type_tok : /i[0-9]+/
 'arcp' : /arcp/
 'arg:' : /arg:/
 'argmemonly' : /argmemonly/
+'args:' : /args:/
 'arm_aapcs_vfpcc' : /arm_aapcs_vfpcc/
 'arm_aapcscc' : /arm_aapcscc/
 'arm_apcscc' : /arm_apcscc/
@@ -219,14 +238,27 @@ int_type_tok : /i[0-9]+/
 'avr_intrcc' : /avr_intrcc/
 'avr_signalcc' : /avr_signalcc/
 'baseType:' : /baseType:/
+'bfloat' : /bfloat/
+'bit:' : /bit:/
+'bitMask:' : /bitMask:/
 'bitcast' : /bitcast/
 'blockaddress' : /blockaddress/
+'blockcount:' : /blockcount:/
 'br' : /br/
+'branchFunnel' : /branchFunnel/
 'builtin' : /builtin/
+'byArg:' : /byArg:/
+'byref' : /byref/
+'byte:' : /byte:/
+'byteArray' : /byteArray/
 'byval' : /byval/
 'c' : /c/
 'call' : /call/
+'callbr' : /callbr/
+'callee:' : /callee:/
 'caller' : /caller/
+'calls:' : /calls:/
+'canAutoHide:' : /canAutoHide:/
 'catch' : /catch/
 'catchpad' : /catchpad/
 'catchret' : /catchret/
@@ -247,10 +279,13 @@ int_type_tok : /i[0-9]+/
 'common' : /common/
 'configMacros:' : /configMacros:/
 'constant' : /constant/
+'constant:' : /constant:/
 'containingType:' : /containingType:/
 'contract' : /contract/
 'convergent' : /convergent/
+'coroSuspendIdx:' : /coroSuspendIdx:/
 'count:' : /count:/
+'critical' : /critical/
 'cxx_fast_tlscc' : /cxx_fast_tlscc/
 'datalayout' : /datalayout/
 'debugInfoForProfiling:' : /debugInfoForProfiling:/
@@ -266,11 +301,14 @@ int_type_tok : /i[0-9]+/
 'dllexport' : /dllexport/
 'dllimport' : /dllimport/
 'double' : /double/
+'dsoLocal:' : /dsoLocal:/
 'dso_local' : /dso_local/
+'dso_local_equivalent' : /dso_local_equivalent/
 'dso_preemptable' : /dso_preemptable/
 'dwarfAddressSpace:' : /dwarfAddressSpace:/
 'dwoId:' : /dwoId:/
 'elements:' : /elements:/
+'elementtype' : /elementtype/
 'emissionKind:' : /emissionKind:/
 'encoding:' : /encoding:/
 'entity:' : /entity:/
@@ -299,25 +337,35 @@ int_type_tok : /i[0-9]+/
 'flags:' : /flags:/
 'float' : /float/
 'fmul' : /fmul/
+'fneg' : /fneg/
 'fp128' : /fp128/
 'fpext' : /fpext/
 'fptosi' : /fptosi/
 'fptoui' : /fptoui/
 'fptrunc' : /fptrunc/
+'freeze' : /freeze/
 'frem' : /frem/
 'from' : /from/
 'fsub' : /fsub/
+'funcFlags:' : /funcFlags:/
+'function:' : /function:/
 'gc' : /gc/
 'getelementptr' : /getelementptr/
 'getter:' : /getter:/
 'ghccc' : /ghccc/
 'global' : /global/
 'globals:' : /globals:/
+'guid:' : /guid:/
+'gv:' : /gv:/
 'half' : /half/
+'hasUnknownCall:' : /hasUnknownCall:/
+'hash:' : /hash:/
 'header:' : /header:/
 'hhvm_ccc' : /hhvm_ccc/
 'hhvmcc' : /hhvmcc/
 'hidden' : /hidden/
+'hot' : /hot/
+'hotness:' : /hotness:/
 'icmp' : /icmp/
 'identifier:' : /identifier:/
 'ifunc' : /ifunc/
@@ -327,19 +375,25 @@ int_type_tok : /i[0-9]+/
 'inalloca' : /inalloca/
 'inbounds' : /inbounds/
 'includePath:' : /includePath:/
+'indir' : /indir/
 'indirectbr' : /indirectbr/
+'info:' : /info:/
 'initialexec' : /initialexec/
+'inline' : /inline/
+'inlineBits:' : /inlineBits:/
 'inlinedAt:' : /inlinedAt:/
 'inlinehint' : /inlinehint/
 'inrange' : /inrange/
 'inreg' : /inreg/
 'insertelement' : /insertelement/
 'insertvalue' : /insertvalue/
+'insts:' : /insts:/
 'intel_ocl_bicc' : /intel_ocl_bicc/
 'inteldialect' : /inteldialect/
 'internal' : /internal/
 'inttoptr' : /inttoptr/
 'invoke' : /invoke/
+'isArtificial:' : /isArtificial:/
 'isDefinition:' : /isDefinition:/
 'isImplicitCode:' : /isImplicitCode:/
 'isLocal:' : /isLocal:/
@@ -347,14 +401,17 @@ int_type_tok : /i[0-9]+/
 'isUnsigned:' : /isUnsigned:/
 'isysroot:' : /isysroot:/
 'jumptable' : /jumptable/
+'kind:' : /kind:/
 'label' : /label/
 'landingpad' : /landingpad/
 'language:' : /language:/
 'largest' : /largest/
 'line:' : /line:/
+'linkage:' : /linkage:/
 'linkageName:' : /linkageName:/
 'linkonce_odr' : /linkonce_odr/
 'linkonce' : /linkonce/
+'live:' : /live:/
 'load' : /load/
 'local_unnamed_addr' : /local_unnamed_addr/
 'localdynamic' : /localdynamic/
@@ -363,13 +420,16 @@ int_type_tok : /i[0-9]+/
 'lshr' : /lshr/
 'macros:' : /macros:/
 'max' : /max/
+'mayThrow:' : /mayThrow:/
 'metadata' : /metadata/
 'min' : /min/
 'minsize' : /minsize/
 'module' : /module/
+'module:' : /module:/
 'monotonic' : /monotonic/
 'msp430_intrcc' : /msp430_intrcc/
 'mul' : /mul/
+'mustBeUnreachable:' : /mustBeUnreachable:/
 'musttail' : /musttail/
 'naked' : /naked/
 'name:' : /name:/
@@ -379,6 +439,10 @@ int_type_tok : /i[0-9]+/
 'nest' : /nest/
 'ninf' : /ninf/
 'nnan' : /nnan/
+'noInline:' : /noInline:/
+'noRecurse:' : /noRecurse:/
+'noUnwind:' : /noUnwind:/
+'no_cfi' : /no_cfi/
 'noalias' : /noalias/
 'nobuiltin' : /nobuiltin/
 'nocapture' : /nocapture/
@@ -394,6 +458,7 @@ int_type_tok : /i[0-9]+/
 'norecurse' : /norecurse/
 'noredzone' : /noredzone/
 'noreturn' : /noreturn/
+'notEligibleToImport:' : /notEligibleToImport:/
 'notail' : /notail/
 'nounwind' : /nounwind/
 'nsw' : /nsw/
@@ -414,8 +479,10 @@ int_type_tok : /i[0-9]+/
 'optsize' : /optsize/
 'or' : /or/
 'ord' : /ord/
+'path:' : /path:/
 'personality' : /personality/
 'phi' : /phi/
+'poison' : /poison/
 'ppc_fp128' : /ppc_fp128/
 'prefix' : /prefix/
 'preserve_allcc' : /preserve_allcc/
@@ -424,17 +491,25 @@ int_type_tok : /i[0-9]+/
 'producer:' : /producer:/
 'prologue' : /prologue/
 'protected' : /protected/
+'ptr' : /ptr/
 'ptrtoint' : /ptrtoint/
 'ptx_device' : /ptx_device/
 'ptx_kernel' : /ptx_kernel/
+'readNone:' : /readNone:/
+'readOnly:' : /readOnly:/
 'readnone' : /readnone/
 'readonly' : /readonly/
+'readonly:' : /readonly:/
 'reassoc' : /reassoc/
+'refs:' : /refs:/
+'relbf:' : /relbf:/
 'release' : /release/
+'resByArg:' : /resByArg:/
 'resume' : /resume/
 'ret' : /ret/
 'retainedNodes:' : /retainedNodes:/
 'retainedTypes:' : /retainedTypes:/
+'returnDoesNotAlias:' : /returnDoesNotAlias:/
 'returned' : /returned/
 'returns_twice' : /returns_twice/
 'runtimeLang:' : /runtimeLang:/
@@ -460,13 +535,19 @@ int_type_tok : /i[0-9]+/
 'shufflevector' : /shufflevector/
 'sideeffect' : /sideeffect/
 'signext' : /signext/
+'single' : /single/
+'singleImpl' : /singleImpl/
+'singleImplName:' : /singleImplName:/
 'singlethread' : /singlethread/
 'sitofp' : /sitofp/
 'size:' : /size:/
+'sizeM1:' : /sizeM1:/
+'sizeM1BitWidth:' : /sizeM1BitWidth:/
 'sle' : /sle/
 'slt' : /slt/
 'source_filename' : /source_filename/
 'source:' : /source:/
+'spFlags:' : /spFlags:/
 'speculatable' : /speculatable/
 'speculative_load_hardening' : /speculative_load_hardening/
 'spir_func' : /spir_func/
@@ -480,7 +561,13 @@ int_type_tok : /i[0-9]+/
 'sspstrong' : /sspstrong/
 'store' : /store/
 'strictfp' : /strictfp/
+'stride:' : /stride:/
+'stringLength:' : /stringLength:/
+'stringLengthExpression:' : /stringLengthExpression:/
+'stringLocationExpression:' : /stringLocationExpression:/
 'sub' : /sub/
+'summaries:' : /summaries:/
+'summary:' : /summary:/
 'swiftcc' : /swiftcc/
 'swifterror' : /swifterror/
 'swiftself' : /swiftself/
@@ -488,6 +575,7 @@ int_type_tok : /i[0-9]+/
 'syncscope' : /syncscope/
 'tag:' : /tag:/
 'tail' : /tail/
+'tail:' : /tail:/
 'target' : /target/
 'templateParams:' : /templateParams:/
 'thisAdjustment:' : /thisAdjustment:/
@@ -500,6 +588,15 @@ int_type_tok : /i[0-9]+/
 'trunc' : /trunc/
 'type:' : /type:/
 'type' : /type/
+'typeCheckedLoadConstVCalls:' : /typeCheckedLoadConstVCalls:/
+'typeCheckedLoadVCalls:' : /typeCheckedLoadVCalls:/
+'typeIdInfo:' : /typeIdInfo:/
+'typeTestAssumeConstVCalls:' : /typeTestAssumeConstVCalls:/
+'typeTestAssumeVCalls:' : /typeTestAssumeVCalls:/
+'typeTestRes:' : /typeTestRes:/
+'typeTests:' : /typeTests:/
+'typeid:' : /typeid:/
+'typeidCompatibleVTable:' : /typeidCompatibleVTable:/
 'types:' : /types:/
 'udiv' : /udiv/
 'ueq' : /ueq/
@@ -512,32 +609,50 @@ int_type_tok : /i[0-9]+/
 'umin' : /umin/
 'undef' : /undef/
 'une' : /une/
+'uniformRetVal' : /uniformRetVal/
+'uniqueRetVal' : /uniqueRetVal/
 'unit:' : /unit:/
+'unknown' : /unknown/
 'unnamed_addr' : /unnamed_addr/
 'uno' : /uno/
 'unordered' : /unordered/
 'unreachable' : /unreachable/
+'unsat' : /unsat/
 'unwind' : /unwind/
+'upperBound:' : /upperBound:/
 'urem' : /urem/
 'uselistorder_bb' : /uselistorder_bb/
 'uselistorder' : /uselistorder/
 'uwtable' : /uwtable/
+'vFuncId:' : /vFuncId:/
+'vTableFuncs:' : /vTableFuncs:/
 'va_arg' : /va_arg/
 'value:' : /value:/
 'var:' : /var:/
+'varFlags:' : /varFlags:/
+'variable:' : /variable:/
+'vcall_visibility:' : /vcall_visibility:/
+'virtFunc:' : /virtFunc:/
+'virtualConstProp' : /virtualConstProp/
 'virtualIndex:' : /virtualIndex:/
 'virtuality:' : /virtuality:/
+'visibility:' : /visibility:/
 'void' : /void/
 'volatile' : /volatile/
+'vscale' : /vscale/
 'vtableHolder:' : /vtableHolder:/
 'weak_odr' : /weak_odr/
 'weak' : /weak/
 'webkit_jscc' : /webkit_jscc/
 'win64cc' : /win64cc/
 'within' : /within/
+'wpdRes:' : /wpdRes:/
+'wpdResolutions:' : /wpdResolutions:/
 'writeonly' : /writeonly/
+'writeonly:' : /writeonly:/
 'x' : /x/
 'x86_64_sysvcc' : /x86_64_sysvcc/
+'x86_amx' : /x86_amx/
 'x86_fastcallcc' : /x86_fastcallcc/
 'x86_fp80' : /x86_fp80/
 'x86_intrcc' : /x86_intrcc/
@@ -574,7 +689,18 @@ int_type_tok : /i[0-9]+/
 
 :: parser
 
-%input Module;
+# Module is the start symbol of LLVM IR assembly files. Type, TypeConst and
+# FuncDef are additional start symbols for parsing standalone snippets of LLVM
+# IR (e.g. `{ i32, [4 x i8]* }`).
+
+%input Module, Type, TypeConst, FuncDef, Instruction;
+
+# The address space following 'ptr' belongs to the opaque pointer type (e.g.
+# `ptr addrspace(1)`), rather than to a typed pointer type with `ptr` as element
+# type (e.g. `ptr addrspace(1)*`, which is invalid).
+
+%nonassoc 'ptr';
+%nonassoc 'addrspace';
 
 # === [ Identifiers ] ==========================================================
 
@@ -618,6 +744,12 @@ MetadataID -> MetadataID
 	: metadata_id_tok
 ;
 
+# --- [ Summary Identifiers ] --------------------------------------------------
+
+SummaryID -> SummaryID
+	: summary_id_tok
+;
+
 # === [ Literals ] =============================================================
 
 # --- [ Integer literals ] -----------------------------------------------------
@@ -687,6 +819,12 @@ TopLevelEntity -> TopLevelEntity
 	| MetadataDef
 	| UseListOrder
 	| UseListOrderBB
+	| ModuleEntry
+	| GVEntry
+	| TypeIDEntry
+	| TypeIDCompatibleVTableEntry
+	| FlagsEntry
+	| BlockCountEntry
 ;
 
 # ~~~ [ Source Filename ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -980,10 +1118,13 @@ MetadataNode -> MetadataNode
 # ref: ParseStandaloneMetadata
 #
 #   !42 = !{...}
+#   !42 = metadata !{...}        (legacy; pre LLVM 3.7)
 
 MetadataDef -> MetadataDef
 	: Name=MetadataID '=' Distinctopt MDNode=MDTuple
 	| Name=MetadataID '=' Distinctopt MDNode=SpecializedMDNode
+	# Legacy metadata definition.
+	| Name=MetadataID '=' MetadataType MDNode=MDTuple
 ;
 
 Distinct -> Distinct
@@ -1012,12 +1153,533 @@ UseListOrderBB -> UseListOrderBB
 	: 'uselistorder_bb' Func=GlobalIdent ',' Block=LocalIdent ',' '{' Indicies=(UintLit separator ',')+ '}'
 ;
 
+# ~~~ [ Summary Entries ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#thinlto-summary
+
+# ref: parseSummaryEntry
+#
+#   ::= SummaryID '=' GVEntry
+#   ::= SummaryID '=' ModuleEntry
+#   ::= SummaryID '=' TypeIdEntry
+#   ::= SummaryID '=' TypeIdCompatibleVtableEntry
+#   ::= SummaryID '=' 'flags' ':' UInt64
+#   ::= SummaryID '=' 'blockcount' ':' UInt64
+
+# ref: parseModuleEntry
+#
+#   ::= 'module' ':' '(' 'path' ':' STRINGCONSTANT ',' 'hash' ':' Hash ')'
+#  Hash ::= '(' UInt32 ',' UInt32 ',' UInt32 ',' UInt32 ',' UInt32 ')'
+
+ModuleEntry -> ModuleEntry
+	: Name=SummaryID '=' 'module:' '(' 'path:' Path=StringLit ',' 'hash:' '(' Hash=(UintLit separator ',')+ ')' ')'
+;
+
+# ref: parseGVEntry
+#
+#   ::= 'gv' ':' '(' 'name' ':' STRINGCONSTANT
+#         [',' 'summaries' ':' Summary[',' Summary]* ]? ')'
+#   ::= 'gv' ':' '(' 'guid' ':' UInt64
+#         [',' 'summaries' ':' Summary[',' Summary]* ]? ')'
+
+GVEntry -> GVEntry
+	: Name=SummaryID '=' 'gv:' '(' Ident=GVIdent ')'
+	| Name=SummaryID '=' 'gv:' '(' Ident=GVIdent ',' 'summaries:' '(' Summaries=(GVSummary separator ',')+ ')' ')'
+;
+
+%interface GVIdent;
+
+GVIdent -> GVIdent
+	: GVName
+	| GUID
+;
+
+GVName -> GVName
+	: 'name:' Name=StringLit
+;
+
+GUID -> GUID
+	: 'guid:' GUID=UintLit
+;
+
+%interface GVSummary;
+
+GVSummary -> GVSummary
+	: FunctionSummary
+	| VariableSummary
+	| AliasSummary
+;
+
+# ref: parseFunctionSummary
+#
+#   ::= 'function' ':' '(' 'module' ':' ModuleReference ',' GVFlags
+#         ',' 'insts' ':' UInt32 [',' OptionalFFlags]? [',' OptionalCalls]?
+#         [',' OptionalTypeIdInfo]? [',' OptionalParamAccesses]?
+#         [',' OptionalRefs]? ')'
+#
+# TODO: add support for params (OptionalParamAccesses).
+
+FunctionSummary -> FunctionSummary
+	: 'function:' '(' Fields=(FunctionSummaryField separator ',')* ')'
+;
+
+%interface FunctionSummaryField;
+
+FunctionSummaryField -> FunctionSummaryField
+	: ModuleRefField
+	| GVFlagsField
+	| InstsField
+	| FuncFlagsField
+	| CallsField
+	| TypeIDInfoField
+	| RefsField
+;
+
+# ref: parseVariableSummary
+#
+#   ::= 'variable' ':' '(' 'module' ':' ModuleReference ',' GVFlags
+#         [',' OptionalRefs]? ')'
+
+VariableSummary -> VariableSummary
+	: 'variable:' '(' Fields=(VariableSummaryField separator ',')* ')'
+;
+
+%interface VariableSummaryField;
+
+VariableSummaryField -> VariableSummaryField
+	: ModuleRefField
+	| GVFlagsField
+	| VarFlagsField
+	| VTableFuncsField
+	| RefsField
+;
+
+# ref: parseAliasSummary
+#
+#   ::= 'alias' ':' '(' 'module' ':' ModuleReference ',' GVFlags ','
+#         'aliasee' ':' GVReference ')'
+
+AliasSummary -> AliasSummary
+	: 'alias:' '(' Fields=(AliasSummaryField separator ',')* ')'
+;
+
+%interface AliasSummaryField;
+
+AliasSummaryField -> AliasSummaryField
+	: ModuleRefField
+	| GVFlagsField
+	| AliaseeField
+;
+
+# ref: parseTypeIdEntry
+#
+#   ::= 'typeid' ':' '(' 'name' ':' STRINGCONSTANT ',' TypeIdSummary ')'
+#  TypeIdSummary
+#   ::= 'summary' ':' '(' TypeTestResolution [',' OptionalWpdResolutions]? ')'
+
+TypeIDEntry -> TypeIDEntry
+	: Name=SummaryID '=' 'typeid:' '(' 'name:' TypeName=StringLit ',' 'summary:' '(' TypeTestRes=TypeTestRes ')' ')'
+	| Name=SummaryID '=' 'typeid:' '(' 'name:' TypeName=StringLit ',' 'summary:' '(' TypeTestRes=TypeTestRes ',' 'wpdResolutions:' '(' WPDResolutions=(WPDResolution separator ',')+ ')' ')' ')'
+;
+
+# ref: parseTypeTestResolution
+#
+#   ::= 'typeTestRes' ':' '(' 'kind' ':'
+#         ( 'unsat' | 'byteArray' | 'inline' | 'single' | 'allOnes' ) ','
+#         'sizeM1BitWidth' ':' SizeM1BitWidth [',' 'alignLog2' ':' UInt64]?
+#         [',' 'sizeM1' ':' UInt64]? [',' 'bitMask' ':' UInt8]?
+#         [',' 'inlinesBits' ':' UInt64]? ')'
+
+TypeTestRes -> TypeTestRes
+	: 'typeTestRes:' '(' Fields=(TypeTestResField separator ',')* ')'
+;
+
+%interface TypeTestResField;
+
+TypeTestResField -> TypeTestResField
+	: TypeTestResKindField
+	| SummaryIntField
+;
+
+TypeTestResKindField -> TypeTestResKindField
+	: 'kind:' Kind=TypeTestResKind
+;
+
+TypeTestResKind -> TypeTestResKind
+	: 'allOnes'
+	| 'byteArray'
+	| 'inline'
+	| 'single'
+	| 'unknown'
+	| 'unsat'
+;
+
+# ref: parseOptionalWpdResolutions
+#
+#   ::= 'wpdResolutions' ':' '(' WpdResolution [',' WpdResolution]* ')'
+#  WpdResolution ::= '(' 'offset' ':' UInt64 ',' WpdRes ')'
+#
+# ref: parseWpdRes
+#
+#   ::= 'wpdRes' ':' '(' 'kind' ':' 'indir'
+#         [',' OptionalResByArg]? ')'
+#   ::= 'wpdRes' ':' '(' 'kind' ':' 'singleImpl'
+#         ',' 'singleImplName' ':' STRINGCONSTANT ','
+#         [',' OptionalResByArg]? ')'
+#   ::= 'wpdRes' ':' '(' 'kind' ':' 'branchFunnel'
+#         [',' OptionalResByArg]? ')'
+
+WPDResolution -> WPDResolution
+	: '(' 'offset:' Offset=UintLit ',' 'wpdRes:' '(' Fields=(WPDResField separator ',')* ')' ')'
+;
+
+%interface WPDResField;
+
+WPDResField -> WPDResField
+	: WPDResKindField
+	| SingleImplNameField
+	| ResByArgField
+;
+
+WPDResKindField -> WPDResKindField
+	: 'kind:' Kind=WPDResKind
+;
+
+WPDResKind -> WPDResKind
+	: 'branchFunnel'
+	| 'indir'
+	| 'singleImpl'
+;
+
+SingleImplNameField -> SingleImplNameField
+	: 'singleImplName:' Name=StringLit
+;
+
+# ref: parseOptionalResByArg
+#
+#   ::= 'resByArg' ':' '(' ResByArg [',' ResByArg]* ')'
+#  ResByArg ::= Args ',' 'byArg' ':' '(' 'kind' ':'
+#                 ( 'indir' | 'uniformRetVal' | 'UniqueRetVal' |
+#                   'virtualConstProp' )
+#                 [',' 'info' ':' UInt64]? [',' 'byte' ':' UInt32]?
+#                 [',' 'bit' ':' UInt32]? ')'
+
+ResByArgField -> ResByArgField
+	: 'resByArg:' '(' ResByArgs=(ResByArg separator ',')* ')'
+;
+
+ResByArg -> ResByArg
+	: 'args:' '(' Args=(UintLit separator ',')* ')' ',' 'byArg:' '(' Fields=(ByArgField separator ',')* ')'
+;
+
+%interface ByArgField;
+
+ByArgField -> ByArgField
+	: ByArgKindField
+	| SummaryIntField
+;
+
+ByArgKindField -> ByArgKindField
+	: 'kind:' Kind=ByArgKind
+;
+
+ByArgKind -> ByArgKind
+	: 'indir'
+	| 'uniformRetVal'
+	| 'uniqueRetVal'
+	| 'virtualConstProp'
+;
+
+# ref: parseTypeIdCompatibleVtableEntry
+#
+#   ::= 'typeidCompatibleVTable' ':' '(' 'name' ':' STRINGCONSTANT ','
+#   'summary' ':' '(' VTableFuncs? ')' ')'
+
+TypeIDCompatibleVTableEntry -> TypeIDCompatibleVTableEntry
+	: Name=SummaryID '=' 'typeidCompatibleVTable:' '(' 'name:' TypeName=StringLit ',' 'summary:' '(' VTables=(CompatibleVTable separator ',')* ')' ')'
+;
+
+CompatibleVTable -> CompatibleVTable
+	: '(' 'offset:' Offset=UintLit ',' VTable=SummaryID ')'
+;
+
+FlagsEntry -> FlagsEntry
+	: Name=SummaryID '=' 'flags:' Flags=UintLit
+;
+
+BlockCountEntry -> BlockCountEntry
+	: Name=SummaryID '=' 'blockcount:' Count=UintLit
+;
+
+# ___ [ Summary fields ] _______________________________________________________
+
+# ref: parseModuleReference
+#
+#   ::= 'module' ':' UInt
+
+ModuleRefField -> ModuleRefField
+	: 'module:' Module=SummaryID
+;
+
+# ref: parseGVFlags
+#
+#   ::= 'flags' ':' '(' 'linkage' ':' OptionalLinkageAux ','
+#         'notEligibleToImport' ':' Flag ',' 'live' ':' Flag ','
+#         'dsoLocal' ':' Flag ',' 'canAutoHide' ':' Flag ')'
+
+GVFlagsField -> GVFlagsField
+	: 'flags:' '(' Fields=(GVFlagField separator ',')* ')'
+;
+
+%interface GVFlagField;
+
+GVFlagField -> GVFlagField
+	: SummaryLinkageField
+	| SummaryVisibilityField
+	| SummaryIntField
+;
+
+SummaryLinkageField -> SummaryLinkageField
+	: 'linkage:' Linkage=SummaryLinkage
+;
+
+%interface SummaryLinkage;
+
+SummaryLinkage -> SummaryLinkage
+	: Linkage
+	| ExternLinkage
+;
+
+SummaryVisibilityField -> SummaryVisibilityField
+	: 'visibility:' Visibility=Visibility
+;
+
+InstsField -> InstsField
+	: 'insts:' Insts=UintLit
+;
+
+# ref: parseOptionalFFlags
+#
+#   ::= 'funcFlags' ':' '(' ['readNone' ':' Flag]?
+#         [',' 'readOnly' ':' Flag]? [',' 'noRecurse' ':' Flag]?
+#         [',' 'returnDoesNotAlias' ':' Flag]? ')'
+#         [',' 'noInline' ':' Flag]? ')'
+#         [',' 'alwaysInline' ':' Flag]? ')'
+
+FuncFlagsField -> FuncFlagsField
+	: 'funcFlags:' '(' Fields=(SummaryIntField separator ',')* ')'
+;
+
+# ref: parseOptionalCalls
+#
+#   ::= 'calls' ':' '(' Call [',' Call]* ')'
+#  Call ::= '(' 'callee' ':' GVReference
+#             [( ',' 'hotness' ':' Hotness | ',' 'relbf' ':' UInt32 )]? ')'
+
+CallsField -> CallsField
+	: 'calls:' '(' Calls=(Call separator ',')* ')'
+;
+
+Call -> Call
+	: '(' Fields=(CallField separator ',')* ')'
+;
+
+%interface CallField;
+
+CallField -> CallField
+	: CalleeField
+	| HotnessField
+	| SummaryIntField
+;
+
+CalleeField -> CalleeField
+	: 'callee:' Callee=SummaryID
+;
+
+HotnessField -> HotnessField
+	: 'hotness:' Hotness=Hotness
+;
+
+Hotness -> Hotness
+	: 'cold'
+	| 'critical'
+	| 'hot'
+	| 'none'
+	| 'unknown'
+;
+
+# ref: parseOptionalTypeIdInfo
+#
+#   ::= 'typeIdInfo' ':' '(' [',' TypeTests]? [',' TypeTestAssumeVCalls]?
+#         [',' TypeCheckedLoadVCalls]? [',' TypeTestAssumeConstVCalls]?
+#         [',' TypeCheckedLoadConstVCalls]? ')'
+
+TypeIDInfoField -> TypeIDInfoField
+	: 'typeIdInfo:' '(' Fields=(TypeIDInfoEntry separator ',')* ')'
+;
+
+%interface TypeIDInfoEntry;
+
+TypeIDInfoEntry -> TypeIDInfoEntry
+	: TypeTestsField
+	| VFuncIDsField
+	| ConstVCallsField
+;
+
+# ref: parseTypeTests
+#
+#   ::= 'typeTests' ':' '(' (SummaryID | UInt64)
+#         [',' (SummaryID | UInt64)]* ')'
+
+TypeTestsField -> TypeTestsField
+	: 'typeTests:' '(' TypeIDs=(TypeIDRef separator ',')* ')'
+;
+
+%interface TypeIDRef;
+
+TypeIDRef -> TypeIDRef
+	: SummaryID
+	| UintLit
+;
+
+# ref: parseVFuncIdList
+#
+#   ::= Kind ':' '(' VFuncId [',' VFuncId]* ')'
+
+VFuncIDsField -> VFuncIDsField
+	: Kind=VFuncIDsKind '(' VFuncIDs=(VFuncID separator ',')* ')'
+;
+
+VFuncIDsKind -> VFuncIDsKind
+	: 'typeCheckedLoadVCalls:'
+	| 'typeTestAssumeVCalls:'
+;
+
+# ref: parseConstVCallList
+#
+#   ::= Kind ':' '(' ConstVCall [',' ConstVCall]* ')'
+#  ConstVCall ::= '(' VFuncId ',' Args ')'
+
+ConstVCallsField -> ConstVCallsField
+	: Kind=ConstVCallsKind '(' ConstVCalls=(ConstVCall separator ',')* ')'
+;
+
+ConstVCallsKind -> ConstVCallsKind
+	: 'typeCheckedLoadConstVCalls:'
+	| 'typeTestAssumeConstVCalls:'
+;
+
+ConstVCall -> ConstVCall
+	: '(' VFuncID=VFuncID ')'
+	| '(' VFuncID=VFuncID ',' 'args:' '(' Args=(UintLit separator ',')* ')' ')'
+;
+
+# ref: parseVFuncId
+#
+#   ::= 'vFuncId' ':' '(' (SummaryID | 'guid' ':' UInt64) ','
+#         'offset' ':' UInt64 ')'
+
+VFuncID -> VFuncID
+	: 'vFuncId:' '(' TypeID=SummaryID ',' 'offset:' Offset=UintLit ')'
+	| 'vFuncId:' '(' GUID=GUID ',' 'offset:' Offset=UintLit ')'
+;
+
+# ref: parseOptionalRefs
+#
+#   ::= 'refs' ':' '(' GVReference [',' GVReference]* ')'
+#  GVReference ::= ('readonly' | 'writeonly')? SummaryID
+
+RefsField -> RefsField
+	: 'refs:' '(' Refs=(Ref separator ',')* ')'
+;
+
+Ref -> Ref
+	: Access=RefAccessopt ID=SummaryID
+;
+
+RefAccess -> RefAccess
+	: 'readonly'
+	| 'writeonly'
+;
+
+# ref: parseGVarFlags
+#
+#   ::= 'varFlags' ':' '(' 'readonly' ':' Flag
+#                      ',' 'writeonly' ':' Flag
+#                      ',' 'constant' ':' Flag ')'
+
+VarFlagsField -> VarFlagsField
+	: 'varFlags:' '(' Fields=(SummaryIntField separator ',')* ')'
+;
+
+# ref: parseOptionalVTableFuncs
+#
+#   ::= 'vTableFuncs' ':' '(' VTableFunc [',' VTableFunc]* ')'
+#  VTableFunc ::= '(' 'virtFunc' ':' GVReference ',' 'offset' ':' UInt64 ')'
+
+VTableFuncsField -> VTableFuncsField
+	: 'vTableFuncs:' '(' VTableFuncs=(VTableFunc separator ',')* ')'
+;
+
+VTableFunc -> VTableFunc
+	: '(' 'virtFunc:' Func=SummaryID ',' 'offset:' Offset=UintLit ')'
+;
+
+AliaseeField -> AliaseeField
+	: 'aliasee:' Aliasee=SummaryID
+;
+
+# SummaryIntField is an integer field (or flag) of a summary entry, for which
+# the set of valid field names depends on the enclosing entry.
+
+SummaryIntField -> SummaryIntField
+	: Name=SummaryIntFieldName Val=UintLit
+;
+
+SummaryIntFieldName -> SummaryIntFieldName
+	# GVFlags
+	: 'canAutoHide:'
+	| 'dsoLocal:'
+	| 'live:'
+	| 'notEligibleToImport:'
+	# FFlags
+	| 'alwaysInline:'
+	| 'hasUnknownCall:'
+	| 'mayThrow:'
+	| 'mustBeUnreachable:'
+	| 'noInline:'
+	| 'noRecurse:'
+	| 'noUnwind:'
+	| 'readNone:'
+	| 'readOnly:'
+	| 'returnDoesNotAlias:'
+	# GVarFlags
+	| 'constant:'
+	| 'readonly:'
+	| 'vcall_visibility:'
+	| 'writeonly:'
+	# Calls
+	| 'relbf:'
+	| 'tail:'
+	# TypeTestResolution
+	| 'alignLog2:'
+	| 'bitMask:'
+	| 'inlineBits:'
+	| 'sizeM1:'
+	| 'sizeM1BitWidth:'
+	# ResByArg
+	| 'bit:'
+	| 'byte:'
+	| 'info:'
+;
+
 # === [ Types ] ================================================================
 
 # ref: ParseType
 #
 #  TYPEKEYWORD("void",      Type::getVoidTy(Context));
 #  TYPEKEYWORD("half",      Type::getHalfTy(Context));
+#  TYPEKEYWORD("bfloat",    Type::getBFloatTy(Context));
 #  TYPEKEYWORD("float",     Type::getFloatTy(Context));
 #  TYPEKEYWORD("double",    Type::getDoubleTy(Context));
 #  TYPEKEYWORD("x86_fp80",  Type::getX86_FP80Ty(Context));
@@ -1026,7 +1688,9 @@ UseListOrderBB -> UseListOrderBB
 #  TYPEKEYWORD("label",     Type::getLabelTy(Context));
 #  TYPEKEYWORD("metadata",  Type::getMetadataTy(Context));
 #  TYPEKEYWORD("x86_mmx",   Type::getX86_MMXTy(Context));
+#  TYPEKEYWORD("x86_amx",   Type::getX86_AMXTy(Context));
 #  TYPEKEYWORD("token",     Type::getTokenTy(Context));
+#  TYPEKEYWORD("ptr",       PointerType::getUnqual(Context));
 
 %interface Type;
 
@@ -1052,6 +1716,9 @@ ConcreteType -> ConcreteType
 	# Type ::= Type '*'
 	# Type ::= Type 'addrspace' '(' uint32 ')' '*'
 	| PointerType
+	# Type ::= 'ptr'
+	# Type ::= 'ptr' 'addrspace' '(' uint32 ')'
+	| OpaquePointerType
 	# Type ::= '<' ... '>'
 	| VectorType
 	| LabelType
@@ -1063,7 +1730,10 @@ ConcreteType -> ConcreteType
 	# Type ::= %4
 	| NamedType
 	| MMXType
+	| AMXType
 	| TokenType
+	# Type ::= 'target' '(' ... ')'
+	| TargetExtType
 ;
 
 # --- [ Void Types ] -----------------------------------------------------------
@@ -1096,6 +1766,7 @@ FloatType -> FloatType
 
 FloatKind -> FloatKind
 	: 'half'
+	| 'bfloat'
 	| 'float'
 	| 'double'
 	| 'x86_fp80'
@@ -1109,20 +1780,49 @@ MMXType -> MMXType
 	: 'x86_mmx'
 ;
 
+# --- [ AMX Types ] ------------------------------------------------------------
+
+AMXType -> AMXType
+	: 'x86_amx'
+;
+
 # --- [ Pointer Types ] --------------------------------------------------------
 
 PointerType -> PointerType
 	: Elem=Type AddrSpaceopt '*'
 ;
 
+# Opaque pointer types carry no element type; the element type of memory
+# accesses is given explicitly by each instruction (e.g. load, getelementptr and
+# call).
+#
+#    ptr
+#    ptr addrspace(1)
+
+OpaquePointerType -> OpaquePointerType
+	: 'ptr'
+	| 'ptr' AddrSpace
+;
+
 # --- [ Vector Types ] ---------------------------------------------------------
 
 # ref: ParseArrayVectorType
 #
 #     ::= '<' APSINTVAL 'x' Types '>'
+#     ::= '<' 'vscale' 'x' APSINTVAL 'x' Types '>'
+
+# The number of elements of scalable vector types is an unknown multiple of
+# the vector length.
+#
+#    <4 x i32>
+#    <vscale x 4 x i32>
 
 VectorType -> VectorType
-	: '<' Len=UintLit 'x' Elem=Type '>'
+	: '<' Scalableopt Len=UintLit 'x' Elem=Type '>'
+;
+
+Scalable -> Scalable
+	: 'vscale' 'x'
 ;
 
 # --- [ Label Types ] ----------------------------------------------------------
@@ -1137,6 +1837,30 @@ TokenType -> TokenType
 	: 'token'
 ;
 
+# --- [ Target Extension Types ] -----------------------------------------------
+
+# ref: parseTargetExtType
+#
+#   ::= 'target' '(' STRINGCONSTANT TargetExtTypeParams TargetExtIntParams ')'
+#
+#   TargetExtTypeParams ::= /*empty*/ | ',' Type TargetExtTypeParams
+#   TargetExtIntParams ::= /*empty*/ | ',' uint32 TargetExtIntParams
+#
+# NOTE: the type parameters and integer parameters are parsed as a single list
+# to resolve the shift/reduce conflict on ','. The type parameters are required
+# to precede the integer parameters; which is validated during translation.
+
+TargetExtType -> TargetExtType
+	: 'target' '(' Name=StringLit Params=(',' TargetExtParam)* ')'
+;
+
+%interface TargetExtParam;
+
+TargetExtParam -> TargetExtParam
+	: Type
+	| UintLit
+;
+
 # --- [ Metadata Types ] -------------------------------------------------------
 
 MetadataType -> MetadataType
@@ -1242,7 +1966,10 @@ Constant -> Constant
 	# @foo
 	| GlobalIdent
 	| UndefConst
+	| PoisonConst
 	| BlockAddressConst
+	| DSOLocalEquivalentConst
+	| NoCFIConst
 	| ConstantExpr
 ;
 
@@ -1355,6 +2082,16 @@ UndefConst -> UndefConst
 	: 'undef'
 ;
 
+# --- [ Poison Values ] --------------------------------------------------------
+
+# https://llvm.org/docs/LangRef.html#poison-values
+
+# ref: ParseValID
+
+PoisonConst -> PoisonConst
+	: 'poison'
+;
+
 # --- [ Addresses of Basic Blocks ] --------------------------------------------
 
 # https://llvm.org/docs/LangRef.html#addresses-of-basic-blocks
@@ -1367,6 +2104,30 @@ BlockAddressConst -> BlockAddressConst
 	: 'blockaddress' '(' Func=GlobalIdent ',' Block=LocalIdent ')'
 ;
 
+# --- [ DSO Local Equivalent ] -------------------------------------------------
+
+# https://llvm.org/docs/LangRef.html#dso-local-equivalent
+
+# ref: ParseValID
+#
+#  ::= 'dso_local_equivalent' @foo
+
+DSOLocalEquivalentConst -> DSOLocalEquivalentConst
+	: 'dso_local_equivalent' Func=GlobalIdent
+;
+
+# --- [ No CFI ] ---------------------------------------------------------------
+
+# https://llvm.org/docs/LangRef.html#no-cfi
+
+# ref: ParseValID
+#
+#  ::= 'no_cfi' @foo
+
+NoCFIConst -> NoCFIConst
+	: 'no_cfi' Func=GlobalIdent
+;
+
 # === [ Constant expressions ] =================================================
 
 # https://llvm.org/docs/LangRef.html#constant-expressions
@@ -1635,6 +2396,8 @@ InsertValueExpr -> InsertValueExpr
 
 GetElementPtrExpr -> GetElementPtrExpr
 	: 'getelementptr' InBoundsopt '(' ElemType=Type ',' Src=TypeConst Indices=(',' GEPIndex)* ')'
+	# Legacy getelementptr expression with implicit element type.
+	| 'getelementptr' InBoundsopt '(' Src=TypeConst Indices=(',' GEPIndex)* ')'
 ;
 
 # ref: ParseGlobalValueVector
@@ -1820,8 +2583,10 @@ LocalDefInst -> LocalDefInst
 %interface ValueInstruction;
 
 ValueInstruction -> ValueInstruction
+	# Unary instructions
+	: FNegInst
 	# Binary instructions
-	: AddInst
+	| AddInst
 	| FAddInst
 	| SubInst
 	| FSubInst
@@ -1872,6 +2637,7 @@ ValueInstruction -> ValueInstruction
 	| FCmpInst
 	| PhiInst
 	| SelectInst
+	| FreezeInst
 	| CallInst
 	| VAArgInst
 	| LandingPadInst
@@ -1879,6 +2645,20 @@ ValueInstruction -> ValueInstruction
 	| CleanupPadInst
 ;
 
+# --- [ Unary instructions ] ---------------------------------------------------
+
+# ~~~ [ fneg ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#fneg-instruction
+
+# ref: ParseUnaryOp
+#
+#  ::= UnaryOp TypeAndValue
+
+FNegInst -> FNegInst
+	: 'fneg' FastMathFlags=FastMathFlag* X=TypeValue Metadata=(',' MetadataAttachment)+?
+;
+
 # --- [ Binary instructions ] --------------------------------------------------
 
 # ~~~ [ add ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -2205,6 +2985,10 @@ LoadInst -> LoadInst
 	: 'load' Volatileopt ElemType=Type ',' Src=TypeValue (',' Alignment)? Metadata=(',' MetadataAttachment)+?
 	# Atomic load.
 	| 'load' Atomic Volatileopt ElemType=Type ',' Src=TypeValue SyncScopeopt AtomicOrdering (',' Alignment)? Metadata=(',' MetadataAttachment)+?
+	# Legacy load with implicit element type.
+	| 'load' Volatileopt Src=TypeValue (',' Alignment)? Metadata=(',' MetadataAttachment)+?
+	# Legacy atomic load with implicit element type.
+	| 'load' Atomic Volatileopt Src=TypeValue SyncScopeopt AtomicOrdering (',' Alignment)? Metadata=(',' MetadataAttachment)+?
 ;
 
 # ~~~ [ store ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -2288,6 +3072,8 @@ AtomicOp -> AtomicOp
 
 GetElementPtrInst -> GetElementPtrInst
 	: 'getelementptr' InBoundsopt ElemType=Type ',' Src=TypeValue Indices=(',' TypeValue)* Metadata=(',' MetadataAttachment)+?
+	# Legacy getelementptr with implicit element type.
+	| 'getelementptr' InBoundsopt Src=TypeValue Indices=(',' TypeValue)* Metadata=(',' MetadataAttachment)+?
 ;
 
 # --- [ Conversion instructions ] ----------------------------------------------
@@ -2502,6 +3288,18 @@ SelectInst -> SelectInst
 	: 'select' Cond=TypeValue ',' X=TypeValue ',' Y=TypeValue Metadata=(',' MetadataAttachment)+?
 ;
 
+# ~~~ [ freeze ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#freeze-instruction
+
+# ref: ParseFreeze
+#
+#   ::= 'freeze' Type Value
+
+FreezeInst -> FreezeInst
+	: 'freeze' X=TypeValue Metadata=(',' MetadataAttachment)+?
+;
+
 # ~~~ [ call ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 # https://llvm.org/docs/LangRef.html#call-instruction
@@ -2637,6 +3435,7 @@ LocalDefTerm -> LocalDefTerm
 
 ValueTerminator -> ValueTerminator
 	: InvokeTerm
+	| CallBrTerm
 	| CatchSwitchTerm
 ;
 
@@ -2721,6 +3520,20 @@ InvokeTerm -> InvokeTerm
 	: 'invoke' CallingConvopt ReturnAttrs=ReturnAttr* AddrSpaceopt Typ=Type Invokee=Value '(' Args ')' FuncAttrs=FuncAttr* OperandBundles=('[' (OperandBundle separator ',')+ ']')? 'to' Normal=Label 'unwind' Exception=Label Metadata=(',' MetadataAttachment)+?
 ;
 
+# --- [ callbr ] ---------------------------------------------------------------
+
+# https://llvm.org/docs/LangRef.html#callbr-instruction
+
+# ref: ParseCallBr
+#
+#   ::= 'callbr' OptionalCallingConv OptionalAttrs Type Value ParamList
+#       OptionalAttrs OptionalOperandBundles 'to' TypeAndValue
+#       '[' LabelList ']'
+
+CallBrTerm -> CallBrTerm
+	: 'callbr' CallingConvopt ReturnAttrs=ReturnAttr* AddrSpaceopt Typ=Type Callee=Value '(' Args ')' FuncAttrs=FuncAttr* OperandBundles=('[' (OperandBundle separator ',')+ ']')? 'to' Normal=Label '[' Others=(Label separator ',')* ']' Metadata=(',' MetadataAttachment)+?
+;
+
 # --- [ resume ] ---------------------------------------------------------------
 
 # https://llvm.org/docs/LangRef.html#resume-instruction
@@ -2811,6 +3624,24 @@ MDField -> MDField
 	# Null is a special case since it is typeless.
 	: NullLit
 	| Metadata
+	| LegacyMDField
+;
+
+# Metadata fields of the legacy dialect (pre LLVM 3.7) are prefixed by the
+# metadata type.
+#
+#   !{metadata !"foo", metadata !{...}, metadata !42}
+
+LegacyMDField -> LegacyMDField
+	: MetadataType Val=LegacyMetadata
+;
+
+%interface LegacyMetadata;
+
+LegacyMetadata -> LegacyMetadata
+	: MDString
+	| MDTuple
+	| MetadataID
 ;
 
 # --- [ Metadata ] -------------------------------------------------------------
@@ -2884,13 +3715,17 @@ MDNode -> MDNode
 %interface SpecializedMDNode;
 
 SpecializedMDNode -> SpecializedMDNode
-	: DIBasicType
+	: DIArgList
+	| DIAssignID
+	| DIBasicType
+	| DICommonBlock
 	| DICompileUnit
 	| DICompositeType
 	| DIDerivedType
 	| DIEnumerator
 	| DIExpression
 	| DIFile
+	| DIGenericSubrange
 	| DIGlobalVariable
 	| DIGlobalVariableExpression # not in spec as of 2018-02-21
 	| DIImportedEntity
@@ -2904,6 +3739,7 @@ SpecializedMDNode -> SpecializedMDNode
 	| DIModule # not in spec as of 2018-02-21
 	| DINamespace
 	| DIObjCProperty
+	| DIStringType
 	| DISubprogram
 	| DISubrange
 	| DISubroutineType
@@ -2912,6 +3748,30 @@ SpecializedMDNode -> SpecializedMDNode
 	| GenericDINode # not in spec as of 2018-02-21
 ;
 
+# ~~~ [ DIArgList ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#diarglist
+
+# ref: parseDIArgList
+#
+#   ::= !DIArgList(i32 7, i64 %0)
+
+DIArgList -> DIArgList
+	: '!DIArgList' '(' Fields=(TypeValue separator ',')* ')'
+;
+
+# ~~~ [ DIAssignID ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#diassignid
+
+# ref: parseDIAssignID
+#
+#   ::= distinct !DIAssignID()
+
+DIAssignID -> DIAssignID
+	: '!DIAssignID' '(' ')'
+;
+
 # ~~~ [ DIBasicType ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 # https://llvm.org/docs/LangRef.html#dibasictype
@@ -2943,6 +3803,35 @@ DIBasicTypeField -> DIBasicTypeField
 	| FlagsField
 ;
 
+# ~~~ [ DICommonBlock ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# TODO: add link to LangRef.html.
+
+# ref: parseDICommonBlock
+#
+#   ::= !DICommonBlock(scope: !0, decl: !1, name: "COMMON name", file: !2,
+#                      line: 9)
+#
+#  REQUIRED(scope, MDField, );
+#  OPTIONAL(declaration, MDField, );
+#  OPTIONAL(name, MDStringField, );
+#  OPTIONAL(file, MDField, );
+#  OPTIONAL(line, LineField, );
+
+DICommonBlock -> DICommonBlock
+	: '!DICommonBlock' '(' Fields=(DICommonBlockField separator ',')* ')'
+;
+
+%interface DICommonBlockField;
+
+DICommonBlockField -> DICommonBlockField
+	: ScopeField
+	| DeclarationField
+	| NameField
+	| FileField
+	| LineField
+;
+
 # ~~~ [ DICompileUnit ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 # https://llvm.org/docs/LangRef.html#dicompileunit
@@ -3167,6 +4056,33 @@ DIFileField -> DIFileField
 	| SourceField
 ;
 
+# ~~~ [ DIGenericSubrange ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# https://llvm.org/docs/LangRef.html#digenericsubrange
+
+# ref: parseDIGenericSubrange
+#
+#   ::= !DIGenericSubrange(lowerBound: !node1, upperBound: !node2, stride:
+#   !node3)
+#
+#  OPTIONAL(count, MDSignedOrMDField, );
+#  OPTIONAL(lowerBound, MDSignedOrMDField, );
+#  OPTIONAL(upperBound, MDSignedOrMDField, );
+#  OPTIONAL(stride, MDSignedOrMDField, );
+
+DIGenericSubrange -> DIGenericSubrange
+	: '!DIGenericSubrange' '(' Fields=(DIGenericSubrangeField separator ',')* ')'
+;
+
+%interface DIGenericSubrangeField;
+
+DIGenericSubrangeField -> DIGenericSubrangeField
+	: CountField
+	| LowerBoundField
+	| UpperBoundField
+	| StrideField
+;
+
 # ~~~ [ DIGlobalVariable ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 # https://llvm.org/docs/LangRef.html#diglobalvariable
@@ -3269,12 +4185,16 @@ DIImportedEntityField -> DIImportedEntityField
 
 # ref: ParseDILabel:
 #
-#   ::= !DILabel(scope: !0, name: "foo", file: !1, line: 7)
+#   ::= !DILabel(scope: !0, name: "foo", file: !1, line: 7, column: 4,
+#                isArtificial: true, coroSuspendIdx: 3)
 #
 #  REQUIRED(scope, MDField, (/* AllowNull */ false));                           \
 #  REQUIRED(name, MDStringField, );                                             \
 #  REQUIRED(file, MDField, );                                                   \
-#  REQUIRED(line, LineField, );
+#  REQUIRED(line, LineField, );                                                 \
+#  OPTIONAL(column, ColumnField, );                                             \
+#  OPTIONAL(isArtificial, MDBoolField, );                                       \
+#  OPTIONAL(coroSuspendIdx, MDUnsignedField, );
 
 DILabel -> DILabel
 	: '!DILabel' '(' Fields=(DILabelField separator ',')* ')'
@@ -3287,6 +4207,9 @@ DILabelField -> DILabelField
 	| NameField
 	| FileField
 	| LineField
+	| ColumnField
+	| IsArtificialField
+	| CoroSuspendIdxField
 ;
 
 # ~~~ [ DILexicalBlock ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -3545,6 +4468,40 @@ DIObjCPropertyField -> DIObjCPropertyField
 	| TypeField
 ;
 
+# ~~~ [ DIStringType ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+
+# TODO: add link to LangRef.html.
+
+# ref: parseDIStringType
+#
+#   ::= !DIStringType(name: "character(4)", size: 32, align: 32)
+#
+#  OPTIONAL(tag, DwarfTagField, (dwarf::DW_TAG_string_type));
+#  OPTIONAL(name, MDStringField, );
+#  OPTIONAL(stringLength, MDField, );
+#  OPTIONAL(stringLengthExpression, MDField, );
+#  OPTIONAL(stringLocationExpression, MDField, );
+#  OPTIONAL(size, MDUnsignedField, (0, UINT64_MAX));
+#  OPTIONAL(align, MDUnsignedField, (0, UINT32_MAX));
+#  OPTIONAL(encoding, DwarfAttEncodingField, );
+
+DIStringType -> DIStringType
+	: '!DIStringType' '(' Fields=(DIStringTypeField separator ',')* ')'
+;
+
+%interface DIStringTypeField;
+
+DIStringTypeField -> DIStringTypeField
+	: TagField
+	| NameField
+	| StringLengthField
+	| StringLengthExpressionField
+	| StringLocationExpressionField
+	| SizeField
+	| AlignField
+	| EncodingField
+;
+
 # ~~~ [ DISubprogram ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 # https://llvm.org/docs/LangRef.html#disubprogram
@@ -3573,6 +4530,7 @@ DIObjCPropertyField -> DIObjCPropertyField
 #  OPTIONAL(virtualIndex, MDUnsignedField, (0, UINT32_MAX));                    \
 #  OPTIONAL(thisAdjustment, MDSignedField, (0, INT32_MIN, INT32_MAX));          \
 #  OPTIONAL(flags, DIFlagField, );                                              \
+#  OPTIONAL(spFlags, DISPFlagField, );                                          \
 #  OPTIONAL(isOptimized, MDBoolField, );                                        \
 #  OPTIONAL(unit, MDField, );                                                   \
 #  OPTIONAL(templateParams, MDField, );                                         \
@@ -3601,6 +4559,7 @@ DISubprogramField -> DISubprogramField
 	| VirtualIndexField
 	| ThisAdjustmentField
 	| FlagsField
+	| SPFlagsField
 	| IsOptimizedField
 	| UnitField
 	| TemplateParamsField
@@ -3617,9 +4576,12 @@ DISubprogramField -> DISubprogramField
 #
 #   ::= !DISubrange(count: 30, lowerBound: 2)
 #   ::= !DISubrange(count: !node, lowerBound: 2)
+#   ::= !DISubrange(lowerBound: !node1, upperBound: !node2, stride: !node3)
 #
-#  REQUIRED(count, MDSignedOrMDField, (-1, -1, INT64_MAX, false));
-#  OPTIONAL(lowerBound, MDSignedField, );
+#  OPTIONAL(count, MDSignedOrMDField, (-1, -1, INT64_MAX, false));
+#  OPTIONAL(lowerBound, MDSignedOrMDField, );
+#  OPTIONAL(upperBound, MDSignedOrMDField, );
+#  OPTIONAL(stride, MDSignedOrMDField, );
 
 DISubrange -> DISubrange
 	: '!DISubrange' '(' Fields=(DISubrangeField separator ',')* ')'
@@ -3630,6 +4592,8 @@ DISubrange -> DISubrange
 DISubrangeField -> DISubrangeField
 	: CountField
 	| LowerBoundField
+	| UpperBoundField
+	| StrideField
 ;
 
 # ~~~ [ DISubroutineType ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -3769,6 +4733,10 @@ ContainingTypeField -> ContainingTypeField
 	: 'containingType:' MDField
 ;
 
+CoroSuspendIdxField -> CoroSuspendIdxField
+	: 'coroSuspendIdx:' IntLit
+;
+
 CountField -> CountField
 	: 'count:' MDFieldOrInt
 ;
@@ -3877,6 +4845,10 @@ InlinedAtField -> InlinedAtField
 	: 'inlinedAt:' MDField
 ;
 
+IsArtificialField -> IsArtificialField
+	: 'isArtificial:' BoolLit
+;
+
 IsDefinitionField -> IsDefinitionField
 	: 'isDefinition:' BoolLit
 ;
@@ -3914,7 +4886,7 @@ LinkageNameField -> LinkageNameField
 ;
 
 LowerBoundField -> LowerBoundField
-	: 'lowerBound:' IntLit
+	: 'lowerBound:' MDFieldOrInt
 ;
 
 MacrosField -> MacrosField
@@ -3981,6 +4953,10 @@ SourceField -> SourceField
 	: 'source:' StringLit
 ;
 
+SPFlagsField -> SPFlagsField
+	: 'spFlags:' DISPFlags
+;
+
 SplitDebugFilenameField -> SplitDebugFilenameField
 	: 'splitDebugFilename:' StringLit
 ;
@@ -3989,6 +4965,22 @@ SplitDebugInliningField -> SplitDebugInliningField
 	: 'splitDebugInlining:' BoolLit
 ;
 
+StrideField -> StrideField
+	: 'stride:' MDFieldOrInt
+;
+
+StringLengthField -> StringLengthField
+	: 'stringLength:' MDField
+;
+
+StringLengthExpressionField -> StringLengthExpressionField
+	: 'stringLengthExpression:' MDField
+;
+
+StringLocationExpressionField -> StringLocationExpressionField
+	: 'stringLocationExpression:' MDField
+;
+
 TagField -> TagField
 	: 'tag:' DwarfTag
 ;
@@ -4021,6 +5013,10 @@ UnitField -> UnitField
 	: 'unit:' MDField
 ;
 
+UpperBoundField -> UpperBoundField
+	: 'upperBound:' MDFieldOrInt
+;
+
 ValueField -> ValueField
 	: 'value:' MDField
 ;
@@ -4083,6 +5079,22 @@ DIFlag -> DIFlag
 	| di_flag_tok
 ;
 
+# ref: parseMDField(DISPFlagField &)
+#
+#  ::= uint32
+#  ::= DISPFlagVector
+#  ::= DISPFlagVector '|' DISPFlag* '|' uint32
+
+DISPFlags -> DISPFlags
+	: Flags=(DISPFlag separator '|')+
+;
+
+DISPFlag -> DISPFlag
+	: UintLit
+	# DISPFlagFoo
+	| disp_flag_tok
+;
+
 # ref: ParseMDField(DwarfAttEncodingField &)
 
 DwarfAttEncoding -> DwarfAttEncoding
@@ -4613,6 +5625,38 @@ ParamAttr -> ParamAttr
 	| Alignment
 	| Dereferenceable
 	| ParamAttribute
+	| ByRefAttr
+	| ByvalAttr
+	| ElementTypeAttr
+	| InAllocaAttr
+	| SRetAttr
+;
+
+# Type-carrying parameter attributes; the bare 'byval', 'inalloca' and 'sret'
+# forms of ParamAttribute are accepted for compatibility with LLVM < 12.
+#
+# ref: parseRequiredTypeAttr
+#
+#   ::= AttrKind '(' Type ')'
+
+ByRefAttr -> ByRefAttr
+	: 'byref' '(' Typ=Type ')'
+;
+
+ByvalAttr -> ByvalAttr
+	: 'byval' '(' Typ=Type ')'
+;
+
+ElementTypeAttr -> ElementTypeAttr
+	: 'elementtype' '(' Typ=Type ')'
+;
+
+InAllocaAttr -> InAllocaAttr
+	: 'inalloca' '(' Typ=Type ')'
+;
+
+SRetAttr -> SRetAttr
+	: 'sret' '(' Typ=Type ')'
 ;
 
 # TODO: Figure out a cleaner way of handling ParamAttribute.
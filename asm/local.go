@@ -21,6 +21,7 @@ package asm
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/llir/l/ir"
 	"github.com/llir/l/ir/types"
@@ -54,11 +55,14 @@ func newFuncGen(gen *generator, f *ir.Function) *funcGen {
 // identifier (without '%' prefix) to the corresponding IR value.
 func (fgen *funcGen) resolveLocals(body ast.FuncBody) (map[string]value.Value, error) {
 	// Create instructions (without bodies), in preparation for index.
+	localIndexStart := time.Now()
 	oldBlocks := body.Blocks()
 	if err := fgen.indexLocals(oldBlocks); err != nil {
 		return nil, errors.WithStack(err)
 	}
+	fgen.gen.localIndexTime += time.Since(localIndexStart)
 	// Translate instructions.
+	bodyTranslationStart := time.Now()
 	f := fgen.f
 	for i, block := range f.Blocks {
 		insts := oldBlocks[i].Insts()
@@ -76,6 +80,7 @@ func (fgen *funcGen) resolveLocals(body ast.FuncBody) (map[string]value.Value, e
 			return nil, errors.WithStack(err)
 		}
 	}
+	fgen.gen.bodyTranslationTime += time.Since(bodyTranslationStart)
 	return fgen.ls, nil
 }
 
@@ -103,6 +108,13 @@ func (fgen *funcGen) newIRInst(old ast.Instruction) (ir.Instruction, error) {
 // type) based on the given AST value instruction.
 func (fgen *funcGen) newIRValueInst(name string, old ast.ValueInstruction) (ir.Instruction, error) {
 	switch old := old.(type) {
+	// Unary instructions
+	case *ast.FNegInst:
+		typ, err := fgen.gen.irType(old.X().Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &ir.InstFNeg{LocalName: name, Typ: typ}, nil
 	// Binary instructions
 	case *ast.AddInst:
 		typ, err := fgen.gen.irType(old.X().Typ())
@@ -251,7 +263,7 @@ func (fgen *funcGen) newIRValueInst(name string, old ast.ValueInstruction) (ir.I
 		if !ok {
 			panic(fmt.Errorf("invalid vector type; expected *types.VectorType, got %T", maskType))
 		}
-		typ := types.NewVector(mt.Len, xt.ElemType)
+		typ := vectorOf(mt, xt.ElemType)
 		return &ir.InstShuffleVector{LocalName: name, Typ: typ}, nil
 	// Aggregate instructions
 	case *ast.ExtractValueInst:
@@ -274,9 +286,13 @@ func (fgen *funcGen) newIRValueInst(name string, old ast.ValueInstruction) (ir.I
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		return &ir.InstAlloca{LocalName: name, ElemType: elemType}, nil
+		inst := &ir.InstAlloca{LocalName: name, ElemType: elemType}
+		if fgen.gen.opaquePointers {
+			inst.Typ = &types.PointerType{}
+		}
+		return inst, nil
 	case *ast.LoadInst:
-		elemType, err := fgen.gen.irType(old.ElemType())
+		elemType, err := fgen.gen.irElemType(old.ElemType(), old.Src().Typ())
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -286,12 +302,26 @@ func (fgen *funcGen) newIRValueInst(name string, old ast.ValueInstruction) (ir.I
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		typ := types.NewStruct(oldType, types.I8)
+		typ := types.NewStruct(oldType, types.I1)
 		return &ir.InstCmpXchg{LocalName: name, Typ: typ}, nil
 	case *ast.AtomicRMWInst:
 		return &ir.InstAtomicRMW{LocalName: name}, nil
 	case *ast.GetElementPtrInst:
-		return &ir.InstGetElementPtr{LocalName: name}, nil
+		elemType, err := fgen.gen.irElemType(old.ElemType(), old.Src().Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		inst := &ir.InstGetElementPtr{LocalName: name, ElemType: elemType}
+		// The result of getelementptr on an opaque pointer is an opaque pointer
+		// in the same address space.
+		srcType, err := fgen.gen.irType(old.Src().Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ptr, ok := srcType.(*types.PointerType); ok && isOpaquePointer(ptr) {
+			inst.Typ = &types.PointerType{AddrSpace: ptr.AddrSpace}
+		}
+		return inst, nil
 	// Conversion instructions
 	case *ast.TruncInst:
 		to, err := fgen.gen.irType(old.To())
@@ -388,14 +418,24 @@ func (fgen *funcGen) newIRValueInst(name string, old ast.ValueInstruction) (ir.I
 			return nil, errors.WithStack(err)
 		}
 		return &ir.InstSelect{LocalName: name, Typ: typ}, nil
+	case *ast.FreezeInst:
+		typ, err := fgen.gen.irType(old.X().Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &ir.InstFreeze{LocalName: name, Typ: typ}, nil
 	case *ast.CallInst:
 		// NOTE: We need to store the type of call instructions before invoking
 		// f.AssignIDs, since call instructions may be value instructions or
 		// non-value instructions based on return type.
-		typ, err := fgen.gen.irType(old.Typ())
+		typ, err := fgen.gen.irCallType(old.Typ())
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		// The type of the call instruction is the return type of the callee.
+		if sig, ok := typ.(*types.FuncType); ok {
+			typ = sig.RetType
+		}
 		return &ir.InstCall{LocalName: name, Typ: typ}, nil
 	case *ast.VAArgInst:
 		return &ir.InstVAArg{LocalName: name}, nil
@@ -443,6 +483,9 @@ func (fgen *funcGen) astToIRInst(inst ir.Instruction, old ast.Instruction) (ir.I
 // value instruction.
 func (fgen *funcGen) astToIRValueInst(inst ir.Instruction, old ast.ValueInstruction) (ir.Instruction, error) {
 	switch old := old.(type) {
+	// Unary instructions
+	case *ast.FNegInst:
+		return fgen.astToIRInstFNeg(inst, old)
 	// Binary instructions
 	case *ast.AddInst:
 		return fgen.astToIRInstAdd(inst, old)
@@ -540,6 +583,8 @@ func (fgen *funcGen) astToIRValueInst(inst ir.Instruction, old ast.ValueInstruct
 		return fgen.astToIRInstPhi(inst, old)
 	case *ast.SelectInst:
 		return fgen.astToIRInstSelect(inst, old)
+	case *ast.FreezeInst:
+		return fgen.astToIRInstFreeze(inst, old)
 	case *ast.CallInst:
 		return fgen.astToIRInstCall(inst, old)
 	case *ast.VAArgInst:
@@ -0,0 +1,150 @@
+package asm
+
+import (
+	"strings"
+
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// MetadataDefs returns the source text of the named and unnamed metadata
+// definitions of the given module, in order of occurrence. Metadata definitions
+// of the legacy dialect (pre LLVM 3.7) are upgraded to current LLVM IR by
+// removing the metadata type prefixes of metadata nodes (e.g. `!0 = metadata
+// !{metadata !"foo"}` is upgraded to `!0 = !{!"foo"}`).
+//
+// NOTE: metadata is not yet translated to IR, and is thus omitted from
+// translated modules; MetadataDefs may be used to carry metadata through to the
+// LLVM IR assembly of translated modules.
+func MetadataDefs(module *ast.Module) []string {
+	var defs []string
+	for _, entity := range module.TopLevelEntities() {
+		switch entity := entity.(type) {
+		case *ast.NamedMetadataDef:
+			defs = append(defs, entity.Text())
+		case *ast.MetadataDef:
+			defs = append(defs, upgradeMetadataDef(entity))
+		}
+	}
+	return defs
+}
+
+// upgradeMetadataDef returns the source text of the given metadata definition,
+// without the metadata type prefixes of the legacy dialect.
+func upgradeMetadataDef(def *ast.MetadataDef) string {
+	n := def.LlvmNode()
+	text, base := n.Text(), n.Offset()
+	buf := &strings.Builder{}
+	pos := 0
+	ast.Inspect(def, func(n ast.LlvmNode) bool {
+		// Within metadata definitions, metadata types only occur as prefixes of
+		// the legacy dialect.
+		t, ok := n.(*ast.MetadataType)
+		if !ok {
+			return true
+		}
+		start, end := t.LlvmNode().Offset()-base, t.LlvmNode().Endoffset()-base
+		// Remove whitespace separating the prefix from the metadata node.
+		for end < len(text) && strings.IndexByte(" \t\r\n", text[end]) != -1 {
+			end++
+		}
+		buf.WriteString(text[pos:start])
+		pos = end
+		return false
+	})
+	buf.WriteString(text[pos:])
+	return buf.String()
+}
+
+// checkMetadata checks the metadata definitions of the given module for
+// specialized metadata nodes lacking required fields, as reported by the LLVM
+// IR assembly parser and verifier of LLVM. Metadata type prefixes of metadata
+// nodes are only accepted in the legacy dialect (see Options.Legacy).
+//
+// TODO: check specialized metadata nodes during translation, once metadata is
+// translated to IR.
+func checkMetadata(module *ast.Module, legacy bool) error {
+	for _, entity := range module.TopLevelEntities() {
+		def, ok := entity.(*ast.MetadataDef)
+		if !ok {
+			continue
+		}
+		if err := checkMetadataDef(def, legacy); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// checkMetadataDef checks the specialized metadata nodes of the given metadata
+// definition.
+func checkMetadataDef(def *ast.MetadataDef, legacy bool) error {
+	if _, ok := def.MDNode().(*ast.DIAssignID); ok && def.Distinct() != nil {
+		// Distinct DIAssignID metadata definition.
+		return nil
+	}
+	var err error
+	ast.Inspect(def, func(n ast.LlvmNode) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.MetadataType:
+			// Within metadata definitions, metadata types only occur as prefixes
+			// of the legacy dialect (e.g. `!0 = metadata !{metadata !"foo"}`).
+			if !legacy {
+				err = errors.Errorf("metadata type prefix of metadata node in `%s`; only supported by the legacy dialect (see Options.Legacy)", def.Text())
+			}
+		case *ast.DIArgList:
+			err = errors.Errorf("DIArgList `%s` may only appear in function-local metadata", n.Text())
+		case *ast.DIAssignID:
+			err = errors.Errorf("missing 'distinct', required for DIAssignID `%s`", n.Text())
+		case *ast.DICommonBlock:
+			err = checkDICommonBlock(n)
+		case *ast.DIGenericSubrange:
+			err = checkDIGenericSubrange(n)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// checkDICommonBlock checks the given DICommonBlock specialized metadata node
+// for missing required fields.
+func checkDICommonBlock(old *ast.DICommonBlock) error {
+	for _, field := range old.Fields() {
+		if _, ok := field.(*ast.ScopeField); ok {
+			return nil
+		}
+	}
+	return errors.Errorf("missing required field 'scope' of DICommonBlock `%s`", old.Text())
+}
+
+// checkDIGenericSubrange checks the given DIGenericSubrange specialized metadata
+// node for missing required fields.
+func checkDIGenericSubrange(old *ast.DIGenericSubrange) error {
+	var hasCount, hasLowerBound, hasUpperBound, hasStride bool
+	for _, field := range old.Fields() {
+		switch field.(type) {
+		case *ast.CountField:
+			hasCount = true
+		case *ast.LowerBoundField:
+			hasLowerBound = true
+		case *ast.UpperBoundField:
+			hasUpperBound = true
+		case *ast.StrideField:
+			hasStride = true
+		}
+	}
+	switch {
+	case !hasCount && !hasUpperBound:
+		return errors.Errorf("missing required field 'count' or 'upperBound' of DIGenericSubrange `%s`", old.Text())
+	case hasCount && hasUpperBound:
+		return errors.Errorf("fields 'count' and 'upperBound' of DIGenericSubrange `%s` are mutually exclusive", old.Text())
+	case !hasLowerBound:
+		return errors.Errorf("missing required field 'lowerBound' of DIGenericSubrange `%s`", old.Text())
+	case !hasStride:
+		return errors.Errorf("missing required field 'stride' of DIGenericSubrange `%s`", old.Text())
+	}
+	return nil
+}
@@ -2,29 +2,112 @@
 package asm
 
 import (
-	"io/ioutil"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
 
+	"github.com/llir/l/ir"
 	"github.com/mewmew/l-tm/asm/ll/ast"
 	"github.com/pkg/errors"
 )
 
+// ParseModule parses the given LLVM IR assembly file into an LLVM IR module.
+func ParseModule(path string) (*ir.Module, error) {
+	module, err := ParseFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Translate(module)
+}
+
+// ParseModuleFromReader parses the given LLVM IR assembly file into an LLVM IR
+// module, reading from r. An optional path to the source file may be specified
+// for error reporting.
+func ParseModuleFromReader(path string, r io.Reader) (*ir.Module, error) {
+	content, err := readAll(r, 0)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseModule(path, content)
+}
+
+// ParseModuleFromBytes parses the given LLVM IR assembly file into an LLVM IR
+// module, reading from b. An optional path to the source file may be specified
+// for error reporting.
+//
+// The contents of b is not copied, and must therefore not be modified after
+// the call.
+func ParseModuleFromBytes(path string, b []byte) (*ir.Module, error) {
+	return parseModule(path, bytesToString(b))
+}
+
+// parseModule parses the given LLVM IR assembly file into an LLVM IR module,
+// reading from content.
+func parseModule(path, content string) (*ir.Module, error) {
+	module, err := Parse(path, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Translate(module)
+}
+
 // ParseFile parses the given LLVM IR assembly file into an LLVM IR module.
 func ParseFile(path string) (*ast.Module, error) {
-	buf, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	// Use the file size as a hint to avoid growing the buffer while reading.
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	content, err := readAll(f, size)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	content := string(buf)
 	return Parse(path, content)
 }
 
 // Parse parses the given LLVM IR assembly file into an LLVM IR module, reading
 // from content.
 func Parse(path, content string) (*ast.Module, error) {
+	parseStart := time.Now()
 	tree, err := ast.Parse(path, content)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	root := ast.ToLlvmNode(tree.Root())
+	observePhase(PhaseParse, time.Since(parseStart))
 	return root.(*ast.Module), nil
 }
+
+// ### [ Helper functions ] ####################################################
+
+// readAll reads from r until EOF and returns the data read as a string. An
+// optional size hint may be specified to preallocate the underlying buffer.
+//
+// The data is read directly into the buffer backing the returned string, thus
+// avoiding a full copy of the contents as would be required to convert the
+// []byte of ioutil.ReadAll to string.
+func readAll(r io.Reader, sizeHint int64) (string, error) {
+	buf := &strings.Builder{}
+	if sizeHint > 0 {
+		buf.Grow(int(sizeHint))
+	}
+	if _, err := io.Copy(buf, r); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return buf.String(), nil
+}
+
+// bytesToString returns a string sharing the underlying data of b.
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
@@ -0,0 +1,67 @@
+package asm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Observer, if non-nil, is notified of the time taken by each phase of parsing
+// and translation.
+var Observer PhaseObserver
+
+// PhaseObserver is notified of the time taken by each phase of parsing and
+// translation.
+type PhaseObserver interface {
+	// ObservePhase is invoked once the given phase has finished, with the total
+	// time spent in the phase.
+	ObservePhase(phase Phase, d time.Duration)
+}
+
+// Phase is a phase of parsing and translation.
+type Phase uint8
+
+// Phases of parsing and translation.
+//
+// The phases are disjoint; e.g. the time spent in the global resolution phase
+// does not include the time spent indexing locals and translating function
+// bodies.
+const (
+	// PhaseParse is the parsing of LLVM IR assembly into AST.
+	PhaseParse Phase = iota + 1
+	// PhaseTypeResolution is the type resolution of type definitions.
+	PhaseTypeResolution
+	// PhaseGlobalResolution is the global resolution of global variable and
+	// function declarations and definitions.
+	PhaseGlobalResolution
+	// PhaseLocalIndex is the indexing of local identifiers of function bodies.
+	PhaseLocalIndex
+	// PhaseBodyTranslation is the translation of instructions and terminators
+	// of function bodies.
+	PhaseBodyTranslation
+)
+
+// String returns the string representation of the phase.
+func (phase Phase) String() string {
+	switch phase {
+	case PhaseParse:
+		return "parse"
+	case PhaseTypeResolution:
+		return "types"
+	case PhaseGlobalResolution:
+		return "globals"
+	case PhaseLocalIndex:
+		return "locals"
+	case PhaseBodyTranslation:
+		return "bodies"
+	default:
+		return fmt.Sprintf("Phase(%d)", uint8(phase))
+	}
+}
+
+// observePhase notifies the observer, if any, of the time taken by the given
+// phase.
+func observePhase(phase Phase, d time.Duration) {
+	if Observer != nil {
+		Observer.ObservePhase(phase, d)
+	}
+}
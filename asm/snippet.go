@@ -0,0 +1,143 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/llir/l/ir"
+	"github.com/llir/l/ir/types"
+	"github.com/llir/l/ir/value"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// snippetPath is the path used for error reporting when parsing standalone
+// snippets of LLVM IR.
+const snippetPath = "<snippet>"
+
+// ParseType parses the given LLVM IR type (e.g. `{ i32, [4 x i8]* }`). Named
+// types are resolved against the type definitions of the optional module m.
+func ParseType(content string, m *ir.Module) (types.Type, error) {
+	tree, err := ast.ParseType(snippetPath, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old := ast.ToLlvmNode(tree.Root())
+	gen := newGenerator()
+	gen.indexModule(m)
+	return gen.irType(old)
+}
+
+// ParseConstant parses the given LLVM IR constant, prefixed by its type (e.g.
+// `i8* getelementptr ([4 x i8], [4 x i8]* @s, i64 0, i64 0)`). Named types and
+// global identifiers are resolved against the optional module m.
+func ParseConstant(content string, m *ir.Module) (ir.Constant, error) {
+	tree, err := ast.ParseTypeConst(snippetPath, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old, ok := ast.ToLlvmNode(tree.Root()).(*ast.TypeConst)
+	if !ok {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid AST root of constant; expected *ast.TypeConst, got %T", tree.Root()))
+	}
+	gen := newGenerator()
+	gen.indexModule(m)
+	c, err := gen.irTypeConst(*old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := gen.fixTodo(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return c, nil
+}
+
+// ParseFunction parses the given LLVM IR function definition. Named types and
+// global identifiers are resolved against the optional module m. The function
+// is not added to m. The function uses opaque pointers if m does, or if its
+// definition contains an opaque pointer type.
+func ParseFunction(content string, m *ir.Module) (*ir.Function, error) {
+	tree, err := ast.ParseFuncDef(snippetPath, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old, ok := ast.ToLlvmNode(tree.Root()).(*ast.FuncDef)
+	if !ok {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid AST root of function; expected *ast.FuncDef, got %T", tree.Root()))
+	}
+	gen := newGenerator()
+	gen.indexModule(m)
+	// Create function (without body but with type), so that it may be
+	// referenced from within its own body.
+	name := global(old.Header().Name())
+	g, err := gen.newGlobal(name, old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gen.gs[name] = g
+	f, err := gen.astToIRFuncDef(g, old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if gen.opaquePointers {
+		setOpaquePointer(f)
+	}
+	if err := gen.fixTodo(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return f, nil
+}
+
+// ParseInstruction parses the given LLVM IR instruction (e.g. `%x = add i32 %y,
+// 1`). Local identifiers are resolved against the function parameters, basic
+// blocks and local variables of the optional function f, which must have local
+// IDs assigned. Named types and global identifiers are resolved against the
+// optional module m. The instruction is not added to f. Alloca instructions are
+// of opaque pointer type if m or f uses opaque pointers.
+func ParseInstruction(content string, f *ir.Function, m *ir.Module) (ir.Instruction, error) {
+	tree, err := ast.ParseInstruction(snippetPath, content)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	old, ok := ast.ToLlvmNode(tree.Root()).(ast.Instruction)
+	if !ok {
+		// NOTE: panic since this would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid AST root of instruction; expected ast.Instruction, got %T", tree.Root()))
+	}
+	gen := newGenerator()
+	gen.indexModule(m)
+	if f != nil && isOpaquePointer(f.Typ) {
+		gen.opaquePointers = true
+	}
+	fgen := newFuncGen(gen, f)
+	if f != nil {
+		if err := fgen.addLocals(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	// Create instruction (without body but with type).
+	inst, err := fgen.newIRInst(old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if def, ok := old.(*ast.LocalDefInst); ok {
+		v, ok := inst.(value.Value)
+		if !ok {
+			// NOTE: panic since this would indicate a bug in the implementation.
+			panic(fmt.Errorf("invalid IR instruction of local variable definition; expected value.Value, got %T", inst))
+		}
+		name := local(def.Name())
+		if err := fgen.addLocal(name, v); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	// Translate instruction body.
+	if _, err := fgen.astToIRInst(inst, old); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := gen.fixTodo(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return inst, nil
+}
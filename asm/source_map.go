@@ -0,0 +1,81 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/mewmew/l-tm/asm/ll/ast"
+)
+
+// SourceMap maps translated IR values to the AST nodes they were translated
+// from. Supported IR values include type definitions and types, global
+// variables and functions, function parameters, basic blocks, instructions,
+// terminators, constants and constant expressions.
+//
+// Type definitions are recorded at their definition, and other types at each
+// occurrence. Named types are not recorded at their uses, as they refer to the
+// IR type of the type definition. Similarly, global identifiers used as
+// constants refer to the IR value of the global declaration or definition.
+//
+// IR values are recorded by identity. As such, IR types and constants shared
+// between several occurrences in the source (e.g. the none token constant)
+// keep only the position of their last occurrence.
+type SourceMap struct {
+	// nodes maps from IR value to corresponding AST node.
+	nodes map[interface{}]ast.LlvmNode
+}
+
+// newSourceMap returns a new empty source map.
+func newSourceMap() *SourceMap {
+	return &SourceMap{
+		nodes: make(map[interface{}]ast.LlvmNode),
+	}
+}
+
+// Node returns the AST node that the given IR value was translated from.
+func (sm *SourceMap) Node(v interface{}) (ast.LlvmNode, bool) {
+	n, ok := sm.nodes[v]
+	return n, ok
+}
+
+// Span returns the source span of the AST node that the given IR value was
+// translated from.
+func (sm *SourceMap) Span(v interface{}) (Span, bool) {
+	n, ok := sm.nodes[v]
+	if !ok {
+		return Span{}, false
+	}
+	node := n.LlvmNode()
+	if node == nil {
+		return Span{}, false
+	}
+	line, col := node.LineColumn()
+	span := Span{
+		Start: node.Offset(),
+		End:   node.Endoffset(),
+		Line:  line,
+		Col:   col,
+	}
+	return span, true
+}
+
+// Span is a span of source code.
+type Span struct {
+	// Start and end byte offsets of the span.
+	Start, End int
+	// Line and column number of the start of the span (1-based).
+	Line, Col int
+}
+
+// String returns the string representation of the source span.
+func (span Span) String() string {
+	return fmt.Sprintf("%d:%d", span.Line, span.Col)
+}
+
+// record records the AST node that the given IR value was translated from, if
+// tracking of source positions is enabled.
+func (gen *generator) record(v interface{}, old ast.LlvmNode) {
+	if gen.sm == nil {
+		return
+	}
+	gen.sm.nodes[v] = old
+}
@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// Entity is a top-level entity of an LLVM IR assembly file, as delivered by
+// Stream.
+type Entity struct {
+	// AST node of the top-level entity.
+	Node ast.TopLevelEntity
+	// Byte offset of the source text of the top-level entity within the file.
+	Offset int
+	// Line number of the source text of the top-level entity within the file
+	// (1-based).
+	Line int
+	// Byte offset and line number (1-based) within the file of the window of
+	// source text from which the top-level entity was parsed. Offsets and line
+	// numbers of AST nodes are relative to the window.
+	WindowOffset, WindowLine int
+}
+
+// streamBlockSize specifies the number of bytes read at a time by Stream.
+var streamBlockSize = 1 << 20
+
+// Stream parses the LLVM IR assembly file read from r, invoking f for each
+// top-level entity in order of occurrence. Function definitions are delivered
+// with their bodies parsed. An optional path to the source file may be
+// specified for error reporting.
+//
+// In contrast to Parse, neither the source text nor the AST of the entire
+// module is held in memory. Instead, the input is read incrementally into a
+// window of source text, which is parsed by the event-based parser; the AST of
+// each top-level entity is built once from the node events of the parser. A
+// top-level entity is delivered once the parser has reported the succeeding
+// top-level entity (or the end of input), as the window may end within the
+// top-level entity; source text of delivered top-level entities is then
+// dropped from the window. Thus memory use is bounded by the size of the
+// largest top-level entity (e.g. function definition) and the block size of
+// reads, unless the delivered AST nodes are retained by f.
+//
+// Top-level entities preceding a syntax error are delivered before the error
+// is reported; the error is reported once the window reaches the end of input.
+// Streaming stops at the first error returned by f, which is returned by
+// Stream.
+func Stream(path string, r io.Reader, f func(entity *Entity) error) error {
+	parseStart := time.Now()
+	// Source text not yet delivered, starting at the given byte offset and line
+	// number of the file.
+	var buf []byte
+	offset, line := 0, 1
+	blockSize := streamBlockSize
+	eof := false
+	for !eof {
+		// Read next block of input.
+		n := len(buf)
+		buf = append(buf, make([]byte, blockSize)...)
+		m, err := io.ReadFull(r, buf[n:])
+		buf = buf[:n+m]
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			eof = true
+		default:
+			return errors.WithStack(err)
+		}
+		// Parse top-level entities of window. The last top-level entity reported
+		// may end within the window unless at the end of input.
+		window := string(buf)
+		var entities []ast.TopLevelEntity
+		var last ast.TopLevelEntity
+		perr := ast.ParseEntities(path, window, func(entity ast.TopLevelEntity) {
+			if last != nil {
+				entities = append(entities, last)
+			}
+			last = entity
+		})
+		if eof && last != nil {
+			entities = append(entities, last)
+		}
+		// Deliver complete top-level entities.
+		pos, entityLine := 0, line
+		for _, entity := range entities {
+			start := entity.LlvmNode().Offset()
+			entityLine += strings.Count(window[pos:start], "\n")
+			pos = start
+			e := &Entity{
+				Node:         entity,
+				Offset:       offset + start,
+				Line:         entityLine,
+				WindowOffset: offset,
+				WindowLine:   line,
+			}
+			if err := f(e); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if eof && perr != nil {
+			return errors.Wrapf(perr, "unable to parse top-level entities at line %d", line)
+		}
+		if len(entities) == 0 {
+			// The window ends within the first top-level entity; read larger
+			// blocks until it is complete.
+			blockSize *= 2
+			continue
+		}
+		blockSize = streamBlockSize
+		// Drop source text of delivered top-level entities.
+		end := entities[len(entities)-1].LlvmNode().Endoffset()
+		offset += end
+		line += strings.Count(window[:end], "\n")
+		buf = append([]byte(nil), buf[end:]...)
+	}
+	observePhase(PhaseParse, time.Since(parseStart))
+	return nil
+}
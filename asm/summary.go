@@ -0,0 +1,992 @@
+package asm
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/llir/l/ir/enum"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// Summary is a ThinLTO module summary index, as specified by the summary
+// entries (e.g. `^0 = module: (...)`) of an LLVM IR assembly file.
+//
+// The summary is translated from the AST of the module, and may thus be
+// inspected (e.g. to determine import and export decisions) without
+// translating the module to LLVM IR.
+type Summary struct {
+	// Modules of the summary index.
+	Modules []*SummaryModule
+	// Global value summaries.
+	GlobalValues []*GlobalValueSummary
+	// Type identifier summaries.
+	TypeIDs []*TypeIDSummary
+	// Compatible virtual tables of type identifiers.
+	TypeIDCompatibleVTables []*TypeIDCompatibleVTable
+	// Flags of the summary index.
+	Flags uint64
+	// Number of basic blocks of the summary index.
+	BlockCount uint64
+}
+
+// GlobalValue returns the global value summary of the given name (without '@'
+// prefix), or nil if not present.
+func (s *Summary) GlobalValue(name string) *GlobalValueSummary {
+	for _, gv := range s.GlobalValues {
+		if gv.Name == name {
+			return gv
+		}
+	}
+	// Global value entries may be identified by GUID only.
+	return s.GlobalValueByGUID(GUID(name))
+}
+
+// GlobalValueByGUID returns the global value summary of the given GUID, or nil
+// if not present.
+func (s *Summary) GlobalValueByGUID(guid uint64) *GlobalValueSummary {
+	for _, gv := range s.GlobalValues {
+		if gv.GUID == guid {
+			return gv
+		}
+	}
+	return nil
+}
+
+// TypeID returns the type identifier summary of the given name, or nil if not
+// present.
+func (s *Summary) TypeID(name string) *TypeIDSummary {
+	for _, t := range s.TypeIDs {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// GUID returns the globally unique identifier of the given global value name
+// (without '@' prefix) or type identifier name; i.e. the lower 64 bits of the
+// MD5 hash of the name.
+//
+// Note, the GUID of global values with local linkage is computed from the name
+// prefixed by the source file name (e.g. "foo.c;bar").
+func GUID(name string) uint64 {
+	sum := md5.Sum([]byte(name))
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
+// SummaryModule is a module of a summary index.
+type SummaryModule struct {
+	// Summary ID (without '^' prefix).
+	ID int64
+	// Module path.
+	Path string
+	// Module hash.
+	Hash [5]uint32
+}
+
+// GlobalValueSummary is a summary of a global value.
+type GlobalValueSummary struct {
+	// Summary ID (without '^' prefix).
+	ID int64
+	// Global value name (without '@' prefix); empty if only the GUID is known.
+	Name string
+	// Globally unique identifier of the global value.
+	GUID uint64
+	// Summaries of the global value (one per defining module); empty for
+	// external global values.
+	Summaries []GVSummary
+}
+
+// GVSummary is a summary of a global value defined by a given module.
+//
+// A GVSummary has one of the following underlying types.
+//
+//    *asm.FunctionSummary
+//    *asm.VariableSummary
+//    *asm.AliasSummary
+type GVSummary interface {
+	// GVInfo returns the defining module and flags of the global value summary.
+	GVInfo() (*SummaryModule, GVFlags)
+}
+
+// GVFlags are the flags of a global value summary.
+type GVFlags struct {
+	// Linkage of the global value.
+	Linkage enum.Linkage
+	// Visibility of the global value.
+	Visibility enum.Visibility
+	// The global value may not be imported into other modules.
+	NotEligibleToImport bool
+	// The global value is live.
+	Live bool
+	// The global value is local to the linkage unit.
+	DSOLocal bool
+	// The global value may be auto-hidden.
+	CanAutoHide bool
+}
+
+// FunctionSummary is a summary of a function.
+type FunctionSummary struct {
+	// Defining module.
+	Module *SummaryModule
+	// Global value flags.
+	Flags GVFlags
+	// Number of instructions.
+	Insts uint64
+	// Function flags.
+	FuncFlags FuncFlags
+	// Call edges.
+	Calls []*SummaryCall
+	// Type identifier information.
+	TypeIDInfo TypeIDInfo
+	// References to global values.
+	Refs []*SummaryRef
+}
+
+// GVInfo returns the defining module and flags of the global value summary.
+func (s *FunctionSummary) GVInfo() (*SummaryModule, GVFlags) {
+	return s.Module, s.Flags
+}
+
+// FuncFlags are the flags of a function summary.
+type FuncFlags struct {
+	ReadNone           bool
+	ReadOnly           bool
+	NoRecurse          bool
+	ReturnDoesNotAlias bool
+	NoInline           bool
+	AlwaysInline       bool
+	NoUnwind           bool
+	MayThrow           bool
+	HasUnknownCall     bool
+	MustBeUnreachable  bool
+}
+
+// SummaryCall is a call edge of a function summary.
+type SummaryCall struct {
+	// Callee.
+	Callee *GlobalValueSummary
+	// Hotness of call edge (unknown, cold, none, hot or critical); empty if
+	// not present.
+	Hotness string
+	// Relative block frequency of call edge.
+	RelBF uint64
+	// Tail call.
+	Tail bool
+}
+
+// TypeIDInfo is the type identifier information of a function summary.
+type TypeIDInfo struct {
+	// GUIDs of type identifiers used by type tests.
+	TypeTests []uint64
+	// Virtual function calls of type tests with assumes.
+	TypeTestAssumeVCalls []VFuncID
+	// Virtual function calls of checked loads.
+	TypeCheckedLoadVCalls []VFuncID
+	// Virtual function calls of type tests with assumes, with constant
+	// arguments.
+	TypeTestAssumeConstVCalls []ConstVCall
+	// Virtual function calls of checked loads, with constant arguments.
+	TypeCheckedLoadConstVCalls []ConstVCall
+}
+
+// VFuncID identifies a virtual function by type identifier and offset.
+type VFuncID struct {
+	// GUID of type identifier.
+	GUID uint64
+	// Byte offset into the virtual table.
+	Offset uint64
+}
+
+// ConstVCall is a virtual function call with constant arguments.
+type ConstVCall struct {
+	// Virtual function.
+	VFunc VFuncID
+	// Constant arguments.
+	Args []uint64
+}
+
+// SummaryRef is a reference to a global value.
+type SummaryRef struct {
+	// Referenced global value.
+	GV *GlobalValueSummary
+	// Read-only reference.
+	ReadOnly bool
+	// Write-only reference.
+	WriteOnly bool
+}
+
+// VariableSummary is a summary of a global variable.
+type VariableSummary struct {
+	// Defining module.
+	Module *SummaryModule
+	// Global value flags.
+	Flags GVFlags
+	// Global variable flags.
+	VarFlags VarFlags
+	// Virtual functions of virtual table.
+	VTableFuncs []*VTableFunc
+	// References to global values.
+	Refs []*SummaryRef
+}
+
+// GVInfo returns the defining module and flags of the global value summary.
+func (s *VariableSummary) GVInfo() (*SummaryModule, GVFlags) {
+	return s.Module, s.Flags
+}
+
+// VarFlags are the flags of a global variable summary.
+type VarFlags struct {
+	ReadOnly        bool
+	WriteOnly       bool
+	Constant        bool
+	VCallVisibility uint64
+}
+
+// VTableFunc is a virtual function of a virtual table.
+type VTableFunc struct {
+	// Virtual function.
+	Func *GlobalValueSummary
+	// Byte offset into the virtual table.
+	Offset uint64
+}
+
+// AliasSummary is a summary of an alias.
+type AliasSummary struct {
+	// Defining module.
+	Module *SummaryModule
+	// Global value flags.
+	Flags GVFlags
+	// Aliasee.
+	Aliasee *GlobalValueSummary
+}
+
+// GVInfo returns the defining module and flags of the global value summary.
+func (s *AliasSummary) GVInfo() (*SummaryModule, GVFlags) {
+	return s.Module, s.Flags
+}
+
+// TypeIDSummary is a summary of a type identifier.
+type TypeIDSummary struct {
+	// Summary ID (without '^' prefix).
+	ID int64
+	// Type identifier name.
+	Name string
+	// Globally unique identifier of the type identifier.
+	GUID uint64
+	// Type test resolution.
+	TypeTestRes TypeTestResolution
+	// Whole-program devirtualization resolutions.
+	WPDResolutions []*WPDResolution
+}
+
+// TypeTestResolution is a type test resolution of a type identifier.
+type TypeTestResolution struct {
+	// Resolution kind (unsat, byteArray, inline, single, allOnes or unknown).
+	Kind           string
+	SizeM1BitWidth uint64
+	AlignLog2      uint64
+	SizeM1         uint64
+	BitMask        uint64
+	InlineBits     uint64
+}
+
+// WPDResolution is a whole-program devirtualization resolution of a type
+// identifier.
+type WPDResolution struct {
+	// Byte offset into the virtual table.
+	Offset uint64
+	// Resolution kind (indir, singleImpl or branchFunnel).
+	Kind string
+	// Name of the single implementation; used by singleImpl.
+	SingleImplName string
+	// Resolutions by constant arguments.
+	ResByArg []*ResByArg
+}
+
+// ResByArg is a whole-program devirtualization resolution of virtual calls
+// with the given constant arguments.
+type ResByArg struct {
+	// Constant arguments.
+	Args []uint64
+	// Resolution kind (indir, uniformRetVal, uniqueRetVal or
+	// virtualConstProp).
+	Kind string
+	Info uint64
+	Byte uint64
+	Bit  uint64
+}
+
+// TypeIDCompatibleVTable records the virtual tables compatible with a type
+// identifier.
+type TypeIDCompatibleVTable struct {
+	// Summary ID (without '^' prefix).
+	ID int64
+	// Type identifier name.
+	Name string
+	// Globally unique identifier of the type identifier.
+	GUID uint64
+	// Compatible virtual tables.
+	VTables []*CompatibleVTable
+}
+
+// CompatibleVTable is a virtual table compatible with a type identifier.
+type CompatibleVTable struct {
+	// Byte offset into the virtual table.
+	Offset uint64
+	// Virtual table.
+	VTable *GlobalValueSummary
+}
+
+// TranslateSummary translates the summary entries of the given module to an
+// equivalent summary index.
+func TranslateSummary(module *ast.Module) (*Summary, error) {
+	sgen := &summaryGen{
+		s:       &Summary{},
+		modules: make(map[int64]*SummaryModule),
+		gvs:     make(map[int64]*GlobalValueSummary),
+		typeIDs: make(map[int64]*TypeIDSummary),
+		ids:     make(map[int64]ast.LlvmNode),
+	}
+	// Index summary entries, thus making them available for resolution of
+	// summary IDs.
+	if err := sgen.indexEntries(module); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Translate summary entries.
+	for _, entity := range module.TopLevelEntities() {
+		var err error
+		switch entity := entity.(type) {
+		case *ast.GVEntry:
+			err = sgen.translateGVEntry(entity)
+		case *ast.TypeIDEntry:
+			err = sgen.translateTypeIDEntry(entity)
+		case *ast.TypeIDCompatibleVTableEntry:
+			err = sgen.translateTypeIDCompatibleVTableEntry(entity)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return sgen.s, nil
+}
+
+// summaryGen is a generator of summary indices.
+type summaryGen struct {
+	// Summary index being generated.
+	s *Summary
+	// modules maps from summary ID to module.
+	modules map[int64]*SummaryModule
+	// gvs maps from summary ID to global value summary.
+	gvs map[int64]*GlobalValueSummary
+	// typeIDs maps from summary ID to type identifier summary.
+	typeIDs map[int64]*TypeIDSummary
+	// ids maps from summary ID to AST summary entry.
+	ids map[int64]ast.LlvmNode
+}
+
+// indexEntries indexes the summary entries of the given module, translating
+// the entries which do not refer to other summary entries.
+func (sgen *summaryGen) indexEntries(module *ast.Module) error {
+	for _, entity := range module.TopLevelEntities() {
+		var name ast.SummaryID
+		switch entity := entity.(type) {
+		case *ast.ModuleEntry:
+			name = entity.Name()
+			m := &SummaryModule{ID: summaryID(name), Path: stringLit(entity.Path())}
+			hash := entity.Hash()
+			if len(hash) != len(m.Hash) {
+				return errors.Errorf("invalid hash of module %q; expected %d elements, got %d", m.Path, len(m.Hash), len(hash))
+			}
+			for i, n := range hash {
+				x := uintLit(n)
+				if x > 0xFFFFFFFF {
+					return errors.Errorf("invalid hash of module %q; element %d out of 32-bit range", m.Path, i)
+				}
+				m.Hash[i] = uint32(x)
+			}
+			sgen.modules[m.ID] = m
+			sgen.s.Modules = append(sgen.s.Modules, m)
+		case *ast.GVEntry:
+			name = entity.Name()
+			gv := &GlobalValueSummary{ID: summaryID(name)}
+			switch ident := entity.Ident().(type) {
+			case *ast.GVName:
+				gv.Name = stringLit(ident.Name())
+				gv.GUID = GUID(gv.Name)
+			case *ast.GUID:
+				gv.GUID = uintLit(ident.GUID())
+			default:
+				panic(fmt.Errorf("support for global value identifier %T not yet implemented", ident))
+			}
+			sgen.gvs[gv.ID] = gv
+			sgen.s.GlobalValues = append(sgen.s.GlobalValues, gv)
+		case *ast.TypeIDEntry:
+			name = entity.Name()
+			typeName := stringLit(entity.TypeName())
+			t := &TypeIDSummary{ID: summaryID(name), Name: typeName, GUID: GUID(typeName)}
+			sgen.typeIDs[t.ID] = t
+			sgen.s.TypeIDs = append(sgen.s.TypeIDs, t)
+		case *ast.TypeIDCompatibleVTableEntry:
+			name = entity.Name()
+		case *ast.FlagsEntry:
+			name = entity.Name()
+			sgen.s.Flags = uintLit(entity.Flags())
+		case *ast.BlockCountEntry:
+			name = entity.Name()
+			sgen.s.BlockCount = uintLit(entity.Count())
+		default:
+			continue
+		}
+		id := summaryID(name)
+		if prev, ok := sgen.ids[id]; ok {
+			return errors.Errorf("summary ID %q already present; prev `%s`, new `%s`", name.Text(), text(prev), text(entity))
+		}
+		sgen.ids[id] = entity
+	}
+	return nil
+}
+
+// --- [ Global value entries ] ------------------------------------------------
+
+// translateGVEntry translates the given AST global value summary entry.
+func (sgen *summaryGen) translateGVEntry(old *ast.GVEntry) error {
+	gv := sgen.gvs[summaryID(old.Name())]
+	for _, oldSummary := range old.Summaries() {
+		var (
+			summary GVSummary
+			err     error
+		)
+		switch oldSummary := oldSummary.(type) {
+		case *ast.FunctionSummary:
+			summary, err = sgen.functionSummary(oldSummary)
+		case *ast.VariableSummary:
+			summary, err = sgen.variableSummary(oldSummary)
+		case *ast.AliasSummary:
+			summary, err = sgen.aliasSummary(oldSummary)
+		default:
+			panic(fmt.Errorf("support for global value summary %T not yet implemented", oldSummary))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to translate summary of global value entry %q", old.Name().Text())
+		}
+		gv.Summaries = append(gv.Summaries, summary)
+	}
+	return nil
+}
+
+// functionSummary returns the function summary corresponding to the given AST
+// function summary.
+func (sgen *summaryGen) functionSummary(old *ast.FunctionSummary) (*FunctionSummary, error) {
+	s := &FunctionSummary{}
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.ModuleRefField:
+			m, err := sgen.module(field.Module())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Module = m
+		case *ast.GVFlagsField:
+			flags, err := gvFlags(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Flags = flags
+		case *ast.InstsField:
+			s.Insts = uintLit(field.Insts())
+		case *ast.FuncFlagsField:
+			flags, err := funcFlags(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.FuncFlags = flags
+		case *ast.CallsField:
+			for _, oldCall := range field.Calls() {
+				call, err := sgen.call(oldCall)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				s.Calls = append(s.Calls, call)
+			}
+		case *ast.TypeIDInfoField:
+			info, err := sgen.typeIDInfo(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.TypeIDInfo = info
+		case *ast.RefsField:
+			refs, err := sgen.refs(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Refs = refs
+		default:
+			panic(fmt.Errorf("support for function summary field %T not yet implemented", field))
+		}
+	}
+	if s.Module == nil {
+		return nil, errors.Errorf("missing module of function summary `%s`", old.Text())
+	}
+	return s, nil
+}
+
+// variableSummary returns the global variable summary corresponding to the
+// given AST global variable summary.
+func (sgen *summaryGen) variableSummary(old *ast.VariableSummary) (*VariableSummary, error) {
+	s := &VariableSummary{}
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.ModuleRefField:
+			m, err := sgen.module(field.Module())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Module = m
+		case *ast.GVFlagsField:
+			flags, err := gvFlags(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Flags = flags
+		case *ast.VarFlagsField:
+			flags, err := varFlags(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.VarFlags = flags
+		case *ast.VTableFuncsField:
+			for _, oldFunc := range field.VTableFuncs() {
+				f, err := sgen.gv(oldFunc.Func())
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				vf := &VTableFunc{Func: f, Offset: uintLit(oldFunc.Offset())}
+				s.VTableFuncs = append(s.VTableFuncs, vf)
+			}
+		case *ast.RefsField:
+			refs, err := sgen.refs(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Refs = refs
+		default:
+			panic(fmt.Errorf("support for variable summary field %T not yet implemented", field))
+		}
+	}
+	if s.Module == nil {
+		return nil, errors.Errorf("missing module of variable summary `%s`", old.Text())
+	}
+	return s, nil
+}
+
+// aliasSummary returns the alias summary corresponding to the given AST alias
+// summary.
+func (sgen *summaryGen) aliasSummary(old *ast.AliasSummary) (*AliasSummary, error) {
+	s := &AliasSummary{}
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.ModuleRefField:
+			m, err := sgen.module(field.Module())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Module = m
+		case *ast.GVFlagsField:
+			flags, err := gvFlags(field)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Flags = flags
+		case *ast.AliaseeField:
+			aliasee, err := sgen.gv(field.Aliasee())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			s.Aliasee = aliasee
+		default:
+			panic(fmt.Errorf("support for alias summary field %T not yet implemented", field))
+		}
+	}
+	if s.Module == nil {
+		return nil, errors.Errorf("missing module of alias summary `%s`", old.Text())
+	}
+	if s.Aliasee == nil {
+		return nil, errors.Errorf("missing aliasee of alias summary `%s`", old.Text())
+	}
+	return s, nil
+}
+
+// call returns the call edge corresponding to the given AST call edge.
+func (sgen *summaryGen) call(old ast.Call) (*SummaryCall, error) {
+	call := &SummaryCall{}
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.CalleeField:
+			callee, err := sgen.gv(field.Callee())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			call.Callee = callee
+		case *ast.HotnessField:
+			call.Hotness = field.Hotness().Text()
+		case *ast.SummaryIntField:
+			name, x := summaryIntField(field)
+			switch name {
+			case "relbf":
+				call.RelBF = x
+			case "tail":
+				call.Tail = x != 0
+			default:
+				return nil, errors.Errorf("invalid field %q of call edge", name)
+			}
+		default:
+			panic(fmt.Errorf("support for call edge field %T not yet implemented", field))
+		}
+	}
+	if call.Callee == nil {
+		return nil, errors.Errorf("missing callee of call edge `%s`", old.Text())
+	}
+	return call, nil
+}
+
+// typeIDInfo returns the type identifier information corresponding to the
+// given AST type identifier information.
+func (sgen *summaryGen) typeIDInfo(old *ast.TypeIDInfoField) (TypeIDInfo, error) {
+	var info TypeIDInfo
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.TypeTestsField:
+			for _, ref := range field.TypeIDs() {
+				guid, err := sgen.typeIDRef(ref)
+				if err != nil {
+					return TypeIDInfo{}, errors.WithStack(err)
+				}
+				info.TypeTests = append(info.TypeTests, guid)
+			}
+		case *ast.VFuncIDsField:
+			var vfuncs []VFuncID
+			for _, oldVFunc := range field.VFuncIDs() {
+				vfunc, err := sgen.vfuncID(oldVFunc)
+				if err != nil {
+					return TypeIDInfo{}, errors.WithStack(err)
+				}
+				vfuncs = append(vfuncs, vfunc)
+			}
+			switch kind := field.Kind().Text(); kind {
+			case "typeTestAssumeVCalls:":
+				info.TypeTestAssumeVCalls = vfuncs
+			case "typeCheckedLoadVCalls:":
+				info.TypeCheckedLoadVCalls = vfuncs
+			default:
+				panic(fmt.Errorf("support for virtual function call kind %q not yet implemented", kind))
+			}
+		case *ast.ConstVCallsField:
+			var calls []ConstVCall
+			for _, oldCall := range field.ConstVCalls() {
+				vfunc, err := sgen.vfuncID(oldCall.VFuncID())
+				if err != nil {
+					return TypeIDInfo{}, errors.WithStack(err)
+				}
+				call := ConstVCall{VFunc: vfunc, Args: uintSlice(oldCall.Args())}
+				calls = append(calls, call)
+			}
+			switch kind := field.Kind().Text(); kind {
+			case "typeTestAssumeConstVCalls:":
+				info.TypeTestAssumeConstVCalls = calls
+			case "typeCheckedLoadConstVCalls:":
+				info.TypeCheckedLoadConstVCalls = calls
+			default:
+				panic(fmt.Errorf("support for virtual function call kind %q not yet implemented", kind))
+			}
+		default:
+			panic(fmt.Errorf("support for type identifier information field %T not yet implemented", field))
+		}
+	}
+	return info, nil
+}
+
+// vfuncID returns the virtual function identifier corresponding to the given
+// AST virtual function identifier.
+func (sgen *summaryGen) vfuncID(old ast.VFuncID) (VFuncID, error) {
+	vfunc := VFuncID{Offset: uintLit(old.Offset())}
+	if n := old.TypeID(); n != nil {
+		t, err := sgen.typeID(*n)
+		if err != nil {
+			return VFuncID{}, errors.WithStack(err)
+		}
+		vfunc.GUID = t.GUID
+	} else {
+		vfunc.GUID = uintLit(old.GUID().GUID())
+	}
+	return vfunc, nil
+}
+
+// typeIDRef returns the GUID of the type identifier of the given AST type
+// identifier reference.
+func (sgen *summaryGen) typeIDRef(old ast.TypeIDRef) (uint64, error) {
+	switch old := old.(type) {
+	case *ast.SummaryID:
+		t, err := sgen.typeID(*old)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return t.GUID, nil
+	case *ast.UintLit:
+		return uintLit(*old), nil
+	default:
+		panic(fmt.Errorf("support for type identifier reference %T not yet implemented", old))
+	}
+}
+
+// refs returns the references to global values corresponding to the given AST
+// references.
+func (sgen *summaryGen) refs(old *ast.RefsField) ([]*SummaryRef, error) {
+	var refs []*SummaryRef
+	for _, oldRef := range old.Refs() {
+		gv, err := sgen.gv(oldRef.ID())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ref := &SummaryRef{GV: gv}
+		if access := oldRef.Access(); access != nil {
+			switch text := access.Text(); text {
+			case "readonly":
+				ref.ReadOnly = true
+			case "writeonly":
+				ref.WriteOnly = true
+			default:
+				panic(fmt.Errorf("support for reference access %q not yet implemented", text))
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// --- [ Type identifier entries ] ---------------------------------------------
+
+// translateTypeIDEntry translates the given AST type identifier summary entry.
+func (sgen *summaryGen) translateTypeIDEntry(old *ast.TypeIDEntry) error {
+	t := sgen.typeIDs[summaryID(old.Name())]
+	// Type test resolution.
+	for _, field := range old.TypeTestRes().Fields() {
+		switch field := field.(type) {
+		case *ast.TypeTestResKindField:
+			t.TypeTestRes.Kind = field.Kind().Text()
+		case *ast.SummaryIntField:
+			name, x := summaryIntField(field)
+			switch name {
+			case "sizeM1BitWidth":
+				t.TypeTestRes.SizeM1BitWidth = x
+			case "alignLog2":
+				t.TypeTestRes.AlignLog2 = x
+			case "sizeM1":
+				t.TypeTestRes.SizeM1 = x
+			case "bitMask":
+				t.TypeTestRes.BitMask = x
+			case "inlineBits":
+				t.TypeTestRes.InlineBits = x
+			default:
+				return errors.Errorf("invalid field %q of type test resolution of type identifier %q", name, t.Name)
+			}
+		default:
+			panic(fmt.Errorf("support for type test resolution field %T not yet implemented", field))
+		}
+	}
+	if len(t.TypeTestRes.Kind) == 0 {
+		return errors.Errorf("missing kind of type test resolution of type identifier %q", t.Name)
+	}
+	// Whole-program devirtualization resolutions.
+	for _, oldRes := range old.WPDResolutions() {
+		res := &WPDResolution{Offset: uintLit(oldRes.Offset())}
+		for _, field := range oldRes.Fields() {
+			switch field := field.(type) {
+			case *ast.WPDResKindField:
+				res.Kind = field.Kind().Text()
+			case *ast.SingleImplNameField:
+				res.SingleImplName = stringLit(field.Name())
+			case *ast.ResByArgField:
+				for _, oldByArg := range field.ResByArgs() {
+					byArg, err := resByArg(oldByArg)
+					if err != nil {
+						return errors.Wrapf(err, "unable to translate resolution of type identifier %q", t.Name)
+					}
+					res.ResByArg = append(res.ResByArg, byArg)
+				}
+			default:
+				panic(fmt.Errorf("support for whole-program devirtualization resolution field %T not yet implemented", field))
+			}
+		}
+		t.WPDResolutions = append(t.WPDResolutions, res)
+	}
+	return nil
+}
+
+// resByArg returns the resolution by constant arguments corresponding to the
+// given AST resolution by constant arguments.
+func resByArg(old ast.ResByArg) (*ResByArg, error) {
+	res := &ResByArg{Args: uintSlice(old.Args())}
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.ByArgKindField:
+			res.Kind = field.Kind().Text()
+		case *ast.SummaryIntField:
+			name, x := summaryIntField(field)
+			switch name {
+			case "info":
+				res.Info = x
+			case "byte":
+				res.Byte = x
+			case "bit":
+				res.Bit = x
+			default:
+				return nil, errors.Errorf("invalid field %q of resolution by argument", name)
+			}
+		default:
+			panic(fmt.Errorf("support for resolution by argument field %T not yet implemented", field))
+		}
+	}
+	return res, nil
+}
+
+// translateTypeIDCompatibleVTableEntry translates the given AST type
+// identifier compatible virtual table entry.
+func (sgen *summaryGen) translateTypeIDCompatibleVTableEntry(old *ast.TypeIDCompatibleVTableEntry) error {
+	name := stringLit(old.TypeName())
+	t := &TypeIDCompatibleVTable{ID: summaryID(old.Name()), Name: name, GUID: GUID(name)}
+	for _, oldVTable := range old.VTables() {
+		vtable, err := sgen.gv(oldVTable.VTable())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		t.VTables = append(t.VTables, &CompatibleVTable{Offset: uintLit(oldVTable.Offset()), VTable: vtable})
+	}
+	sgen.s.TypeIDCompatibleVTables = append(sgen.s.TypeIDCompatibleVTables, t)
+	return nil
+}
+
+// ### [ Helpers ] #############################################################
+
+// module returns the module of the given summary ID.
+func (sgen *summaryGen) module(n ast.SummaryID) (*SummaryModule, error) {
+	if m, ok := sgen.modules[summaryID(n)]; ok {
+		return m, nil
+	}
+	return nil, errors.Errorf("unable to locate module of summary ID %q", n.Text())
+}
+
+// gv returns the global value summary of the given summary ID.
+func (sgen *summaryGen) gv(n ast.SummaryID) (*GlobalValueSummary, error) {
+	if gv, ok := sgen.gvs[summaryID(n)]; ok {
+		return gv, nil
+	}
+	return nil, errors.Errorf("unable to locate global value of summary ID %q", n.Text())
+}
+
+// typeID returns the type identifier summary of the given summary ID.
+func (sgen *summaryGen) typeID(n ast.SummaryID) (*TypeIDSummary, error) {
+	if t, ok := sgen.typeIDs[summaryID(n)]; ok {
+		return t, nil
+	}
+	return nil, errors.Errorf("unable to locate type identifier of summary ID %q", n.Text())
+}
+
+// gvFlags returns the global value flags corresponding to the given AST global
+// value flags.
+func gvFlags(old *ast.GVFlagsField) (GVFlags, error) {
+	var flags GVFlags
+	for _, field := range old.Fields() {
+		switch field := field.(type) {
+		case *ast.SummaryLinkageField:
+			flags.Linkage = irOptLinkage(field.Linkage())
+		case *ast.SummaryVisibilityField:
+			visibility := field.Visibility()
+			flags.Visibility = irOptVisibility(&visibility)
+		case *ast.SummaryIntField:
+			name, x := summaryIntField(field)
+			switch name {
+			case "notEligibleToImport":
+				flags.NotEligibleToImport = x != 0
+			case "live":
+				flags.Live = x != 0
+			case "dsoLocal":
+				flags.DSOLocal = x != 0
+			case "canAutoHide":
+				flags.CanAutoHide = x != 0
+			default:
+				return GVFlags{}, errors.Errorf("invalid field %q of global value flags", name)
+			}
+		default:
+			panic(fmt.Errorf("support for global value flag %T not yet implemented", field))
+		}
+	}
+	return flags, nil
+}
+
+// funcFlags returns the function flags corresponding to the given AST function
+// flags.
+func funcFlags(old *ast.FuncFlagsField) (FuncFlags, error) {
+	var flags FuncFlags
+	for _, field := range old.Fields() {
+		name, x := summaryIntField(&field)
+		set := x != 0
+		switch name {
+		case "readNone":
+			flags.ReadNone = set
+		case "readOnly":
+			flags.ReadOnly = set
+		case "noRecurse":
+			flags.NoRecurse = set
+		case "returnDoesNotAlias":
+			flags.ReturnDoesNotAlias = set
+		case "noInline":
+			flags.NoInline = set
+		case "alwaysInline":
+			flags.AlwaysInline = set
+		case "noUnwind":
+			flags.NoUnwind = set
+		case "mayThrow":
+			flags.MayThrow = set
+		case "hasUnknownCall":
+			flags.HasUnknownCall = set
+		case "mustBeUnreachable":
+			flags.MustBeUnreachable = set
+		default:
+			return FuncFlags{}, errors.Errorf("invalid field %q of function flags", name)
+		}
+	}
+	return flags, nil
+}
+
+// varFlags returns the global variable flags corresponding to the given AST
+// global variable flags.
+func varFlags(old *ast.VarFlagsField) (VarFlags, error) {
+	var flags VarFlags
+	for _, field := range old.Fields() {
+		name, x := summaryIntField(&field)
+		switch name {
+		case "readonly":
+			flags.ReadOnly = x != 0
+		case "writeonly":
+			flags.WriteOnly = x != 0
+		case "constant":
+			flags.Constant = x != 0
+		case "vcall_visibility":
+			flags.VCallVisibility = x
+		default:
+			return VarFlags{}, errors.Errorf("invalid field %q of global variable flags", name)
+		}
+	}
+	return flags, nil
+}
+
+// summaryIntField returns the field name (without ':' suffix) and value of the
+// given AST summary integer field.
+func summaryIntField(n *ast.SummaryIntField) (string, uint64) {
+	name := strings.TrimSuffix(n.Name().Text(), ":")
+	return name, uintLit(n.Val())
+}
@@ -54,11 +54,24 @@ func (fgen *funcGen) newIRValueTerm(name string, old ast.ValueTerminator) (ir.Te
 	switch old := old.(type) {
 	case *ast.InvokeTerm:
 		// Invokee type.
-		typ, err := fgen.gen.irType(old.Typ())
+		typ, err := fgen.gen.irCallType(old.Typ())
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 		return &ir.TermInvoke{LocalName: name, Typ: typ}, nil
+	case *ast.CallBrTerm:
+		// NOTE: We need to store the type of callbr terminators before invoking
+		// f.AssignIDs, since callbr terminators may be value terminators or
+		// non-value terminators based on return type.
+		typ, err := fgen.gen.irType(old.Typ())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// The type of the callbr terminator is the return type of the callee.
+		if sig, ok := typ.(*types.FuncType); ok {
+			typ = sig.RetType
+		}
+		return &ir.TermCallBr{LocalName: name, Typ: typ}, nil
 	case *ast.CatchSwitchTerm:
 		return &ir.TermCatchSwitch{LocalName: name}, nil
 	default:
@@ -116,6 +129,8 @@ func (fgen *funcGen) astToIRValueTerm(term ir.Terminator, old ast.ValueTerminato
 	switch old := old.(type) {
 	case *ast.InvokeTerm:
 		return fgen.astToIRTermInvoke(term, old)
+	case *ast.CallBrTerm:
+		return fgen.astToIRTermCallBr(term, old)
 	case *ast.CatchSwitchTerm:
 		return fgen.astToIRTermCatchSwitch(term, old)
 	default:
@@ -282,6 +297,56 @@ func (fgen *funcGen) astToIRTermInvoke(term ir.Terminator, old *ast.InvokeTerm)
 	return nil
 }
 
+// --- [ callbr ] --------------------------------------------------------------
+
+// astToIRTermCallBr translates the given AST callbr terminator into an
+// equivalent IR terminator.
+func (fgen *funcGen) astToIRTermCallBr(term ir.Terminator, old *ast.CallBrTerm) error {
+	t, ok := term.(*ir.TermCallBr)
+	if !ok {
+		panic(fmt.Errorf("invalid IR terminator for AST terminator; expected *ir.TermCallBr, got %T", term))
+	}
+	// Calling convention.
+	t.CallingConv = irOptCallingConv(old.CallingConv())
+	// TODO: handle return attributes and address space.
+	// Function arguments.
+	args, err := fgen.irArgs(old.Args())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	t.Args = args
+	// Callee.
+	//
+	// NOTE: the type of the callbr terminator records the return type; the
+	// function type (if explicitly specified) is retrieved from the AST.
+	typ, err := fgen.gen.irType(old.Typ())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	callee, err := fgen.irCallee(typ, old.Callee(), args)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	t.Callee = callee
+	// TODO: handle function attributes and operand bundles.
+	// Normal return target.
+	normal, err := fgen.irBasicBlock(old.Normal())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	t.NormalRetTarget = normal
+	// Other (indirect) return targets.
+	for _, oldOther := range old.Others() {
+		other, err := fgen.irBasicBlock(oldOther)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		t.OtherRetTargets = append(t.OtherRetTargets, other)
+	}
+	// TODO: handle metadata.
+	return nil
+}
+
 // --- [ resume ] --------------------------------------------------------------
 
 // astToIRTermResume translates the given AST resume terminator into an
@@ -0,0 +1,31 @@
+source_filename = "bitcode.c"
+target datalayout = "e-m:e-i64:64-f80:128-n8:16:32:64-S128"
+target triple = "x86_64-unknown-linux-gnu"
+
+%list = type { i32, %list* }
+
+@x = global i32 42
+@s = private unnamed_addr constant [6 x i8] c"hello\00"
+@l = global %list { i32 1, %list* null }
+@v = global <2 x double> <double 1.500000e+00, double 2.500000e+00>
+
+declare i32 @printf(i8*, ...)
+
+define i32 @sum(i32* %p, i32 %n) {
+entry:
+	br label %loop
+
+loop:
+	%i = phi i32 [ 0, %entry ], [ %i.next, %loop ]
+	%acc = phi i32 [ 0, %entry ], [ %acc.next, %loop ]
+	%q = getelementptr i32, i32* %p, i32 %i
+	%v = load i32, i32* %q
+	%acc.next = add nsw i32 %acc, %v
+	%i.next = add i32 %i, 1
+	%c = icmp ult i32 %i.next, %n
+	br i1 %c, label %loop, label %exit
+
+exit:
+	%r = call i32 (i8*, ...) @printf(i8* getelementptr inbounds ([6 x i8], [6 x i8]* @s, i64 0, i64 0), i32 %acc.next)
+	ret i32 %acc.next
+}
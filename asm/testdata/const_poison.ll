@@ -0,0 +1,12 @@
+@p = global i32 poison
+@v = global <2 x i32> <i32 poison, i32 1>
+@d = global void ()* dso_local_equivalent @f
+@n = global void ()* no_cfi @f
+
+declare void @f()
+
+define <4 x i32> @g(<4 x i32> %x) {
+	%y = shufflevector <4 x i32> %x, <4 x i32> poison, <4 x i32> <i32 0, i32 0, i32 poison, i32 poison>
+	%z = add i32 poison, 1
+	ret <4 x i32> %y
+}
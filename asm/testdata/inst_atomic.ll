@@ -0,0 +1,17 @@
+define void @f(i32* %p, i32 %v) {
+	%a = load atomic i32, i32* %p seq_cst, align 4
+	%b = load atomic volatile i32, i32* %p syncscope("agent") acquire, align 4
+	%c = load volatile i32, i32* %p
+	store atomic i32 %v, i32* %p syncscope("singlethread") release, align 4
+	store atomic volatile i32 %v, i32* %p syncscope("workgroup") monotonic, align 4
+	store volatile i32 %v, i32* %p
+	fence syncscope("agent") acq_rel
+	fence seq_cst
+	%x = cmpxchg i32* %p, i32 %a, i32 %v seq_cst monotonic
+	%y = cmpxchg weak volatile i32* %p, i32 %a, i32 %v syncscope("agent") acq_rel acquire
+	%x0 = extractvalue { i32, i1 } %x, 0
+	%r = atomicrmw add i32* %p, i32 %v seq_cst
+	%s = atomicrmw volatile xchg i32* %p, i32 %x0 syncscope("workgroup") monotonic
+	%t = atomicrmw umin i32* %p, i32 %v release
+	ret void
+}
@@ -0,0 +1,5 @@
+define void @f() {
+	fneg double 1.0
+	fneg fast float 2.0
+	ret void
+}
@@ -0,0 +1,10 @@
+@x = global i32 42
+
+define i32 @f(i32* %p) {
+	%a = alloca i32, align 4
+	store volatile i32 1, i32* %a, align 4
+	%v = load atomic i32, i32* %p syncscope("singlethread") acquire, align 4
+	%w = load volatile i32, i32* %a, align 4
+	store atomic i32 %v, i32* @x seq_cst, align 4
+	ret i32 %w
+}
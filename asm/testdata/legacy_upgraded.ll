@@ -0,0 +1,16 @@
+@str = private constant [4 x i8] c"foo\00"
+@p = global i8* getelementptr inbounds ([4 x i8], [4 x i8]* @str, i32 0, i32 0)
+
+declare i32 @printf(i8*, ...)
+
+define i32 @main(i32* %x) {
+	%1 = load i32, i32* %x, align 4
+	%2 = load atomic volatile i32, i32* %x seq_cst, align 4
+	%3 = getelementptr inbounds i32, i32* %x, i64 1
+	%4 = load i8*, i8** @p
+	%5 = call i32 (i8*, ...) @printf(i8* %4, i32 %1)
+	ret i32 %5
+}
+
+!llvm.ident = !{!0}
+!0 = !{!"clang version 3.6.0"}
@@ -0,0 +1,26 @@
+define void @f(i32 %a, i32 %b) !dbg !8 {
+  call void @llvm.dbg.value(metadata !DIArgList(i32 %a, i32 %b), metadata !12, metadata !DIExpression(DW_OP_LLVM_arg, 0, DW_OP_LLVM_arg, 1, DW_OP_plus, DW_OP_stack_value)), !dbg !14
+  ret void
+}
+
+declare void @llvm.dbg.value(metadata, metadata, metadata)
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_Fortran95, file: !1, producer: "flang", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug, globals: !2)
+!1 = !DIFile(filename: "a.f90", directory: "/tmp")
+!2 = !{}
+!3 = !{i32 2, !"Debug Info Version", i32 3}
+!4 = !DICommonBlock(scope: !8, declaration: null, name: "blk", file: !1, line: 3)
+!5 = !DIStringType(name: "character(*)!2", stringLength: !12, stringLengthExpression: !DIExpression(), size: 32, align: 8)
+!6 = !DIGenericSubrange(lowerBound: !DIExpression(DW_OP_constu, 1), upperBound: !DIExpression(DW_OP_constu, 10), stride: !DIExpression(DW_OP_constu, 4))
+!7 = !DISubrange(lowerBound: 1, upperBound: !12, stride: 4)
+!8 = distinct !DISubprogram(name: "f", scope: !1, file: !1, line: 1, type: !9, scopeLine: 1, flags: DIFlagPrototyped | DIFlagArtificial, spFlags: DISPFlagDefinition | DISPFlagMainSubprogram, unit: !0, retainedNodes: !2)
+!9 = !DISubroutineType(types: !10)
+!10 = !{null, !11, !11}
+!11 = !DIBasicType(name: "integer", size: 32, encoding: DW_ATE_signed)
+!12 = !DILocalVariable(name: "a", arg: 1, scope: !8, file: !1, line: 1, type: !11)
+!13 = !DILabel(scope: !8, name: "l", file: !1, line: 2, column: 3, isArtificial: true, coroSuspendIdx: 1)
+!14 = !DILocation(line: 1, column: 1, scope: !8)
+!15 = distinct !DIAssignID()
@@ -0,0 +1,20 @@
+source_filename = "opaque_ptr.c"
+
+%pair = type { i32, ptr }
+
+@x = global i32 42
+@p = global ptr @x
+@q = global ptr addrspace(1) null
+
+declare i32 @printf(ptr, ...)
+
+define i32 @f(ptr %s) {
+entry:
+	%t = alloca ptr
+	store ptr %s, ptr %t
+	%q = getelementptr %pair, ptr %s, i32 0, i32 1
+	%r = load ptr, ptr %q
+	%v = load i32, ptr %r
+	%n = call i32 (ptr, ...) @printf(ptr @x, i32 %v)
+	ret i32 %v
+}
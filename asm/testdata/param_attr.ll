@@ -0,0 +1,17 @@
+%T = type { i32, i64 }
+%U = type { i8 }
+
+declare void @byval(%T* byval(%T) %x)
+
+declare void @sret(%T* sret(%T) align 8 %x)
+
+declare void @byref(%U* byref(%U) %x)
+
+declare void @inalloca(%U* inalloca(%U) %x)
+
+define void @f(%T* %p, i32* %q) {
+  call void @byval(%T* byval(%T) %p)
+  call void @sret(%T* sret(%T) align 8 %p)
+  call void asm "", "=*m"(i32* elementtype(i32) %q)
+  ret void
+}
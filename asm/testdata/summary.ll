@@ -0,0 +1,38 @@
+source_filename = "summary.ll"
+target triple = "x86_64-unknown-linux-gnu"
+
+@g = global i32 1
+@vt = constant [1 x i8*] [i8* bitcast (void ()* @h to i8*)], !type !0
+
+@a = alias i32, i32* @g
+
+declare i1 @llvm.type.test(i8*, metadata)
+
+declare void @llvm.assume(i1)
+
+define internal void @h() {
+  ret void
+}
+
+define i32 @main(i8* %p) {
+  %x = load i32, i32* @g, align 4
+  call void @h()
+  %t = call i1 @llvm.type.test(i8* %p, metadata !"T")
+  call void @llvm.assume(i1 %t)
+  ret i32 %x
+}
+
+!0 = !{i64 0, !"T"}
+
+^0 = module: (path: "summary.bc", hash: (0, 0, 0, 0, 0))
+^1 = gv: (name: "llvm.type.test") ; guid = 608142985856744218
+^2 = gv: (name: "h", summaries: (function: (module: ^0, flags: (linkage: internal, visibility: default, notEligibleToImport: 0, live: 0, dsoLocal: 1, canAutoHide: 0), insts: 1))) ; guid = 4444343700116431073
+^3 = gv: (name: "llvm.assume") ; guid = 6385187066495850096
+^4 = gv: (name: "vt", summaries: (variable: (module: ^0, flags: (linkage: external, visibility: default, notEligibleToImport: 0, live: 0, dsoLocal: 0, canAutoHide: 0), varFlags: (readonly: 1, writeonly: 0, constant: 1, vcall_visibility: 0), vTableFuncs: ((virtFunc: ^2, offset: 0)), refs: (^2)))) ; guid = 10027328872143257552
+^5 = gv: (name: "a", summaries: (alias: (module: ^0, flags: (linkage: external, visibility: default, notEligibleToImport: 0, live: 0, dsoLocal: 0, canAutoHide: 0), aliasee: ^6))) ; guid = 12157170054180749580
+^6 = gv: (name: "g", summaries: (variable: (module: ^0, flags: (linkage: external, visibility: default, notEligibleToImport: 0, live: 0, dsoLocal: 0, canAutoHide: 0), varFlags: (readonly: 1, writeonly: 1, constant: 0)))) ; guid = 13146401226427987378
+^7 = gv: (name: "main", summaries: (function: (module: ^0, flags: (linkage: external, visibility: default, notEligibleToImport: 0, live: 0, dsoLocal: 0, canAutoHide: 0), insts: 5, calls: ((callee: ^2)), refs: (readonly ^6)))) ; guid = 15822663052811949562
+^8 = typeidCompatibleVTable: (name: "T", summary: ((offset: 0, ^4))) ; guid = 18085060366066379961
+^9 = blockcount: 2
+^10 = typeid: (name: "T", summary: (typeTestRes: (kind: single, sizeM1BitWidth: 0), wpdResolutions: ((offset: 0, wpdRes: (kind: singleImpl, singleImplName: "h", resByArg: (args: (1, 2), byArg: (kind: uniformRetVal, info: 1)))))))
+^11 = flags: 8
@@ -0,0 +1,12 @@
+define i32 @f(i32 %x) {
+entry:
+	%y = freeze i32 %x
+	%r = callbr i32 asm "jmp ${2:l}", "=r,r,X"(i32 %y, i8* blockaddress(@f, %indirect))
+		to label %normal [label %indirect]
+
+normal:
+	ret i32 %r
+
+indirect:
+	ret i32 0
+}
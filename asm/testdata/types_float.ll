@@ -0,0 +1,16 @@
+@b = global bfloat 0xR3F80
+@bb = global [2 x bfloat] [bfloat 0xR4000, bfloat 0xR7FC0]
+@h = global half 0xH3C00
+@p = global ppc_fp128 0xM3FF00000000000000000000000000000
+@q = global fp128 0xL00000000000000003FFF000000000000
+
+define bfloat @f(bfloat %x) {
+	%y = fadd bfloat %x, 0xR3F80
+	ret bfloat %y
+}
+
+define void @g(<256 x i32>* %p) {
+	%t = load <256 x i32>, <256 x i32>* %p
+	%a = bitcast <256 x i32> %t to x86_amx
+	ret void
+}
@@ -0,0 +1,7 @@
+%spirv.Image = type target("spirv.Image", void, 1, 0, 0, 0, 0, 0, 0)
+
+@g = external global target("spirv.DeviceEvent")
+
+declare void @f(target("spirv.Image", void, 1, 0, 0, 0, 0, 0, 0), %spirv.Image)
+
+declare target("aarch64.svcount") @h()
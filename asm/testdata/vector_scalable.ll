@@ -0,0 +1,8 @@
+define <vscale x 4 x i32> @f(<vscale x 4 x i32> %x, i32 %e) {
+entry:
+	%a = insertelement <vscale x 4 x i32> %x, i32 %e, i32 0
+	%b = shufflevector <vscale x 4 x i32> %a, <vscale x 4 x i32> undef, <vscale x 4 x i32> zeroinitializer
+	%c = extractelement <vscale x 4 x i32> %b, i32 1
+	%d = add <vscale x 4 x i32> %b, zeroinitializer
+	ret <vscale x 4 x i32> %d
+}
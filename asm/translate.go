@@ -1,12 +1,12 @@
 package asm
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/llir/l/ir"
 	"github.com/llir/l/ir/types"
 	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/mewmew/l-tm/internal/enc"
 	"github.com/pkg/errors"
 )
 
@@ -23,6 +23,81 @@ var (
 // module.
 func Translate(module *ast.Module) (*ir.Module, error) {
 	gen := newGenerator()
+	return gen.translate(module)
+}
+
+// Options specifies options for the translation of an AST module.
+type Options struct {
+	// Module, if non-nil, specifies an existing LLVM IR module to translate
+	// into. The type definitions, global variables and functions of the existing
+	// module may be referenced by the translated module, and those of the
+	// translated module are appended to the existing module.
+	//
+	// Declarations of global variables and functions already present in the
+	// existing module refer to the existing global variable or function.
+	Module *ir.Module
+	// Resolver, if non-nil, resolves named types and global identifiers not
+	// defined by the translated module (or the existing module).
+	Resolver Resolver
+	// Legacy enables the legacy dialect of LLVM IR (pre LLVM 3.7), which is
+	// upgraded to current LLVM IR during translation. The legacy dialect
+	// accepts load and getelementptr without explicit element type (e.g. `load
+	// i32* %p`), callees of pointer to function type (e.g. `call i32 (i8*,
+	// ...)* @printf(...)`) and metadata nodes prefixed by the metadata type
+	// (e.g. `!0 = metadata !{metadata !"foo"}`); the latter are rejected
+	// unless Legacy is set. As metadata is not yet translated, upgraded
+	// metadata definitions are provided by MetadataDefs.
+	Legacy bool
+}
+
+// Resolver resolves named types and global identifiers not defined by the
+// translated module.
+type Resolver interface {
+	// ResolveType returns the IR type of the given type name (without '%'
+	// prefix).
+	ResolveType(name string) (types.Type, error)
+	// ResolveGlobal returns the IR global variable or function of the given
+	// global identifier (without '@' prefix).
+	ResolveGlobal(name string) (ir.Constant, error)
+}
+
+// TranslateWithOptions translates the AST of the given module to an
+// equivalent LLVM IR module, based on the given options. A nil opts is
+// equivalent to the zero value of Options.
+func TranslateWithOptions(module *ast.Module, opts *Options) (*ir.Module, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	gen := newGenerator()
+	if opts.Module != nil {
+		gen.m = opts.Module
+		gen.indexModule(opts.Module)
+	}
+	gen.resolver = opts.Resolver
+	gen.legacy = opts.Legacy
+	return gen.translate(module)
+}
+
+// TranslateWithSourceMap translates the AST of the given module to an
+// equivalent LLVM IR module. The returned source map maps from translated IR
+// values back to the AST nodes they were translated from.
+func TranslateWithSourceMap(module *ast.Module) (*ir.Module, *SourceMap, error) {
+	gen := newGenerator()
+	gen.sm = newSourceMap()
+	m, err := gen.translate(module)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return m, gen.sm, nil
+}
+
+// translate translates the AST of the given module to an equivalent LLVM IR
+// module.
+func (gen *generator) translate(module *ast.Module) (*ir.Module, error) {
+	// Check metadata.
+	if err := checkMetadata(module, gen.legacy); err != nil {
+		return nil, errors.WithStack(err)
+	}
 	// Resolve types.
 	if DoTypeResolution {
 		typeResolutionStart := time.Now()
@@ -30,8 +105,7 @@ func Translate(module *ast.Module) (*ir.Module, error) {
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		fmt.Println("type resolution of type definitions took:", time.Since(typeResolutionStart))
-		fmt.Println()
+		observePhase(PhaseTypeResolution, time.Since(typeResolutionStart))
 	}
 	// Resolve globals.
 	if DoGlobalResolution {
@@ -40,16 +114,18 @@ func Translate(module *ast.Module) (*ir.Module, error) {
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		fmt.Println("global resolution of global variable and function declarations and definitions took:", time.Since(globalResolutionStart))
-		fmt.Println()
+		// Local indexing and translation of function bodies are reported as
+		// separate phases.
+		globalResolution := time.Since(globalResolutionStart) - gen.localIndexTime - gen.bodyTranslationTime
+		observePhase(PhaseGlobalResolution, globalResolution)
+		observePhase(PhaseLocalIndex, gen.localIndexTime)
+		observePhase(PhaseBodyTranslation, gen.bodyTranslationTime)
 	}
 	// Resolve functions.
 	// TODO: implement.
 	// Fix dummy values.
-	for _, c := range gen.todo {
-		if err := fixBlockAddressConst(c); err != nil {
-			return nil, errors.WithStack(err)
-		}
+	if err := gen.fixTodo(); err != nil {
+		return nil, errors.WithStack(err)
 	}
 	return gen.m, nil
 }
@@ -70,21 +146,132 @@ type generator struct {
 	// Fix dummy basic blocks after translation of function bodies and assignment
 	// of local IDs.
 	todo []*ir.ConstBlockAddress
+
+	// Resolver of named types and global identifiers not defined by the module;
+	// nil if not present.
+	resolver Resolver
+
+	// Linker of the module being generated; nil if not linking.
+	linker *Linker
+
+	// bodies maps from IR function to the AST definition of its function body,
+	// for functions which have yet to be materialized; nil if function bodies
+	// are translated eagerly.
+	bodies map[*ir.Function]*ast.FuncDef
+
+	// sm maps from IR values to the AST nodes they were translated from; nil if
+	// tracking of source positions is disabled.
+	sm *SourceMap
+
+	// Indicates whether the module uses opaque pointers; set when translating
+	// the first opaque pointer type, or when indexing an existing module using
+	// opaque pointers.
+	opaquePointers bool
+
+	// Indicates whether the legacy dialect of LLVM IR (pre LLVM 3.7) is
+	// accepted; see Options.Legacy.
+	legacy bool
+
+	// Accumulated time spent indexing locals and translating function bodies.
+	localIndexTime      time.Duration
+	bodyTranslationTime time.Duration
 }
 
 // newGenerator returns a new generator for translating an LLVM IR module from
 // AST to IR representation.
 func newGenerator() *generator {
 	return &generator{
-		m: &ir.Module{},
+		m:  &ir.Module{},
+		ts: make(map[string]types.Type),
+		gs: make(map[string]ir.Constant),
 	}
 }
 
+// indexModule indexes the type definitions, global variables and functions of
+// the given optional IR module, thus making them available for resolution of
+// named types and global identifiers.
+func (gen *generator) indexModule(m *ir.Module) {
+	if m == nil {
+		return
+	}
+	for _, t := range m.TypeDefs {
+		gen.ts[typeAlias(t)] = t
+	}
+	for _, g := range m.Globals {
+		gen.gs[g.GlobalName] = g
+		if isOpaquePointer(g.Typ) {
+			gen.opaquePointers = true
+		}
+	}
+	for _, f := range m.Funcs {
+		gen.gs[f.GlobalName] = f
+		if isOpaquePointer(f.Typ) {
+			gen.opaquePointers = true
+		}
+	}
+}
+
+// fixTodo fixes the dummy values recorded during translation.
+//
+// Pre-condition: translate function bodies and assign local IDs.
+func (gen *generator) fixTodo() error {
+	// Dummy values referring to functions not yet materialized are fixed once
+	// materialized.
+	var pending []*ir.ConstBlockAddress
+	for _, c := range gen.todo {
+		if _, ok := gen.bodies[c.Func]; ok {
+			pending = append(pending, c)
+			continue
+		}
+		if err := fixBlockAddressConst(c); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	gen.todo = pending
+	return nil
+}
+
+// lookupType returns the IR type of the given type name (without '%' prefix).
+// Named types not defined by the module are resolved through the resolver, if
+// present.
+func (gen *generator) lookupType(name string) (types.Type, error) {
+	if t, ok := gen.ts[name]; ok {
+		return t, nil
+	}
+	if gen.resolver == nil {
+		return nil, errors.Errorf("unable to locate type definition of named type %q", enc.Local(name))
+	}
+	t, err := gen.resolver.ResolveType(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gen.ts[name] = t
+	return t, nil
+}
+
+// lookupGlobal returns the IR value of the given global identifier (without
+// '@' prefix). Global identifiers not defined by the module are resolved
+// through the resolver, if present.
+func (gen *generator) lookupGlobal(name string) (ir.Constant, error) {
+	if v, ok := gen.gs[name]; ok {
+		return v, nil
+	}
+	if gen.resolver == nil {
+		return nil, errors.Errorf("unable to locate global identifier %q", enc.Global(name))
+	}
+	v, err := gen.resolver.ResolveGlobal(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gen.gs[name] = v
+	return v, nil
+}
+
 // global returns the IR global variable of the given name.
 func (gen *generator) global(name string) (*ir.Global, error) {
-	v, ok := gen.gs[name]
-	if !ok {
-		return nil, errors.Errorf("unable to locate global variable %q", name)
+	v, err := gen.lookupGlobal(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 	g, ok := v.(*ir.Global)
 	if !ok {
@@ -95,9 +282,9 @@ func (gen *generator) global(name string) (*ir.Global, error) {
 
 // function returns the IR function of the given name.
 func (gen *generator) function(name string) (*ir.Function, error) {
-	v, ok := gen.gs[name]
-	if !ok {
-		return nil, errors.Errorf("unable to locate function %q", name)
+	v, err := gen.lookupGlobal(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 	f, ok := v.(*ir.Function)
 	if !ok {
@@ -18,6 +18,8 @@ import (
 func (gen *generator) resolveTypeDefs(module *ast.Module) (map[string]types.Type, error) {
 	// index maps from type name to underlying AST type.
 	index := make(map[string]ast.LlvmNode)
+	// defs maps from type name to AST type definition.
+	defs := make(map[string]*ast.TypeDef)
 	// Record order of type definitions.
 	added := make(map[string]bool)
 	var order []string
@@ -47,23 +49,39 @@ func (gen *generator) resolveTypeDefs(module *ast.Module) (map[string]types.Type
 				}
 			}
 			index[alias] = typ
+			defs[alias] = entity
 		}
 	}
 
 	// Create corresponding named IR types (without bodies).
-	gen.ts = make(map[string]types.Type)
+	//
+	// existing records type definitions already present in the IR module being
+	// translated into.
+	existing := make(map[string]bool)
 	for alias, old := range index {
+		if prev, ok := gen.ts[alias]; ok {
+			if err := checkTypeRedef(alias, prev, old); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			existing[alias] = true
+			continue
+		}
 		// track is used to identify self-referential named types.
 		track := make(map[string]bool)
-		t, err := newIRType(alias, old, index, track)
+		t, err := gen.newIRType(alias, old, index, track)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 		gen.ts[alias] = t
+		gen.record(t, defs[alias])
 	}
 
 	// Translate type defintions (including bodies).
 	for alias, old := range index {
+		if _, ok := old.(*ast.OpaqueType); ok && existing[alias] {
+			// Opaque type definition refers to the existing type definition.
+			continue
+		}
 		t := gen.ts[alias]
 		_, err := gen.astToIRTypeDef(t, old)
 		if err != nil {
@@ -73,6 +91,9 @@ func (gen *generator) resolveTypeDefs(module *ast.Module) (map[string]types.Type
 
 	// Add type definitions to IR module in order of occurrence in input.
 	for _, key := range order {
+		if existing[key] {
+			continue
+		}
 		t := gen.ts[key]
 		gen.m.TypeDefs = append(gen.m.TypeDefs, t)
 	}
@@ -80,8 +101,10 @@ func (gen *generator) resolveTypeDefs(module *ast.Module) (map[string]types.Type
 }
 
 // newIRType returns a new IR type (without body) based on the given AST type.
-// Named types are resolved to their underlying type through lookup in index. An
-// error is returned for (potentially recursive) self-referential name types.
+// Named types are resolved to their underlying type through lookup in index.
+// Named types not defined in index refer to type definitions of the existing
+// IR module or the resolver, and are resolved to a copy (including body) of the
+// corresponding IR type. An error is returned for (potentially recursive) self-referential name types.
 //
 // For instance, the following is disallowed.
 //
@@ -96,7 +119,7 @@ func (gen *generator) resolveTypeDefs(module *ast.Module) (map[string]types.Type
 //
 //    ; struct type containing pointer to itself.
 //    %d = type { %d* }
-func newIRType(alias string, old ast.LlvmNode, index map[string]ast.LlvmNode, track map[string]bool) (types.Type, error) {
+func (gen *generator) newIRType(alias string, old ast.LlvmNode, index map[string]ast.LlvmNode, track map[string]bool) (types.Type, error) {
 	switch old := old.(type) {
 	case *ast.OpaqueType:
 		return &types.StructType{Alias: alias}, nil
@@ -112,6 +135,8 @@ func newIRType(alias string, old ast.LlvmNode, index map[string]ast.LlvmNode, tr
 		return &types.LabelType{Alias: alias}, nil
 	case *ast.MMXType:
 		return &types.MMXType{Alias: alias}, nil
+	case *ast.AMXType:
+		return &types.AMXType{Alias: alias}, nil
 	case *ast.MetadataType:
 		return &types.MetadataType{Alias: alias}, nil
 	case *ast.NamedType:
@@ -125,16 +150,27 @@ func newIRType(alias string, old ast.LlvmNode, index map[string]ast.LlvmNode, tr
 		}
 		track[alias] = true
 		newAlias := local(old.Name())
-		newTyp := index[newAlias]
-		return newIRType(newAlias, newTyp, index, track)
+		newTyp, ok := index[newAlias]
+		if !ok {
+			t, err := gen.lookupType(newAlias)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return copyType(t, alias), nil
+		}
+		return gen.newIRType(newAlias, newTyp, index, track)
 	case *ast.PointerType:
 		return &types.PointerType{Alias: alias}, nil
+	case *ast.OpaquePointerType:
+		return &types.PointerType{Alias: alias}, nil
 	case *ast.StructType:
 		return &types.StructType{Alias: alias}, nil
 	case *ast.PackedStructType:
 		return &types.StructType{Alias: alias}, nil
 	case *ast.TokenType:
 		return &types.TokenType{Alias: alias}, nil
+	case *ast.TargetExtType:
+		return &types.TargetExtType{Alias: alias}, nil
 	case *ast.VectorType:
 		return &types.VectorType{Alias: alias}, nil
 	case *ast.VoidType:
@@ -144,6 +180,22 @@ func newIRType(alias string, old ast.LlvmNode, index map[string]ast.LlvmNode, tr
 	}
 }
 
+// checkTypeRedef checks that the AST type definition of the given type name may
+// refer to the existing IR type definition prev. Opaque type definitions may
+// refer to any existing type definition, and struct type definitions may
+// provide the body of existing opaque struct type definitions.
+func checkTypeRedef(alias string, prev types.Type, old ast.LlvmNode) error {
+	switch old.(type) {
+	case *ast.OpaqueType:
+		return nil
+	case *ast.StructType, *ast.PackedStructType:
+		if t, ok := prev.(*types.StructType); ok && t.Opaque {
+			return nil
+		}
+	}
+	return errors.Errorf("type definition with alias %q already present; prev `%s`, new `%s`", enc.Local(alias), prev, text(old))
+}
+
 // === [ Types ] ===============================================================
 
 // astToIRTypeDef translates the AST type into an equivalent IR type. A new IR
@@ -165,18 +217,24 @@ func (gen *generator) astToIRTypeDef(t types.Type, old ast.LlvmNode) (types.Type
 		return gen.astToIRLabelType(t, old)
 	case *ast.MMXType:
 		return gen.astToIRMMXType(t, old)
+	case *ast.AMXType:
+		return gen.astToIRAMXType(t, old)
 	case *ast.MetadataType:
 		return gen.astToIRMetadataType(t, old)
 	case *ast.NamedType:
 		return gen.astToIRNamedType(t, old)
 	case *ast.PointerType:
 		return gen.astToIRPointerType(t, old)
+	case *ast.OpaquePointerType:
+		return gen.astToIROpaquePointerType(t, old)
 	case *ast.StructType:
 		return gen.astToIRStructType(t, old)
 	case *ast.PackedStructType:
 		return gen.astToIRPackedStructType(t, old)
 	case *ast.TokenType:
 		return gen.astToIRTokenType(t, old)
+	case *ast.TargetExtType:
+		return gen.astToIRTargetExtType(t, old)
 	case *ast.VectorType:
 		return gen.astToIRVectorType(t, old)
 	case *ast.VoidType:
@@ -289,6 +347,8 @@ func irFloatKind(kind ast.FloatKind) types.FloatKind {
 	switch text {
 	case "half":
 		return types.FloatKindHalf
+	case "bfloat":
+		return types.FloatKindBFloat
 	case "float":
 		return types.FloatKindFloat
 	case "double":
@@ -300,6 +360,8 @@ func irFloatKind(kind ast.FloatKind) types.FloatKind {
 	case "ppc_fp128":
 		return types.FloatKindPPCFP128
 	default:
+		// NOTE: panic since this would indicate a bug in the implementation; the
+		// floating-point kinds are restricted by the grammar.
 		panic(fmt.Errorf("support for floating-point kind %q not yet implemented", text))
 	}
 }
@@ -319,6 +381,21 @@ func (gen *generator) astToIRMMXType(t types.Type, old *ast.MMXType) (types.Type
 	return typ, nil
 }
 
+// --- [ AMX Types ] -----------------------------------------------------------
+
+func (gen *generator) astToIRAMXType(t types.Type, old *ast.AMXType) (types.Type, error) {
+	typ, ok := t.(*types.AMXType)
+	if t == nil {
+		typ = &types.AMXType{}
+	} else if !ok {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible, and would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid IR type for AST AMX type; expected *types.AMXType, got %T", t))
+	}
+	// nothing to do.
+	return typ, nil
+}
+
 // --- [ Pointer Types ] -------------------------------------------------------
 
 func (gen *generator) astToIRPointerType(t types.Type, old *ast.PointerType) (types.Type, error) {
@@ -341,6 +418,25 @@ func (gen *generator) astToIRPointerType(t types.Type, old *ast.PointerType) (ty
 	return typ, nil
 }
 
+// astToIROpaquePointerType translates the AST opaque pointer type into an
+// equivalent IR pointer type. Opaque pointer types are represented by pointer
+// types without element type (i.e. nil ElemType).
+func (gen *generator) astToIROpaquePointerType(t types.Type, old *ast.OpaquePointerType) (types.Type, error) {
+	typ, ok := t.(*types.PointerType)
+	if t == nil {
+		typ = &types.PointerType{}
+	} else if !ok {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible, and would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid IR type for AST opaque pointer type; expected *types.PointerType, got %T", t))
+	}
+	// Opaque pointer types only occur in modules using opaque pointers.
+	gen.opaquePointers = true
+	// Address space.
+	typ.AddrSpace = irOptAddrSpace(old.AddrSpace())
+	return typ, nil
+}
+
 // --- [ Vector Types ] --------------------------------------------------------
 
 func (gen *generator) astToIRVectorType(t types.Type, old *ast.VectorType) (types.Type, error) {
@@ -352,7 +448,9 @@ func (gen *generator) astToIRVectorType(t types.Type, old *ast.VectorType) (type
 		// possible, and would indicate a bug in the implementation.
 		panic(fmt.Errorf("invalid IR type for AST vector type; expected *types.VectorType, got %T", t))
 	}
-	// Vector length.
+	// Scalable vector.
+	typ.Scalable = old.Scalable() != nil
+	// Vector length; or minimum vector length if scalable.
 	len := uintLit(old.Len())
 	typ.Len = int64(len)
 	// Element type.
@@ -394,6 +492,40 @@ func (gen *generator) astToIRTokenType(t types.Type, old *ast.TokenType) (types.
 	return typ, nil
 }
 
+// --- [ Target Extension Types ] ----------------------------------------------
+
+func (gen *generator) astToIRTargetExtType(t types.Type, old *ast.TargetExtType) (types.Type, error) {
+	typ, ok := t.(*types.TargetExtType)
+	if t == nil {
+		typ = &types.TargetExtType{}
+	} else if !ok {
+		// NOTE: Panic instead of returning error as this case should not be
+		// possible, and would indicate a bug in the implementation.
+		panic(fmt.Errorf("invalid IR type for AST target extension type; expected *types.TargetExtType, got %T", t))
+	}
+	// Target extension type name.
+	typ.TypeName = stringLit(old.Name())
+	// Type parameters followed by integer parameters.
+	typ.TypeParams = nil
+	typ.IntParams = nil
+	for _, param := range old.Params() {
+		switch param := param.(type) {
+		case *ast.UintLit:
+			typ.IntParams = append(typ.IntParams, uintLit(*param))
+		default:
+			if len(typ.IntParams) > 0 {
+				return nil, errors.Errorf("invalid type parameter `%s` of target extension type %q; type parameters must precede integer parameters", text(param), typ.TypeName)
+			}
+			tp, err := gen.irType(param)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			typ.TypeParams = append(typ.TypeParams, tp)
+		}
+	}
+	return typ, nil
+}
+
 // --- [ Metadata Types ] ------------------------------------------------------
 
 func (gen *generator) astToIRMetadataType(t types.Type, old *ast.MetadataType) (types.Type, error) {
@@ -502,18 +634,162 @@ func (gen *generator) astToIRPackedStructType(t types.Type, old *ast.PackedStruc
 func (gen *generator) astToIRNamedType(t types.Type, old *ast.NamedType) (types.Type, error) {
 	// Resolve named type.
 	alias := local(old.Name())
-	typ, ok := gen.ts[alias]
-	if !ok {
-		return nil, errors.Errorf("unable to locate type definition of named type %q", enc.Local(alias))
-	}
-	return typ, nil
+	return gen.lookupType(alias)
 }
 
 // ### [ Helpers ] #############################################################
 
+// vectorOf returns a vector type with the given element type, and the same
+// length and scalable flag as the vector type shape.
+func vectorOf(shape *types.VectorType, elem types.Type) *types.VectorType {
+	return &types.VectorType{Len: shape.Len, Scalable: shape.Scalable, ElemType: elem}
+}
+
+// isOpaquePointer reports whether the given type is an opaque pointer type
+// (i.e. a pointer type without element type).
+func isOpaquePointer(t types.Type) bool {
+	ptr, ok := t.(*types.PointerType)
+	return ok && ptr.ElemType == nil
+}
+
+// typeAlias returns the type name (without '%' prefix) of the given type, or
+// the empty string if unnamed.
+func typeAlias(t types.Type) string {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return t.Alias
+	case *types.FuncType:
+		return t.Alias
+	case *types.IntType:
+		return t.Alias
+	case *types.FloatType:
+		return t.Alias
+	case *types.MMXType:
+		return t.Alias
+	case *types.AMXType:
+		return t.Alias
+	case *types.PointerType:
+		return t.Alias
+	case *types.VectorType:
+		return t.Alias
+	case *types.LabelType:
+		return t.Alias
+	case *types.TokenType:
+		return t.Alias
+	case *types.TargetExtType:
+		return t.Alias
+	case *types.MetadataType:
+		return t.Alias
+	case *types.ArrayType:
+		return t.Alias
+	case *types.StructType:
+		return t.Alias
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+}
+
+// setTypeAlias sets the type name (without '%' prefix) of the given type.
+func setTypeAlias(t types.Type, alias string) {
+	switch t := t.(type) {
+	case *types.VoidType:
+		t.Alias = alias
+	case *types.FuncType:
+		t.Alias = alias
+	case *types.IntType:
+		t.Alias = alias
+	case *types.FloatType:
+		t.Alias = alias
+	case *types.MMXType:
+		t.Alias = alias
+	case *types.AMXType:
+		t.Alias = alias
+	case *types.PointerType:
+		t.Alias = alias
+	case *types.VectorType:
+		t.Alias = alias
+	case *types.LabelType:
+		t.Alias = alias
+	case *types.TokenType:
+		t.Alias = alias
+	case *types.TargetExtType:
+		t.Alias = alias
+	case *types.MetadataType:
+		t.Alias = alias
+	case *types.ArrayType:
+		t.Alias = alias
+	case *types.StructType:
+		t.Alias = alias
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+}
+
+// copyType returns a shallow copy of the given type, with the given type name
+// (without '%' prefix).
+func copyType(t types.Type, alias string) types.Type {
+	var c types.Type
+	switch t := t.(type) {
+	case *types.VoidType:
+		tt := *t
+		c = &tt
+	case *types.FuncType:
+		tt := *t
+		c = &tt
+	case *types.IntType:
+		tt := *t
+		c = &tt
+	case *types.FloatType:
+		tt := *t
+		c = &tt
+	case *types.MMXType:
+		tt := *t
+		c = &tt
+	case *types.AMXType:
+		tt := *t
+		c = &tt
+	case *types.PointerType:
+		tt := *t
+		c = &tt
+	case *types.VectorType:
+		tt := *t
+		c = &tt
+	case *types.LabelType:
+		tt := *t
+		c = &tt
+	case *types.TokenType:
+		tt := *t
+		c = &tt
+	case *types.TargetExtType:
+		tt := *t
+		c = &tt
+	case *types.MetadataType:
+		tt := *t
+		c = &tt
+	case *types.ArrayType:
+		tt := *t
+		c = &tt
+	case *types.StructType:
+		tt := *t
+		c = &tt
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+	setTypeAlias(c, alias)
+	return c
+}
+
 // TODO: rename irType to astToIRType?
 
 // irType returns the IR type corresponding to the given AST type.
 func (gen *generator) irType(old ast.LlvmNode) (types.Type, error) {
-	return gen.astToIRTypeDef(nil, old)
+	t, err := gen.astToIRTypeDef(nil, old)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, ok := old.(*ast.NamedType); !ok {
+		// Named types are recorded by their type definitions.
+		gen.record(t, old)
+	}
+	return t, nil
 }
@@ -3,6 +3,7 @@ package asm
 import (
 	"fmt"
 
+	"github.com/llir/l/ir"
 	"github.com/llir/l/ir/types"
 	"github.com/llir/l/ir/value"
 	"github.com/mewmew/l-tm/asm/ll/ast"
@@ -13,11 +14,7 @@ func (fgen *funcGen) astToIRValue(typ types.Type, old ast.Value) (value.Value, e
 	switch old := old.(type) {
 	case *ast.GlobalIdent:
 		name := global(*old)
-		v, ok := fgen.gen.gs[name]
-		if !ok {
-			return nil, errors.Errorf("unable to locate global identifier %q", name)
-		}
-		return v, nil
+		return fgen.gen.lookupGlobal(name)
 	case *ast.LocalIdent:
 		name := local(*old)
 		v, ok := fgen.ls[name]
@@ -26,8 +23,7 @@ func (fgen *funcGen) astToIRValue(typ types.Type, old ast.Value) (value.Value, e
 		}
 		return v, nil
 	case *ast.InlineAsm:
-		// TODO: implement
-		panic("not yet implemented")
+		return irInlineAsm(typ, old), nil
 	case ast.Constant:
 		return fgen.gen.irConstant(typ, old)
 	default:
@@ -44,3 +40,16 @@ func (fgen *funcGen) astToIRTypeValue(old ast.TypeValue) (value.Value, error) {
 	// Value.
 	return fgen.astToIRValue(typ, old.Val())
 }
+
+// irInlineAsm returns the IR inline assembler expression of the given type
+// corresponding to the given AST inline assembler expression.
+func irInlineAsm(typ types.Type, old *ast.InlineAsm) *ir.InlineAsm {
+	return &ir.InlineAsm{
+		Typ:          typ,
+		Asm:          stringLit(old.Asm()),
+		Constraint:   stringLit(old.Constraints()),
+		SideEffect:   old.SideEffect() != nil,
+		AlignStack:   old.AlignStack() != nil,
+		IntelDialect: old.IntelDialect() != nil,
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mewmew/l-tm/asm"
+	"github.com/mewmew/l-tm/asm/ll/ast"
+	"github.com/pkg/errors"
+)
+
+// dumpMain dumps the LLVM IR files given by the command line arguments of the
+// dump subcommand.
+func dumpMain(args []string) {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	var (
+		format  string
+		dumpAST bool
+	)
+	fs.StringVar(&format, "format", "json", "output format (json or ll)")
+	fs.BoolVar(&dumpAST, "ast", false, "dump AST of LLVM IR assembly files instead of IR")
+	fs.Parse(args)
+	for _, path := range fs.Args() {
+		if err := dumpFile(path, format, dumpAST); err != nil {
+			log.Fatalf("%q: %+v", path, err)
+		}
+	}
+}
+
+// dumpFile dumps the given LLVM IR assembly file, LLVM bitcode file, or object
+// file with embedded LLVM bitcode to standard output in the specified format.
+// Each module is dumped as a separate JSON value.
+func dumpFile(path, format string, dumpAST bool) error {
+	if dumpAST {
+		if format != "json" {
+			return errors.Errorf("support for AST output format %q not yet implemented", format)
+		}
+		module, err := asm.ParseFile(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return ast.WriteJSON(os.Stdout, module)
+	}
+	ms, err := parseFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, m := range ms {
+		switch format {
+		case "json":
+			if err := asm.WriteJSON(os.Stdout, m); err != nil {
+				return errors.WithStack(err)
+			}
+		case "ll":
+			fmt.Println(m)
+		default:
+			return errors.Errorf("support for output format %q not yet implemented", format)
+		}
+	}
+	return nil
+}
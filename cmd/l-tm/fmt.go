@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/mewmew/l-tm/asm/format"
+	"github.com/pkg/errors"
+)
+
+// fmtMain formats the LLVM IR assembly files given by the command line
+// arguments of the fmt subcommand.
+func fmtMain(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	var write bool
+	fs.BoolVar(&write, "w", false, "write result to (source) file instead of standard output")
+	fs.Parse(args)
+	for _, llPath := range fs.Args() {
+		if err := fmtFile(llPath, write); err != nil {
+			log.Fatalf("%q: %+v", llPath, err)
+		}
+	}
+}
+
+// fmtFile formats the given LLVM IR assembly file, writing the result to
+// standard output or back to the file.
+func fmtFile(llPath string, write bool) error {
+	src, err := ioutil.ReadFile(llPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res, err := format.Source(llPath, src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if write {
+		fi, err := os.Stat(llPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(llPath, res, fi.Mode().Perm()); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+	if _, err := os.Stdout.Write(res); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
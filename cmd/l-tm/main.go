@@ -1,35 +1,171 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"time"
 
+	"github.com/llir/l/ir"
 	"github.com/mewmew/l-tm/asm"
+	"github.com/pkg/errors"
 )
 
 func main() {
+	// Subcommands.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			fmtMain(os.Args[2:])
+			return
+		case "dump":
+			dumpMain(os.Args[2:])
+			return
+		}
+	}
+	var (
+		jsonOutput bool
+		link       bool
+	)
 	flag.BoolVar(&asm.DoTypeResolution, "types", true, "enable type resolution of type definitions")
 	flag.BoolVar(&asm.DoGlobalResolution, "globals", true, "enable global resolution of global variable and function declarations and definitions")
+	flag.BoolVar(&jsonOutput, "json", false, "output per-file phase timings in JSON format")
+	flag.BoolVar(&link, "link", false, "link the given LLVM IR assembly files into a single module")
 	flag.Parse()
+	if link {
+		m, err := asm.LinkFiles(flag.Args()...)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		fmt.Print(m.Def())
+		return
+	}
+	obs := &timings{}
+	asm.Observer = obs
+	enc := json.NewEncoder(os.Stdout)
 	for _, llPath := range flag.Args() {
-		fmt.Printf("=== [ %v ] =======================\n", llPath)
-		fmt.Println()
+		obs.reset(llPath)
+		if !jsonOutput {
+			fmt.Printf("=== [ %v ] =======================\n", llPath)
+			fmt.Println()
+		}
 		fileStart := time.Now()
-		parseStart := time.Now()
-		module, err := asm.ParseFile(llPath)
+		ms, err := parseFile(llPath)
 		if err != nil {
 			log.Fatalf("%q: %+v", llPath, err)
 		}
-		fmt.Println("parsing into AST took:", time.Since(parseStart))
+		_ = ms
+		//pretty.Println(m)
+		total := time.Since(fileStart)
+		if jsonOutput {
+			if err := enc.Encode(obs.result(total)); err != nil {
+				log.Fatalf("%q: %+v", llPath, err)
+			}
+			continue
+		}
+		fmt.Println("parsing into AST took:", obs.phases[asm.PhaseParse])
 		fmt.Println()
-		m, err := asm.Translate(module)
+		if asm.DoTypeResolution {
+			fmt.Println("type resolution of type definitions took:", obs.phases[asm.PhaseTypeResolution])
+			fmt.Println()
+		}
+		if asm.DoGlobalResolution {
+			fmt.Println("global resolution of global variable and function declarations and definitions took:", obs.phases[asm.PhaseGlobalResolution])
+			fmt.Println("indexing of local identifiers took:", obs.phases[asm.PhaseLocalIndex])
+			fmt.Println("translation of function bodies took:", obs.phases[asm.PhaseBodyTranslation])
+			fmt.Println()
+		}
+		fmt.Printf("total time for file %q: %v\n", llPath, total)
+	}
+}
+
+// parseFile parses the given LLVM IR assembly file, LLVM bitcode file, or ELF
+// object file or archive with embedded LLVM bitcode into LLVM IR modules. The
+// file format is detected based on the magic number of the file.
+func parseFile(path string) ([]*ir.Module, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	magic := make([]byte, 8)
+	n, err := io.ReadFull(f, magic)
+	f.Close()
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, errors.WithStack(err)
+	}
+	switch {
+	case asm.IsBitcode(magic[:n]):
+		m, err := asm.ParseBitcodeFile(path)
 		if err != nil {
-			log.Fatalf("%q: %+v", llPath, err)
+			return nil, errors.WithStack(err)
 		}
-		_ = m
-		//pretty.Println(m)
-		fmt.Printf("total time for file %q: %v\n", llPath, time.Since(fileStart))
+		return []*ir.Module{m}, nil
+	case asm.IsObject(magic[:n]):
+		es, err := asm.ParseEmbeddedFile(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var ms []*ir.Module
+		for _, e := range es {
+			ms = append(ms, e.Module)
+		}
+		return ms, nil
+	default:
+		m, err := asm.ParseModule(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []*ir.Module{m}, nil
+	}
+}
+
+// timings records the time taken by each phase of parsing and translation of
+// an LLVM IR assembly file.
+type timings struct {
+	// Path of LLVM IR assembly file.
+	path string
+	// phases maps from phase to time taken.
+	phases map[asm.Phase]time.Duration
+}
+
+// reset prepares the recording of phase timings for the given file.
+func (t *timings) reset(path string) {
+	t.path = path
+	t.phases = make(map[asm.Phase]time.Duration)
+}
+
+// ObservePhase records the time taken by the given phase.
+func (t *timings) ObservePhase(phase asm.Phase, d time.Duration) {
+	t.phases[phase] += d
+}
+
+// fileTimings is the JSON representation of the phase timings of an LLVM IR
+// assembly file. Durations are in milliseconds.
+type fileTimings struct {
+	Path string `json:"path"`
+	// Phases maps from phase name to time taken.
+	Phases map[string]float64 `json:"phases"`
+	Total  float64            `json:"total"`
+}
+
+// result returns the JSON representation of the recorded phase timings, given
+// the total time taken by the file.
+func (t *timings) result(total time.Duration) *fileTimings {
+	res := &fileTimings{
+		Path:   t.path,
+		Phases: make(map[string]float64),
+		Total:  millis(total),
+	}
+	for phase, d := range t.phases {
+		res.Phases[phase.String()] = millis(d)
 	}
+	return res
+}
+
+// millis returns the given duration in milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
 }
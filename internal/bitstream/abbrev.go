@@ -0,0 +1,157 @@
+package bitstream
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Standard abbreviation IDs.
+const (
+	abbrevIDEndBlock       = 0
+	abbrevIDEnterSubBlock  = 1
+	abbrevIDDefineAbbrev   = 2
+	abbrevIDUnabbrevRecord = 3
+	// First application-defined abbreviation ID.
+	firstAbbrevID = 4
+)
+
+// Width in bits of abbreviation IDs outside of blocks.
+const topLevelAbbrevWidth = 2
+
+// BlockInfoID is the block ID of the standard BLOCKINFO block.
+const BlockInfoID = 0
+
+// Record codes of the BLOCKINFO block.
+const (
+	// SETBID: [blockid]
+	BlockInfoCodeSetBID = 1
+	// BLOCKNAME: [name]
+	BlockInfoCodeBlockName = 2
+	// SETRECORDNAME: [id, name]
+	BlockInfoCodeSetRecordName = 3
+)
+
+// Abbrev is an abbreviation, specifying the encoding of the record code and
+// operands of abbreviated records.
+type Abbrev struct {
+	// Abbreviation operands.
+	Ops []AbbrevOp
+}
+
+// AbbrevOp is an abbreviation operand.
+type AbbrevOp struct {
+	// Operand kind.
+	Kind OpKind
+	// Literal value of literal operands, or width in bits of fixed and variable
+	// bit rate operands.
+	Value uint64
+}
+
+// OpKind specifies the kind of an abbreviation operand.
+type OpKind uint8
+
+// Abbreviation operand kinds.
+const (
+	// Literal value.
+	OpLiteral OpKind = 0
+	// Fixed-width value.
+	OpFixed OpKind = 1
+	// Variable bit rate value.
+	OpVBR OpKind = 2
+	// Array of values, encoded by the succeeding operand.
+	OpArray OpKind = 3
+	// 6-bit character ([a-zA-Z0-9._]).
+	OpChar6 OpKind = 4
+	// Blob of bytes.
+	OpBlob OpKind = 5
+)
+
+// Literal returns a literal abbreviation operand of the given value.
+func Literal(v uint64) AbbrevOp {
+	return AbbrevOp{Kind: OpLiteral, Value: v}
+}
+
+// Fixed returns a fixed-width abbreviation operand of the given width in bits.
+func Fixed(width uint64) AbbrevOp {
+	return AbbrevOp{Kind: OpFixed, Value: width}
+}
+
+// VBR returns a variable bit rate abbreviation operand with chunks of the
+// given width in bits.
+func VBR(width uint64) AbbrevOp {
+	return AbbrevOp{Kind: OpVBR, Value: width}
+}
+
+// Array returns an array abbreviation operand; the encoding of elements is
+// specified by the succeeding operand.
+func Array() AbbrevOp {
+	return AbbrevOp{Kind: OpArray}
+}
+
+// Char6 returns a 6-bit character abbreviation operand.
+func Char6() AbbrevOp {
+	return AbbrevOp{Kind: OpChar6}
+}
+
+// Blob returns a blob abbreviation operand.
+func Blob() AbbrevOp {
+	return AbbrevOp{Kind: OpBlob}
+}
+
+// validate validates the abbreviation. Array operands must be the second to
+// last operand, and blob operands the last.
+func (a *Abbrev) validate() error {
+	for i, op := range a.Ops {
+		switch op.Kind {
+		case OpArray:
+			if i != len(a.Ops)-2 {
+				return errors.New("invalid abbreviation; array operand must be the second to last operand")
+			}
+			switch a.Ops[i+1].Kind {
+			case OpArray, OpBlob:
+				return errors.New("invalid abbreviation; array element must be scalar")
+			}
+		case OpBlob:
+			if i != len(a.Ops)-1 {
+				return errors.New("invalid abbreviation; blob operand must be the last operand")
+			}
+		}
+	}
+	return nil
+}
+
+// char6 is the alphabet of 6-bit characters.
+const char6 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._"
+
+// decodeChar6 decodes the given 6-bit character.
+func decodeChar6(v uint64) byte {
+	return char6[v&0x3F]
+}
+
+// encodeChar6 encodes the given character as a 6-bit character. The boolean
+// return value indicates success.
+func encodeChar6(c byte) (uint64, bool) {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return uint64(c - 'a'), true
+	case 'A' <= c && c <= 'Z':
+		return uint64(c-'A') + 26, true
+	case '0' <= c && c <= '9':
+		return uint64(c-'0') + 52, true
+	case c == '.':
+		return 62, true
+	case c == '_':
+		return 63, true
+	}
+	return 0, false
+}
+
+// IsChar6 reports whether the given string consists only of characters
+// representable as 6-bit characters.
+func IsChar6(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if _, ok := encodeChar6(s[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
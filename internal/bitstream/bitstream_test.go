@@ -0,0 +1,260 @@
+package bitstream
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	w := NewWriter()
+	if err := w.EnterBlock(8, 3); err != nil {
+		t.Fatalf("unable to enter block; %v", err)
+	}
+	abbrevs := []*Abbrev{
+		{Ops: []AbbrevOp{Literal(1), Fixed(4), VBR(6)}},
+		{Ops: []AbbrevOp{Literal(2), Array(), Char6()}},
+		{Ops: []AbbrevOp{Literal(3), VBR(8), Blob()}},
+	}
+	var ids []uint64
+	for _, a := range abbrevs {
+		id, err := w.DefineAbbrev(a)
+		if err != nil {
+			t.Fatalf("unable to define abbreviation; %v", err)
+		}
+		ids = append(ids, id)
+	}
+	golden := []struct {
+		// Abbreviation ID; or 0 for unabbreviated records.
+		abbrevID uint64
+		rec      *Record
+	}{
+		// i=0
+		{rec: &Record{Code: 7, Ops: []uint64{0, 1, math.MaxUint64}}},
+		// i=1
+		{abbrevID: ids[0], rec: &Record{Code: 1, Ops: []uint64{15, 1 << 40}}},
+		// i=2
+		{abbrevID: ids[1], rec: &Record{Code: 2, Ops: []uint64{'f', 'o', 'o', '.', '_', '9'}}},
+		// i=3
+		{abbrevID: ids[2], rec: &Record{Code: 3, Ops: []uint64{42}, Blob: []byte("hello")}},
+		// i=4
+		{rec: &Record{Code: 8, Ops: []uint64{}}},
+	}
+	for i, g := range golden {
+		var err error
+		if g.abbrevID == 0 {
+			err = w.WriteRecord(g.rec)
+		} else {
+			err = w.WriteAbbrevRecord(g.abbrevID, g.rec)
+		}
+		if err != nil {
+			t.Fatalf("i=%d: unable to write record; %v", i, err)
+		}
+	}
+	if err := w.ExitBlock(); err != nil {
+		t.Fatalf("unable to exit block; %v", err)
+	}
+	buf, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("unable to retrieve bitstream contents; %v", err)
+	}
+	if len(buf)%4 != 0 {
+		t.Errorf("bitstream length mismatch; expected multiple of 4, got %d", len(buf))
+	}
+	r := NewReader(buf)
+	e, err := r.Next()
+	if err != nil {
+		t.Fatalf("unable to read entry; %v", err)
+	}
+	if e.Kind != EntrySubBlock || e.BlockID != 8 {
+		t.Fatalf("entry mismatch; expected subblock with block ID 8, got %+v", e)
+	}
+	if err := r.EnterBlock(); err != nil {
+		t.Fatalf("unable to enter block; %v", err)
+	}
+	for i, g := range golden {
+		e, err := r.Next()
+		if err != nil {
+			t.Fatalf("i=%d: unable to read entry; %v", i, err)
+		}
+		if e.Kind != EntryRecord {
+			t.Fatalf("i=%d: entry kind mismatch; expected record, got %v", i, e.Kind)
+		}
+		if !reflect.DeepEqual(g.rec, e.Record) {
+			t.Errorf("i=%d: record mismatch; expected %+v, got %+v", i, g.rec, e.Record)
+		}
+	}
+	e, err = r.Next()
+	if err != nil {
+		t.Fatalf("unable to read entry; %v", err)
+	}
+	if e.Kind != EntryEndBlock {
+		t.Errorf("entry kind mismatch; expected end of block, got %v", e.Kind)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("error mismatch; expected io.EOF, got %v", err)
+	}
+}
+
+func TestSkipBlock(t *testing.T) {
+	w := NewWriter()
+	for _, id := range []uint64{8, 9} {
+		if err := w.EnterBlock(id, 3); err != nil {
+			t.Fatalf("unable to enter block; %v", err)
+		}
+		if err := w.WriteRecord(&Record{Code: id, Ops: []uint64{1, 2, 3}}); err != nil {
+			t.Fatalf("unable to write record; %v", err)
+		}
+		if err := w.ExitBlock(); err != nil {
+			t.Fatalf("unable to exit block; %v", err)
+		}
+	}
+	buf, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("unable to retrieve bitstream contents; %v", err)
+	}
+	r := NewReader(buf)
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("unable to read entry; %v", err)
+	}
+	if _, err := r.Next(); err == nil {
+		t.Errorf("expected error when reading past subblock neither entered nor skipped")
+	}
+	if err := r.SkipBlock(); err != nil {
+		t.Fatalf("unable to skip block; %v", err)
+	}
+	e, err := r.Next()
+	if err != nil {
+		t.Fatalf("unable to read entry; %v", err)
+	}
+	if e.Kind != EntrySubBlock || e.BlockID != 9 {
+		t.Errorf("entry mismatch; expected subblock with block ID 9, got %+v", e)
+	}
+}
+
+func TestReadVBR(t *testing.T) {
+	golden := []struct {
+		v     uint64
+		width uint
+	}{
+		{v: 0, width: 2},
+		{v: 3, width: 3},
+		{v: 4, width: 3},
+		{v: 1<<32 + 5, width: 6},
+		{v: math.MaxUint64, width: 8},
+		{v: math.MaxUint64, width: 32},
+	}
+	for i, g := range golden {
+		w := NewWriter()
+		w.WriteVBR(g.v, g.width)
+		buf, err := w.Bytes()
+		if err != nil {
+			t.Errorf("i=%d: unable to retrieve bitstream contents; %v", i, err)
+			continue
+		}
+		r := NewReader(buf)
+		v, err := r.ReadVBR(g.width)
+		if err != nil {
+			t.Errorf("i=%d: unable to read variable bit rate value; %v", i, err)
+			continue
+		}
+		if g.v != v {
+			t.Errorf("i=%d: value mismatch; expected %d, got %d", i, g.v, v)
+		}
+	}
+	// Variable bit rate value overflowing 64 bits.
+	w := NewWriter()
+	for i := 0; i < 20; i++ {
+		w.Write(0xFF, 8)
+	}
+	buf, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("unable to retrieve bitstream contents; %v", err)
+	}
+	if _, err := NewReader(buf).ReadVBR(8); err == nil {
+		t.Errorf("expected error when reading variable bit rate value overflowing 64 bits")
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	golden := []struct {
+		name string
+		// Abbreviation defined in the block; or nil if not present.
+		abbrev *Abbrev
+		// Writes the body of the invalid record.
+		write func(w *Writer)
+	}{
+		{
+			name: "unabbreviated record with too many operands",
+			write: func(w *Writer) {
+				w.Write(abbrevIDUnabbrevRecord, 3)
+				w.WriteVBR(1, 6)
+				w.WriteVBR(1<<60, 6)
+			},
+		},
+		{
+			name:   "array longer than bitstream",
+			abbrev: &Abbrev{Ops: []AbbrevOp{Literal(1), Array(), Fixed(8)}},
+			write: func(w *Writer) {
+				w.Write(firstAbbrevID, 3)
+				w.WriteVBR(1<<40, 6)
+			},
+		},
+		{
+			name:   "array of literals longer than bitstream",
+			abbrev: &Abbrev{Ops: []AbbrevOp{Literal(1), Array(), Literal(0)}},
+			write: func(w *Writer) {
+				w.Write(firstAbbrevID, 3)
+				w.WriteVBR(1<<40, 6)
+			},
+		},
+		{
+			name:   "blob longer than bitstream",
+			abbrev: &Abbrev{Ops: []AbbrevOp{Literal(1), Blob()}},
+			write: func(w *Writer) {
+				w.Write(firstAbbrevID, 3)
+				w.WriteVBR(16, 6)
+			},
+		},
+		{
+			name:   "blob length overflowing offset",
+			abbrev: &Abbrev{Ops: []AbbrevOp{Literal(1), Blob()}},
+			write: func(w *Writer) {
+				w.Write(firstAbbrevID, 3)
+				w.WriteVBR(math.MaxUint64-3, 6)
+			},
+		},
+		{
+			name: "undefined abbreviation",
+			write: func(w *Writer) {
+				w.Write(firstAbbrevID, 3)
+			},
+		},
+	}
+	for _, g := range golden {
+		w := NewWriter()
+		if err := w.EnterBlock(8, 3); err != nil {
+			t.Fatalf("%s: unable to enter block; %v", g.name, err)
+		}
+		if g.abbrev != nil {
+			if _, err := w.DefineAbbrev(g.abbrev); err != nil {
+				t.Fatalf("%s: unable to define abbreviation; %v", g.name, err)
+			}
+		}
+		g.write(w)
+		// NOTE: the block is not exited, as the record is truncated.
+		r := NewReader(w.buf)
+		if _, err := r.Next(); err != nil {
+			t.Errorf("%s: unable to read entry; %v", g.name, err)
+			continue
+		}
+		if err := r.EnterBlock(); err != nil {
+			t.Errorf("%s: unable to enter block; %v", g.name, err)
+			continue
+		}
+		if _, err := r.Next(); err == nil {
+			t.Errorf("%s: expected error when reading invalid record", g.name)
+		}
+	}
+}
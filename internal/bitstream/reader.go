@@ -0,0 +1,522 @@
+// Package bitstream implements reading and writing of the LLVM bitstream
+// container format.
+//
+// References:
+//
+//	https://llvm.org/docs/BitCodeFormat.html
+package bitstream
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Reader reads entries (blocks and records) of an LLVM bitstream.
+type Reader struct {
+	// Bitstream contents.
+	buf []byte
+	// Current bit position within buf.
+	pos uint64
+	// Stack of blocks entered; the last element is the current block.
+	blocks []*block
+	// Abbreviations of the BLOCKINFO block; maps from block ID to abbreviations
+	// of blocks with the given ID.
+	blockInfo map[uint64][]*Abbrev
+	// Subblock pending to be entered or skipped; nil if not present.
+	pending *block
+}
+
+// block is a block entered by the reader.
+type block struct {
+	// Block ID.
+	id uint64
+	// Width in bits of abbreviation IDs.
+	abbrevWidth uint
+	// Abbreviations of the block; abbreviation ID 4 is at index 0.
+	abbrevs []*Abbrev
+	// Bit position of the end of the block.
+	end uint64
+}
+
+// NewReader returns a new reader of the given bitstream. The magic number is
+// expected to have been stripped from buf.
+func NewReader(buf []byte) *Reader {
+	return &Reader{
+		buf:       buf,
+		blockInfo: make(map[uint64][]*Abbrev),
+	}
+}
+
+// EntryKind specifies the kind of a bitstream entry.
+type EntryKind uint8
+
+// Bitstream entry kinds.
+const (
+	// End of the current block.
+	EntryEndBlock EntryKind = iota + 1
+	// Start of a subblock; to be entered with EnterBlock or skipped with
+	// SkipBlock.
+	EntrySubBlock
+	// Data record.
+	EntryRecord
+)
+
+// Entry is an entry of a bitstream.
+type Entry struct {
+	// Entry kind.
+	Kind EntryKind
+	// Block ID of subblock entries.
+	BlockID uint64
+	// Record of data record entries.
+	Record *Record
+}
+
+// Record is a data record of a bitstream.
+type Record struct {
+	// Record code.
+	Code uint64
+	// Record operands.
+	Ops []uint64
+	// Blob operand; or nil if not present.
+	Blob []byte
+}
+
+// Next returns the next entry of the current block. The BLOCKINFO block and
+// abbreviation definitions are handled by the reader, and not returned. At the
+// end of the bitstream (outside of blocks), io.EOF is returned.
+func (r *Reader) Next() (*Entry, error) {
+	if r.pending != nil {
+		return nil, errors.Errorf("subblock (block ID %d) neither entered nor skipped", r.pending.id)
+	}
+	for {
+		if len(r.blocks) == 0 && r.pos+32 > uint64(len(r.buf))*8 {
+			// Top-level bitstream ends at a 32-bit boundary, possibly followed by
+			// padding.
+			return nil, io.EOF
+		}
+		abbrevID, err := r.Read(r.abbrevWidth())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch abbrevID {
+		case abbrevIDEndBlock:
+			if err := r.endBlock(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &Entry{Kind: EntryEndBlock}, nil
+		case abbrevIDEnterSubBlock:
+			b, err := r.readSubBlock()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if b.id == BlockInfoID {
+				if err := r.readBlockInfo(b); err != nil {
+					return nil, errors.WithStack(err)
+				}
+				continue
+			}
+			r.pending = b
+			return &Entry{Kind: EntrySubBlock, BlockID: b.id}, nil
+		case abbrevIDDefineAbbrev:
+			a, err := r.readAbbrev()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			cur := r.cur()
+			if cur == nil {
+				return nil, errors.New("abbreviation definition outside of block")
+			}
+			cur.abbrevs = append(cur.abbrevs, a)
+		default:
+			rec, err := r.readRecord(abbrevID)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &Entry{Kind: EntryRecord, Record: rec}, nil
+		}
+	}
+}
+
+// EnterBlock enters the subblock of the previous entry.
+func (r *Reader) EnterBlock() error {
+	b := r.pending
+	if b == nil {
+		return errors.New("no subblock to enter")
+	}
+	r.pending = nil
+	// Abbreviations of the BLOCKINFO block precede those defined by the block.
+	b.abbrevs = append([]*Abbrev(nil), r.blockInfo[b.id]...)
+	r.blocks = append(r.blocks, b)
+	return nil
+}
+
+// SkipBlock skips the subblock of the previous entry.
+func (r *Reader) SkipBlock() error {
+	b := r.pending
+	if b == nil {
+		return errors.New("no subblock to skip")
+	}
+	r.pending = nil
+	if b.end > uint64(len(r.buf))*8 {
+		return errors.Errorf("end of block (block ID %d) past end of bitstream", b.id)
+	}
+	r.pos = b.end
+	return nil
+}
+
+// BlockID returns the ID of the current block.
+func (r *Reader) BlockID() uint64 {
+	if cur := r.cur(); cur != nil {
+		return cur.id
+	}
+	return 0
+}
+
+// Offset returns the current byte offset within the bitstream, rounded up to
+// the next byte boundary.
+func (r *Reader) Offset() uint64 {
+	return (r.pos + 7) / 8
+}
+
+// Read reads a fixed-width value of the given width in bits (at most 64).
+func (r *Reader) Read(width uint) (uint64, error) {
+	if r.pos+uint64(width) > uint64(len(r.buf))*8 {
+		return 0, errors.WithStack(io.ErrUnexpectedEOF)
+	}
+	var v uint64
+	for i := uint(0); i < width; {
+		// Read the remaining bits of the current byte.
+		byteIndex, bitIndex := r.pos/8, uint(r.pos%8)
+		n := 8 - bitIndex
+		if n > width-i {
+			n = width - i
+		}
+		bits := uint64(r.buf[byteIndex]>>bitIndex) & (1<<n - 1)
+		v |= bits << i
+		i += n
+		r.pos += uint64(n)
+	}
+	return v, nil
+}
+
+// ReadVBR reads a variable bit rate value with chunks of the given width in
+// bits.
+func (r *Reader) ReadVBR(width uint) (uint64, error) {
+	hiBit := uint64(1) << (width - 1)
+	var v uint64
+	for shift := uint(0); ; shift += width - 1 {
+		if shift >= 64 {
+			return 0, errors.New("variable bit rate value overflows 64 bits")
+		}
+		chunk, err := r.Read(width)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		v |= (chunk &^ hiBit) << shift
+		if chunk&hiBit == 0 {
+			return v, nil
+		}
+	}
+}
+
+// remaining returns the number of bits remaining in the bitstream.
+func (r *Reader) remaining() uint64 {
+	end := uint64(len(r.buf)) * 8
+	if r.pos >= end {
+		return 0
+	}
+	return end - r.pos
+}
+
+// align32 skips to the next 32-bit boundary.
+func (r *Reader) align32() {
+	r.pos = (r.pos + 31) &^ 31
+}
+
+// cur returns the current block; or nil if outside of blocks.
+func (r *Reader) cur() *block {
+	if len(r.blocks) == 0 {
+		return nil
+	}
+	return r.blocks[len(r.blocks)-1]
+}
+
+// abbrevWidth returns the width in bits of abbreviation IDs in the current
+// block.
+func (r *Reader) abbrevWidth() uint {
+	if cur := r.cur(); cur != nil {
+		return cur.abbrevWidth
+	}
+	return topLevelAbbrevWidth
+}
+
+// readSubBlock reads the header of a subblock (after the ENTER_SUBBLOCK
+// abbreviation ID).
+//
+//	[ENTER_SUBBLOCK, blockid(vbr8), newabbrevlen(vbr4), <align32bits>, blocklen_32]
+func (r *Reader) readSubBlock() (*block, error) {
+	id, err := r.ReadVBR(8)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	width, err := r.ReadVBR(4)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if width < 1 || width > 32 {
+		return nil, errors.Errorf("invalid abbreviation ID width %d of block (block ID %d)", width, id)
+	}
+	r.align32()
+	nwords, err := r.Read(32)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b := &block{
+		id:          id,
+		abbrevWidth: uint(width),
+		end:         r.pos + nwords*32,
+	}
+	return b, nil
+}
+
+// endBlock leaves the current block (after the END_BLOCK abbreviation ID).
+//
+//	[END_BLOCK, <align32bits>]
+func (r *Reader) endBlock() error {
+	if len(r.blocks) == 0 {
+		return errors.New("end of block outside of block")
+	}
+	r.align32()
+	r.blocks = r.blocks[:len(r.blocks)-1]
+	return nil
+}
+
+// readBlockInfo reads the contents of the BLOCKINFO block b.
+func (r *Reader) readBlockInfo(b *block) error {
+	r.blocks = append(r.blocks, b)
+	// Block ID of abbreviations being defined; set by SETBID.
+	var bid *uint64
+	for {
+		abbrevID, err := r.Read(b.abbrevWidth)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch abbrevID {
+		case abbrevIDEndBlock:
+			return r.endBlock()
+		case abbrevIDEnterSubBlock:
+			sub, err := r.readSubBlock()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			r.pos = sub.end
+		case abbrevIDDefineAbbrev:
+			a, err := r.readAbbrev()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if bid == nil {
+				return errors.New("abbreviation definition in BLOCKINFO block before SETBID record")
+			}
+			r.blockInfo[*bid] = append(r.blockInfo[*bid], a)
+		default:
+			rec, err := r.readRecord(abbrevID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			switch rec.Code {
+			case BlockInfoCodeSetBID:
+				if len(rec.Ops) < 1 {
+					return errors.New("invalid SETBID record; missing block ID")
+				}
+				id := rec.Ops[0]
+				bid = &id
+			default:
+				// Block and record names (BLOCKNAME and SETRECORDNAME) are only
+				// used for debugging, and ignored.
+			}
+		}
+	}
+}
+
+// readAbbrev reads an abbreviation definition (after the DEFINE_ABBREV
+// abbreviation ID).
+//
+//	[DEFINE_ABBREV, numabbrevops(vbr5), abbrevop0, abbrevop1, ...]
+func (r *Reader) readAbbrev() (*Abbrev, error) {
+	n, err := r.ReadVBR(5)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	a := &Abbrev{}
+	for i := uint64(0); i < n; i++ {
+		isLiteral, err := r.Read(1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if isLiteral == 1 {
+			v, err := r.ReadVBR(8)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			a.Ops = append(a.Ops, AbbrevOp{Kind: OpLiteral, Value: v})
+			continue
+		}
+		enc, err := r.Read(3)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		op := AbbrevOp{Kind: OpKind(enc)}
+		switch op.Kind {
+		case OpFixed, OpVBR:
+			width, err := r.ReadVBR(5)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if width > 64 || (op.Kind == OpVBR && width == 1) {
+				return nil, errors.Errorf("invalid width %d of abbreviation operand", width)
+			}
+			op.Value = width
+		case OpArray, OpChar6, OpBlob:
+			// no value.
+		default:
+			return nil, errors.Errorf("invalid encoding %d of abbreviation operand", enc)
+		}
+		a.Ops = append(a.Ops, op)
+	}
+	if err := a.validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return a, nil
+}
+
+// readRecord reads a data record with the given abbreviation ID.
+func (r *Reader) readRecord(abbrevID uint64) (*Record, error) {
+	if abbrevID == abbrevIDUnabbrevRecord {
+		// [UNABBREV_RECORD, code(vbr6), numops(vbr6), op0(vbr6), op1(vbr6), ...]
+		code, err := r.ReadVBR(6)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		n, err := r.ReadVBR(6)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Each operand occupies at least 6 bits; bound the number of operands
+		// before allocating.
+		if n > r.remaining()/6 {
+			return nil, errors.WithStack(io.ErrUnexpectedEOF)
+		}
+		rec := &Record{Code: code, Ops: make([]uint64, 0, n)}
+		for i := uint64(0); i < n; i++ {
+			op, err := r.ReadVBR(6)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			rec.Ops = append(rec.Ops, op)
+		}
+		return rec, nil
+	}
+	cur := r.cur()
+	index := abbrevID - firstAbbrevID
+	if cur == nil || index >= uint64(len(cur.abbrevs)) {
+		return nil, errors.Errorf("invalid abbreviation ID %d", abbrevID)
+	}
+	a := cur.abbrevs[index]
+	var vals []uint64
+	var blob []byte
+	for i := 0; i < len(a.Ops); i++ {
+		op := a.Ops[i]
+		switch op.Kind {
+		case OpArray:
+			// Array length followed by elements encoded by the succeeding
+			// operand.
+			n, err := r.ReadVBR(6)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			i++
+			elem := a.Ops[i]
+			// Bound the array length by the remaining bits, as each element
+			// occupies at least the width of its encoding; literal elements
+			// occupy no bits but are bound by one element per remaining bit.
+			maxLen := r.remaining()
+			if width := elemWidth(elem); width > 0 {
+				maxLen /= width
+			}
+			if n > maxLen {
+				return nil, errors.WithStack(io.ErrUnexpectedEOF)
+			}
+			for j := uint64(0); j < n; j++ {
+				v, err := r.readScalar(elem)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				vals = append(vals, v)
+			}
+		case OpBlob:
+			// [len(vbr6), <align32bits>, bytes, <align32bits>]
+			n, err := r.ReadVBR(6)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			r.align32()
+			start := r.pos / 8
+			// Compare against the remaining length to prevent start+n from
+			// overflowing.
+			if start > uint64(len(r.buf)) || n > uint64(len(r.buf))-start {
+				return nil, errors.WithStack(io.ErrUnexpectedEOF)
+			}
+			blob = r.buf[start : start+n]
+			r.pos += n * 8
+			r.align32()
+		default:
+			v, err := r.readScalar(op)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			vals = append(vals, v)
+		}
+	}
+	if len(vals) == 0 {
+		return nil, errors.Errorf("invalid abbreviated record; missing record code")
+	}
+	return &Record{Code: vals[0], Ops: vals[1:], Blob: blob}, nil
+}
+
+// readScalar reads a scalar value encoded by the given abbreviation operand.
+func (r *Reader) readScalar(op AbbrevOp) (uint64, error) {
+	switch op.Kind {
+	case OpLiteral:
+		return op.Value, nil
+	case OpFixed:
+		return r.Read(uint(op.Value))
+	case OpVBR:
+		if op.Value == 0 {
+			return 0, nil
+		}
+		return r.ReadVBR(uint(op.Value))
+	case OpChar6:
+		v, err := r.Read(6)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return uint64(decodeChar6(v)), nil
+	default:
+		return 0, errors.Errorf("invalid scalar abbreviation operand kind %d", op.Kind)
+	}
+}
+
+// elemWidth returns the minimum width in bits of a scalar value encoded by the
+// given abbreviation operand.
+func elemWidth(op AbbrevOp) uint64 {
+	switch op.Kind {
+	case OpFixed, OpVBR:
+		return op.Value
+	case OpChar6:
+		return 6
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,277 @@
+package bitstream
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Writer writes entries (blocks and records) of an LLVM bitstream.
+type Writer struct {
+	// Bitstream contents.
+	buf []byte
+	// Current bit position within buf.
+	pos uint64
+	// Stack of blocks entered; the last element is the current block.
+	blocks []*wblock
+}
+
+// wblock is a block entered by the writer.
+type wblock struct {
+	// Block ID.
+	id uint64
+	// Width in bits of abbreviation IDs.
+	abbrevWidth uint
+	// Abbreviations of the block; abbreviation ID 4 is at index 0.
+	abbrevs []*Abbrev
+	// Byte offset of the block length word.
+	lenOffset uint64
+}
+
+// NewWriter returns a new writer of an LLVM bitstream. The magic number is not
+// written by the writer.
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Bytes returns the contents of the bitstream, padded to a 32-bit boundary.
+//
+// Pre-condition: all blocks have been exited.
+func (w *Writer) Bytes() ([]byte, error) {
+	if len(w.blocks) > 0 {
+		return nil, errors.Errorf("block (block ID %d) not exited", w.cur().id)
+	}
+	w.align32()
+	return w.buf, nil
+}
+
+// EnterBlock enters a subblock of the given block ID, using abbreviation IDs of
+// the given width in bits.
+//
+//	[ENTER_SUBBLOCK, blockid(vbr8), newabbrevlen(vbr4), <align32bits>, blocklen_32]
+func (w *Writer) EnterBlock(id uint64, abbrevWidth uint) error {
+	if abbrevWidth < 1 || abbrevWidth > 32 {
+		return errors.Errorf("invalid abbreviation ID width %d of block (block ID %d)", abbrevWidth, id)
+	}
+	w.Write(abbrevIDEnterSubBlock, w.abbrevWidth())
+	w.WriteVBR(id, 8)
+	w.WriteVBR(uint64(abbrevWidth), 4)
+	w.align32()
+	b := &wblock{
+		id:          id,
+		abbrevWidth: abbrevWidth,
+		lenOffset:   w.pos / 8,
+	}
+	// Block length; filled in by ExitBlock.
+	w.Write(0, 32)
+	w.blocks = append(w.blocks, b)
+	return nil
+}
+
+// ExitBlock exits the current block.
+//
+//	[END_BLOCK, <align32bits>]
+func (w *Writer) ExitBlock() error {
+	b := w.cur()
+	if b == nil {
+		return errors.New("end of block outside of block")
+	}
+	w.Write(abbrevIDEndBlock, b.abbrevWidth)
+	w.align32()
+	// Number of 32-bit words of the block, excluding the block length word.
+	nwords := (w.pos/8 - b.lenOffset - 4) / 4
+	binary.LittleEndian.PutUint32(w.buf[b.lenOffset:], uint32(nwords))
+	w.blocks = w.blocks[:len(w.blocks)-1]
+	return nil
+}
+
+// DefineAbbrev defines the given abbreviation in the current block, and returns
+// its abbreviation ID.
+//
+//	[DEFINE_ABBREV, numabbrevops(vbr5), abbrevop0, abbrevop1, ...]
+func (w *Writer) DefineAbbrev(a *Abbrev) (uint64, error) {
+	b := w.cur()
+	if b == nil {
+		return 0, errors.New("abbreviation definition outside of block")
+	}
+	if err := a.validate(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	w.Write(abbrevIDDefineAbbrev, b.abbrevWidth)
+	w.WriteVBR(uint64(len(a.Ops)), 5)
+	for _, op := range a.Ops {
+		if op.Kind == OpLiteral {
+			w.Write(1, 1)
+			w.WriteVBR(op.Value, 8)
+			continue
+		}
+		w.Write(0, 1)
+		w.Write(uint64(op.Kind), 3)
+		switch op.Kind {
+		case OpFixed, OpVBR:
+			w.WriteVBR(op.Value, 5)
+		}
+	}
+	b.abbrevs = append(b.abbrevs, a)
+	return firstAbbrevID + uint64(len(b.abbrevs)-1), nil
+}
+
+// WriteRecord writes the given data record, as an unabbreviated record. The
+// blob operand of the record is not supported by unabbreviated records.
+//
+//	[UNABBREV_RECORD, code(vbr6), numops(vbr6), op0(vbr6), op1(vbr6), ...]
+func (w *Writer) WriteRecord(rec *Record) error {
+	if rec.Blob != nil {
+		return errors.New("invalid unabbreviated record; blob operand not supported")
+	}
+	w.Write(abbrevIDUnabbrevRecord, w.abbrevWidth())
+	w.WriteVBR(rec.Code, 6)
+	w.WriteVBR(uint64(len(rec.Ops)), 6)
+	for _, op := range rec.Ops {
+		w.WriteVBR(op, 6)
+	}
+	return nil
+}
+
+// WriteAbbrevRecord writes the given data record, using the abbreviation of the
+// given abbreviation ID.
+func (w *Writer) WriteAbbrevRecord(abbrevID uint64, rec *Record) error {
+	b := w.cur()
+	index := abbrevID - firstAbbrevID
+	if b == nil || abbrevID < firstAbbrevID || index >= uint64(len(b.abbrevs)) {
+		return errors.Errorf("invalid abbreviation ID %d", abbrevID)
+	}
+	a := b.abbrevs[index]
+	vals := append([]uint64{rec.Code}, rec.Ops...)
+	w.Write(abbrevID, b.abbrevWidth)
+	for i := 0; i < len(a.Ops); i++ {
+		op := a.Ops[i]
+		switch op.Kind {
+		case OpArray:
+			// Array length followed by elements encoded by the succeeding
+			// operand.
+			i++
+			elem := a.Ops[i]
+			w.WriteVBR(uint64(len(vals)), 6)
+			for _, v := range vals {
+				if err := w.writeScalar(elem, v); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			vals = nil
+		case OpBlob:
+			// [len(vbr6), <align32bits>, bytes, <align32bits>]
+			w.WriteVBR(uint64(len(rec.Blob)), 6)
+			w.align32()
+			w.buf = append(w.buf, rec.Blob...)
+			w.pos += uint64(len(rec.Blob)) * 8
+			w.align32()
+		default:
+			if len(vals) == 0 {
+				return errors.Errorf("invalid abbreviated record (code %d); too few operands for abbreviation", rec.Code)
+			}
+			if err := w.writeScalar(op, vals[0]); err != nil {
+				return errors.WithStack(err)
+			}
+			vals = vals[1:]
+		}
+	}
+	if len(vals) > 0 {
+		return errors.Errorf("invalid abbreviated record (code %d); too many operands for abbreviation", rec.Code)
+	}
+	return nil
+}
+
+// Write writes a fixed-width value of the given width in bits (at most 64).
+func (w *Writer) Write(v uint64, width uint) {
+	for i := uint(0); i < width; {
+		// Fill the remaining bits of the current byte.
+		bitIndex := uint(w.pos % 8)
+		if bitIndex == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		n := 8 - bitIndex
+		if n > width-i {
+			n = width - i
+		}
+		bits := byte(v>>i) & (1<<n - 1)
+		w.buf[len(w.buf)-1] |= bits << bitIndex
+		i += n
+		w.pos += uint64(n)
+	}
+}
+
+// WriteVBR writes a variable bit rate value with chunks of the given width in
+// bits.
+func (w *Writer) WriteVBR(v uint64, width uint) {
+	hiBit := uint64(1) << (width - 1)
+	for v >= hiBit {
+		w.Write(v&(hiBit-1)|hiBit, width)
+		v >>= width - 1
+	}
+	w.Write(v, width)
+}
+
+// writeScalar writes a scalar value encoded by the given abbreviation operand.
+func (w *Writer) writeScalar(op AbbrevOp, v uint64) error {
+	switch op.Kind {
+	case OpLiteral:
+		if v != op.Value {
+			return errors.Errorf("invalid value %d of literal abbreviation operand; expected %d", v, op.Value)
+		}
+	case OpFixed:
+		if op.Value < 64 && v>>op.Value != 0 {
+			return errors.Errorf("invalid value %d of fixed-width abbreviation operand; exceeds %d bits", v, op.Value)
+		}
+		w.Write(v, uint(op.Value))
+	case OpVBR:
+		if op.Value == 0 {
+			if v != 0 {
+				return errors.Errorf("invalid value %d of zero-width abbreviation operand", v)
+			}
+			return nil
+		}
+		w.WriteVBR(v, uint(op.Value))
+	case OpChar6:
+		if v > 0xFF {
+			return errors.Errorf("invalid value %d of 6-bit character abbreviation operand", v)
+		}
+		c, ok := encodeChar6(byte(v))
+		if !ok {
+			return errors.Errorf("invalid value %d of 6-bit character abbreviation operand", v)
+		}
+		w.Write(c, 6)
+	default:
+		return errors.Errorf("invalid scalar abbreviation operand kind %d", op.Kind)
+	}
+	return nil
+}
+
+// align32 pads the bitstream with zero bits to the next 32-bit boundary.
+func (w *Writer) align32() {
+	for w.pos%32 != 0 {
+		if w.pos%8 == 0 {
+			w.buf = append(w.buf, 0)
+			w.pos += 8
+			continue
+		}
+		w.pos += 8 - w.pos%8
+	}
+}
+
+// cur returns the current block; or nil if outside of blocks.
+func (w *Writer) cur() *wblock {
+	if len(w.blocks) == 0 {
+		return nil
+	}
+	return w.blocks[len(w.blocks)-1]
+}
+
+// abbrevWidth returns the width in bits of abbreviation IDs in the current
+// block.
+func (w *Writer) abbrevWidth() uint {
+	if cur := w.cur(); cur != nil {
+		return cur.abbrevWidth
+	}
+	return topLevelAbbrevWidth
+}